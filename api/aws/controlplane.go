@@ -0,0 +1,145 @@
+package eks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	log "github.com/kubesimplify/ksctl/api/logger"
+	util "github.com/kubesimplify/ksctl/api/utils"
+)
+
+// k3sVersion the version of k3s installed on the nodes of the HA cluster
+// the clusters created before the version was recorded in their state run v1.24.6+k3s1
+func (obj *AwsProvider) k3sVersion() string {
+	if len(obj.Config.KubernetesVersion) == 0 {
+		return "v1.24.6+k3s1"
+	}
+	release, err := util.GetKubernetesRelease(obj.Config.KubernetesVersion)
+	if err != nil {
+		return "v1.24.6+k3s1"
+	}
+	return release.K3s
+}
+
+func scriptWithoutCP_1(dbEndpoint, pubIPlb, k3sVersion string) string {
+
+	return fmt.Sprintf(`#!/bin/bash
+cat <<EOF > control-setup.sh
+#!/bin/bash
+curl -sfL https://get.k3s.io | INSTALL_K3S_VERSION="%s" sh -s - server \
+	--node-taint CriticalAddonsOnly=true:NoExecute \
+	--datastore-endpoint "%s" \
+	--tls-san %s
+EOF
+
+sudo chmod +x control-setup.sh
+sudo ./control-setup.sh
+`, k3sVersion, dbEndpoint, pubIPlb)
+}
+
+func scriptWithCP_1() string {
+	return `#!/bin/bash
+sudo cat /var/lib/rancher/k3s/server/token
+`
+}
+
+func scriptCP_n(dbEndpoint, pubIPlb, token, k3sVersion string) string {
+	return fmt.Sprintf(`#!/bin/bash
+cat <<EOF > control-setupN.sh
+#!/bin/bash
+curl -sfL https://get.k3s.io | INSTALL_K3S_VERSION="%s" sh -s - server --token %s --datastore-endpoint="%s" --node-taint CriticalAddonsOnly=true:NoExecute --tls-san %s
+EOF
+
+sudo chmod +x control-setupN.sh
+sudo ./control-setupN.sh
+`, k3sVersion, token, dbEndpoint, pubIPlb)
+}
+
+func scriptKUBECONFIG() string {
+	return `#!/bin/bash
+sudo cat /etc/rancher/k3s/k3s.yaml`
+}
+
+// TODO: Add more firewall rules
+func getControlPlaneFirewallRules() []types.IpPermission {
+	return []types.IpPermission{
+		tcpRule(22, 22, "0.0.0.0/0"),
+		tcpRule(6443, 6443, "0.0.0.0/0"),
+		allTrafficRule(VPC_CIDR),
+	}
+}
+
+func (obj *AwsProvider) FetchKUBECONFIG(ctx context.Context, logging log.Logger, publicIP string) (string, error) {
+	obj.SSH_Payload.PublicIP = publicIP
+	obj.SSH_Payload.Output = ""
+	err := obj.SSH_Payload.SSHExecute(ctx, logging, util.EXEC_WITH_OUTPUT, scriptKUBECONFIG(), true)
+	if err != nil {
+		return "", err
+	}
+
+	return obj.SSH_Payload.Output, nil
+}
+
+// GetTokenFromCP_1 used to extract the K3S_TOKEN from the first Controlplane node
+func (obj *AwsProvider) GetTokenFromCP_1(ctx context.Context, logging log.Logger, publicIP string) string {
+	obj.SSH_Payload.PublicIP = publicIP
+	obj.SSH_Payload.Output = ""
+	err := obj.SSH_Payload.SSHExecute(ctx, logging, util.EXEC_WITH_OUTPUT, scriptWithCP_1(), true)
+	if err != nil {
+		return ""
+	}
+	token := obj.SSH_Payload.Output
+	obj.SSH_Payload.Output = ""
+	token = strings.Trim(token, "\n")
+	obj.Config.K3sToken = token
+
+	obj.ConfigWriter(logging, "ha")
+
+	return token
+}
+
+// HelperExecNoOutputControlPlane helps with script execution without returning us the output
+func (obj *AwsProvider) HelperExecNoOutputControlPlane(ctx context.Context, logging log.Logger, publicIP, script string, fastMode bool) error {
+	obj.SSH_Payload.PublicIP = publicIP
+	obj.SSH_Payload.Output = ""
+	return obj.SSH_Payload.SSHExecute(ctx, logging, util.EXEC_WITHOUT_OUTPUT, script, fastMode)
+}
+
+// createControlPlane is called in parallel so the network must be created before by haCreateClusterHandler
+func (obj *AwsProvider) createControlPlane(ctx context.Context, logging log.Logger, indexOfNode int) error {
+	defer obj.ConfigWriter(logging, "ha")
+
+	// security group is shared by the instances which are created in parallel
+	sgMutex.Lock()
+	if len(obj.Config.InfoControlPlanes.SecurityGroupID) == 0 {
+		sgID, err := obj.CreateSecurityGroup(ctx, logging, obj.ClusterName+"-cp-sg", util.NODE_ROLE_CONTROLPLANE, getControlPlaneFirewallRules())
+		updateState(func() {
+			obj.Config.InfoControlPlanes.SecurityGroupID = sgID
+		})
+		if err != nil {
+			sgMutex.Unlock()
+			return err
+		}
+	}
+	sgMutex.Unlock()
+
+	name := fmt.Sprintf("%s-cp-%d", obj.ClusterName, indexOfNode)
+	index := indexOfNode - 1
+
+	instance, err := obj.CreateInstance(ctx, logging, name, util.NODE_ROLE_CONTROLPLANE, obj.Config.InfoControlPlanes.SecurityGroupID, "")
+	if len(instance.ID) != 0 {
+		updateState(func() {
+			obj.Config.InfoControlPlanes.Names = setAt(obj.Config.InfoControlPlanes.Names, index, name)
+			obj.Config.InfoControlPlanes.InstanceIDs = setAt(obj.Config.InfoControlPlanes.InstanceIDs, index, instance.ID)
+			obj.Config.InfoControlPlanes.PublicIPs = setAt(obj.Config.InfoControlPlanes.PublicIPs, index, instance.PublicIP)
+			obj.Config.InfoControlPlanes.PrivateIPs = setAt(obj.Config.InfoControlPlanes.PrivateIPs, index, instance.PrivateIP)
+		})
+	}
+	if err != nil {
+		return err
+	}
+	logging.Info("💻 Booted Control plane VM", name)
+	return nil
+}
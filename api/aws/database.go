@@ -0,0 +1,109 @@
+package eks
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	log "github.com/kubesimplify/ksctl/api/logger"
+	util "github.com/kubesimplify/ksctl/api/utils"
+)
+
+func generateDBPassword(passwordLen int) string {
+	var password strings.Builder
+	var (
+		lowerCharSet = "abcdedfghijklmnopqrst"
+		upperCharSet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+		numberSet    = "0123456789"
+		allCharSet   = lowerCharSet + upperCharSet + numberSet
+	)
+	rand.Seed(time.Now().Unix())
+
+	for i := 0; i < passwordLen; i++ {
+		random := rand.Intn(len(allCharSet))
+		password.WriteString(string(allCharSet[random]))
+	}
+
+	inRune := []rune(password.String())
+	rand.Shuffle(len(inRune), func(i, j int) {
+		inRune[i], inRune[j] = inRune[j], inRune[i]
+	})
+
+	return string(inRune)
+}
+
+func scriptDB(password string) string {
+	return fmt.Sprintf(`#!/bin/bash
+sudo apt update
+sudo apt install -y mysql-server
+
+sudo systemctl start mysql
+
+sudo systemctl enable mysql
+
+cat <<EOF > mysqld.cnf
+[mysqld]
+user		= mysql
+bind-address		= 0.0.0.0
+#mysqlx-bind-address	= 127.0.0.1
+
+key_buffer_size		= 16M
+myisam-recover-options  = BACKUP
+log_error = /var/log/mysql/error.log
+max_binlog_size   = 100M
+
+EOF
+
+sudo mv mysqld.cnf /etc/mysql/mysql.conf.d/mysqld.cnf
+
+sudo systemctl restart mysql
+
+sudo mysql -e "create user 'ksctl' identified by '%s';"
+sudo mysql -e "create database ksctldb; grant all on ksctldb.* to 'ksctl';"
+
+`, password)
+}
+
+// TODO: Add more firewall rules
+func getDatabaseFirewallRules() []types.IpPermission {
+	return []types.IpPermission{
+		tcpRule(22, 22, "0.0.0.0/0"),
+		tcpRule(3306, 3306, VPC_CIDR),
+	}
+}
+
+func (obj *AwsProvider) createDatabase(ctx context.Context, logging log.Logger) error {
+	defer obj.ConfigWriter(logging, "ha")
+	if len(obj.Config.VpcID) == 0 || len(obj.Config.SubnetID) == 0 {
+		if err := obj.CreateNetwork(ctx, logging); err != nil {
+			return err
+		}
+	}
+	generatedPassword := generateDBPassword(20)
+
+	if len(obj.Config.InfoDatabase.SecurityGroupID) == 0 {
+		sgID, err := obj.CreateSecurityGroup(ctx, logging, obj.ClusterName+"-db-sg", util.NODE_ROLE_DATABASE, getDatabaseFirewallRules())
+		obj.Config.InfoDatabase.SecurityGroupID = sgID
+		if err != nil {
+			return err
+		}
+	}
+
+	instance, err := obj.CreateInstance(ctx, logging, obj.ClusterName+"-db", util.NODE_ROLE_DATABASE, obj.Config.InfoDatabase.SecurityGroupID, scriptDB(generatedPassword))
+	if len(instance.ID) != 0 {
+		obj.Config.InfoDatabase.Name = obj.ClusterName + "-db"
+		obj.Config.InfoDatabase.InstanceID = instance.ID
+		obj.Config.InfoDatabase.PublicIP = instance.PublicIP
+		obj.Config.InfoDatabase.PrivateIP = instance.PrivateIP
+	}
+	if err != nil {
+		return err
+	}
+
+	obj.Config.DBEndpoint = fmt.Sprintf("mysql://ksctl:%s@tcp(%s:3306)/ksctldb", generatedPassword, obj.Config.InfoDatabase.PrivateIP)
+	logging.Info("💻 Booted Database VM ", "")
+	return nil
+}
@@ -0,0 +1,211 @@
+package eks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/kubesimplify/ksctl/api/logger"
+	util "github.com/kubesimplify/ksctl/api/utils"
+)
+
+func haCreateClusterHandler(ctx context.Context, logging log.Logger, obj *AwsProvider) error {
+	if !util.IsValidName(obj.ClusterName) {
+		return fmt.Errorf("invalid cluster name: %v", obj.ClusterName)
+	}
+
+	if !isValidNodeSize(obj.Spec.Disk) {
+		return fmt.Errorf("node size {%s} is invalid", obj.Spec.Disk)
+	}
+
+	if !isValidRegion(obj.Region) {
+		return fmt.Errorf("region {%s} is invalid", obj.Region)
+	}
+
+	if err := util.IsValidNoOfControlPlanes(obj.Spec.HAControlPlaneNodes); err != nil {
+		return err
+	}
+
+	logging.Info("Started to Create your HA cluster on AWS provider")
+	defer obj.ConfigWriter(logging, "ha")
+
+	err := obj.UploadSSHKey(ctx, logging)
+	if err != nil {
+		return err
+	}
+
+	err = obj.CreateNetwork(ctx, logging)
+	if err != nil {
+		return err
+	}
+
+	err = obj.createLoadBalancer(ctx, logging)
+	if err != nil {
+		return err
+	}
+
+	err = obj.createDatabase(ctx, logging)
+	if err != nil {
+		return err
+	}
+
+	err = util.RunInParallel(obj.Spec.HAControlPlaneNodes, util.MAX_PARALLEL_NODES, func(i int) error {
+		if err := obj.createControlPlane(ctx, logging, i+1); err != nil {
+			return fmt.Errorf("%s-cp-%d: %v", obj.ClusterName, i+1, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var controlPlaneIPs = make([]string, obj.Spec.HAControlPlaneNodes)
+	for i := 0; i < obj.Spec.HAControlPlaneNodes; i++ {
+		controlPlaneIPs[i] = obj.Config.InfoControlPlanes.PrivateIPs[i] + ":6443"
+	}
+
+	err = obj.ConfigLoadBalancer(ctx, logging, controlPlaneIPs)
+	if err != nil {
+		return err
+	}
+
+	token := ""
+	mysqlEndpoint := obj.Config.DBEndpoint
+	loadBalancerPubIP := obj.Config.InfoLoadBalancer.PublicIP
+	for i := 0; i < obj.Spec.HAControlPlaneNodes; i++ {
+		if i == 0 {
+			err = obj.HelperExecNoOutputControlPlane(ctx, logging, obj.Config.InfoControlPlanes.PublicIPs[i], scriptWithoutCP_1(mysqlEndpoint, loadBalancerPubIP, obj.k3sVersion()), true)
+			if err != nil {
+				return err
+			}
+
+			token = obj.GetTokenFromCP_1(ctx, logging, obj.Config.InfoControlPlanes.PublicIPs[0])
+			if len(token) == 0 {
+				return fmt.Errorf("🚨 Cannot retrieve k3s token")
+			}
+		} else {
+			err = obj.HelperExecNoOutputControlPlane(ctx, logging, obj.Config.InfoControlPlanes.PublicIPs[i], scriptCP_n(mysqlEndpoint, loadBalancerPubIP, token, obj.k3sVersion()), true)
+			if err != nil {
+				return err
+			}
+		}
+		logging.Info("✅ Configured", fmt.Sprintf("%s-cp-%d", obj.ClusterName, i+1))
+	}
+
+	kubeconfig, err := obj.FetchKUBECONFIG(ctx, logging, obj.Config.InfoControlPlanes.PublicIPs[0])
+	if err != nil {
+		return fmt.Errorf("Cannot fetch kubeconfig\n" + err.Error())
+	}
+	err = obj.SaveKubeconfig(logging, obj.haKubeconfig(kubeconfig))
+	if err != nil {
+		return err
+	}
+
+	logging.Info("⛓  JOINING WORKER NODES", "")
+
+	err = util.RunInParallel(obj.Spec.HAWorkerNodes, util.MAX_PARALLEL_NODES, func(i int) error {
+		if err := obj.createWorkerPlane(ctx, logging, i+1); err != nil {
+			return fmt.Errorf("%s-wp-%d: %v", obj.ClusterName, i+1, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	logging.Info("Created your HA aws cluster!!🥳 🎉 ")
+
+	var printKubeconfig util.PrinterKubeconfigPATH
+	printKubeconfig = printer{ClusterName: obj.ClusterName, Region: obj.Region}
+	printKubeconfig.Printer(logging, true, 0)
+	return nil
+}
+
+func haDeleteClusterHandler(ctx context.Context, logging log.Logger, obj *AwsProvider, showMsg bool) error {
+	if !util.IsValidName(obj.ClusterName) {
+		return fmt.Errorf("invalid cluster name: %v", obj.ClusterName)
+	}
+
+	if !isValidRegion(obj.Region) {
+		return fmt.Errorf("region {%s} is invalid", obj.Region)
+	}
+
+	if showMsg {
+		logging.Note(fmt.Sprintf(`🚨	THIS IS A DESTRUCTIVE STEP MAKE SURE IF YOU WANT TO DELETE THE CLUSTER '%s'
+	`, obj.ClusterName+" "+obj.Region))
+
+		fmt.Println("Enter your choice to continue..[y/N]")
+		choice := "n"
+		unsafe := false
+		fmt.Scanf("%s", &choice)
+		if strings.Compare("y", choice) == 0 ||
+			strings.Compare("yes", choice) == 0 ||
+			strings.Compare("Y", choice) == 0 {
+			unsafe = true
+		}
+
+		if !unsafe {
+			return fmt.Errorf("permission denied")
+		}
+	}
+
+	logging.Info("start deleting the cluster...", "")
+
+	err := obj.ConfigReader(logging, "ha")
+	if err != nil {
+		return fmt.Errorf("Unable to read configuration: %v", err)
+	}
+	// the partial progress is saved so that the deletion can be retried
+	defer func() {
+		if isPresent("ha", *obj) {
+			obj.ConfigWriter(logging, "ha")
+		}
+	}()
+
+	err = obj.DeleteAllInstances(ctx, logging)
+	if err != nil {
+		return err
+	}
+
+	err = obj.DeleteAllSecurityGroups(ctx, logging)
+	if err != nil {
+		return err
+	}
+
+	err = obj.DeleteNetwork(ctx, logging)
+	if err != nil {
+		return err
+	}
+
+	err = obj.DeleteSSHKeyPair(ctx, logging)
+	if err != nil {
+		return err
+	}
+
+	if err := util.DeleteClusterState(obj.clusterRef(util.CLUSTER_TYPE_HA)); err != nil {
+		return err
+	}
+
+	var printKubeconfig util.PrinterKubeconfigPATH
+	printKubeconfig = printer{ClusterName: obj.ClusterName, Region: obj.Region}
+	printKubeconfig.Printer(logging, false, 1)
+	return nil
+}
+
+// haKubeconfig the kubeconfig of k3s points at the loadbalancer instead of the controlplane
+// and its entries are named after the cluster
+func (obj *AwsProvider) haKubeconfig(kubeconfig string) string {
+	kubeconfig = strings.Replace(kubeconfig, "127.0.0.1", obj.Config.InfoLoadBalancer.PublicIP, 1)
+	return strings.Replace(kubeconfig, "default", obj.ClusterName+"-"+obj.Region+"-ha-aws-ksctl", -1)
+}
+
+// refreshHAKubeconfig fetches the kubeconfig of the HA cluster again from its first controlplane
+func (obj *AwsProvider) refreshHAKubeconfig(ctx context.Context, logging log.Logger) error {
+	if len(obj.Config.InfoControlPlanes.PublicIPs) == 0 || len(obj.Config.InfoControlPlanes.PublicIPs[0]) == 0 || len(obj.Config.InfoLoadBalancer.PublicIP) == 0 {
+		return fmt.Errorf("the cluster %s has no controlplane or loadbalancer", obj.ClusterName)
+	}
+	kubeconfig, err := obj.FetchKUBECONFIG(ctx, logging, obj.Config.InfoControlPlanes.PublicIPs[0])
+	if err != nil {
+		return fmt.Errorf("Cannot fetch kubeconfig\n" + err.Error())
+	}
+	return obj.SaveKubeconfig(logging, obj.haKubeconfig(kubeconfig))
+}
@@ -0,0 +1,98 @@
+package eks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	log "github.com/kubesimplify/ksctl/api/logger"
+	util "github.com/kubesimplify/ksctl/api/utils"
+)
+
+func scriptLB() string {
+	return `#!/bin/bash
+sudo apt update
+sudo apt install haproxy -y
+sudo systemctl start haproxy && sudo systemctl enable haproxy
+`
+}
+
+func configLBscript(controlPlaneIPs []string) string {
+	script := `#!/bin/bash
+cat <<EOF > haproxy.cfg
+frontend kubernetes-frontend
+  bind *:6443
+  mode tcp
+  option tcplog
+  timeout client 10s
+  default_backend kubernetes-backend
+
+backend kubernetes-backend
+  timeout connect 10s
+  timeout server 10s
+  mode tcp
+  option tcp-check
+  balance roundrobin
+`
+
+	for index, controlPlaneIP := range controlPlaneIPs {
+		script += fmt.Sprintf(`  server k3sserver-%d %s check
+`, index+1, controlPlaneIP)
+	}
+
+	script += `EOF
+
+sudo mv haproxy.cfg /etc/haproxy/haproxy.cfg
+sudo systemctl restart haproxy
+`
+	return script
+}
+
+// TODO: Add more firewall rules
+func getLoadBalancerFirewallRules() []types.IpPermission {
+	return []types.IpPermission{
+		tcpRule(22, 22, "0.0.0.0/0"),
+		tcpRule(6443, 6443, "0.0.0.0/0"),
+	}
+}
+
+func (obj *AwsProvider) ConfigLoadBalancer(ctx context.Context, logging log.Logger, CPIPs []string) error {
+	getScript := configLBscript(CPIPs)
+	obj.SSH_Payload.PublicIP = obj.Config.InfoLoadBalancer.PublicIP
+	err := obj.SSH_Payload.SSHExecute(ctx, logging, util.EXEC_WITHOUT_OUTPUT, getScript, true)
+	if err == nil {
+		logging.Info("✅ Configured LoadBalancer", "")
+		return nil
+	}
+	return err
+}
+
+func (obj *AwsProvider) createLoadBalancer(ctx context.Context, logging log.Logger) error {
+	defer obj.ConfigWriter(logging, "ha")
+	if len(obj.Config.VpcID) == 0 || len(obj.Config.SubnetID) == 0 {
+		if err := obj.CreateNetwork(ctx, logging); err != nil {
+			return err
+		}
+	}
+
+	if len(obj.Config.InfoLoadBalancer.SecurityGroupID) == 0 {
+		sgID, err := obj.CreateSecurityGroup(ctx, logging, obj.ClusterName+"-lb-sg", util.NODE_ROLE_LOADBALANCER, getLoadBalancerFirewallRules())
+		obj.Config.InfoLoadBalancer.SecurityGroupID = sgID
+		if err != nil {
+			return err
+		}
+	}
+
+	instance, err := obj.CreateInstance(ctx, logging, obj.ClusterName+"-lb", util.NODE_ROLE_LOADBALANCER, obj.Config.InfoLoadBalancer.SecurityGroupID, scriptLB())
+	if len(instance.ID) != 0 {
+		obj.Config.InfoLoadBalancer.Name = obj.ClusterName + "-lb"
+		obj.Config.InfoLoadBalancer.InstanceID = instance.ID
+		obj.Config.InfoLoadBalancer.PublicIP = instance.PublicIP
+		obj.Config.InfoLoadBalancer.PrivateIP = instance.PrivateIP
+	}
+	if err != nil {
+		return err
+	}
+	logging.Info("💻 Booted LoadBalancer VM ", "")
+	return nil
+}
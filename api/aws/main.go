@@ -6,49 +6,562 @@ Kubesimplify
 package eks
 
 import (
+	"context"
 	"fmt"
-	"os"
 
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	log "github.com/kubesimplify/ksctl/api/logger"
 	util "github.com/kubesimplify/ksctl/api/utils"
 )
 
-// fetchAPIKey returns the API key from the cred/civo file store
-func fetchAPIKey() string {
+func init() {
+	util.RegisterProvider("aws", func(payload util.ClusterPayload) util.Provider {
+		return &AwsProvider{
+			AwsProvider: util.AwsProvider{
+				ClusterName: payload.ClusterName,
+				HACluster:   payload.HACluster,
+				Region:      payload.Region,
+				Spec:        payload.Spec,
+			},
+			Tags:              payload.Tags,
+			KubernetesVersion: payload.KubernetesVersion,
+			AutoApprove:       payload.AutoApprove,
+		}
+	})
+	util.RegisterCredentials("aws", util.CredentialType{
+		Fields: []util.CredentialField{
+			{Key: "access_key_id", Env: "AWS_ACCESS_KEY_ID", Prompt: "Enter your ACCESS KEY ID", Secret: true},
+			{Key: "secret_access_key", Env: "AWS_SECRET_ACCESS_KEY", Prompt: "Enter your SECRET ACCESS KEY", Secret: true},
+		},
+		Verify: verifyCredentials,
+	})
+}
+
+func Credentials(logger log.Logger) bool {
+	logger.Print("Enter your ACCESS KEY ID 👇")
+	accessKey, err := util.UserInputCredentials(logger)
+	if err != nil {
+		logger.Err(err.Error())
+		return false
+	}
 
-	_, err := os.ReadFile(util.GetPath(0, "aws"))
+	logger.Print("Enter your SECRET ACCESS KEY 👇")
+	secret, err := util.UserInputCredentials(logger)
 	if err != nil {
-		return ""
+		logger.Err(err.Error())
+		return false
 	}
 
-	return ""
+	err = util.SetCred(context.Background(), logger, "aws", map[string]string{
+		"access_key_id":     accessKey,
+		"secret_access_key": secret,
+	}, true)
+	if err != nil {
+		logger.Err(err.Error())
+		return false
+	}
+	return true
 }
 
-func Credentials() bool {
-	// _, err := os.OpenFile(util.GetPath(0, "aws"), os.O_WRONLY, 0640)
-	// if err != nil {
-	// 	fmt.Println(err.Error())
-	// 	return false
-	// }
-	acckey := ""
-	secacckey := ""
-	func() {
-		fmt.Println("Enter your ACCESS-KEY: ")
-		_, err := fmt.Scan(&acckey)
+// verifyCredentials describes the regions which is the cheapest ec2 call needing valid keys
+func verifyCredentials(ctx context.Context, cred map[string]string) error {
+	obj := &AwsProvider{}
+	obj.Region = "us-east-1"
+	obj.AccessKey, obj.Secret = cred["access_key_id"], cred["secret_access_key"]
+	_, err := getEC2Client(obj).DescribeRegions(ctx, &ec2.DescribeRegionsInput{})
+	return err
+}
+
+type AwsProvider struct {
+	util.AwsProvider
+	Config      *AwsStateCluster `json:"config"`
+	SSH_Payload *util.SSHPayload `json:"ssh___payload"`
+	// Tags given by the user for the resources of the cluster
+	Tags map[string]string `json:"tags"`
+	// KubernetesVersion the minor version of kubernetes of the cluster
+	KubernetesVersion string `json:"kubernetes_version"`
+	// AutoApprove the worker nodes are deleted without asking for the confirmation
+	AutoApprove bool `json:"auto_approve"`
+
+	ec2Client *ec2.Client
+	eksClient *eks.Client
+	iamClient *iam.Client
+	imageID   string
+}
+
+// setup loads the credentials and initializes the clients and the state
+func (obj *AwsProvider) setup(logging log.Logger) error {
+	if err := setRequiredENV_VAR(logging, obj); err != nil {
+		return err
+	}
+	obj.ec2Client = getEC2Client(obj)
+	obj.eksClient = getEKSClient(obj)
+	obj.iamClient = getIAMClient(obj)
+	obj.Config = &AwsStateCluster{ClusterName: obj.ClusterName, Region: obj.Region}
+	obj.SSH_Payload = &util.SSHPayload{
+		UserName:       "ubuntu",
+		PathPrivateKey: util.GetPath(util.SSH_PATH, "aws", "ha", obj.clusterRef(util.CLUSTER_TYPE_HA).ID()),
+	}
+	return nil
+}
+
+// AddMoreWorkerNodes adds more worker nodes to the existing HA cluster
+func (obj *AwsProvider) AddMoreWorkerNodes(ctx context.Context, logging log.Logger) error {
+	if !util.IsValidName(obj.ClusterName) {
+		return fmt.Errorf("invalid cluster name: %v", obj.ClusterName)
+	}
+	if !isValidNodeSize(obj.Spec.Disk) {
+		return fmt.Errorf("node size {%s} is invalid", obj.Spec.Disk)
+	}
+
+	if !isValidRegion(obj.Region) {
+		return fmt.Errorf("region {%s} is invalid", obj.Region)
+	}
+
+	if err := obj.setup(logging); err != nil {
+		return err
+	}
+	if !isPresent("ha", *obj) {
+		return fmt.Errorf("cluster does not exists: %v", obj.ClusterName)
+	}
+
+	err := obj.ConfigReader(logging, "ha")
+	if err != nil {
+		return fmt.Errorf("Unable to read configuration: %v", err)
+	}
+
+	// the worker planes are created in parallel so the network they share is created before
+	if len(obj.Config.VpcID) == 0 || len(obj.Config.SubnetID) == 0 {
+		if err := obj.CreateNetwork(ctx, logging); err != nil {
+			return err
+		}
+	}
+
+	logging.Info("JOINING Additional WORKER NODES", "")
+
+	noOfWorkerNodes := len(obj.Config.InfoWorkerPlanes.Names)
+
+	err = util.RunInParallel(obj.Spec.HAWorkerNodes, util.MAX_PARALLEL_NODES, func(i int) error {
+		if err := obj.createWorkerPlane(ctx, logging, i+noOfWorkerNodes+1); err != nil {
+			return fmt.Errorf("%s-wp-%d: %v", obj.ClusterName, i+noOfWorkerNodes+1, err)
+		}
+		return nil
+	})
+	if err != nil {
+		logging.Err("Failed to add more nodes..")
+		return err
+	}
+
+	logging.Info("Added more nodes 🥳 🎉 ")
+	return nil
+}
+
+// DeleteSomeWorkerNodes deletes workerNodes from existing HA cluster
+func (obj *AwsProvider) DeleteSomeWorkerNodes(ctx context.Context, logging log.Logger) error {
+	if !util.IsValidName(obj.ClusterName) {
+		return fmt.Errorf("invalid cluster name: %v", obj.ClusterName)
+	}
+
+	if !isValidRegion(obj.Region) {
+		return fmt.Errorf("region {%s} is invalid", obj.Region)
+	}
+
+	logging.Note(`🚨 ((Deleteion of nodes happens from most recent added to first created worker node))
+i.e. of workernodes 1, 2, 3, 4
+then deletion will happen from 4, 3, 2, 1
+1) make sure you first drain the no of nodes
+		kubectl drain node <node name>
+2) then delete before deleting the instance
+		kubectl delete node <node name>
+`)
+	if err := util.ConfirmNodesDeletion(logging, obj.AutoApprove); err != nil {
+		return err
+	}
+
+	if err := obj.setup(logging); err != nil {
+		return err
+	}
+	if !isPresent("ha", *obj) {
+		return fmt.Errorf("cluster does not exists: %v", obj.ClusterName)
+	}
+
+	err := obj.ConfigReader(logging, "ha")
+	if err != nil {
+		return fmt.Errorf("Unable to read configuration: %v", err)
+	}
+
+	requestedNoOfWP := obj.Spec.HAWorkerNodes
+
+	currNoOfWorkerNodes := len(obj.Config.InfoWorkerPlanes.Names)
+	if requestedNoOfWP > currNoOfWorkerNodes {
+		return fmt.Errorf("Requested no of deletion is more than present")
+	}
+
+	for i := 0; i < requestedNoOfWP; i++ {
+
+		currLen := len(obj.Config.InfoWorkerPlanes.Names)
+		// the node which failed to be created has no instance
+		if instanceID := obj.Config.InfoWorkerPlanes.InstanceIDs[currLen-1]; len(instanceID) != 0 {
+			if err := obj.DeleteInstances(ctx, logging, []string{instanceID}); err != nil {
+				return err
+			}
+		}
+
+		obj.Config.InfoWorkerPlanes.Names = obj.Config.InfoWorkerPlanes.Names[:currLen-1]
+		obj.Config.InfoWorkerPlanes.InstanceIDs = obj.Config.InfoWorkerPlanes.InstanceIDs[:currLen-1]
+		obj.Config.InfoWorkerPlanes.PrivateIPs = obj.Config.InfoWorkerPlanes.PrivateIPs[:currLen-1]
+		obj.Config.InfoWorkerPlanes.PublicIPs = obj.Config.InfoWorkerPlanes.PublicIPs[:currLen-1]
+
+		err = obj.ConfigWriter(logging, "ha")
 		if err != nil {
-			panic(err.Error())
+			return err
 		}
-		fmt.Println("Enter your SECRET-ACCESS-KEY: ")
-		_, err = fmt.Scan(&secacckey)
+	}
+
+	logging.Info("Deleted some nodes 🥳 🎉 ")
+	return nil
+}
+
+func (obj *AwsProvider) CreateCluster(ctx context.Context, logging log.Logger) error {
+	getRelease := util.GetKubernetesRelease
+	if !obj.HACluster {
+		getRelease = util.GetManagedKubernetesRelease
+	}
+	release, err := getRelease(obj.KubernetesVersion)
+	if err != nil {
+		return err
+	}
+	if err := obj.setup(logging); err != nil {
+		return err
+	}
+	if obj.HACluster {
+		obj.Config.ClusterMetadata = util.NewClusterMetadata(obj.Spec.Disk, 0)
+	} else {
+		obj.Config.ClusterMetadata = util.NewClusterMetadata(obj.Spec.Disk, obj.Spec.ManagedNodes)
+	}
+	obj.Config.Tags = obj.Tags
+	obj.Config.KubernetesVersion = release.Version
+
+	if !obj.HACluster {
+		if isPresent("managed", *obj) {
+			return fmt.Errorf("cluster already exists: %v", obj.ClusterName)
+		}
+		err := managedCreateClusterHandler(ctx, logging, obj)
 		if err != nil {
-			panic(err.Error())
+			if ctx.Err() != nil {
+				// an interrupted creation keeps its state so that it can be deleted later
+				logging.Note("Cluster creation was interrupted, use delete-cluster to remove it")
+				return err
+			}
+			logging.Err("CLEANUP TRIGGERED!: failed to create")
+			_ = managedDeleteClusterHandler(ctx, logging, obj, false)
+			return err
 		}
-	}()
+		return nil
+	}
 
-	// _, err = file.Write([]byte(fmt.Sprintf(`Access-Key: %s
-	// 	Secret-Access-Key: %s`, acckey, secacckey)))
-	// if err != nil {
-	// 	fmt.Println(err.Error())
-	// 	return false
-	// }
-	return true
+	if isPresent("ha", *obj) {
+		return fmt.Errorf("cluster already exists: %v", obj.ClusterName)
+	}
+	err = haCreateClusterHandler(ctx, logging, obj)
+	if err != nil {
+		if ctx.Err() != nil {
+			logging.Note("Cluster creation was interrupted, use delete-cluster to remove it")
+			return err
+		}
+		logging.Err("CLEANUP TRIGGERED!: failed to create")
+		_ = haDeleteClusterHandler(ctx, logging, obj, false)
+		return err
+	}
+	return nil
+}
+
+func (obj *AwsProvider) DeleteCluster(ctx context.Context, logging log.Logger) error {
+	if err := obj.setup(logging); err != nil {
+		return err
+	}
+
+	if !obj.HACluster {
+		if !isPresent("managed", *obj) {
+			return fmt.Errorf("cluster doesn't exists: %v", obj.ClusterName)
+		}
+		return managedDeleteClusterHandler(ctx, logging, obj, true)
+	}
+	if !isPresent("ha", *obj) {
+		return fmt.Errorf("cluster doesn't exists: %v", obj.ClusterName)
+	}
+	return haDeleteClusterHandler(ctx, logging, obj, true)
+}
+
+// SwitchContext merges the kubeconfig of the cluster into the default kubeconfig and makes it the current context
+func (obj *AwsProvider) SwitchContext(ctx context.Context, logging log.Logger) error {
+	kind := "managed"
+	if obj.HACluster {
+		kind = "ha"
+	}
+	if !isPresent(kind, *obj) {
+		return fmt.Errorf("ERR Cluster not found")
+	}
+	// the kubeconfig is copied from the state store to the local path which is merged
+	if _, err := util.FetchStateFile("aws", kind, obj.clusterRef(kind).ID(), "config"); err != nil {
+		logging.Warn("unable to fetch the kubeconfig", err.Error())
+	}
+	return util.SwitchKubeconfig(logging, obj.clusterRef(kind))
+}
+
+// Kubeconfig implements util.Provider, the kubeconfig of the EKS cluster is generated from its endpoint
+// and of the HA cluster is fetched from its first controlplane
+func (obj *AwsProvider) Kubeconfig(ctx context.Context, logging log.Logger, refresh bool) ([]byte, error) {
+	clusterType := util.CLUSTER_TYPE_MANAGED
+	if obj.HACluster {
+		clusterType = util.CLUSTER_TYPE_HA
+	}
+	if !isPresent(clusterType, *obj) {
+		return nil, fmt.Errorf("cluster does not exists: %v", obj.ClusterName)
+	}
+	if refresh {
+		provider := *obj
+		if err := provider.setup(logging); err != nil {
+			return nil, err
+		}
+		if err := provider.ConfigReader(logging, clusterType); err != nil {
+			return nil, fmt.Errorf("Unable to read configuration: %v", err)
+		}
+		var err error
+		if provider.HACluster {
+			err = provider.refreshHAKubeconfig(ctx, logging)
+		} else {
+			err = provider.refreshManagedKubeconfig(ctx, logging)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return util.ReadStateFile("aws", clusterType, obj.clusterRef(clusterType).ID(), "config")
+}
+
+// Create implements util.Provider
+func (obj *AwsProvider) Create(ctx context.Context, logging log.Logger) error {
+	return obj.CreateCluster(ctx, logging)
+}
+
+// Delete implements util.Provider
+func (obj *AwsProvider) Delete(ctx context.Context, logging log.Logger) error {
+	return obj.DeleteCluster(ctx, logging)
+}
+
+// AddNodes implements util.Provider, only HA clusters are supported
+func (obj *AwsProvider) AddNodes(ctx context.Context, logging log.Logger) error {
+	if !obj.HACluster {
+		return fmt.Errorf("adding nodes is only supported for HA cluster")
+	}
+	return obj.AddMoreWorkerNodes(ctx, logging)
+}
+
+// DeleteNodes implements util.Provider, only HA clusters are supported
+func (obj *AwsProvider) DeleteNodes(ctx context.Context, logging log.Logger) error {
+	if !obj.HACluster {
+		return fmt.Errorf("deleting nodes is only supported for HA cluster")
+	}
+	return obj.DeleteSomeWorkerNodes(ctx, logging)
+}
+
+// List returns all the managed and HA clusters present in the state management files
+// the node counts, node size and creation time are read from their info.json
+func (obj *AwsProvider) List(ctx context.Context, logging log.Logger) ([]util.ClusterInfo, error) {
+	var clusters []util.ClusterInfo
+
+	for _, clusterType := range []string{util.CLUSTER_TYPE_MANAGED, util.CLUSTER_TYPE_HA} {
+		refs, err := util.IndexedClusters("aws", clusterType)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			cluster, _ := clusterInfo(logging, clusterType, ref.Name, ref.Region)
+			clusters = append(clusters, cluster)
+		}
+	}
+	return clusters, nil
+}
+
+// clusterInfo the summary of the cluster along with its state, the state is nil when
+// it cannot be read and only the name and region are known
+func clusterInfo(logging log.Logger, clusterType, clusterName, region string) (util.ClusterInfo, *AwsStateCluster) {
+	cluster := util.ClusterInfo{
+		ClusterName:    clusterName,
+		Region:         region,
+		Provider:       "aws",
+		Type:           clusterType,
+		KubeconfigPath: util.NewClusterRef("aws", clusterType, clusterName, region).Path("config"),
+	}
+
+	state := &AwsProvider{}
+	state.ClusterName, state.Region = clusterName, region
+	if err := state.ConfigReader(logging, clusterType); err != nil {
+		return cluster, nil
+	}
+	cluster.NodeSize = state.Config.NodeSize
+	cluster.CreatedAt = state.Config.CreatedAt
+	if clusterType == util.CLUSTER_TYPE_HA {
+		cluster.ControlPlaneNodes = util.NoOfNodes(state.Config.InfoControlPlanes.InstanceIDs)
+		cluster.WorkerNodes = util.NoOfNodes(state.Config.InfoWorkerPlanes.InstanceIDs)
+	} else {
+		cluster.WorkerNodes = state.Config.ManagedNodes
+	}
+	return cluster, state.Config
+}
+
+// Describe implements util.Provider
+func (obj *AwsProvider) Describe(ctx context.Context, logging log.Logger) (util.ClusterDescription, error) {
+	clusterType := util.CLUSTER_TYPE_MANAGED
+	if obj.HACluster {
+		clusterType = util.CLUSTER_TYPE_HA
+	}
+	if !isPresent(clusterType, *obj) {
+		return util.ClusterDescription{}, fmt.Errorf("cluster does not exists: %v", obj.ClusterName)
+	}
+
+	cluster, state := clusterInfo(logging, clusterType, obj.ClusterName, obj.Region)
+	description := util.ClusterDescription{ClusterInfo: cluster}
+	if state == nil {
+		return description, fmt.Errorf("Unable to read configuration of %v", obj.ClusterName)
+	}
+
+	description.DatabaseEndpoint = util.RedactPassword(state.DBEndpoint)
+	// the resources are recorded either by their ID or by their name
+	for _, resource := range []util.ClusterResource{
+		{Kind: "vpc", ID: state.VpcID},
+		{Kind: "subnet", ID: state.SubnetID},
+		{Kind: "internet-gateway", ID: state.InternetGatewayID},
+		{Kind: "route-table", ID: state.RouteTableID},
+		{Kind: "security-group", Name: util.NODE_ROLE_CONTROLPLANE, ID: state.InfoControlPlanes.SecurityGroupID},
+		{Kind: "security-group", Name: util.NODE_ROLE_WORKER, ID: state.InfoWorkerPlanes.SecurityGroupID},
+		{Kind: "security-group", Name: util.NODE_ROLE_LOADBALANCER, ID: state.InfoLoadBalancer.SecurityGroupID},
+		{Kind: "security-group", Name: util.NODE_ROLE_DATABASE, ID: state.InfoDatabase.SecurityGroupID},
+	} {
+		if len(resource.ID) != 0 {
+			description.Resources = append(description.Resources, resource)
+		}
+	}
+	for _, subnet := range state.SubnetIDs {
+		description.Resources = append(description.Resources, util.ClusterResource{Kind: "subnet", ID: subnet})
+	}
+	for _, resource := range []util.ClusterResource{
+		{Kind: "key-pair", Name: state.SSHKeyName},
+		{Kind: "iam-role", Name: state.ClusterRoleName},
+		{Kind: "iam-role", Name: state.NodeRoleName},
+		{Kind: "node-group", Name: state.NodeGroupName},
+	} {
+		if len(resource.Name) != 0 {
+			description.Resources = append(description.Resources, resource)
+		}
+	}
+
+	for _, instance := range []struct {
+		role string
+		info AwsStateInstance
+	}{
+		{util.NODE_ROLE_LOADBALANCER, state.InfoLoadBalancer},
+		{util.NODE_ROLE_DATABASE, state.InfoDatabase},
+	} {
+		if len(instance.info.InstanceID) != 0 {
+			description.Nodes = append(description.Nodes, util.ClusterNode{
+				Role:      instance.role,
+				Name:      instance.info.Name,
+				ID:        instance.info.InstanceID,
+				PublicIP:  instance.info.PublicIP,
+				PrivateIP: instance.info.PrivateIP,
+			})
+		}
+	}
+	description.Nodes = append(description.Nodes, awsNodes(util.NODE_ROLE_CONTROLPLANE, state.InfoControlPlanes)...)
+	description.Nodes = append(description.Nodes, awsNodes(util.NODE_ROLE_WORKER, state.InfoWorkerPlanes)...)
+	return description, nil
+}
+
+// awsNodes the instances of the role which were created
+func awsNodes(role string, instances AwsStateInstances) []util.ClusterNode {
+	var nodes []util.ClusterNode
+	for index, id := range instances.InstanceIDs {
+		if len(id) == 0 {
+			continue
+		}
+		node := util.ClusterNode{Role: role, ID: id}
+		if index < len(instances.Names) {
+			node.Name = instances.Names[index]
+		}
+		if index < len(instances.PublicIPs) {
+			node.PublicIP = instances.PublicIPs[index]
+		}
+		if index < len(instances.PrivateIPs) {
+			node.PrivateIP = instances.PrivateIPs[index]
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// CheckDrift implements util.Provider
+// the ec2 resources are tagged with the cluster, the tagged ones which are not in the state are unexpected
+func (obj *AwsProvider) CheckDrift(ctx context.Context, logging log.Logger, fix bool) (util.DriftReport, error) {
+	clusterType := util.CLUSTER_TYPE_MANAGED
+	if obj.HACluster {
+		clusterType = util.CLUSTER_TYPE_HA
+	}
+	report := util.DriftReport{ClusterName: obj.ClusterName, Provider: "aws", Type: clusterType, Region: obj.Region}
+	if !isPresent(clusterType, *obj) {
+		return report, fmt.Errorf("cluster does not exists: %v", obj.ClusterName)
+	}
+	provider := *obj
+	if err := provider.setup(logging); err != nil {
+		return report, err
+	}
+	if err := provider.ConfigReader(logging, clusterType); err != nil {
+		return report, fmt.Errorf("Unable to read configuration: %v", err)
+	}
+
+	found, err := provider.listTagged(ctx)
+	if err != nil {
+		return report, err
+	}
+	recorded, err := provider.listRecorded(ctx)
+	if err != nil {
+		return report, err
+	}
+	provider.Config.checkDrift(&report, append(found, recorded...))
+	if fix && len(report.Missing()) != 0 {
+		updateState(func() {
+			provider.Config.forgetMissing(clusterType, report.Missing())
+		})
+		if err := provider.ConfigWriter(logging, clusterType); err != nil {
+			return report, err
+		}
+		report.Fixed = true
+	}
+	return report, nil
+}
+
+// IsPresent implements util.Provider
+func (obj *AwsProvider) IsPresent() bool {
+	if obj.HACluster {
+		return isPresent("ha", *obj)
+	}
+	return isPresent("managed", *obj)
+}
+
+// NoOfWorkerNodes implements util.Provider, only HA clusters are supported
+func (obj *AwsProvider) NoOfWorkerNodes(ctx context.Context, logging log.Logger) (int, error) {
+	if !obj.HACluster {
+		return 0, fmt.Errorf("worker nodes are only tracked for HA cluster")
+	}
+	if !isPresent("ha", *obj) {
+		return 0, fmt.Errorf("cluster does not exists: %v", obj.ClusterName)
+	}
+	provider := *obj
+	if err := provider.ConfigReader(logging, "ha"); err != nil {
+		return 0, fmt.Errorf("Unable to read configuration: %v", err)
+	}
+	return len(provider.Config.InfoWorkerPlanes.Names), nil
 }
@@ -0,0 +1,450 @@
+package eks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	log "github.com/kubesimplify/ksctl/api/logger"
+	util "github.com/kubesimplify/ksctl/api/utils"
+)
+
+// clusterPollInterval time between the eks cluster and node group status checks
+var clusterPollInterval = 30 * time.Second
+
+var (
+	clusterRolePolicies = []string{
+		"arn:aws:iam::aws:policy/AmazonEKSClusterPolicy",
+	}
+	nodeRolePolicies = []string{
+		"arn:aws:iam::aws:policy/AmazonEKSWorkerNodePolicy",
+		"arn:aws:iam::aws:policy/AmazonEKS_CNI_Policy",
+		"arn:aws:iam::aws:policy/AmazonEC2ContainerRegistryReadOnly",
+	}
+)
+
+func assumeRolePolicy(service string) string {
+	return fmt.Sprintf(`{
+	"Version": "2012-10-17",
+	"Statement": [
+		{
+			"Effect": "Allow",
+			"Principal": {"Service": "%s"},
+			"Action": "sts:AssumeRole"
+		}
+	]
+}`, service)
+}
+
+// managedKubeconfig returns the kubeconfig which uses the aws cli to generate the token
+// as the eks tokens are short lived, the credentials of ksctl are passed to it in the env
+// so that the cli needs no credentials of its own
+func managedKubeconfig(clusterName, region, endpoint, caData, accessKey, secret string) string {
+	contextName := clusterName + "-" + region + "-aws-ksctl"
+	return fmt.Sprintf(`apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: %s
+    server: %s
+  name: %s
+contexts:
+- context:
+    cluster: %s
+    user: %s
+  name: %s
+current-context: %s
+kind: Config
+preferences: {}
+users:
+- name: %s
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: aws
+      args:
+      - eks
+      - get-token
+      - --cluster-name
+      - %s
+      - --region
+      - %s
+      env:
+      - name: AWS_ACCESS_KEY_ID
+        value: %q
+      - name: AWS_SECRET_ACCESS_KEY
+        value: %q
+`, caData, endpoint, contextName, contextName, contextName, contextName, contextName, contextName, clusterName, region, accessKey, secret)
+}
+
+// iamTags the tags in the form taken by the iam api
+func iamTags(tags map[string]string) []iamtypes.Tag {
+	var list []iamtypes.Tag
+	for _, key := range sortedKeys(tags) {
+		list = append(list, iamtypes.Tag{Key: aws.String(key), Value: aws.String(tags[key])})
+	}
+	return list
+}
+
+// createRole creates the iam role assumable by the service and attaches the policies to it
+func (obj *AwsProvider) createRole(ctx context.Context, logging log.Logger, name, service string, policies []string) (string, error) {
+	role, err := obj.iamClient.CreateRole(ctx, &iam.CreateRoleInput{
+		RoleName:                 aws.String(name),
+		AssumeRolePolicyDocument: aws.String(assumeRolePolicy(service)),
+		Description:              aws.String("role managed by ksctl for " + obj.ClusterName),
+		Tags:                     iamTags(obj.resourceTags(util.TAG_ROLE_CLUSTER)),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for _, policy := range policies {
+		_, err := obj.iamClient.AttachRolePolicy(ctx, &iam.AttachRolePolicyInput{
+			RoleName:  aws.String(name),
+			PolicyArn: aws.String(policy),
+		})
+		if err != nil {
+			return *role.Role.Arn, err
+		}
+	}
+	logging.Info("Created iam role", name)
+	return *role.Role.Arn, nil
+}
+
+// deleteRole detaches the policies and deletes the iam role
+func (obj *AwsProvider) deleteRole(ctx context.Context, logging log.Logger, name string, policies []string) error {
+	if len(name) == 0 {
+		return nil
+	}
+	var notFound *iamtypes.NoSuchEntityException
+	for _, policy := range policies {
+		_, err := obj.iamClient.DetachRolePolicy(ctx, &iam.DetachRolePolicyInput{
+			RoleName:  aws.String(name),
+			PolicyArn: aws.String(policy),
+		})
+		if err != nil && !errors.As(err, &notFound) {
+			return err
+		}
+	}
+	_, err := obj.iamClient.DeleteRole(ctx, &iam.DeleteRoleInput{RoleName: aws.String(name)})
+	if err != nil && !errors.As(err, &notFound) {
+		return err
+	}
+	logging.Info("Deleted iam role", name)
+	return nil
+}
+
+// waitForCluster polls the eks cluster till it is active
+func (obj *AwsProvider) waitForCluster(ctx context.Context, logging log.Logger) (*ekstypes.Cluster, error) {
+	for retry := 0; retry < util.MAX_RETRY_COUNT*8; retry++ {
+		resp, err := obj.eksClient.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: aws.String(obj.ClusterName)})
+		if err != nil {
+			return nil, err
+		}
+		switch resp.Cluster.Status {
+		case ekstypes.ClusterStatusActive:
+			return resp.Cluster, nil
+		case ekstypes.ClusterStatusFailed:
+			return nil, fmt.Errorf("eks cluster %s failed to create", obj.ClusterName)
+		}
+		logging.Print("⏳ Waiting for the EKS cluster to be active...")
+		if err := util.Sleep(ctx, clusterPollInterval); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("eks cluster %s is not active", obj.ClusterName)
+}
+
+// waitForNodeGroup polls the node group till it is active
+func (obj *AwsProvider) waitForNodeGroup(ctx context.Context, logging log.Logger) error {
+	for retry := 0; retry < util.MAX_RETRY_COUNT*8; retry++ {
+		resp, err := obj.eksClient.DescribeNodegroup(ctx, &eks.DescribeNodegroupInput{
+			ClusterName:   aws.String(obj.ClusterName),
+			NodegroupName: aws.String(obj.Config.NodeGroupName),
+		})
+		if err != nil {
+			return err
+		}
+		switch resp.Nodegroup.Status {
+		case ekstypes.NodegroupStatusActive:
+			return nil
+		case ekstypes.NodegroupStatusCreateFailed, ekstypes.NodegroupStatusDegraded:
+			return fmt.Errorf("node group %s failed to create", obj.Config.NodeGroupName)
+		}
+		logging.Print("⏳ Waiting for the node group to be active...")
+		if err := util.Sleep(ctx, clusterPollInterval); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("node group %s is not active", obj.Config.NodeGroupName)
+}
+
+// waitForDeletion polls using describe till the resource is not found
+func waitForDeletion(ctx context.Context, describe func() error) error {
+	var notFound *ekstypes.ResourceNotFoundException
+	for retry := 0; retry < util.MAX_RETRY_COUNT*8; retry++ {
+		err := describe()
+		if errors.As(err, &notFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := util.Sleep(ctx, clusterPollInterval); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("resource is not deleted")
+}
+
+func managedCreateClusterHandler(ctx context.Context, logging log.Logger, obj *AwsProvider) error {
+	if !util.IsValidName(obj.ClusterName) {
+		return fmt.Errorf("invalid cluster name: %v", obj.ClusterName)
+	}
+
+	if !isValidNodeSize(obj.Spec.Disk) {
+		return fmt.Errorf("node size {%s} is invalid", obj.Spec.Disk)
+	}
+
+	if !isValidRegion(obj.Region) {
+		return fmt.Errorf("region {%s} is invalid", obj.Region)
+	}
+
+	if obj.Spec.ManagedNodes < 1 {
+		return fmt.Errorf("atleast 1 node is required")
+	}
+
+	release, err := util.GetManagedKubernetesRelease(obj.Config.KubernetesVersion)
+	if err != nil {
+		return err
+	}
+	var kubernetesVersion *string
+	if len(release.EKS) != 0 {
+		kubernetesVersion = aws.String(release.EKS)
+	}
+
+	logging.Info("Started to Create your EKS cluster on AWS provider")
+	defer obj.ConfigWriter(logging, "managed")
+
+	err = obj.CreateManagedNetwork(ctx, logging)
+	if err != nil {
+		return err
+	}
+
+	// iam roles are global so the region is part of the name
+	clusterRoleName := obj.ClusterName + "-" + obj.Region + "-ksctl-cluster-role"
+	clusterRoleArn, err := obj.createRole(ctx, logging, clusterRoleName, "eks.amazonaws.com", clusterRolePolicies)
+	if len(clusterRoleArn) != 0 {
+		obj.Config.ClusterRoleName = clusterRoleName
+	}
+	if err != nil {
+		return err
+	}
+
+	nodeRoleName := obj.ClusterName + "-" + obj.Region + "-ksctl-node-role"
+	nodeRoleArn, err := obj.createRole(ctx, logging, nodeRoleName, "ec2.amazonaws.com", nodeRolePolicies)
+	if len(nodeRoleArn) != 0 {
+		obj.Config.NodeRoleName = nodeRoleName
+	}
+	if err != nil {
+		return err
+	}
+
+	// the newly created role takes some time before it can be assumed by eks
+	var cluster *eks.CreateClusterOutput
+	for retry := 0; retry < util.MAX_RETRY_COUNT; retry++ {
+		cluster, err = obj.eksClient.CreateCluster(ctx, &eks.CreateClusterInput{
+			Name:    aws.String(obj.ClusterName),
+			Version: kubernetesVersion,
+			RoleArn: aws.String(clusterRoleArn),
+			ResourcesVpcConfig: &ekstypes.VpcConfigRequest{
+				SubnetIds:            obj.Config.SubnetIDs,
+				EndpointPublicAccess: aws.Bool(true),
+			},
+			Tags: obj.resourceTags(util.TAG_ROLE_CLUSTER),
+		})
+		var invalidParameter *ekstypes.InvalidParameterException
+		if !errors.As(err, &invalidParameter) || !strings.Contains(invalidParameter.ErrorMessage(), "Role") {
+			break
+		}
+		if err := util.Sleep(ctx, instancePollInterval); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	logging.Info("EKS cluster is creating ", *cluster.Cluster.Name)
+
+	active, err := obj.waitForCluster(ctx, logging)
+	if err != nil {
+		return err
+	}
+	logging.Info("Created the EKS cluster", obj.ClusterName)
+
+	nodeGroupName := obj.ClusterName + "-ksctl-nodes"
+	_, err = obj.eksClient.CreateNodegroup(ctx, &eks.CreateNodegroupInput{
+		ClusterName:   aws.String(obj.ClusterName),
+		NodegroupName: aws.String(nodeGroupName),
+		NodeRole:      aws.String(nodeRoleArn),
+		Subnets:       obj.Config.SubnetIDs,
+		InstanceTypes: []string{obj.Spec.Disk},
+		ScalingConfig: &ekstypes.NodegroupScalingConfig{
+			DesiredSize: aws.Int32(int32(obj.Spec.ManagedNodes)),
+			MinSize:     aws.Int32(1),
+			MaxSize:     aws.Int32(int32(obj.Spec.ManagedNodes)),
+		},
+		Tags: obj.resourceTags(util.NODE_ROLE_WORKER),
+	})
+	if err != nil {
+		return err
+	}
+	obj.Config.NodeGroupName = nodeGroupName
+
+	if err := obj.waitForNodeGroup(ctx, logging); err != nil {
+		return err
+	}
+	logging.Info("Created the node group", nodeGroupName)
+
+	if active.CertificateAuthority == nil || active.Endpoint == nil {
+		return fmt.Errorf("eks cluster %s has no endpoint", obj.ClusterName)
+	}
+	err = obj.SaveKubeconfig(logging, managedKubeconfig(obj.ClusterName, obj.Region, *active.Endpoint, aws.ToString(active.CertificateAuthority.Data), obj.AccessKey, obj.Secret))
+	if err != nil {
+		return err
+	}
+
+	logging.Note("kubeconfig runs the aws cli (`aws eks get-token`) with the credentials of ksctl, make sure it is installed")
+	logging.Info("Created your managed aws cluster!!🥳 🎉 ")
+
+	var printKubeconfig util.PrinterKubeconfigPATH
+	printKubeconfig = printer{ClusterName: obj.ClusterName, Region: obj.Region}
+	printKubeconfig.Printer(logging, false, 0)
+	return nil
+}
+
+func managedDeleteClusterHandler(ctx context.Context, logging log.Logger, obj *AwsProvider, showMsg bool) error {
+	if !util.IsValidName(obj.ClusterName) {
+		return fmt.Errorf("invalid cluster name: %v", obj.ClusterName)
+	}
+
+	if !isValidRegion(obj.Region) {
+		return fmt.Errorf("region {%s} is invalid", obj.Region)
+	}
+
+	if showMsg {
+		logging.Note(fmt.Sprintf(`🚨	THIS IS A DESTRUCTIVE STEP MAKE SURE IF YOU WANT TO DELETE THE CLUSTER '%s'
+	`, obj.ClusterName+" "+obj.Region))
+
+		fmt.Println("Enter your choice to continue..[y/N]")
+		choice := "n"
+		unsafe := false
+		fmt.Scanf("%s", &choice)
+		if strings.Compare("y", choice) == 0 ||
+			strings.Compare("yes", choice) == 0 ||
+			strings.Compare("Y", choice) == 0 {
+			unsafe = true
+		}
+
+		if !unsafe {
+			return fmt.Errorf("permission denied")
+		}
+	}
+
+	logging.Info("start deleting the cluster...", "")
+
+	err := obj.ConfigReader(logging, "managed")
+	if err != nil {
+		return fmt.Errorf("Unable to read configuration: %v", err)
+	}
+	// the partial progress is saved so that the deletion can be retried
+	defer func() {
+		if isPresent("managed", *obj) {
+			obj.ConfigWriter(logging, "managed")
+		}
+	}()
+
+	if len(obj.Config.NodeGroupName) != 0 {
+		_, err := obj.eksClient.DeleteNodegroup(ctx, &eks.DeleteNodegroupInput{
+			ClusterName:   aws.String(obj.ClusterName),
+			NodegroupName: aws.String(obj.Config.NodeGroupName),
+		})
+		var notFound *ekstypes.ResourceNotFoundException
+		if err != nil && !errors.As(err, &notFound) {
+			return err
+		}
+		err = waitForDeletion(ctx, func() error {
+			_, err := obj.eksClient.DescribeNodegroup(ctx, &eks.DescribeNodegroupInput{
+				ClusterName:   aws.String(obj.ClusterName),
+				NodegroupName: aws.String(obj.Config.NodeGroupName),
+			})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		logging.Info("Deleted the node group", obj.Config.NodeGroupName)
+		obj.Config.NodeGroupName = ""
+	}
+
+	_, err = obj.eksClient.DeleteCluster(ctx, &eks.DeleteClusterInput{Name: aws.String(obj.ClusterName)})
+	var notFound *ekstypes.ResourceNotFoundException
+	if err != nil && !errors.As(err, &notFound) {
+		return err
+	}
+	err = waitForDeletion(ctx, func() error {
+		_, err := obj.eksClient.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: aws.String(obj.ClusterName)})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	logging.Info("Deleted the EKS cluster", obj.ClusterName)
+
+	if err := obj.deleteRole(ctx, logging, obj.Config.NodeRoleName, nodeRolePolicies); err != nil {
+		return err
+	}
+	obj.Config.NodeRoleName = ""
+
+	if err := obj.deleteRole(ctx, logging, obj.Config.ClusterRoleName, clusterRolePolicies); err != nil {
+		return err
+	}
+	obj.Config.ClusterRoleName = ""
+
+	err = obj.DeleteNetwork(ctx, logging)
+	if err != nil {
+		return err
+	}
+
+	if err := util.DeleteClusterState(obj.clusterRef(util.CLUSTER_TYPE_MANAGED)); err != nil {
+		return err
+	}
+
+	var printKubeconfig util.PrinterKubeconfigPATH
+	printKubeconfig = printer{ClusterName: obj.ClusterName, Region: obj.Region}
+	printKubeconfig.Printer(logging, false, 1)
+	return nil
+}
+
+// refreshManagedKubeconfig generates the kubeconfig of the EKS cluster again from its endpoint
+func (obj *AwsProvider) refreshManagedKubeconfig(ctx context.Context, logging log.Logger) error {
+	resp, err := obj.eksClient.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: aws.String(obj.ClusterName)})
+	if err != nil {
+		return err
+	}
+	if resp.Cluster.CertificateAuthority == nil || resp.Cluster.Endpoint == nil {
+		return fmt.Errorf("eks cluster %s has no endpoint", obj.ClusterName)
+	}
+	err = obj.SaveKubeconfig(logging, managedKubeconfig(obj.ClusterName, obj.Region, *resp.Cluster.Endpoint, aws.ToString(resp.Cluster.CertificateAuthority.Data), obj.AccessKey, obj.Secret))
+	if err != nil {
+		return err
+	}
+	logging.Note("kubeconfig runs the aws cli (`aws eks get-token`) with the credentials of ksctl, make sure it is installed")
+	return nil
+}
@@ -0,0 +1,1027 @@
+package eks
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	log "github.com/kubesimplify/ksctl/api/logger"
+	util "github.com/kubesimplify/ksctl/api/utils"
+)
+
+const (
+	// ENDPOINT_ENV_VAR overrides the EC2 endpoint, used to point ksctl to an EC2 compatible API
+	ENDPOINT_ENV_VAR = "KSCTL_AWS_EC2_ENDPOINT"
+	// EKS_ENDPOINT_ENV_VAR and IAM_ENDPOINT_ENV_VAR do the same for the managed cluster apis
+	EKS_ENDPOINT_ENV_VAR = "KSCTL_AWS_EKS_ENDPOINT"
+	IAM_ENDPOINT_ENV_VAR = "KSCTL_AWS_IAM_ENDPOINT"
+	VPC_CIDR             = "10.1.0.0/16"
+	SUBNET_CIDR          = "10.1.0.0/24"
+	UBUNTU_OWNER_ID      = "099720109477"
+	UBUNTU_IMAGE         = "ubuntu/images/hvm-ssd/ubuntu-jammy-22.04-amd64-server-*"
+)
+
+// MANAGED_SUBNET_CIDRS eks needs the subnets to be in atleast two availability zones
+var MANAGED_SUBNET_CIDRS = []string{"10.1.1.0/24", "10.1.2.0/24"}
+
+// instancePollInterval time between the instance state checks
+var instancePollInterval = 10 * time.Second
+
+type AwsStateInstances struct {
+	Names           []string `json:"names"`
+	InstanceIDs     []string `json:"instance_ids"`
+	SecurityGroupID string   `json:"security_group_id"`
+	PrivateIPs      []string `json:"private_ips"`
+	PublicIPs       []string `json:"public_ips"`
+}
+
+type AwsStateInstance struct {
+	Name            string `json:"name"`
+	InstanceID      string `json:"instance_id"`
+	SecurityGroupID string `json:"security_group_id"`
+	PrivateIP       string `json:"private_ip"`
+	PublicIP        string `json:"public_ip"`
+}
+
+type AwsStateCluster struct {
+	ClusterName string `json:"cluster_name"`
+	Region      string `json:"region"`
+	SSHKeyName  string `json:"ssh_key_name"`
+	DBEndpoint  string `json:"database_endpoint"`
+	K3sToken    string `json:"k3s_token"`
+
+	VpcID             string `json:"vpc_id"`
+	SubnetID          string `json:"subnet_id"`
+	InternetGatewayID string `json:"internet_gateway_id"`
+	RouteTableID      string `json:"route_table_id"`
+
+	// managed (EKS) cluster
+	SubnetIDs       []string `json:"subnet_ids"`
+	ClusterRoleName string   `json:"cluster_role_name"`
+	NodeRoleName    string   `json:"node_role_name"`
+	NodeGroupName   string   `json:"node_group_name"`
+
+	InfoControlPlanes AwsStateInstances `json:"info_control_planes"`
+	InfoWorkerPlanes  AwsStateInstances `json:"info_worker_planes"`
+	InfoDatabase      AwsStateInstance  `json:"info_database"`
+	InfoLoadBalancer  AwsStateInstance  `json:"info_load_balancer"`
+
+	// ClusterMetadata is kept in the envelope of the state, see util.ClusterState
+	util.ClusterMetadata `json:"-"`
+}
+
+type printer struct {
+	ClusterName string
+	Region      string
+}
+
+// TODO: query the available instance types from the ec2 client
+func isValidNodeSize(size string) bool {
+	validSizes := []string{
+		"t2.micro", "t2.small", "t2.medium", "t2.large", "t2.xlarge", "t2.2xlarge",
+		"t3.micro", "t3.small", "t3.medium", "t3.large", "t3.xlarge", "t3.2xlarge",
+		"t3a.micro", "t3a.small", "t3a.medium", "t3a.large", "t3a.xlarge", "t3a.2xlarge",
+		"m5.large", "m5.xlarge", "m5.2xlarge", "m5.4xlarge",
+		"c5.large", "c5.xlarge", "c5.2xlarge", "c5.4xlarge",
+	}
+	for _, validSize := range validSizes {
+		if validSize == size {
+			return true
+		}
+	}
+	return false
+}
+
+// TODO: make the region validation using the ec2 client
+func isValidRegion(region string) bool {
+	validRegions := []string{
+		"us-east-1", "us-east-2", "us-west-1", "us-west-2",
+		"af-south-1", "ap-east-1", "ap-south-1", "ap-northeast-1", "ap-northeast-2", "ap-northeast-3",
+		"ap-southeast-1", "ap-southeast-2", "ca-central-1",
+		"eu-central-1", "eu-west-1", "eu-west-2", "eu-west-3", "eu-south-1", "eu-north-1",
+		"me-south-1", "sa-east-1",
+	}
+	for _, validRegion := range validRegions {
+		if validRegion == region {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterRef identifies the aws cluster of the type in the state management files
+func (obj *AwsProvider) clusterRef(clusterType string) util.ClusterRef {
+	return util.NewClusterRef("aws", clusterType, obj.ClusterName, obj.Region)
+}
+
+// stateMutex serializes the updates of the state as the instances of the HA cluster are created in parallel
+var stateMutex sync.Mutex
+
+// imageMutex guards the lookup of the ubuntu image shared by the instances created in parallel
+var imageMutex sync.Mutex
+
+// sgMutex guards the creation of the security group shared by the instances created in parallel
+var sgMutex sync.Mutex
+
+// updateState applies the change to the state while no other instance is updating or saving it
+func updateState(change func()) {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+	change()
+}
+
+// setAt places the value at the index, the positions of the instances which are not yet created are left empty
+// the instances are created in parallel so their values are kept in the order of the node number
+func setAt(values []string, index int, value string) []string {
+	for len(values) <= index {
+		values = append(values, "")
+	}
+	values[index] = value
+	return values
+}
+
+func (obj *AwsProvider) ConfigWriter(logging log.Logger, clusterType string) error {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+	return util.SaveState(logging, obj.Config, obj.clusterRef(clusterType))
+}
+
+func isPresent(kind string, obj AwsProvider) bool {
+	return obj.clusterRef(kind).IsPresent()
+}
+
+func (obj *AwsProvider) ConfigReader(logging log.Logger, clusterType string) error {
+	var structData AwsStateCluster
+	if err := util.GetState(logging, &structData, obj.clusterRef(clusterType)); err != nil {
+		return err
+	}
+
+	obj.Config = &structData
+	obj.ClusterName = obj.Config.ClusterName
+	return nil
+}
+
+// setRequiredENV_VAR checks if the environment variables are set
+// otherwise loads the credentials saved using `ksctl cred`
+func setRequiredENV_VAR(logging log.Logger, obj *AwsProvider) error {
+
+	envAccessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	envSecret := os.Getenv("AWS_SECRET_ACCESS_KEY")
+
+	if len(envAccessKey) != 0 && len(envSecret) != 0 {
+		obj.AccessKey = envAccessKey
+		obj.Secret = envSecret
+		return nil
+	}
+
+	msg := "environment vars not set:"
+	if len(envAccessKey) == 0 {
+		msg = msg + " AWS_ACCESS_KEY_ID"
+	}
+
+	if len(envSecret) == 0 {
+		msg = msg + " AWS_SECRET_ACCESS_KEY"
+	}
+
+	logging.Warn(msg)
+
+	tokens, err := util.GetCred(logging, "aws")
+	if err != nil {
+		return err
+	}
+
+	obj.AccessKey = tokens["access_key_id"]
+	obj.Secret = tokens["secret_access_key"]
+	if len(obj.AccessKey) == 0 || len(obj.Secret) == 0 {
+		return fmt.Errorf("aws credentials are missing, use `ksctl cred` to save them")
+	}
+	return nil
+}
+
+// getEC2Client returns the ec2 client for the region of the cluster
+func getEC2Client(obj *AwsProvider) *ec2.Client {
+	options := ec2.Options{
+		Region:      obj.Region,
+		Credentials: aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider(obj.AccessKey, obj.Secret, "")),
+	}
+	if endpoint := os.Getenv(ENDPOINT_ENV_VAR); len(endpoint) != 0 {
+		options.EndpointResolver = ec2.EndpointResolverFromURL(endpoint)
+	}
+	return ec2.New(options)
+}
+
+// getEKSClient returns the eks client for the region of the cluster
+func getEKSClient(obj *AwsProvider) *eks.Client {
+	options := eks.Options{
+		Region:      obj.Region,
+		Credentials: aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider(obj.AccessKey, obj.Secret, "")),
+	}
+	if endpoint := os.Getenv(EKS_ENDPOINT_ENV_VAR); len(endpoint) != 0 {
+		options.EndpointResolver = eks.EndpointResolverFromURL(endpoint)
+	}
+	return eks.New(options)
+}
+
+// getIAMClient returns the iam client, the roles are global but the region is needed for signing
+func getIAMClient(obj *AwsProvider) *iam.Client {
+	options := iam.Options{
+		Region:      obj.Region,
+		Credentials: aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider(obj.AccessKey, obj.Secret, "")),
+	}
+	if endpoint := os.Getenv(IAM_ENDPOINT_ENV_VAR); len(endpoint) != 0 {
+		options.EndpointResolver = iam.EndpointResolverFromURL(endpoint)
+	}
+	return iam.New(options)
+}
+
+// resourceTags the ownership tags of the resource of the given role along with the tags of the user
+func (obj *AwsProvider) resourceTags(role string) map[string]string {
+	clusterType := util.CLUSTER_TYPE_MANAGED
+	if obj.HACluster {
+		clusterType = util.CLUSTER_TYPE_HA
+	}
+	var userTags map[string]string
+	if obj.Config != nil {
+		userTags = obj.Config.Tags
+	}
+	return util.ResourceTags(obj.clusterRef(clusterType), role, userTags)
+}
+
+// tagsFor returns the tags which are attached to every resource created by ksctl
+func (obj *AwsProvider) tagsFor(resourceType types.ResourceType, name, role string) []types.TagSpecification {
+	tags := []types.Tag{{Key: aws.String("Name"), Value: aws.String(name)}}
+	resourceTags := obj.resourceTags(role)
+	for _, key := range sortedKeys(resourceTags) {
+		tags = append(tags, types.Tag{Key: aws.String(key), Value: aws.String(resourceTags[key])})
+	}
+	return []types.TagSpecification{{ResourceType: resourceType, Tags: tags}}
+}
+
+// sortedKeys keeps the order of the tags stable across the calls
+func sortedKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// createVpc creates the VPC with the internet gateway routed to it
+func (obj *AwsProvider) createVpc(ctx context.Context, logging log.Logger) error {
+	vpc, err := obj.ec2Client.CreateVpc(ctx, &ec2.CreateVpcInput{
+		CidrBlock:         aws.String(VPC_CIDR),
+		TagSpecifications: obj.tagsFor(types.ResourceTypeVpc, obj.ClusterName+"-vpc", util.TAG_ROLE_CLUSTER),
+	})
+	if err != nil {
+		return err
+	}
+	obj.Config.VpcID = *vpc.Vpc.VpcId
+	logging.Info("Created vpc", obj.Config.VpcID)
+
+	igw, err := obj.ec2Client.CreateInternetGateway(ctx, &ec2.CreateInternetGatewayInput{
+		TagSpecifications: obj.tagsFor(types.ResourceTypeInternetGateway, obj.ClusterName+"-igw", util.TAG_ROLE_CLUSTER),
+	})
+	if err != nil {
+		return err
+	}
+	obj.Config.InternetGatewayID = *igw.InternetGateway.InternetGatewayId
+
+	_, err = obj.ec2Client.AttachInternetGateway(ctx, &ec2.AttachInternetGatewayInput{
+		InternetGatewayId: aws.String(obj.Config.InternetGatewayID),
+		VpcId:             aws.String(obj.Config.VpcID),
+	})
+	if err != nil {
+		return err
+	}
+	logging.Info("Created internet gateway", obj.Config.InternetGatewayID)
+
+	routeTables, err := obj.ec2Client.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{
+		Filters: []types.Filter{{Name: aws.String("vpc-id"), Values: []string{obj.Config.VpcID}}},
+	})
+	if err != nil {
+		return err
+	}
+	if len(routeTables.RouteTables) == 0 {
+		return fmt.Errorf("no route table found for vpc %s", obj.Config.VpcID)
+	}
+	obj.Config.RouteTableID = *routeTables.RouteTables[0].RouteTableId
+
+	_, err = obj.ec2Client.CreateRoute(ctx, &ec2.CreateRouteInput{
+		RouteTableId:         aws.String(obj.Config.RouteTableID),
+		DestinationCidrBlock: aws.String("0.0.0.0/0"),
+		GatewayId:            aws.String(obj.Config.InternetGatewayID),
+	})
+	return err
+}
+
+// CreateNetwork creates the VPC, internet gateway, route and subnet used by all the instances
+func (obj *AwsProvider) CreateNetwork(ctx context.Context, logging log.Logger) error {
+	if err := obj.createVpc(ctx, logging); err != nil {
+		return err
+	}
+
+	subnet, err := obj.ec2Client.CreateSubnet(ctx, &ec2.CreateSubnetInput{
+		VpcId:             aws.String(obj.Config.VpcID),
+		CidrBlock:         aws.String(SUBNET_CIDR),
+		TagSpecifications: obj.tagsFor(types.ResourceTypeSubnet, obj.ClusterName+"-subnet", util.TAG_ROLE_CLUSTER),
+	})
+	if err != nil {
+		return err
+	}
+	obj.Config.SubnetID = *subnet.Subnet.SubnetId
+	logging.Info("Created subnet", obj.Config.SubnetID)
+	return nil
+}
+
+// CreateManagedNetwork creates the VPC with public subnets spread across availability zones for EKS
+func (obj *AwsProvider) CreateManagedNetwork(ctx context.Context, logging log.Logger) error {
+	if err := obj.createVpc(ctx, logging); err != nil {
+		return err
+	}
+
+	zones, err := obj.ec2Client.DescribeAvailabilityZones(ctx, &ec2.DescribeAvailabilityZonesInput{
+		Filters: []types.Filter{{Name: aws.String("state"), Values: []string{"available"}}},
+	})
+	if err != nil {
+		return err
+	}
+	if len(zones.AvailabilityZones) < len(MANAGED_SUBNET_CIDRS) {
+		return fmt.Errorf("region %s needs atleast %d availability zones", obj.Region, len(MANAGED_SUBNET_CIDRS))
+	}
+	sort.Slice(zones.AvailabilityZones, func(i, j int) bool {
+		return aws.ToString(zones.AvailabilityZones[i].ZoneName) < aws.ToString(zones.AvailabilityZones[j].ZoneName)
+	})
+
+	for i, cidr := range MANAGED_SUBNET_CIDRS {
+		name := fmt.Sprintf("%s-subnet-%d", obj.ClusterName, i+1)
+		tags := obj.tagsFor(types.ResourceTypeSubnet, name, util.TAG_ROLE_CLUSTER)
+		// required by the kubernetes cloud provider to place the public load balancers
+		tags[0].Tags = append(tags[0].Tags,
+			types.Tag{Key: aws.String("kubernetes.io/cluster/" + obj.ClusterName), Value: aws.String("shared")},
+			types.Tag{Key: aws.String("kubernetes.io/role/elb"), Value: aws.String("1")})
+
+		subnet, err := obj.ec2Client.CreateSubnet(ctx, &ec2.CreateSubnetInput{
+			VpcId:             aws.String(obj.Config.VpcID),
+			CidrBlock:         aws.String(cidr),
+			AvailabilityZone:  zones.AvailabilityZones[i].ZoneName,
+			TagSpecifications: tags,
+		})
+		if err != nil {
+			return err
+		}
+		obj.Config.SubnetIDs = append(obj.Config.SubnetIDs, *subnet.Subnet.SubnetId)
+
+		// managed nodes are launched in the public subnets so they need a public ip
+		_, err = obj.ec2Client.ModifySubnetAttribute(ctx, &ec2.ModifySubnetAttributeInput{
+			SubnetId:            subnet.Subnet.SubnetId,
+			MapPublicIpOnLaunch: &types.AttributeBooleanValue{Value: aws.Bool(true)},
+		})
+		if err != nil {
+			return err
+		}
+		logging.Info("Created subnet", *subnet.Subnet.SubnetId)
+	}
+	return nil
+}
+
+// DeleteNetwork deletes the subnets, internet gateway and the VPC
+func (obj *AwsProvider) DeleteNetwork(ctx context.Context, logging log.Logger) error {
+	if len(obj.Config.SubnetID) != 0 {
+		if _, err := obj.ec2Client.DeleteSubnet(ctx, &ec2.DeleteSubnetInput{SubnetId: aws.String(obj.Config.SubnetID)}); err != nil {
+			return err
+		}
+		logging.Info("Deleted subnet", obj.Config.SubnetID)
+		obj.Config.SubnetID = ""
+	}
+
+	for len(obj.Config.SubnetIDs) != 0 {
+		subnetID := obj.Config.SubnetIDs[0]
+		if _, err := obj.ec2Client.DeleteSubnet(ctx, &ec2.DeleteSubnetInput{SubnetId: aws.String(subnetID)}); err != nil {
+			return err
+		}
+		logging.Info("Deleted subnet", subnetID)
+		obj.Config.SubnetIDs = obj.Config.SubnetIDs[1:]
+	}
+
+	if len(obj.Config.InternetGatewayID) != 0 {
+		// the gateway is already detached when the vpc is missing
+		if len(obj.Config.VpcID) != 0 {
+			_, err := obj.ec2Client.DetachInternetGateway(ctx, &ec2.DetachInternetGatewayInput{
+				InternetGatewayId: aws.String(obj.Config.InternetGatewayID),
+				VpcId:             aws.String(obj.Config.VpcID),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := obj.ec2Client.DeleteInternetGateway(ctx, &ec2.DeleteInternetGatewayInput{InternetGatewayId: aws.String(obj.Config.InternetGatewayID)}); err != nil {
+			return err
+		}
+		logging.Info("Deleted internet gateway", obj.Config.InternetGatewayID)
+		obj.Config.InternetGatewayID = ""
+	}
+
+	if len(obj.Config.VpcID) != 0 {
+		if _, err := obj.ec2Client.DeleteVpc(ctx, &ec2.DeleteVpcInput{VpcId: aws.String(obj.Config.VpcID)}); err != nil {
+			return err
+		}
+		logging.Info("Deleted vpc", obj.Config.VpcID)
+		obj.Config.VpcID = ""
+		obj.Config.RouteTableID = ""
+	}
+	return nil
+}
+
+// CreateSecurityGroup creates the security group and allows the given ingress rules
+func (obj *AwsProvider) CreateSecurityGroup(ctx context.Context, logging log.Logger, name, role string, rules []types.IpPermission) (string, error) {
+	sg, err := obj.ec2Client.CreateSecurityGroup(ctx, &ec2.CreateSecurityGroupInput{
+		GroupName:         aws.String(name),
+		Description:       aws.String("security group managed by ksctl for " + obj.ClusterName),
+		VpcId:             aws.String(obj.Config.VpcID),
+		TagSpecifications: obj.tagsFor(types.ResourceTypeSecurityGroup, name, role),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	_, err = obj.ec2Client.AuthorizeSecurityGroupIngress(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+		GroupId:       sg.GroupId,
+		IpPermissions: rules,
+	})
+	if err != nil {
+		return *sg.GroupId, err
+	}
+	logging.Info("Created security group", name)
+	return *sg.GroupId, nil
+}
+
+// DeleteSecurityGroup deletes the security group if it was created
+func (obj *AwsProvider) DeleteSecurityGroup(ctx context.Context, logging log.Logger, groupID string) error {
+	if len(groupID) == 0 {
+		return nil
+	}
+	if _, err := obj.ec2Client.DeleteSecurityGroup(ctx, &ec2.DeleteSecurityGroupInput{GroupId: aws.String(groupID)}); err != nil {
+		return err
+	}
+	logging.Info("Deleted security group", groupID)
+	return nil
+}
+
+func (obj *AwsProvider) DeleteAllSecurityGroups(ctx context.Context, logging log.Logger) error {
+	for _, groupID := range []*string{
+		&obj.Config.InfoControlPlanes.SecurityGroupID,
+		&obj.Config.InfoWorkerPlanes.SecurityGroupID,
+		&obj.Config.InfoDatabase.SecurityGroupID,
+		&obj.Config.InfoLoadBalancer.SecurityGroupID,
+	} {
+		if err := obj.DeleteSecurityGroup(ctx, logging, *groupID); err != nil {
+			return err
+		}
+		*groupID = ""
+	}
+	return nil
+}
+
+// tcpRule returns the ingress rule for the given tcp port range
+func tcpRule(fromPort, toPort int32, cidr string) types.IpPermission {
+	return types.IpPermission{
+		IpProtocol: aws.String("tcp"),
+		FromPort:   aws.Int32(fromPort),
+		ToPort:     aws.Int32(toPort),
+		IpRanges:   []types.IpRange{{CidrIp: aws.String(cidr)}},
+	}
+}
+
+// allTrafficRule returns the ingress rule which allows everything from the given cidr
+func allTrafficRule(cidr string) types.IpPermission {
+	return types.IpPermission{
+		IpProtocol: aws.String("-1"),
+		IpRanges:   []types.IpRange{{CidrIp: aws.String(cidr)}},
+	}
+}
+
+// UploadSSHKey creates the ssh keypair and imports the public key to ec2
+func (obj *AwsProvider) UploadSSHKey(ctx context.Context, logging log.Logger) error {
+	ref := obj.clusterRef(util.CLUSTER_TYPE_HA)
+	if err := os.MkdirAll(ref.Path(), 0755); err != nil {
+		return err
+	}
+
+	keyPairToUpload, err := util.CreateSSHKeyPair("aws", ref.ID())
+	if err != nil {
+		return err
+	}
+
+	_, err = obj.ec2Client.ImportKeyPair(ctx, &ec2.ImportKeyPairInput{
+		KeyName:           aws.String(obj.ClusterName + "-ksctl-ssh"),
+		PublicKeyMaterial: []byte(keyPairToUpload),
+		TagSpecifications: obj.tagsFor(types.ResourceTypeKeyPair, obj.ClusterName+"-ksctl-ssh", util.TAG_ROLE_CLUSTER),
+	})
+	if err != nil {
+		return err
+	}
+	obj.Config.SSHKeyName = obj.ClusterName + "-ksctl-ssh"
+	logging.Info("Uploaded ssh key", obj.Config.SSHKeyName)
+
+	// ------- Setting the ssh configs only the public ips used will change
+	obj.SSH_Payload.UserName = "ubuntu"
+	obj.SSH_Payload.PathPrivateKey = util.GetPath(util.SSH_PATH, "aws", "ha", ref.ID())
+	obj.SSH_Payload.Output = ""
+	obj.SSH_Payload.PublicIP = ""
+	// ------
+	return nil
+}
+
+func (obj *AwsProvider) DeleteSSHKeyPair(ctx context.Context, logging log.Logger) error {
+	if len(obj.Config.SSHKeyName) == 0 {
+		return nil
+	}
+	if _, err := obj.ec2Client.DeleteKeyPair(ctx, &ec2.DeleteKeyPairInput{KeyName: aws.String(obj.Config.SSHKeyName)}); err != nil {
+		return err
+	}
+	logging.Info("Deleted the ssh", obj.Config.SSHKeyName)
+	obj.Config.SSHKeyName = ""
+	return nil
+}
+
+// getUbuntuImage returns the latest ubuntu 22.04 image present in the region
+func (obj *AwsProvider) getUbuntuImage(ctx context.Context) (string, error) {
+	// instances are created in parallel, the image is looked up only once
+	imageMutex.Lock()
+	defer imageMutex.Unlock()
+	if len(obj.imageID) != 0 {
+		return obj.imageID, nil
+	}
+	images, err := obj.ec2Client.DescribeImages(ctx, &ec2.DescribeImagesInput{
+		Owners: []string{UBUNTU_OWNER_ID},
+		Filters: []types.Filter{
+			{Name: aws.String("name"), Values: []string{UBUNTU_IMAGE}},
+			{Name: aws.String("state"), Values: []string{"available"}},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(images.Images) == 0 {
+		return "", fmt.Errorf("no ubuntu image found in region %s", obj.Region)
+	}
+	sort.Slice(images.Images, func(i, j int) bool {
+		return aws.ToString(images.Images[i].CreationDate) > aws.ToString(images.Images[j].CreationDate)
+	})
+	obj.imageID = *images.Images[0].ImageId
+	return obj.imageID, nil
+}
+
+// awsInstance details of the booted instance which are stored in the state
+type awsInstance struct {
+	ID        string
+	PublicIP  string
+	PrivateIP string
+}
+
+// CreateInstance boots the instance and waits till it gets its public ip
+// the instance ID is returned even when the wait fails so that it can be cleaned up
+func (obj *AwsProvider) CreateInstance(ctx context.Context, logging log.Logger, name, role, securityGroupID, script string) (awsInstance, error) {
+	imageID, err := obj.getUbuntuImage(ctx)
+	if err != nil {
+		return awsInstance{}, err
+	}
+
+	input := &ec2.RunInstancesInput{
+		ImageId:      aws.String(imageID),
+		InstanceType: types.InstanceType(obj.Spec.Disk),
+		MinCount:     aws.Int32(1),
+		MaxCount:     aws.Int32(1),
+		KeyName:      aws.String(obj.Config.SSHKeyName),
+		NetworkInterfaces: []types.InstanceNetworkInterfaceSpecification{
+			{
+				DeviceIndex:              aws.Int32(0),
+				SubnetId:                 aws.String(obj.Config.SubnetID),
+				Groups:                   []string{securityGroupID},
+				AssociatePublicIpAddress: aws.Bool(true),
+			},
+		},
+		TagSpecifications: obj.tagsFor(types.ResourceTypeInstance, name, role),
+	}
+	if len(script) != 0 {
+		input.UserData = aws.String(base64.StdEncoding.EncodeToString([]byte(script)))
+	}
+
+	resp, err := obj.ec2Client.RunInstances(ctx, input)
+	if err != nil {
+		return awsInstance{}, err
+	}
+	if len(resp.Instances) == 0 {
+		return awsInstance{}, fmt.Errorf("no instance returned for %s", name)
+	}
+	instance := awsInstance{ID: *resp.Instances[0].InstanceId}
+	logging.Info("Created instance", name)
+
+	running, err := obj.waitForInstance(ctx, instance.ID)
+	if err != nil {
+		return instance, err
+	}
+	instance.PublicIP = aws.ToString(running.PublicIpAddress)
+	instance.PrivateIP = aws.ToString(running.PrivateIpAddress)
+	return instance, nil
+}
+
+// waitForInstance polls the instance till it is running with a public ip assigned
+func (obj *AwsProvider) waitForInstance(ctx context.Context, instanceID string) (*types.Instance, error) {
+	for retry := 0; retry < util.MAX_RETRY_COUNT*4; retry++ {
+		resp, err := obj.ec2Client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{instanceID}})
+		if err != nil {
+			return nil, err
+		}
+		for _, reservation := range resp.Reservations {
+			for i := range reservation.Instances {
+				instance := reservation.Instances[i]
+				if instance.State != nil && instance.State.Name == types.InstanceStateNameRunning && instance.PublicIpAddress != nil {
+					return &instance, nil
+				}
+			}
+		}
+		if err := util.Sleep(ctx, instancePollInterval); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("instance %s is not running", instanceID)
+}
+
+// DeleteInstances terminates the instances and waits till they are terminated
+func (obj *AwsProvider) DeleteInstances(ctx context.Context, logging log.Logger, instanceIDs []string) error {
+	if len(instanceIDs) == 0 {
+		return nil
+	}
+	_, err := obj.ec2Client.TerminateInstances(ctx, &ec2.TerminateInstancesInput{InstanceIds: instanceIDs})
+	if err != nil {
+		return err
+	}
+
+	for retry := 0; retry < util.MAX_RETRY_COUNT*4; retry++ {
+		resp, err := obj.ec2Client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: instanceIDs})
+		if err != nil {
+			return err
+		}
+		terminated := true
+		for _, reservation := range resp.Reservations {
+			for _, instance := range reservation.Instances {
+				if instance.State == nil || instance.State.Name != types.InstanceStateNameTerminated {
+					terminated = false
+				}
+			}
+		}
+		if terminated {
+			logging.Info("Deleted the instances", fmt.Sprintf("%v", instanceIDs))
+			return nil
+		}
+		if err := util.Sleep(ctx, instancePollInterval); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("instances %v are not terminated", instanceIDs)
+}
+
+func (obj *AwsProvider) DeleteAllInstances(ctx context.Context, logging log.Logger) error {
+	var instanceIDs []string
+	for _, instanceID := range append(obj.Config.InfoControlPlanes.InstanceIDs, obj.Config.InfoWorkerPlanes.InstanceIDs...) {
+		// empty for the node which failed to be created
+		if len(instanceID) != 0 {
+			instanceIDs = append(instanceIDs, instanceID)
+		}
+	}
+	if len(obj.Config.InfoDatabase.InstanceID) != 0 {
+		instanceIDs = append(instanceIDs, obj.Config.InfoDatabase.InstanceID)
+	}
+	if len(obj.Config.InfoLoadBalancer.InstanceID) != 0 {
+		instanceIDs = append(instanceIDs, obj.Config.InfoLoadBalancer.InstanceID)
+	}
+	if err := obj.DeleteInstances(ctx, logging, instanceIDs); err != nil {
+		return err
+	}
+
+	obj.Config.InfoControlPlanes = AwsStateInstances{SecurityGroupID: obj.Config.InfoControlPlanes.SecurityGroupID}
+	obj.Config.InfoWorkerPlanes = AwsStateInstances{SecurityGroupID: obj.Config.InfoWorkerPlanes.SecurityGroupID}
+	obj.Config.InfoDatabase = AwsStateInstance{SecurityGroupID: obj.Config.InfoDatabase.SecurityGroupID}
+	obj.Config.InfoLoadBalancer = AwsStateInstance{SecurityGroupID: obj.Config.InfoLoadBalancer.SecurityGroupID}
+	logging.Info("Deleted all instances", "")
+	return nil
+}
+
+func (p printer) Printer(logging log.Logger, isHA bool, operation int) {
+	preFix := "export "
+	if runtime.GOOS == "windows" {
+		preFix = "$Env:"
+	}
+	switch operation {
+	case 0:
+		logging.Note("To use this cluster set this environment variable")
+		if isHA {
+			logging.Print(fmt.Sprintf("%sKUBECONFIG=\"%s\"\n", preFix, util.NewClusterRef("aws", "ha", p.ClusterName, p.Region).Path("config")))
+		} else {
+			logging.Print(fmt.Sprintf("%sKUBECONFIG=\"%s\"\n", preFix, util.NewClusterRef("aws", "managed", p.ClusterName, p.Region).Path("config")))
+		}
+	case 1:
+		if isHA {
+			util.ForgetKubeconfig(logging, util.NewClusterRef("aws", "ha", p.ClusterName, p.Region))
+		} else {
+			util.ForgetKubeconfig(logging, util.NewClusterRef("aws", "managed", p.ClusterName, p.Region))
+		}
+	}
+}
+
+// SaveKubeconfig stores the kubeconfig to state management file
+func (obj *AwsProvider) SaveKubeconfig(logging log.Logger, kubeconfig string) error {
+	kind := "managed"
+	if obj.HACluster {
+		kind = "ha"
+	}
+	err := util.WriteStateFile("aws", []byte(kubeconfig), kind, obj.clusterRef(kind).ID(), "config")
+	if err != nil {
+		return err
+	}
+	logging.Info("💾 Kubeconfig", "")
+	return nil
+}
+
+// awsResource a resource of the cluster found in aws, the resources looked up by their
+// name instead of their ID have the ID empty
+type awsResource struct {
+	Kind string
+	Name string
+	ID   string
+}
+
+func (resource awsResource) key() string {
+	if len(resource.ID) != 0 {
+		return resource.Kind + "/" + resource.ID
+	}
+	return resource.Kind + "/" + resource.Name
+}
+
+// driftResource a resource recorded in the state, all of its fields are blanked when it is forgotten
+type driftResource struct {
+	awsResource
+	fields []*string
+}
+
+func tagValue(tags []types.Tag, key string) string {
+	for _, tag := range tags {
+		if tag.Key != nil && *tag.Key == key && tag.Value != nil {
+			return *tag.Value
+		}
+	}
+	return ""
+}
+
+// listTagged the ec2 resources tagged with the cluster, see tagsFor
+func (obj *AwsProvider) listTagged(ctx context.Context) ([]awsResource, error) {
+	var resources []awsResource
+	tagged := []types.Filter{{Name: aws.String("tag:ksctl-cluster"), Values: []string{obj.ClusterName}}}
+
+	instances := ec2.NewDescribeInstancesPaginator(obj.ec2Client, &ec2.DescribeInstancesInput{
+		// the terminated instances are still listed for a while
+		Filters: append(tagged, types.Filter{Name: aws.String("instance-state-name"), Values: []string{"pending", "running", "stopping", "stopped"}}),
+	})
+	for instances.HasMorePages() {
+		page, err := instances.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				resources = append(resources, awsResource{Kind: "instance", Name: tagValue(instance.Tags, "Name"), ID: aws.ToString(instance.InstanceId)})
+			}
+		}
+	}
+
+	groups := ec2.NewDescribeSecurityGroupsPaginator(obj.ec2Client, &ec2.DescribeSecurityGroupsInput{Filters: tagged})
+	for groups.HasMorePages() {
+		page, err := groups.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range page.SecurityGroups {
+			resources = append(resources, awsResource{Kind: "security-group", Name: aws.ToString(group.GroupName), ID: aws.ToString(group.GroupId)})
+		}
+	}
+
+	vpcs := ec2.NewDescribeVpcsPaginator(obj.ec2Client, &ec2.DescribeVpcsInput{Filters: tagged})
+	for vpcs.HasMorePages() {
+		page, err := vpcs.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, vpc := range page.Vpcs {
+			resources = append(resources, awsResource{Kind: "vpc", Name: tagValue(vpc.Tags, "Name"), ID: aws.ToString(vpc.VpcId)})
+		}
+	}
+
+	subnets := ec2.NewDescribeSubnetsPaginator(obj.ec2Client, &ec2.DescribeSubnetsInput{Filters: tagged})
+	for subnets.HasMorePages() {
+		page, err := subnets.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, subnet := range page.Subnets {
+			resources = append(resources, awsResource{Kind: "subnet", Name: tagValue(subnet.Tags, "Name"), ID: aws.ToString(subnet.SubnetId)})
+		}
+	}
+
+	gateways := ec2.NewDescribeInternetGatewaysPaginator(obj.ec2Client, &ec2.DescribeInternetGatewaysInput{Filters: tagged})
+	for gateways.HasMorePages() {
+		page, err := gateways.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, gateway := range page.InternetGateways {
+			resources = append(resources, awsResource{Kind: "internet-gateway", Name: tagValue(gateway.Tags, "Name"), ID: aws.ToString(gateway.InternetGatewayId)})
+		}
+	}
+	return resources, nil
+}
+
+// listRecorded looks up the recorded resources of the cluster which are not tagged
+// these are only reported as missing as the untagged ones cannot be told apart
+func (obj *AwsProvider) listRecorded(ctx context.Context) ([]awsResource, error) {
+	var resources []awsResource
+	config := obj.Config
+
+	if len(config.RouteTableID) != 0 {
+		// the main route table of the vpc
+		routeTables, err := obj.ec2Client.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{
+			Filters: []types.Filter{{Name: aws.String("route-table-id"), Values: []string{config.RouteTableID}}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, routeTable := range routeTables.RouteTables {
+			resources = append(resources, awsResource{Kind: "route-table", ID: aws.ToString(routeTable.RouteTableId)})
+		}
+	}
+
+	if len(config.SSHKeyName) != 0 {
+		keys, err := obj.ec2Client.DescribeKeyPairs(ctx, &ec2.DescribeKeyPairsInput{
+			Filters: []types.Filter{{Name: aws.String("key-name"), Values: []string{config.SSHKeyName}}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys.KeyPairs {
+			resources = append(resources, awsResource{Kind: "key-pair", Name: aws.ToString(key.KeyName)})
+		}
+	}
+
+	if obj.HACluster {
+		return resources, nil
+	}
+
+	var notFound *ekstypes.ResourceNotFoundException
+	_, err := obj.eksClient.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: aws.String(obj.ClusterName)})
+	if err == nil {
+		resources = append(resources, awsResource{Kind: "eks-cluster", Name: obj.ClusterName})
+	} else if !errors.As(err, &notFound) {
+		return nil, err
+	}
+	if err == nil && len(config.NodeGroupName) != 0 {
+		_, err := obj.eksClient.DescribeNodegroup(ctx, &eks.DescribeNodegroupInput{
+			ClusterName:   aws.String(obj.ClusterName),
+			NodegroupName: aws.String(config.NodeGroupName),
+		})
+		if err == nil {
+			resources = append(resources, awsResource{Kind: "node-group", Name: config.NodeGroupName})
+		} else if !errors.As(err, &notFound) {
+			return nil, err
+		}
+	}
+
+	var noRole *iamtypes.NoSuchEntityException
+	for _, role := range []string{config.ClusterRoleName, config.NodeRoleName} {
+		if len(role) == 0 {
+			continue
+		}
+		_, err := obj.iamClient.GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String(role)})
+		if err == nil {
+			resources = append(resources, awsResource{Kind: "iam-role", Name: role})
+		} else if !errors.As(err, &noRole) {
+			return nil, err
+		}
+	}
+	return resources, nil
+}
+
+// recordedResources the resources of the cluster recorded in the state, the IPs of the instance
+// are forgotten along with it
+func (config *AwsStateCluster) recordedResources(clusterType string) []driftResource {
+	resources := []driftResource{
+		{awsResource{Kind: "vpc", ID: config.VpcID}, []*string{&config.VpcID}},
+		{awsResource{Kind: "subnet", ID: config.SubnetID}, []*string{&config.SubnetID}},
+		{awsResource{Kind: "internet-gateway", ID: config.InternetGatewayID}, []*string{&config.InternetGatewayID}},
+		{awsResource{Kind: "route-table", ID: config.RouteTableID}, []*string{&config.RouteTableID}},
+		{awsResource{Kind: "key-pair", Name: config.SSHKeyName}, []*string{&config.SSHKeyName}},
+	}
+	for index := range config.SubnetIDs {
+		resources = append(resources, driftResource{awsResource{Kind: "subnet", ID: config.SubnetIDs[index]}, []*string{&config.SubnetIDs[index]}})
+	}
+
+	if clusterType == util.CLUSTER_TYPE_MANAGED {
+		return append(resources,
+			// the eks cluster is not recorded by any field, it is removed by delete-cluster
+			driftResource{awsResource{Kind: "eks-cluster", Name: config.ClusterName}, nil},
+			driftResource{awsResource{Kind: "node-group", Name: config.NodeGroupName}, []*string{&config.NodeGroupName}},
+			driftResource{awsResource{Kind: "iam-role", Name: config.ClusterRoleName}, []*string{&config.ClusterRoleName}},
+			driftResource{awsResource{Kind: "iam-role", Name: config.NodeRoleName}, []*string{&config.NodeRoleName}},
+		)
+	}
+
+	for _, instances := range []struct {
+		role string
+		info *AwsStateInstances
+	}{
+		{util.NODE_ROLE_CONTROLPLANE, &config.InfoControlPlanes},
+		{util.NODE_ROLE_WORKER, &config.InfoWorkerPlanes},
+	} {
+		info := instances.info
+		resources = append(resources, driftResource{awsResource{Kind: "security-group", Name: instances.role, ID: info.SecurityGroupID}, []*string{&info.SecurityGroupID}})
+		for index := range info.InstanceIDs {
+			instance := driftResource{awsResource{Kind: "instance", ID: info.InstanceIDs[index]}, []*string{&info.InstanceIDs[index]}}
+			if index < len(info.Names) {
+				instance.Name = info.Names[index]
+			}
+			for _, values := range []*[]string{&info.Names, &info.PublicIPs, &info.PrivateIPs} {
+				if index < len(*values) {
+					instance.fields = append(instance.fields, &(*values)[index])
+				}
+			}
+			resources = append(resources, instance)
+		}
+	}
+	for _, instance := range []struct {
+		role string
+		info *AwsStateInstance
+	}{
+		{util.NODE_ROLE_LOADBALANCER, &config.InfoLoadBalancer},
+		{util.NODE_ROLE_DATABASE, &config.InfoDatabase},
+	} {
+		info := instance.info
+		resources = append(resources,
+			driftResource{awsResource{Kind: "security-group", Name: instance.role, ID: info.SecurityGroupID}, []*string{&info.SecurityGroupID}},
+			driftResource{awsResource{Kind: "instance", Name: info.Name, ID: info.InstanceID}, []*string{&info.InstanceID, &info.Name, &info.PublicIP, &info.PrivateIP}},
+		)
+	}
+	return resources
+}
+
+// checkDrift compares the resources recorded in the state of the cluster with the ones found in aws
+func (config *AwsStateCluster) checkDrift(report *util.DriftReport, found []awsResource) {
+	existing := map[string]bool{}
+	for _, resource := range found {
+		existing[resource.key()] = true
+	}
+	recorded := map[string]bool{}
+	for _, resource := range config.recordedResources(report.Type) {
+		if len(resource.fields) != 0 && len(*resource.fields[0]) == 0 {
+			// not created, the first field is the ID or the name the resource is looked up by
+			continue
+		}
+		report.Check(resource.Kind, resource.Name, resource.ID, existing[resource.key()])
+		recorded[resource.key()] = true
+	}
+	for _, resource := range found {
+		if !recorded[resource.key()] {
+			report.Unexpected(resource.Kind, resource.Name, resource.ID)
+		}
+	}
+}
+
+// forgetMissing removes the resources which are not found in aws from the state
+// the positions of the instances are kept as they are the numbers of the nodes
+func (config *AwsStateCluster) forgetMissing(clusterType string, missing []util.Drift) {
+	keys := map[string]bool{}
+	for _, drift := range missing {
+		keys[awsResource{Kind: drift.Kind, Name: drift.Name, ID: drift.ID}.key()] = true
+	}
+	for _, resource := range config.recordedResources(clusterType) {
+		if !keys[resource.key()] {
+			continue
+		}
+		for _, field := range resource.fields {
+			*field = ""
+		}
+	}
+}
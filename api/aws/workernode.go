@@ -0,0 +1,69 @@
+package eks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	log "github.com/kubesimplify/ksctl/api/logger"
+	util "github.com/kubesimplify/ksctl/api/utils"
+)
+
+func scriptWP(privateIPlb, token, k3sVersion string) string {
+	return fmt.Sprintf(`#!/bin/bash
+cat <<EOF > worker-setup.sh
+#!/bin/bash
+curl -sfL https://get.k3s.io | INSTALL_K3S_VERSION="%s" sh -s - agent --token %s --server https://%s:6443
+EOF
+
+sudo chmod +x worker-setup.sh
+sudo ./worker-setup.sh
+`, k3sVersion, token, privateIPlb)
+}
+
+// TODO: Add more firewall rules
+func getWorkerPlaneFirewallRules() []types.IpPermission {
+	return []types.IpPermission{
+		tcpRule(22, 22, "0.0.0.0/0"),
+		tcpRule(30000, 35000, "0.0.0.0/0"),
+		allTrafficRule(VPC_CIDR),
+	}
+}
+
+// TODO: try to make worker nodes as private instances
+// createWorkerPlane is called in parallel so the network must be created before by haCreateClusterHandler and AddMoreWorkerNodes
+func (obj *AwsProvider) createWorkerPlane(ctx context.Context, logging log.Logger, indexOfNode int) error {
+	defer obj.ConfigWriter(logging, "ha")
+
+	// security group is shared by the instances which are created in parallel
+	sgMutex.Lock()
+	if len(obj.Config.InfoWorkerPlanes.SecurityGroupID) == 0 {
+		sgID, err := obj.CreateSecurityGroup(ctx, logging, obj.ClusterName+"-wp-sg", util.NODE_ROLE_WORKER, getWorkerPlaneFirewallRules())
+		updateState(func() {
+			obj.Config.InfoWorkerPlanes.SecurityGroupID = sgID
+		})
+		if err != nil {
+			sgMutex.Unlock()
+			return err
+		}
+	}
+	sgMutex.Unlock()
+
+	name := fmt.Sprintf("%s-wp-%d", obj.ClusterName, indexOfNode)
+	index := indexOfNode - 1
+
+	instance, err := obj.CreateInstance(ctx, logging, name, util.NODE_ROLE_WORKER, obj.Config.InfoWorkerPlanes.SecurityGroupID, scriptWP(obj.Config.InfoLoadBalancer.PrivateIP, obj.Config.K3sToken, obj.k3sVersion()))
+	if len(instance.ID) != 0 {
+		updateState(func() {
+			obj.Config.InfoWorkerPlanes.Names = setAt(obj.Config.InfoWorkerPlanes.Names, index, name)
+			obj.Config.InfoWorkerPlanes.InstanceIDs = setAt(obj.Config.InfoWorkerPlanes.InstanceIDs, index, instance.ID)
+			obj.Config.InfoWorkerPlanes.PublicIPs = setAt(obj.Config.InfoWorkerPlanes.PublicIPs, index, instance.PublicIP)
+			obj.Config.InfoWorkerPlanes.PrivateIPs = setAt(obj.Config.InfoWorkerPlanes.PrivateIPs, index, instance.PrivateIP)
+		})
+	}
+	if err != nil {
+		return err
+	}
+	logging.Info("💻 Booted Worker plane VM: ", name)
+	return nil
+}
@@ -12,43 +12,14 @@ import (
 	util "github.com/kubesimplify/ksctl/api/utils"
 )
 
-func scriptWithoutCP_1(dbEndpoint, pubIPlb string) string {
-
-	return fmt.Sprintf(`#!/bin/bash
-cat <<EOF > control-setup.sh
-#!/bin/bash
-curl -sfL https://get.k3s.io | INSTALL_K3S_CHANNEL="v1.24.6+k3s1" sh -s - server \
-	--node-taint CriticalAddonsOnly=true:NoExecute \
-	--datastore-endpoint "%s" \
-	--tls-san %s
-EOF
-
-sudo chmod +x control-setup.sh
-sudo ./control-setup.sh
-`, dbEndpoint, pubIPlb)
+// asRoot the scripts of the distribution run as root while the ssh user of the VMs is not
+func asRoot(script string) string {
+	return "#!/bin/bash\ncat <<'KSCTL_SCRIPT' > ksctl-script.sh\n" + script + "\nKSCTL_SCRIPT\n\nsudo bash ksctl-script.sh\n"
 }
 
-func scriptWithCP_1() string {
-	return `#!/bin/bash
-sudo cat /var/lib/rancher/k3s/server/token
-`
-}
-
-func scriptCP_n(dbEndpoint, pubIPlb, token string) string {
-	return fmt.Sprintf(`#!/bin/bash
-cat <<EOF > control-setupN.sh
-#!/bin/bash
-curl -sfL https://get.k3s.io | INSTALL_K3S_CHANNEL="v1.24.6+k3s1" sh -s - server --token %s --datastore-endpoint="%s" --node-taint CriticalAddonsOnly=true:NoExecute --tls-san %s
-EOF
-log.Println
-sudo chmod +x control-setupN.sh
-sudo ./control-setupN.sh
-`, token, dbEndpoint, pubIPlb)
-}
-
-func scriptKUBECONFIG() string {
-	return `#!/bin/bash
-sudo cat /etc/rancher/k3s/k3s.yaml`
+// distribution of kubernetes and its version recorded in the state of the HA cluster
+func (obj *AzureProvider) distribution() (util.Distribution, error) {
+	return util.NewDistribution(obj.Config.Distribution, obj.Config.KubernetesVersion)
 }
 
 // TODO: Add more firewallrules
@@ -83,29 +54,37 @@ func getControlPlaneFirewallRules() (securityRules []*armnetwork.SecurityRule) {
 	return
 }
 
-func (obj *AzureProvider) FetchKUBECONFIG(logging log.Logger, publicIP string) (string, error) {
+func (obj *AzureProvider) FetchKUBECONFIG(ctx context.Context, logging log.Logger, publicIP string) (string, error) {
+	dist, err := obj.distribution()
+	if err != nil {
+		return "", err
+	}
 	obj.SSH_Payload.PublicIP = publicIP
 	obj.SSH_Payload.Output = ""
-	err := obj.SSH_Payload.SSHExecute(logging, util.EXEC_WITH_OUTPUT, scriptKUBECONFIG(), true)
+	err = obj.SSH_Payload.SSHExecute(ctx, logging, util.EXEC_WITH_OUTPUT, asRoot(dist.Kubeconfig()), true)
 
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 
 	return obj.SSH_Payload.Output, nil
 }
 
-// GetTokenFromCP_1 used to extract the K3S_TOKEN from the first Controlplane node
-func (obj *AzureProvider) GetTokenFromCP_1(logger log.Logger, PublicIP string) string {
+// GetTokenFromCP_1 used to extract the token the other nodes join with from the first Controlplane node
+func (obj *AzureProvider) GetTokenFromCP_1(ctx context.Context, logger log.Logger, PublicIP string) string {
+	dist, err := obj.distribution()
+	if err != nil {
+		return ""
+	}
 	obj.SSH_Payload.PublicIP = PublicIP
 	obj.SSH_Payload.Output = ""
-	err := obj.SSH_Payload.SSHExecute(logger, util.EXEC_WITH_OUTPUT, scriptWithCP_1(), true)
+	err = obj.SSH_Payload.SSHExecute(ctx, logger, util.EXEC_WITH_OUTPUT, asRoot(dist.Token()), true)
 	if err != nil {
 		return ""
 	}
 	token := obj.SSH_Payload.Output
 	obj.SSH_Payload.Output = ""
-	token = strings.Trim(token, "\n")
+	token = strings.TrimSpace(token)
 	obj.Config.K3sToken = token
 
 	obj.ConfigWriter(logger, "ha")
@@ -114,10 +93,10 @@ func (obj *AzureProvider) GetTokenFromCP_1(logger log.Logger, PublicIP string) s
 }
 
 // HelperExecNoOutputControlPlane helps with script execution without returning us the output
-func (obj *AzureProvider) HelperExecNoOutputControlPlane(logger log.Logger, publicIP, script string, fastMode bool) error {
+func (obj *AzureProvider) HelperExecNoOutputControlPlane(ctx context.Context, logger log.Logger, publicIP, script string, fastMode bool) error {
 	obj.SSH_Payload.PublicIP = publicIP
 	obj.SSH_Payload.Output = ""
-	err := obj.SSH_Payload.SSHExecute(logger, util.EXEC_WITH_OUTPUT, script, fastMode)
+	err := obj.SSH_Payload.SSHExecute(ctx, logger, util.EXEC_WITH_OUTPUT, script, fastMode)
 	if err != nil {
 		return err
 	}
@@ -126,10 +105,10 @@ func (obj *AzureProvider) HelperExecNoOutputControlPlane(logger log.Logger, publ
 }
 
 // HelperExecOutputControlPlane helps with script execution and also returns the script output
-func (obj *AzureProvider) HelperExecOutputControlPlane(logger log.Logger, publicIP, script string, fastMode bool) (string, error) {
+func (obj *AzureProvider) HelperExecOutputControlPlane(ctx context.Context, logger log.Logger, publicIP, script string, fastMode bool) (string, error) {
 	obj.SSH_Payload.Output = ""
 	obj.SSH_Payload.PublicIP = publicIP
-	err := obj.SSH_Payload.SSHExecute(logger, util.EXEC_WITH_OUTPUT, script, fastMode)
+	err := obj.SSH_Payload.SSHExecute(ctx, logger, util.EXEC_WITH_OUTPUT, script, fastMode)
 	if err != nil {
 		return "", err
 	}
@@ -152,39 +131,50 @@ func (obj *AzureProvider) createControlPlane(ctx context.Context, logger log.Log
 	}
 
 	vmName := fmt.Sprintf("%s-cp-%d", obj.ClusterName, indexOfNode)
+	index := indexOfNode - 1
+
+	// names are recorded before creating the resources so that they are deleted even if the creation fails
+	updateState(func() {
+		obj.Config.InfoControlPlanes.Names = setAt(obj.Config.InfoControlPlanes.Names, index, vmName)
+		obj.Config.InfoControlPlanes.DiskNames = setAt(obj.Config.InfoControlPlanes.DiskNames, index, vmName+"-disk")
+		obj.Config.InfoControlPlanes.PublicIPNames = setAt(obj.Config.InfoControlPlanes.PublicIPNames, index, vmName+"-pub-ip")
+		obj.Config.InfoControlPlanes.NetworkInterfaceNames = setAt(obj.Config.InfoControlPlanes.NetworkInterfaceNames, index, vmName+"-nic")
+	})
 
-	publicIP, err := obj.CreatePublicIP(ctx, logger, vmName+"-pub-ip")
+	publicIP, err := obj.CreatePublicIP(ctx, logger, vmName+"-pub-ip", util.NODE_ROLE_CONTROLPLANE)
 	if err != nil {
 		return err
 	}
-	obj.Config.InfoControlPlanes.PublicIPNames = append(obj.Config.InfoControlPlanes.PublicIPNames, *publicIP.Name)
 
-	// network security group
+	// network security group is shared by the VMs which are created in parallel
+	nsgMutex.Lock()
 	if len(obj.Config.InfoControlPlanes.NetworkSecurityGroupName) == 0 {
-		nsg, err := obj.CreateNSG(ctx, logger, obj.ClusterName+"-cp-nsg", getControlPlaneFirewallRules())
+		nsg, err := obj.CreateNSG(ctx, logger, obj.ClusterName+"-cp-nsg", util.NODE_ROLE_CONTROLPLANE, getControlPlaneFirewallRules())
 		if err != nil {
+			nsgMutex.Unlock()
 			return err
 		}
 
-		obj.Config.InfoControlPlanes.NetworkSecurityGroupName = *nsg.Name
-		obj.Config.InfoControlPlanes.NetworkSecurityGroupID = *nsg.ID
+		updateState(func() {
+			obj.Config.InfoControlPlanes.NetworkSecurityGroupName = *nsg.Name
+			obj.Config.InfoControlPlanes.NetworkSecurityGroupID = *nsg.ID
+		})
 	}
+	nsgMutex.Unlock()
 
-	networkInterface, err := obj.CreateNetworkInterface(ctx, logger, obj.Config.ResourceGroupName, vmName+"-nic", obj.Config.SubnetID, *publicIP.ID, obj.Config.InfoControlPlanes.NetworkSecurityGroupID)
+	networkInterface, err := obj.CreateNetworkInterface(ctx, logger, obj.Config.ResourceGroupName, vmName+"-nic", util.NODE_ROLE_CONTROLPLANE, obj.Config.SubnetID, *publicIP.ID, obj.Config.InfoControlPlanes.NetworkSecurityGroupID)
 	if err != nil {
 		return err
 	}
-	obj.Config.InfoControlPlanes.NetworkInterfaceNames = append(obj.Config.InfoControlPlanes.NetworkInterfaceNames, *networkInterface.Name)
-
-	obj.Config.InfoControlPlanes.Names = append(obj.Config.InfoControlPlanes.Names, vmName)
-	obj.Config.InfoControlPlanes.DiskNames = append(obj.Config.InfoControlPlanes.DiskNames, vmName+"-disk")
 
-	_, err = obj.CreateVM(ctx, logger, vmName, *networkInterface.ID, vmName+"-disk", "")
+	_, err = obj.CreateVM(ctx, logger, vmName, util.NODE_ROLE_CONTROLPLANE, *networkInterface.ID, vmName+"-disk", "")
 	if err != nil {
 		return err
 	}
-	obj.Config.InfoControlPlanes.PublicIPs = append(obj.Config.InfoControlPlanes.PublicIPs, *publicIP.Properties.IPAddress)
-	obj.Config.InfoControlPlanes.PrivateIPs = append(obj.Config.InfoControlPlanes.PrivateIPs, *networkInterface.Properties.IPConfigurations[0].Properties.PrivateIPAddress)
+	updateState(func() {
+		obj.Config.InfoControlPlanes.PublicIPs = setAt(obj.Config.InfoControlPlanes.PublicIPs, index, *publicIP.Properties.IPAddress)
+		obj.Config.InfoControlPlanes.PrivateIPs = setAt(obj.Config.InfoControlPlanes.PrivateIPs, index, *networkInterface.Properties.IPConfigurations[0].Properties.PrivateIPAddress)
+	})
 	logger.Info("💻 Booted Control plane VM", vmName)
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	log "github.com/kubesimplify/ksctl/api/logger"
+	util "github.com/kubesimplify/ksctl/api/utils"
 
 	"math/rand"
 	"strings"
@@ -119,7 +120,7 @@ func (obj *AzureProvider) createDatabase(ctx context.Context, logger log.Logger)
 	}
 	generatedPassword := generateDBPassword(20)
 
-	publicIP, err := obj.CreatePublicIP(ctx, logger, obj.ClusterName+"-db-pub-ip")
+	publicIP, err := obj.CreatePublicIP(ctx, logger, obj.ClusterName+"-db-pub-ip", util.NODE_ROLE_DATABASE)
 	if err != nil {
 		return err
 	}
@@ -127,7 +128,7 @@ func (obj *AzureProvider) createDatabase(ctx context.Context, logger log.Logger)
 
 	// network security group
 	if len(obj.Config.InfoDatabase.NetworkSecurityGroupName) == 0 {
-		nsg, err := obj.CreateNSG(ctx, logger, obj.ClusterName+"-db-nsg", getDatabaseFirewallRules())
+		nsg, err := obj.CreateNSG(ctx, logger, obj.ClusterName+"-db-nsg", util.NODE_ROLE_DATABASE, getDatabaseFirewallRules())
 		if err != nil {
 			return err
 		}
@@ -136,7 +137,7 @@ func (obj *AzureProvider) createDatabase(ctx context.Context, logger log.Logger)
 		obj.Config.InfoDatabase.NetworkSecurityGroupID = *nsg.ID
 	}
 
-	networkInterface, err := obj.CreateNetworkInterface(ctx, logger, obj.Config.ResourceGroupName, obj.ClusterName+"-db-nic", obj.Config.SubnetID, *publicIP.ID, obj.Config.InfoDatabase.NetworkSecurityGroupID)
+	networkInterface, err := obj.CreateNetworkInterface(ctx, logger, obj.Config.ResourceGroupName, obj.ClusterName+"-db-nic", util.NODE_ROLE_DATABASE, obj.Config.SubnetID, *publicIP.ID, obj.Config.InfoDatabase.NetworkSecurityGroupID)
 	if err != nil {
 		return err
 	}
@@ -146,7 +147,7 @@ func (obj *AzureProvider) createDatabase(ctx context.Context, logger log.Logger)
 	obj.Config.InfoDatabase.DiskName = obj.ClusterName + "-db-disk"
 
 	obj.Config.InfoDatabase.PrivateIP = *networkInterface.Properties.IPConfigurations[0].Properties.PrivateIPAddress
-	_, err = obj.CreateVM(ctx, logger, obj.ClusterName+"-db", *networkInterface.ID, obj.ClusterName+"-db-disk", scriptDB(generatedPassword))
+	_, err = obj.CreateVM(ctx, logger, obj.ClusterName+"-db", util.NODE_ROLE_DATABASE, *networkInterface.ID, obj.ClusterName+"-db-disk", scriptDB(generatedPassword))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,180 @@
+/*
+Kubesimplify
+@author: Dipankar Das
+*/
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	log "github.com/kubesimplify/ksctl/api/logger"
+	util "github.com/kubesimplify/ksctl/api/utils"
+)
+
+// azureMonthlyPrice the pay-as-you-go monthly price of the common vm sizes in USD
+// the sizes which are not listed are reported without a cost
+var azureMonthlyPrice = map[string]float64{
+	"Standard_B1ls":   3.80,
+	"Standard_B1s":    7.59,
+	"Standard_B1ms":   15.18,
+	"Standard_B2s":    30.37,
+	"Standard_B2ms":   60.74,
+	"Standard_B4ms":   121.47,
+	"Standard_B8ms":   242.94,
+	"Standard_D2s_v3": 70.08,
+	"Standard_D4s_v3": 140.16,
+	"Standard_D8s_v3": 280.32,
+}
+
+// knownResourceGroups the resource groups recorded in the state of every cluster
+// any state which cannot be read fails it as its resource group would be collected otherwise
+func knownResourceGroups(logging log.Logger) (map[string]bool, error) {
+	known := map[string]bool{}
+	for _, clusterType := range []string{util.CLUSTER_TYPE_HA, util.CLUSTER_TYPE_MANAGED} {
+		refs, err := util.IndexedClusters("azure", clusterType)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			state := AzureProvider{ClusterName: ref.Name, Region: ref.Region, Config: &AzureStateCluster{}}
+			if err := state.ConfigReader(logging, clusterType); err != nil {
+				return nil, fmt.Errorf("Unable to read configuration of %v: %v", ref.Name, err)
+			}
+			if len(state.Config.ResourceGroupName) != 0 {
+				known[strings.ToLower(state.Config.ResourceGroupName)] = true
+			}
+		}
+	}
+	return known, nil
+}
+
+// azureOrphans the resource groups of the region named by ksctl which are not known, in every region when it is empty
+// the resource groups of the HA clusters are named <name>-ha-ksctl and of the managed ones <name>-ksctl
+func azureOrphans(region string, known map[string]bool, groups []*armresources.ResourceGroup) []util.OrphanedResource {
+	var orphans []util.OrphanedResource
+	for _, group := range groups {
+		name, location := stringValue(group.Name), stringValue(group.Location)
+		if !strings.HasSuffix(name, "-ksctl") || known[strings.ToLower(name)] {
+			continue
+		}
+		if len(region) != 0 && !strings.EqualFold(location, region) {
+			continue
+		}
+		cluster := strings.TrimSuffix(strings.TrimSuffix(name, "-ksctl"), "-ha")
+		orphans = append(orphans, util.OrphanedResource{Kind: "resource-group", Name: name, ID: stringValue(group.ID), Region: location, Cluster: cluster})
+	}
+	return orphans
+}
+
+// isClusterLocked the resource group named <name>-ha-ksctl can also belong to the managed cluster <name>-ha
+// so both are looked up
+func isClusterLocked(orphan util.OrphanedResource) bool {
+	if strings.HasSuffix(orphan.Name, "-ha-ksctl") &&
+		util.IsLocked("azure", util.ClusterPayload{ClusterName: orphan.Cluster, Region: orphan.Region, HACluster: true}) {
+		return true
+	}
+	return util.IsLocked("azure", util.ClusterPayload{ClusterName: strings.TrimSuffix(orphan.Name, "-ksctl"), Region: orphan.Region})
+}
+
+// gcProvider the provider with the credentials needed by the garbage collector
+func gcProvider(ctx context.Context, logging log.Logger) (*AzureProvider, error) {
+	provider := &AzureProvider{}
+	if err := setRequiredENV_VAR(logging, ctx, provider); err != nil {
+		return nil, err
+	}
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, err
+	}
+	provider.AzureTokenCred = cred
+	return provider, nil
+}
+
+// azureOrphaned implements util.GarbageCollector
+// the resource groups of the clusters being created or deleted are skipped as they may not be recorded yet
+func azureOrphaned(ctx context.Context, logging log.Logger, region string) ([]util.OrphanedResource, error) {
+	if len(region) != 0 && !isValidRegion(region) {
+		return nil, fmt.Errorf("region {%s} is invalid", region)
+	}
+	known, err := knownResourceGroups(logging)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := gcProvider(ctx, logging)
+	if err != nil {
+		return nil, err
+	}
+	resourceGroupClient, err := getAzureResourceGroupsClient(provider)
+	if err != nil {
+		return nil, err
+	}
+	var groups []*armresources.ResourceGroup
+	pager := resourceGroupClient.NewListPager(nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, page.Value...)
+	}
+
+	vmClient, err := armcompute.NewVirtualMachinesClient(provider.SubscriptionID, provider.AzureTokenCred, nil)
+	if err != nil {
+		return nil, err
+	}
+	var orphans []util.OrphanedResource
+	for _, orphan := range azureOrphans(region, known, groups) {
+		if isClusterLocked(orphan) {
+			logging.Note("Skipping the resource of the cluster being changed", orphan.Name)
+			continue
+		}
+		// the cost of the resource group is that of the virtual machines left in it
+		vmPager := vmClient.NewListPager(orphan.Name, nil)
+		for vmPager.More() {
+			page, err := vmPager.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, vm := range page.Value {
+				if vm.Properties != nil && vm.Properties.HardwareProfile != nil && vm.Properties.HardwareProfile.VMSize != nil {
+					orphan.MonthlyCost += azureMonthlyPrice[string(*vm.Properties.HardwareProfile.VMSize)]
+				}
+			}
+		}
+		orphans = append(orphans, orphan)
+	}
+	return orphans, nil
+}
+
+// azureDeleteOrphaned implements util.GarbageCollector
+// deleting the resource group deletes everything left in it
+func azureDeleteOrphaned(ctx context.Context, logging log.Logger, resources []util.OrphanedResource) error {
+	provider, err := gcProvider(ctx, logging)
+	if err != nil {
+		return err
+	}
+	resourceGroupClient, err := getAzureResourceGroupsClient(provider)
+	if err != nil {
+		return err
+	}
+	var errV error
+	for _, resource := range resources {
+		poller, err := resourceGroupClient.BeginDelete(ctx, resource.Name, nil)
+		if err == nil {
+			_, err = poller.PollUntilDone(ctx, nil)
+		}
+		if err != nil {
+			errV = err
+			logging.Err(fmt.Sprintf("❌ unable to delete %s %s: %v", resource.Kind, resource.Name, err))
+			continue
+		}
+		logging.Info(fmt.Sprintf("✅ deleted %s", resource.Kind), resource.Name)
+	}
+	return errV
+}
@@ -6,13 +6,15 @@ package azure
 import (
 	"context"
 	"fmt"
-	"os"
 	"strings"
 
 	log "github.com/kubesimplify/ksctl/api/logger"
 	util "github.com/kubesimplify/ksctl/api/utils"
 )
 
+// haCreateClusterHandler creates a HA type cluster
+// every completed step is saved as a checkpoint in the state management file
+// so that a failed creation can be resumed by running it again with the saved state
 func haCreateClusterHandler(ctx context.Context, logger log.Logger, obj *AzureProvider) error {
 	if !util.IsValidName(obj.ClusterName) {
 		return fmt.Errorf("invalid cluster name: %v", obj.ClusterName)
@@ -30,89 +32,172 @@ func haCreateClusterHandler(ctx context.Context, logger log.Logger, obj *AzurePr
 		return err
 	}
 
+	dist, err := obj.distribution()
+	if err != nil {
+		return err
+	}
+
 	logger.Info("Started to Create your HA cluster on Azure provider")
 	defer obj.ConfigWriter(logger, "ha")
 
-	_, err := obj.CreateResourceGroup(ctx, logger)
+	_, err = obj.CreateResourceGroup(ctx, logger)
 	if err != nil {
 		return err
 	}
 
-	err = obj.UploadSSHKey(ctx)
-	if err != nil {
-		return err
+	if obj.Config.Checkpoints.Done(util.STEP_SSH_KEY) {
+		obj.setSSHPayload()
+	} else {
+		err = obj.UploadSSHKey(ctx)
+		if err != nil {
+			return err
+		}
+		if err := obj.checkpoint(logger, util.STEP_SSH_KEY); err != nil {
+			return err
+		}
 	}
 
-	err = obj.createLoadBalancer(logger, ctx)
-	if err != nil {
-		return err
+	if !obj.Config.Checkpoints.Done(util.STEP_LOADBALANCER) {
+		err = obj.createLoadBalancer(logger, ctx)
+		if err != nil {
+			return err
+		}
+		if err := obj.checkpoint(logger, util.STEP_LOADBALANCER); err != nil {
+			return err
+		}
 	}
 
-	err = obj.createDatabase(ctx, logger)
-	if err != nil {
-		return err
+	// the database is only created for the distribution keeping its state in it
+	if dist.ExternalDatastore() && !obj.Config.Checkpoints.Done(util.STEP_DATABASE) {
+		// the password of a database VM without the saved endpoint is lost so it is recreated
+		if len(obj.Config.InfoDatabase.Name) != 0 {
+			if err := obj.DeleteVM(ctx, logger, obj.Config.InfoDatabase.Name); err != nil {
+				return err
+			}
+			if err := obj.DeleteDisk(ctx, logger, obj.Config.InfoDatabase.DiskName); err != nil {
+				return err
+			}
+		}
+		err = obj.createDatabase(ctx, logger)
+		if err != nil {
+			return err
+		}
+		if err := obj.checkpoint(logger, util.STEP_DATABASE); err != nil {
+			return err
+		}
 	}
 
+	// the control planes which are already booted are kept
+	var pending []int
 	for i := 0; i < obj.Spec.HAControlPlaneNodes; i++ {
-		if err := obj.createControlPlane(ctx, logger, i+1); err != nil {
-			return err
+		if !obj.Config.InfoControlPlanes.booted(i) {
+			pending = append(pending, i+1)
 		}
 	}
+	err = util.RunInParallel(len(pending), util.MAX_PARALLEL_NODES, func(i int) error {
+		if err := obj.createControlPlane(ctx, logger, pending[i]); err != nil {
+			return fmt.Errorf("%s-cp-%d: %v", obj.ClusterName, pending[i], err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := obj.checkpoint(logger, util.STEP_CONTROLPLANES); err != nil {
+		return err
+	}
 
 	var controlPlaneIPs = make([]string, obj.Spec.HAControlPlaneNodes)
 	for i := 0; i < obj.Spec.HAControlPlaneNodes; i++ {
-		controlPlaneIPs[i] = obj.Config.InfoControlPlanes.PrivateIPs[i] + ":6443"
+		controlPlaneIPs[i] = obj.Config.InfoControlPlanes.PrivateIPs[i]
 	}
 
-	err = obj.ConfigLoadBalancer(logger, controlPlaneIPs)
-	if err != nil {
-		return err
+	if !obj.Config.Checkpoints.Done(util.STEP_LOADBALANCER_CONFIG) {
+		err = obj.ConfigLoadBalancer(ctx, logger, controlPlaneIPs)
+		if err != nil {
+			return err
+		}
+		if err := obj.checkpoint(logger, util.STEP_LOADBALANCER_CONFIG); err != nil {
+			return err
+		}
 	}
 
-	token := ""
-	mysqlEndpoint := obj.Config.DBEndpoint
-	loadBalancerPubIP := obj.Config.InfoLoadBalancer.PublicIP
+	token := obj.Config.K3sToken
+	if obj.Config.Checkpoints.Done(util.StepK3sControlPlane(1)) && !obj.Config.Checkpoints.Done(util.StepK3sControlPlane(obj.Spec.HAControlPlaneNodes)) {
+		// on resume the token is fetched again as kubeadm keeps the certificates for the controlplanes for 2 hours
+		if token = obj.GetTokenFromCP_1(ctx, logger, obj.Config.InfoControlPlanes.PublicIPs[0]); len(token) == 0 {
+			return fmt.Errorf("🚨 Cannot retrieve %s token", dist.Name())
+		}
+	}
 	for i := 0; i < obj.Spec.HAControlPlaneNodes; i++ {
+		if obj.Config.Checkpoints.Done(util.StepK3sControlPlane(i + 1)) {
+			continue
+		}
+		distConfig := util.DistributionConfig{
+			LoadBalancerPublicIP:  obj.Config.InfoLoadBalancer.PublicIP,
+			LoadBalancerPrivateIP: obj.Config.InfoLoadBalancer.PrivateIP,
+			DatastoreEndpoint:     obj.Config.DBEndpoint,
+			Token:                 token,
+		}
 		if i == 0 {
-			err = obj.HelperExecNoOutputControlPlane(logger, obj.Config.InfoControlPlanes.PublicIPs[i], scriptWithoutCP_1(mysqlEndpoint, loadBalancerPubIP), true)
+			err = obj.HelperExecNoOutputControlPlane(ctx, logger, obj.Config.InfoControlPlanes.PublicIPs[i], asRoot(dist.InstallFirstControlPlane(distConfig)), true)
 			if err != nil {
 				return err
 			}
 
-			token = obj.GetTokenFromCP_1(logger, obj.Config.InfoControlPlanes.PublicIPs[0])
+			token = obj.GetTokenFromCP_1(ctx, logger, obj.Config.InfoControlPlanes.PublicIPs[0])
 			if len(token) == 0 {
-				return fmt.Errorf("🚨 Cannot retrieve k3s token")
+				return fmt.Errorf("🚨 Cannot retrieve %s token", dist.Name())
 			}
 		} else {
-			err = obj.HelperExecNoOutputControlPlane(logger, obj.Config.InfoControlPlanes.PublicIPs[i], scriptCP_n(mysqlEndpoint, loadBalancerPubIP, token), true)
+			err = obj.HelperExecNoOutputControlPlane(ctx, logger, obj.Config.InfoControlPlanes.PublicIPs[i], asRoot(dist.JoinControlPlane(distConfig)), true)
 			if err != nil {
 				return err
 			}
 		}
+		if err := obj.checkpoint(logger, util.StepK3sControlPlane(i+1)); err != nil {
+			return err
+		}
 		logger.Info("✅ Configured", fmt.Sprintf("%s-cp-%d", obj.ClusterName, i+1))
 	}
 
 	// Configure the Loadbalancer
-	kubeconfig, err := obj.FetchKUBECONFIG(logger, obj.Config.InfoControlPlanes.PublicIPs[0])
-	if err != nil {
-		return fmt.Errorf("Cannot fetch kubeconfig\n" + err.Error())
-	}
-	newKubeconfig := strings.Replace(kubeconfig, "127.0.0.1", obj.Config.InfoLoadBalancer.PublicIP, 1)
-
-	newKubeconfig = strings.Replace(newKubeconfig, "default", obj.ClusterName+"-"+obj.Region+"-ha-azure-ksctl", -1)
-
-	err = obj.SaveKubeconfig(logger, newKubeconfig)
-	if err != nil {
-		return err
+	if !obj.Config.Checkpoints.Done(util.STEP_KUBECONFIG) {
+		kubeconfig, err := obj.FetchKUBECONFIG(ctx, logger, obj.Config.InfoControlPlanes.PublicIPs[0])
+		if err != nil {
+			return fmt.Errorf("Cannot fetch kubeconfig\n" + err.Error())
+		}
+		err = obj.SaveKubeconfig(logger, obj.haKubeconfig(kubeconfig))
+		if err != nil {
+			return err
+		}
+		if err := obj.checkpoint(logger, util.STEP_KUBECONFIG); err != nil {
+			return err
+		}
 	}
 
 	logger.Info("⛓  JOINING WORKER NODES", "")
 
+	// the worker planes which are already booted are kept
+	pending = pending[:0]
 	for i := 0; i < obj.Spec.HAWorkerNodes; i++ {
-		if err := obj.createWorkerPlane(logger, ctx, i+1); err != nil {
-			return err
+		if !obj.Config.InfoWorkerPlanes.booted(i) {
+			pending = append(pending, i+1)
 		}
 	}
+	err = util.RunInParallel(len(pending), util.MAX_PARALLEL_NODES, func(i int) error {
+		if err := obj.createWorkerPlane(logger, ctx, pending[i]); err != nil {
+			return fmt.Errorf("%s-wp-%d: %v", obj.ClusterName, pending[i], err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := obj.checkpoint(logger, util.STEP_WORKERPLANES); err != nil {
+		return err
+	}
+
 	logger.Info("Created your HA azure cluster!!🥳 🎉 ")
 	logger.Note("\n🗒 Currently no firewall Rules are being used so you can add them using CIVO Dashboard")
 
@@ -185,27 +270,32 @@ func haDeleteClusterHandler(ctx context.Context, logger log.Logger, obj *AzurePr
 		return err
 	}
 
-	err = obj.DeleteSubnet(ctx, logger, obj.Config.SubnetName)
-	if err != nil {
-		return err
+	if len(obj.Config.SubnetName) != 0 {
+		err = obj.DeleteSubnet(ctx, logger, obj.Config.SubnetName)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = obj.DeleteVirtualNetwork(ctx, logger)
-	if err != nil {
-		return err
+	if len(obj.Config.VirtualNetworkName) != 0 {
+		err = obj.DeleteVirtualNetwork(ctx, logger)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = obj.DeleteSSHKeyPair(ctx, logger)
-	if err != nil {
-		return err
+	if len(obj.Config.SSHKeyName) != 0 {
+		err = obj.DeleteSSHKeyPair(ctx, logger)
+		if err != nil {
+			return err
+		}
 	}
 
 	err = obj.DeleteResourceGroup(ctx, logger)
 	if err != nil {
 		return err
 	}
-	clusterDir := obj.ClusterName + " " + obj.Config.ResourceGroupName + " " + obj.Region
-	if err := os.RemoveAll(util.GetPath(util.CLUSTER_PATH, "azure", "ha", clusterDir)); err != nil {
+	if err := util.DeleteClusterState(clusterRef("ha", obj.ClusterName, obj.Region)); err != nil {
 		return err
 	}
 
@@ -214,3 +304,24 @@ func haDeleteClusterHandler(ctx context.Context, logger log.Logger, obj *AzurePr
 	printKubeconfig.Printer(logger, false, 1)
 	return nil
 }
+
+// haKubeconfig the kubeconfig of the distribution points at the loadbalancer instead of the controlplane
+// and its entries are named after the cluster
+func (obj *AzureProvider) haKubeconfig(kubeconfig string) string {
+	kubeconfig = strings.Replace(kubeconfig, "127.0.0.1", obj.Config.InfoLoadBalancer.PublicIP, 1)
+	return strings.Replace(kubeconfig, "default", obj.ClusterName+"-"+obj.Region+"-ha-azure-ksctl", -1)
+}
+
+// refreshHAKubeconfig fetches the kubeconfig of the HA cluster again from its first controlplane
+func (obj *AzureProvider) refreshHAKubeconfig(ctx context.Context, logging log.Logger) error {
+	if len(obj.Config.InfoControlPlanes.PublicIPs) == 0 || len(obj.Config.InfoControlPlanes.PublicIPs[0]) == 0 || len(obj.Config.InfoLoadBalancer.PublicIP) == 0 {
+		return fmt.Errorf("the cluster %s has no controlplane or loadbalancer", obj.ClusterName)
+	}
+	obj.SSH_Payload = &util.SSHPayload{}
+	obj.setSSHPayload()
+	kubeconfig, err := obj.FetchKUBECONFIG(ctx, logging, obj.Config.InfoControlPlanes.PublicIPs[0])
+	if err != nil {
+		return fmt.Errorf("Cannot fetch kubeconfig\n" + err.Error())
+	}
+	return obj.SaveKubeconfig(logging, obj.haKubeconfig(kubeconfig))
+}
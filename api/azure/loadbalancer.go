@@ -2,7 +2,6 @@ package azure
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
@@ -18,35 +17,13 @@ sudo systemctl start haproxy && sudo systemctl enable haproxy
 `
 }
 
-func configLBscript(controlPlaneIPs []string) string {
-	script := `#!/bin/bash
-cat <<EOF > haproxy.cfg
-frontend kubernetes-frontend
-  bind *:6443
-  mode tcp
-  option tcplog
-  timeout client 10s
-  default_backend kubernetes-backend
-
-backend kubernetes-backend
-  timeout connect 10s
-  timeout server 10s
-  mode tcp
-  option tcp-check
-  balance roundrobin
-`
-
-	for index, controlPlaneIP := range controlPlaneIPs {
-		script += fmt.Sprintf(`  server k3sserver-%d %s check
-`, index+1, controlPlaneIP)
-	}
-
-	script += `EOF
+// configLBscript forwards the ports of the distribution to the controlplanes
+func configLBscript(controlPlaneIPs []string, ports []int) string {
+	return "#!/bin/bash\ncat <<EOF > haproxy.cfg\n" + util.HAProxyConfig(controlPlaneIPs, ports) + `EOF
 
 sudo mv haproxy.cfg /etc/haproxy/haproxy.cfg
 sudo systemctl restart haproxy
 `
-	return script
 }
 
 // TODO: Add more firewall rules
@@ -96,10 +73,14 @@ func getLoadBalancerFirewallRules() (securityRules []*armnetwork.SecurityRule) {
 	return
 }
 
-func (obj *AzureProvider) ConfigLoadBalancer(logger log.Logger, CPIPs []string) error {
-	getScript := configLBscript(CPIPs)
+func (obj *AzureProvider) ConfigLoadBalancer(ctx context.Context, logger log.Logger, CPIPs []string) error {
+	dist, err := obj.distribution()
+	if err != nil {
+		return err
+	}
+	getScript := configLBscript(CPIPs, dist.Ports())
 	obj.SSH_Payload.PublicIP = obj.Config.InfoLoadBalancer.PublicIP
-	err := obj.SSH_Payload.SSHExecute(logger, util.EXEC_WITHOUT_OUTPUT, getScript, true)
+	err = obj.SSH_Payload.SSHExecute(ctx, logger, util.EXEC_WITHOUT_OUTPUT, getScript, true)
 	if err == nil {
 		logger.Info("✅ Configured LoadBalancer", "")
 		return nil
@@ -122,7 +103,7 @@ func (obj *AzureProvider) createLoadBalancer(logger log.Logger, ctx context.Cont
 		}
 	}
 
-	publicIP, err := obj.CreatePublicIP(ctx, logger, obj.ClusterName+"-lb-pub-ip")
+	publicIP, err := obj.CreatePublicIP(ctx, logger, obj.ClusterName+"-lb-pub-ip", util.NODE_ROLE_LOADBALANCER)
 	if err != nil {
 		return err
 	}
@@ -130,7 +111,7 @@ func (obj *AzureProvider) createLoadBalancer(logger log.Logger, ctx context.Cont
 
 	// network security group
 	if len(obj.Config.InfoLoadBalancer.NetworkSecurityGroupName) == 0 {
-		nsg, err := obj.CreateNSG(ctx, logger, obj.ClusterName+"-lb-nsg", getLoadBalancerFirewallRules())
+		nsg, err := obj.CreateNSG(ctx, logger, obj.ClusterName+"-lb-nsg", util.NODE_ROLE_LOADBALANCER, getLoadBalancerFirewallRules())
 		if err != nil {
 			return err
 		}
@@ -139,7 +120,7 @@ func (obj *AzureProvider) createLoadBalancer(logger log.Logger, ctx context.Cont
 		obj.Config.InfoLoadBalancer.NetworkSecurityGroupID = *nsg.ID
 	}
 
-	networkInterface, err := obj.CreateNetworkInterface(ctx, logger, obj.Config.ResourceGroupName, obj.ClusterName+"-lb-nic", obj.Config.SubnetID, *publicIP.ID, obj.Config.InfoLoadBalancer.NetworkSecurityGroupID)
+	networkInterface, err := obj.CreateNetworkInterface(ctx, logger, obj.Config.ResourceGroupName, obj.ClusterName+"-lb-nic", util.NODE_ROLE_LOADBALANCER, obj.Config.SubnetID, *publicIP.ID, obj.Config.InfoLoadBalancer.NetworkSecurityGroupID)
 	if err != nil {
 		return err
 	}
@@ -148,7 +129,7 @@ func (obj *AzureProvider) createLoadBalancer(logger log.Logger, ctx context.Cont
 	obj.Config.InfoLoadBalancer.Name = obj.ClusterName + "-lb"
 	obj.Config.InfoLoadBalancer.DiskName = obj.ClusterName + "-lb-disk"
 
-	_, err = obj.CreateVM(ctx, logger, obj.ClusterName+"-lb", *networkInterface.ID, obj.ClusterName+"-lb-disk", scriptLB())
+	_, err = obj.CreateVM(ctx, logger, obj.ClusterName+"-lb", util.NODE_ROLE_LOADBALANCER, *networkInterface.ID, obj.ClusterName+"-lb-disk", scriptLB())
 	if err != nil {
 		return err
 	}
@@ -8,9 +8,9 @@ package azure
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	log "github.com/kubesimplify/ksctl/api/logger"
 	util "github.com/kubesimplify/ksctl/api/utils"
@@ -18,46 +18,72 @@ import (
 
 // TODO: add the VMSize as user defined option
 
-func Credentials(logger log.Logger) bool {
-	logger.Print("Enter your SUBSCRIPTION ID 👇")
-	skey, err := util.UserInputCredentials(logger)
-	if err != nil {
-		panic(err.Error())
-	}
-
-	logger.Print("Enter your TENANT ID 👇")
-	tid, err := util.UserInputCredentials(logger)
-	if err != nil {
-		panic(err.Error())
-	}
-
-	logger.Print("Enter your CLIENT ID 👇")
-	cid, err := util.UserInputCredentials(logger)
-	if err != nil {
-		panic(err.Error())
-	}
+func init() {
+	util.RegisterProvider("azure", func(payload util.ClusterPayload) util.Provider {
+		return &AzureProvider{
+			ClusterName: payload.ClusterName,
+			HACluster:   payload.HACluster,
+			Region:      payload.Region,
+			Spec:        payload.Spec,
+
+			Resume:            payload.Resume,
+			CleanupOnFailure:  payload.CleanupOnFailure,
+			Tags:              payload.Tags,
+			Distribution:      payload.Distribution,
+			KubernetesVersion: payload.KubernetesVersion,
+			AdminKubeconfig:   payload.AdminKubeconfig,
+			AutoApprove:       payload.AutoApprove,
+		}
+	})
+	util.RegisterCredentials("azure", util.CredentialType{
+		Fields: []util.CredentialField{
+			{Key: "subscription_id", Env: "AZURE_SUBSCRIPTION_ID", Prompt: "Enter your SUBSCRIPTION ID"},
+			{Key: "tenant_id", Env: "AZURE_TENANT_ID", Prompt: "Enter your TENANT ID"},
+			{Key: "client_id", Env: "AZURE_CLIENT_ID", Prompt: "Enter your CLIENT ID"},
+			{Key: "client_secret", Env: "AZURE_CLIENT_SECRET", Prompt: "Enter your CLIENT SECRET", Secret: true},
+		},
+		Verify: verifyCredentials,
+	})
+	util.RegisterGarbageCollector("azure", util.GarbageCollector{
+		Orphaned: azureOrphaned,
+		Delete:   azureDeleteOrphaned,
+	})
+}
 
-	logger.Print("Enter your CLIENT SECRET 👇")
-	cs, err := util.UserInputCredentials(logger)
+// verifyCredentials acquires a token of the azure resource manager for the service principal
+func verifyCredentials(ctx context.Context, cred map[string]string) error {
+	token, err := azidentity.NewClientSecretCredential(cred["tenant_id"], cred["client_id"], cred["client_secret"], nil)
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
+	_, err = token.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}})
+	return err
+}
 
-	apiStore := util.AzureCredential{
-		SubscriptionID: skey,
-		TenantID:       tid,
-		ClientID:       cid,
-		ClientSecret:   cs,
+func Credentials(logger log.Logger) bool {
+	cred := map[string]string{}
+	for _, field := range []struct{ key, prompt string }{
+		{"subscription_id", "Enter your SUBSCRIPTION ID 👇"},
+		{"tenant_id", "Enter your TENANT ID 👇"},
+		{"client_id", "Enter your CLIENT ID 👇"},
+		{"client_secret", "Enter your CLIENT SECRET 👇"},
+	} {
+		logger.Print(field.prompt)
+		value, err := util.UserInputCredentials(logger)
+		if err != nil {
+			logger.Err(err.Error())
+			return false
+		}
+		cred[field.key] = value
 	}
 
-	err = util.SaveCred(logger, apiStore, "azure")
+	err := util.SetCred(context.Background(), logger, "azure", cred, true)
 
 	if err != nil {
 		logger.Err(err.Error())
 		return false
 	}
 	return true
-
 }
 
 type AzureProvider struct {
@@ -69,10 +95,25 @@ type AzureProvider struct {
 	Config         *AzureStateCluster     `json:"config"`
 	AzureTokenCred azcore.TokenCredential `json:"azure_token_cred"`
 	SSH_Payload    *util.SSHPayload       `json:"ssh___payload"`
+
+	// Resume continues the HA cluster creation from the saved checkpoints
+	Resume bool `json:"resume"`
+	// CleanupOnFailure deletes the partially created HA cluster when the creation fails
+	CleanupOnFailure bool `json:"cleanup_on_failure"`
+	// Tags given by the user for the resources of the cluster
+	Tags map[string]string `json:"tags"`
+	// Distribution of kubernetes of the HA cluster
+	Distribution string `json:"distribution"`
+	// KubernetesVersion the minor version of kubernetes of the cluster
+	KubernetesVersion string `json:"kubernetes_version"`
+	// AdminKubeconfig the kubeconfig of the AKS cluster has its admin credentials instead of the user credentials
+	AdminKubeconfig bool `json:"admin_kubeconfig"`
+	// AutoApprove the worker nodes are deleted without asking for the confirmation
+	AutoApprove bool `json:"auto_approve"`
 }
 
 // AddMoreWorkerNodes adds more worker nodes to the existing HA cluster
-func (obj *AzureProvider) AddMoreWorkerNodes(logging log.Logger) error {
+func (obj *AzureProvider) AddMoreWorkerNodes(ctx context.Context, logging log.Logger) error {
 
 	// logging := log.Logger{Verbose: true} // make it move to cli part
 	if !util.IsValidName(obj.ClusterName) {
@@ -86,7 +127,6 @@ func (obj *AzureProvider) AddMoreWorkerNodes(logging log.Logger) error {
 		return fmt.Errorf("region {%s} is invalid", obj.Region)
 	}
 
-	ctx := context.Background()
 	err := setRequiredENV_VAR(logging, ctx, obj)
 	if err != nil {
 		return err
@@ -112,14 +152,33 @@ func (obj *AzureProvider) AddMoreWorkerNodes(logging log.Logger) error {
 
 	logging.Info("JOINING Additional WORKER NODES", "")
 
+	dist, err := obj.distribution()
+	if err != nil {
+		return err
+	}
+	// the bootstrap token of kubeadm expires so a new one is created on the first controlplane
+	obj.setSSHPayload()
+	obj.Config.K3sToken, err = util.JoinToken(dist, obj.Config.K3sToken, func() (string, error) {
+		if len(obj.Config.InfoControlPlanes.PublicIPs) == 0 {
+			return "", fmt.Errorf("the cluster %s has no controlplane", obj.ClusterName)
+		}
+		return obj.GetTokenFromCP_1(ctx, logging, obj.Config.InfoControlPlanes.PublicIPs[0]), nil
+	})
+	if err != nil {
+		return err
+	}
+
 	noOfWorkerNodes := len(obj.Config.InfoWorkerPlanes.Names)
 
-	for i := 0; i < obj.Spec.HAWorkerNodes; i++ {
-		err := obj.createWorkerPlane(logging, ctx, i+noOfWorkerNodes+1)
-		if err != nil {
-			logging.Err("Failed to add more nodes..")
-			return err
+	err = util.RunInParallel(obj.Spec.HAWorkerNodes, util.MAX_PARALLEL_NODES, func(i int) error {
+		if err := obj.createWorkerPlane(logging, ctx, i+noOfWorkerNodes+1); err != nil {
+			return fmt.Errorf("%s-wp-%d: %v", obj.ClusterName, i+noOfWorkerNodes+1, err)
 		}
+		return nil
+	})
+	if err != nil {
+		logging.Err("Failed to add more nodes..")
+		return err
 	}
 
 	logging.Info("Added more nodes 🥳 🎉 ")
@@ -127,7 +186,7 @@ func (obj *AzureProvider) AddMoreWorkerNodes(logging log.Logger) error {
 }
 
 // DeleteSomeWorkerNodes deletes workerNodes from existing HA cluster
-func (obj *AzureProvider) DeleteSomeWorkerNodes(logging log.Logger) error {
+func (obj *AzureProvider) DeleteSomeWorkerNodes(ctx context.Context, logging log.Logger) error {
 
 	// logging := log.Logger{Verbose: true} // make it move to cli part
 	if !util.IsValidName(obj.ClusterName) {
@@ -150,21 +209,10 @@ then deletion will happen from 4, 3, 2, 1
 2) then delete before deleting the instance
 		kubectl delete node <node name>
 `)
-	logging.Print("Enter your choice to continue..[y/N]")
-	choice := "n"
-	unsafe := false
-	fmt.Scanf("%s", &choice)
-	if strings.Compare("y", choice) == 0 ||
-		strings.Compare("yes", choice) == 0 ||
-		strings.Compare("Y", choice) == 0 {
-		unsafe = true
-	}
-
-	if !unsafe {
-		return nil
+	if err := util.ConfirmNodesDeletion(logging, obj.AutoApprove); err != nil {
+		return err
 	}
 
-	ctx := context.Background()
 	err := setRequiredENV_VAR(logging, ctx, obj)
 	if err != nil {
 		return err
@@ -236,9 +284,8 @@ then deletion will happen from 4, 3, 2, 1
 	return nil
 }
 
-func (obj *AzureProvider) CreateCluster(logging log.Logger) error {
+func (obj *AzureProvider) CreateCluster(ctx context.Context, logging log.Logger) error {
 
-	ctx := context.Background()
 	err := setRequiredENV_VAR(logging, ctx, obj)
 	if err != nil {
 		return err
@@ -252,26 +299,71 @@ func (obj *AzureProvider) CreateCluster(logging log.Logger) error {
 	obj.Config = &AzureStateCluster{}
 	obj.Config.ClusterName = obj.ClusterName
 	obj.SSH_Payload = &util.SSHPayload{}
+	getRelease := util.GetKubernetesRelease
+	if !obj.HACluster {
+		getRelease = util.GetManagedKubernetesRelease
+	}
+	release, err := getRelease(obj.KubernetesVersion)
+	if err != nil {
+		return err
+	}
 	if obj.HACluster {
 		obj.Config.ResourceGroupName = obj.ClusterName + "-ha-ksctl"
+		obj.Config.ClusterMetadata = util.NewClusterMetadata(obj.Spec.Disk, 0)
+		obj.Config.Tags = obj.Tags
+		dist, err := util.NewDistribution(obj.Distribution, release.Version)
+		if err != nil {
+			return err
+		}
+		// on resume the distribution and the version saved in the state are used
+		obj.Config.Distribution = dist.Name()
+		obj.Config.KubernetesVersion = release.Version
+		obj.Config.ControlPlaneNodes, obj.Config.WorkerNodes = obj.Spec.HAControlPlaneNodes, obj.Spec.HAWorkerNodes
 
 		if isPresent("ha", *obj) {
-			return fmt.Errorf("cluster already exists: %v", obj.ClusterName)
+			if !obj.Resume {
+				return fmt.Errorf("cluster already exists: %v, use --resume to continue its creation", obj.ClusterName)
+			}
+			if err := obj.ConfigReader(logging, "ha"); err != nil {
+				return fmt.Errorf("Unable to read configuration: %v", err)
+			}
+			if err := obj.Config.ValidateResume(obj.Spec.Disk, obj.Spec.HAControlPlaneNodes, obj.Spec.HAWorkerNodes,
+				len(obj.Config.InfoControlPlanes.Names), len(obj.Config.InfoWorkerPlanes.Names)); err != nil {
+				return err
+			}
+			logging.Note(fmt.Sprintf("Resuming the cluster creation, completed steps: %v", obj.Config.Checkpoints))
 		}
-		err := haCreateClusterHandler(ctx, logging, obj)
+		err = haCreateClusterHandler(ctx, logging, obj)
 		if err != nil {
-			logging.Err("CLEANUP TRIGGERED!: failed to create")
-			_ = haDeleteClusterHandler(ctx, logging, obj, false)
+			if !isPresent("ha", *obj) {
+				return err
+			}
+			if ctx.Err() != nil {
+				// an interrupted creation keeps its state so that it can be resumed or deleted later
+				logging.Note("Cluster creation was interrupted, rerun with --resume to continue or use delete-cluster to remove it")
+			} else if obj.CleanupOnFailure {
+				logging.Err("CLEANUP TRIGGERED!: failed to create")
+				_ = haDeleteClusterHandler(ctx, logging, obj, false)
+			} else {
+				logging.Note("The created resources are kept, rerun with --resume to continue or use delete-cluster to remove them")
+			}
 			return err
 		}
 	} else {
 		obj.Config.ResourceGroupName = obj.ClusterName + "-ksctl"
+		obj.Config.ClusterMetadata = util.NewClusterMetadata(obj.Spec.Disk, obj.Spec.ManagedNodes)
+		obj.Config.Tags = obj.Tags
+		obj.Config.KubernetesVersion = release.Version
 
 		if isPresent("managed", *obj) {
 			return fmt.Errorf("cluster already exists: %v", obj.ClusterName)
 		}
 		_, err := managedCreateClusterHandler(ctx, logging, obj)
 		if err != nil {
+			if ctx.Err() != nil {
+				logging.Note("Cluster creation was interrupted, use delete-cluster to remove it")
+				return err
+			}
 			logging.Err("CLEANUP TRIGGERED!: failed to create")
 			_ = managedDeleteClusterHandler(ctx, logging, obj, false)
 			return err
@@ -280,9 +372,8 @@ func (obj *AzureProvider) CreateCluster(logging log.Logger) error {
 	return nil
 }
 
-func (obj *AzureProvider) DeleteCluster(logging log.Logger) error {
+func (obj *AzureProvider) DeleteCluster(ctx context.Context, logging log.Logger) error {
 
-	ctx := context.Background()
 	err := setRequiredENV_VAR(logging, ctx, obj)
 	if err != nil {
 		return err
@@ -320,25 +411,297 @@ func (obj *AzureProvider) DeleteCluster(logging log.Logger) error {
 	return nil
 }
 
-func (provider AzureProvider) SwitchContext(logging log.Logger) error {
+// SwitchContext merges the kubeconfig of the cluster into the default kubeconfig and makes it the current context
+func (provider AzureProvider) SwitchContext(ctx context.Context, logging log.Logger) error {
 	provider.Config = &AzureStateCluster{}
-	switch provider.HACluster {
-	case true:
+	kind := "managed"
+	provider.Config.ResourceGroupName = provider.ClusterName + "-ksctl"
+	if provider.HACluster {
+		kind = "ha"
 		provider.Config.ResourceGroupName = provider.ClusterName + "-ha-ksctl"
-		if isPresent("ha", provider) {
-			var printKubeconfig util.PrinterKubeconfigPATH
-			printKubeconfig = printer{ClusterName: provider.ClusterName, Region: provider.Region, ResourceName: provider.Config.ResourceGroupName}
-			printKubeconfig.Printer(logging, true, 0)
-			return nil
-		}
-	case false:
-		provider.Config.ResourceGroupName = provider.ClusterName + "-ksctl"
-		if isPresent("managed", provider) {
-			var printKubeconfig util.PrinterKubeconfigPATH
-			printKubeconfig = printer{ClusterName: provider.ClusterName, Region: provider.Region, ResourceName: provider.Config.ResourceGroupName}
-			printKubeconfig.Printer(logging, false, 0)
-			return nil
-		}
-	}
-	return fmt.Errorf("ERR Cluster not found")
+	}
+	if !isPresent(kind, provider) {
+		return fmt.Errorf("ERR Cluster not found")
+	}
+	provider.fetchKubeconfig(logging, kind)
+	return util.SwitchKubeconfig(logging, clusterRef(kind, provider.ClusterName, provider.Region))
+}
+
+// Kubeconfig implements util.Provider, the kubeconfig of the AKS cluster is fetched with its user credentials
+// or with its admin credentials when asked, and of the HA cluster from its first controlplane
+// the saved kubeconfig of the AKS cluster is kept when its admin credentials are only read
+func (obj *AzureProvider) Kubeconfig(ctx context.Context, logging log.Logger, refresh bool) ([]byte, error) {
+	clusterType, resourceGroup := util.CLUSTER_TYPE_MANAGED, obj.ClusterName+"-ksctl"
+	if obj.HACluster {
+		clusterType, resourceGroup = util.CLUSTER_TYPE_HA, obj.ClusterName+"-ha-ksctl"
+	}
+	provider := *obj
+	provider.Config = &AzureStateCluster{ResourceGroupName: resourceGroup}
+	if !isPresent(clusterType, provider) {
+		return nil, fmt.Errorf("cluster does not exists: %v", obj.ClusterName)
+	}
+	if !refresh && !provider.HACluster && provider.AdminKubeconfig {
+		if err := provider.ConfigReader(logging, clusterType); err != nil {
+			return nil, fmt.Errorf("Unable to read configuration: %v", err)
+		}
+		kubeconfig, err := provider.fetchManagedKubeconfig(ctx, logging)
+		return []byte(kubeconfig), err
+	}
+	if refresh {
+		if err := provider.ConfigReader(logging, clusterType); err != nil {
+			return nil, fmt.Errorf("Unable to read configuration: %v", err)
+		}
+		var err error
+		if provider.HACluster {
+			err = provider.refreshHAKubeconfig(ctx, logging)
+		} else {
+			err = provider.refreshManagedKubeconfig(ctx, logging)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return util.ReadStateFile("azure", clusterType, clusterRef(clusterType, obj.ClusterName, obj.Region).ID(), "config")
+}
+
+// Create implements util.Provider
+func (obj *AzureProvider) Create(ctx context.Context, logging log.Logger) error {
+	return obj.CreateCluster(ctx, logging)
+}
+
+// Delete implements util.Provider
+func (obj *AzureProvider) Delete(ctx context.Context, logging log.Logger) error {
+	return obj.DeleteCluster(ctx, logging)
+}
+
+// AddNodes implements util.Provider, only HA clusters are supported
+func (obj *AzureProvider) AddNodes(ctx context.Context, logging log.Logger) error {
+	if !obj.HACluster {
+		return fmt.Errorf("adding nodes is only supported for HA cluster")
+	}
+	return obj.AddMoreWorkerNodes(ctx, logging)
+}
+
+// DeleteNodes implements util.Provider, only HA clusters are supported
+func (obj *AzureProvider) DeleteNodes(ctx context.Context, logging log.Logger) error {
+	if !obj.HACluster {
+		return fmt.Errorf("deleting nodes is only supported for HA cluster")
+	}
+	return obj.DeleteSomeWorkerNodes(ctx, logging)
+}
+
+// List returns all the HA and managed clusters present in the state management files
+// the node counts, node size and creation time are read from their info.json
+func (obj *AzureProvider) List(ctx context.Context, logging log.Logger) ([]util.ClusterInfo, error) {
+	var clusters []util.ClusterInfo
+
+	for _, clusterType := range []string{util.CLUSTER_TYPE_HA, util.CLUSTER_TYPE_MANAGED} {
+		refs, err := util.IndexedClusters("azure", clusterType)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			cluster, _ := clusterInfo(logging, clusterType, ref.Name, ref.Region)
+			clusters = append(clusters, cluster)
+		}
+	}
+	return clusters, nil
+}
+
+// clusterInfo the summary of the cluster along with its state, the state is nil when
+// it cannot be read and only the name and region are known
+func clusterInfo(logging log.Logger, clusterType, clusterName, region string) (util.ClusterInfo, *AzureStateCluster) {
+	cluster := util.ClusterInfo{
+		ClusterName:    clusterName,
+		Region:         region,
+		Provider:       "azure",
+		Type:           clusterType,
+		KubeconfigPath: clusterRef(clusterType, clusterName, region).Path("config"),
+	}
+
+	state := AzureProvider{ClusterName: clusterName, Region: region}
+	state.Config = &AzureStateCluster{}
+	if err := state.ConfigReader(logging, clusterType); err != nil {
+		return cluster, nil
+	}
+	cluster.NodeSize = state.Config.NodeSize
+	cluster.CreatedAt = state.Config.CreatedAt
+	if clusterType == util.CLUSTER_TYPE_HA {
+		cluster.ControlPlaneNodes = util.NoOfNodes(state.Config.InfoControlPlanes.PrivateIPs)
+		cluster.WorkerNodes = util.NoOfNodes(state.Config.InfoWorkerPlanes.PrivateIPs)
+	} else {
+		cluster.WorkerNodes = state.Config.ManagedNodes
+	}
+	return cluster, state.Config
+}
+
+// Describe implements util.Provider
+func (obj *AzureProvider) Describe(ctx context.Context, logging log.Logger) (util.ClusterDescription, error) {
+	clusterType, resourceGroup := util.CLUSTER_TYPE_MANAGED, obj.ClusterName+"-ksctl"
+	if obj.HACluster {
+		clusterType, resourceGroup = util.CLUSTER_TYPE_HA, obj.ClusterName+"-ha-ksctl"
+	}
+	provider := *obj
+	provider.Config = &AzureStateCluster{ResourceGroupName: resourceGroup}
+	if !isPresent(clusterType, provider) {
+		return util.ClusterDescription{}, fmt.Errorf("cluster does not exists: %v", obj.ClusterName)
+	}
+
+	cluster, state := clusterInfo(logging, clusterType, obj.ClusterName, obj.Region)
+	description := util.ClusterDescription{ClusterInfo: cluster}
+	if state == nil {
+		return description, fmt.Errorf("Unable to read configuration of %v", obj.ClusterName)
+	}
+
+	description.Resources = []util.ClusterResource{{Kind: "resource-group", Name: state.ResourceGroupName}}
+	if !obj.HACluster {
+		description.Resources = append(description.Resources, util.ClusterResource{Kind: "aks-cluster", Name: state.ClusterName})
+		return description, nil
+	}
+
+	description.DatabaseEndpoint = util.RedactPassword(state.DBEndpoint)
+	for _, resource := range []util.ClusterResource{
+		{Kind: "virtual-network", Name: state.VirtualNetworkName, ID: state.VirtualNetworkID},
+		{Kind: "subnet", Name: state.SubnetName, ID: state.SubnetID},
+		{Kind: "nsg", Name: state.InfoControlPlanes.NetworkSecurityGroupName, ID: state.InfoControlPlanes.NetworkSecurityGroupID},
+		{Kind: "nsg", Name: state.InfoWorkerPlanes.NetworkSecurityGroupName, ID: state.InfoWorkerPlanes.NetworkSecurityGroupID},
+		{Kind: "nsg", Name: state.InfoLoadBalancer.NetworkSecurityGroupName, ID: state.InfoLoadBalancer.NetworkSecurityGroupID},
+		{Kind: "nsg", Name: state.InfoDatabase.NetworkSecurityGroupName, ID: state.InfoDatabase.NetworkSecurityGroupID},
+		{Kind: "ssh-key", Name: state.SSHKeyName},
+	} {
+		if len(resource.Name) != 0 || len(resource.ID) != 0 {
+			description.Resources = append(description.Resources, resource)
+		}
+	}
+
+	for _, vm := range []struct {
+		role string
+		info AzureStateVM
+	}{
+		{util.NODE_ROLE_LOADBALANCER, state.InfoLoadBalancer},
+		{util.NODE_ROLE_DATABASE, state.InfoDatabase},
+	} {
+		if len(vm.info.Name) != 0 {
+			description.Nodes = append(description.Nodes, util.ClusterNode{Role: vm.role, Name: vm.info.Name, PublicIP: vm.info.PublicIP, PrivateIP: vm.info.PrivateIP})
+		}
+	}
+	description.Nodes = append(description.Nodes, azureNodes(util.NODE_ROLE_CONTROLPLANE, state.InfoControlPlanes)...)
+	description.Nodes = append(description.Nodes, azureNodes(util.NODE_ROLE_WORKER, state.InfoWorkerPlanes)...)
+	return description, nil
+}
+
+// CheckDrift implements util.Provider
+// the resource group is dedicated to the cluster so the resources in it which are not in the state are unexpected
+func (obj *AzureProvider) CheckDrift(ctx context.Context, logging log.Logger, fix bool) (util.DriftReport, error) {
+	clusterType, resourceGroup := util.CLUSTER_TYPE_MANAGED, obj.ClusterName+"-ksctl"
+	if obj.HACluster {
+		clusterType, resourceGroup = util.CLUSTER_TYPE_HA, obj.ClusterName+"-ha-ksctl"
+	}
+	report := util.DriftReport{ClusterName: obj.ClusterName, Provider: "azure", Type: clusterType, Region: obj.Region}
+	provider := *obj
+	provider.Config = &AzureStateCluster{ResourceGroupName: resourceGroup}
+	if !isPresent(clusterType, provider) {
+		return report, fmt.Errorf("cluster does not exists: %v", obj.ClusterName)
+	}
+	if err := provider.ConfigReader(logging, clusterType); err != nil {
+		return report, fmt.Errorf("Unable to read configuration: %v", err)
+	}
+	if err := setRequiredENV_VAR(logging, ctx, &provider); err != nil {
+		return report, err
+	}
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return report, err
+	}
+	provider.AzureTokenCred = cred
+
+	found, err := provider.listResourceGroup(ctx)
+	if isNotFound(err) {
+		// nothing of the cluster is left
+		report.Check("resource-group", provider.Config.ResourceGroupName, "", false)
+		if fix {
+			if err := util.DeleteClusterState(clusterRef(clusterType, obj.ClusterName, obj.Region)); err != nil {
+				return report, err
+			}
+			report.Fixed = true
+		}
+		return report, nil
+	}
+	if err != nil {
+		return report, err
+	}
+	report.Check("resource-group", provider.Config.ResourceGroupName, "", true)
+
+	if !obj.HACluster {
+		exists := false
+		for _, resource := range found {
+			if resource.Kind == "aks-cluster" && resource.Name == provider.Config.ClusterName {
+				exists = true
+			}
+		}
+		if !report.Check("aks-cluster", provider.Config.ClusterName, "", exists) && fix {
+			// the state is kept as the resource group is still there, it is removed by delete-cluster
+			logging.Note("The resource group of the cluster still exists, use delete-cluster to remove it")
+		}
+		return report, nil
+	}
+
+	provider.Config.checkDrift(&report, found)
+	if fix && len(report.Missing()) != 0 {
+		updateState(func() {
+			provider.Config.forgetMissing(report.Missing())
+		})
+		if err := provider.ConfigWriter(logging, clusterType); err != nil {
+			return report, err
+		}
+		report.Fixed = true
+	}
+	return report, nil
+}
+
+// azureNodes the VMs of the role, the IPs are recorded only after the VM is created
+func azureNodes(role string, vms AzureStateVMs) []util.ClusterNode {
+	var nodes []util.ClusterNode
+	for index, name := range vms.Names {
+		if len(name) == 0 {
+			continue
+		}
+		node := util.ClusterNode{Role: role, Name: name}
+		if index < len(vms.PublicIPs) {
+			node.PublicIP = vms.PublicIPs[index]
+		}
+		if index < len(vms.PrivateIPs) {
+			node.PrivateIP = vms.PrivateIPs[index]
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// IsPresent implements util.Provider
+func (obj *AzureProvider) IsPresent() bool {
+	provider := *obj
+	provider.Config = &AzureStateCluster{}
+	if provider.HACluster {
+		provider.Config.ResourceGroupName = provider.ClusterName + "-ha-ksctl"
+		return isPresent("ha", provider)
+	}
+	provider.Config.ResourceGroupName = provider.ClusterName + "-ksctl"
+	return isPresent("managed", provider)
+}
+
+// NoOfWorkerNodes implements util.Provider, only HA clusters are supported
+func (obj *AzureProvider) NoOfWorkerNodes(ctx context.Context, logging log.Logger) (int, error) {
+	if !obj.HACluster {
+		return 0, fmt.Errorf("worker nodes are only tracked for HA cluster")
+	}
+	provider := *obj
+	provider.Config = &AzureStateCluster{ResourceGroupName: provider.ClusterName + "-ha-ksctl"}
+	if !isPresent("ha", provider) {
+		return 0, fmt.Errorf("cluster does not exists: %v", provider.ClusterName)
+	}
+	if err := provider.ConfigReader(logging, "ha"); err != nil {
+		return 0, fmt.Errorf("Unable to read configuration: %v", err)
+	}
+	return len(provider.Config.InfoWorkerPlanes.Names), nil
 }
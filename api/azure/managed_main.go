@@ -9,6 +9,7 @@ import (
 	log "github.com/kubesimplify/ksctl/api/logger"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice"
 	util "github.com/kubesimplify/ksctl/api/utils"
 )
@@ -64,7 +65,7 @@ func managedDeleteClusterHandler(ctx context.Context, logging log.Logger, azureC
 	if err != nil {
 		return err
 	}
-	if err := os.RemoveAll(util.GetPath(util.CLUSTER_PATH, "azure", "managed", azureConfig.ClusterName+" "+azureConfig.Config.ResourceGroupName+" "+azureConfig.Region)); err != nil {
+	if err := util.DeleteClusterState(clusterRef("managed", azureConfig.ClusterName, azureConfig.Region)); err != nil {
 		return err
 	}
 	var printKubeconfig util.PrinterKubeconfigPATH
@@ -90,10 +91,18 @@ func managedCreateClusterHandler(ctx context.Context, logging log.Logger, azureC
 	if !isValidRegion(azureConfig.Region) {
 		return nil, fmt.Errorf("region {%s} is invalid", azureConfig.Region)
 	}
+	release, err := util.GetManagedKubernetesRelease(azureConfig.Config.KubernetesVersion)
+	if err != nil {
+		return nil, err
+	}
+	var kubernetesVersion *string
+	if len(release.AKS) != 0 {
+		kubernetesVersion = to.Ptr(release.AKS)
+	}
 
 	defer azureConfig.ConfigWriter(logging, "managed")
 
-	_, err := azureConfig.CreateResourceGroup(ctx, logging)
+	_, err = azureConfig.CreateResourceGroup(ctx, logging)
 	if err != nil {
 		return nil, err
 	}
@@ -110,8 +119,10 @@ func managedCreateClusterHandler(ctx context.Context, logging log.Logger, azureC
 		azureConfig.ClusterName,
 		armcontainerservice.ManagedCluster{
 			Location: to.Ptr(azureConfig.Region),
+			Tags:     azureConfig.resourceTags(util.TAG_ROLE_CLUSTER),
 			Properties: &armcontainerservice.ManagedClusterProperties{
-				DNSPrefix: to.Ptr("aksgosdk"),
+				DNSPrefix:         to.Ptr("aksgosdk"),
+				KubernetesVersion: kubernetesVersion,
 				AgentPoolProfiles: []*armcontainerservice.ManagedClusterAgentPoolProfile{
 					{
 						Name:              to.Ptr("askagent"),
@@ -124,6 +135,7 @@ func managedCreateClusterHandler(ctx context.Context, logging log.Logger, azureC
 						Type:              to.Ptr(armcontainerservice.AgentPoolTypeVirtualMachineScaleSets),
 						EnableAutoScaling: to.Ptr(true),
 						Mode:              to.Ptr(armcontainerservice.AgentPoolModeSystem),
+						Tags:              azureConfig.resourceTags(util.NODE_ROLE_WORKER),
 					},
 				},
 				ServicePrincipalProfile: &armcontainerservice.ManagedClusterServicePrincipalProfile{
@@ -144,13 +156,10 @@ func managedCreateClusterHandler(ctx context.Context, logging log.Logger, azureC
 		return nil, err
 	}
 
-	kubeconfig, err := managedClustersClient.ListClusterAdminCredentials(ctx, azureConfig.Config.ResourceGroupName, azureConfig.ClusterName, nil)
+	KUBECONFIG, err := azureConfig.managedKubeconfig(ctx, logging, managedClustersClient)
 	if err != nil {
 		return nil, err
 	}
-	KUBECONFIG := string(kubeconfig.Kubeconfigs[0].Value)
-
-	logging.Note("the kubeconfig to be saved has admin credentials")
 
 	if err := azureConfig.SaveKubeconfig(logging, KUBECONFIG); err != nil {
 		return nil, err
@@ -162,3 +171,53 @@ func managedCreateClusterHandler(ctx context.Context, logging log.Logger, azureC
 	printKubeconfig.Printer(logging, false, 0)
 	return &resp.ManagedCluster, nil
 }
+
+// managedKubeconfig the kubeconfig of the AKS cluster with its user credentials, or its admin credentials
+// when AdminKubeconfig is set
+func (obj *AzureProvider) managedKubeconfig(ctx context.Context, logging log.Logger, client *armcontainerservice.ManagedClustersClient) (string, error) {
+	var credentials armcontainerservice.CredentialResults
+	if obj.AdminKubeconfig {
+		resp, err := client.ListClusterAdminCredentials(ctx, obj.Config.ResourceGroupName, obj.ClusterName, nil)
+		if err != nil {
+			return "", err
+		}
+		credentials = resp.CredentialResults
+		logging.Note("the kubeconfig has admin credentials")
+	} else {
+		resp, err := client.ListClusterUserCredentials(ctx, obj.Config.ResourceGroupName, obj.ClusterName, nil)
+		if err != nil {
+			return "", err
+		}
+		credentials = resp.CredentialResults
+	}
+	if len(credentials.Kubeconfigs) == 0 {
+		return "", fmt.Errorf("no kubeconfig was returned for the cluster %s", obj.ClusterName)
+	}
+	return string(credentials.Kubeconfigs[0].Value), nil
+}
+
+// fetchManagedKubeconfig fetches the kubeconfig of the AKS cluster without saving it
+func (obj *AzureProvider) fetchManagedKubeconfig(ctx context.Context, logging log.Logger) (string, error) {
+	if err := setRequiredENV_VAR(logging, ctx, obj); err != nil {
+		return "", err
+	}
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return "", err
+	}
+	obj.AzureTokenCred = cred
+	managedClustersClient, err := getAzureManagedClusterClient(obj)
+	if err != nil {
+		return "", err
+	}
+	return obj.managedKubeconfig(ctx, logging, managedClustersClient)
+}
+
+// refreshManagedKubeconfig fetches the kubeconfig of the AKS cluster again and saves it
+func (obj *AzureProvider) refreshManagedKubeconfig(ctx context.Context, logging log.Logger) error {
+	kubeconfig, err := obj.fetchManagedKubeconfig(ctx, logging)
+	if err != nil {
+		return err
+	}
+	return obj.SaveKubeconfig(logging, kubeconfig)
+}
@@ -2,14 +2,18 @@ package azure
 
 import (
 	"encoding/base64"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
+	"sync"
 
 	"runtime"
 
 	log "github.com/kubesimplify/ksctl/api/logger"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice"
@@ -19,14 +23,6 @@ import (
 	"golang.org/x/net/context"
 )
 
-type AzureOperations interface {
-	CreateCluster(log.Logger) error
-	DeleteCluster(log.Logger) error
-
-	AddMoreWorkerNodes(log.Logger) error
-	DeleteSomeWorkerNodes(log.Logger) error
-}
-
 type AzureStateVMs struct {
 	Names                    []string `json:"names"`
 	NetworkSecurityGroupName string   `json:"network_security_group_name"`
@@ -38,6 +34,23 @@ type AzureStateVMs struct {
 	NetworkInterfaceNames    []string `json:"network_interface_names"`
 }
 
+// booted whether the VM at the index was created completely
+// the private IP is the last one to be recorded for every VM
+func (vms *AzureStateVMs) booted(index int) bool {
+	return len(vms.PrivateIPs) > index && len(vms.PrivateIPs[index]) != 0
+}
+
+// setAt places the value at the index, the positions of the VMs which are not yet created are left empty
+// the VMs are created in parallel so their values are kept in the order of the node number
+// the empty values, also left by check-drift for the missing resources, are skipped when deleting
+func setAt(values []string, index int, value string) []string {
+	for len(values) <= index {
+		values = append(values, "")
+	}
+	values[index] = value
+	return values
+}
+
 type AzureStateVM struct {
 	Name                     string `json:"name"`
 	NetworkSecurityGroupName string `json:"network_security_group_name"`
@@ -54,7 +67,8 @@ type AzureStateCluster struct {
 	ResourceGroupName string `json:"resource_group_name"`
 	SSHKeyName        string `json:"ssh_key_name"`
 	DBEndpoint        string `json:"database_endpoint"`
-	K3sToken          string `json:"k3s_token"`
+	// K3sToken the token of the distribution the nodes join with, its name is kept for the saved states
+	K3sToken string `json:"k3s_token"`
 
 	SubnetName         string `json:"subnet_name"`
 	SubnetID           string `json:"subnet_id"`
@@ -65,6 +79,12 @@ type AzureStateCluster struct {
 	InfoWorkerPlanes  AzureStateVMs `json:"info_worker_planes"`
 	InfoDatabase      AzureStateVM  `json:"info_database"`
 	InfoLoadBalancer  AzureStateVM  `json:"info_load_balancer"`
+
+	// Checkpoints steps of the HA cluster creation which are completed
+	Checkpoints util.Checkpoints `json:"checkpoints,omitempty"`
+
+	// ClusterMetadata is kept in the envelope of the state, see util.ClusterState
+	util.ClusterMetadata `json:"-"`
 }
 
 type AzureInfra interface {
@@ -75,16 +95,16 @@ type AzureInfra interface {
 	DeleteDisk(context.Context, log.Logger, string) error
 	DeleteSubnet(context.Context, log.Logger) error
 	CreateSubnet(context.Context, log.Logger, string) (*armnetwork.Subnet, error)
-	CreateVM(context.Context, log.Logger, string, string, string) (*armcompute.VirtualMachine, error)
+	CreateVM(context.Context, log.Logger, string, string, string, string, string) (*armcompute.VirtualMachine, error)
 	DeleteVM(context.Context, log.Logger, string) error
 	CreateVirtualNetwork(context.Context, log.Logger, string) (*armnetwork.VirtualNetwork, error)
 	DeleteVirtualNetwork(context.Context, log.Logger) error
-	CreateNSG(context.Context, log.Logger, string, []*armnetwork.SecurityRule) (*armnetwork.SecurityGroup, error)
+	CreateNSG(context.Context, log.Logger, string, string, []*armnetwork.SecurityRule) (*armnetwork.SecurityGroup, error)
 	DeleteNSG(context.Context, log.Logger, string) error
 	DeleteNetworkInterface(context.Context, log.Logger, string) error
-	CreateNetworkInterface(context.Context, log.Logger, string, string, string, string, string) (*armnetwork.Interface, error)
+	CreateNetworkInterface(context.Context, log.Logger, string, string, string, string, string, string) (*armnetwork.Interface, error)
 	DeletePublicIP(context.Context, log.Logger, string) error
-	CreatePublicIP(context.Context, log.Logger, string) (*armnetwork.PublicIPAddress, error)
+	CreatePublicIP(context.Context, log.Logger, string, string) (*armnetwork.PublicIPAddress, error)
 
 	UploadSSHKey(context.Context, log.Logger) (err error)
 	DeleteSSHKey(context.Context, log.Logger) error
@@ -123,30 +143,52 @@ func isValidRegion(region string) bool {
 	return false
 }
 
+// stateMutex serializes the updates of the state as the VMs of the HA cluster are created in parallel
+var stateMutex sync.Mutex
+
+// nsgMutex guards the creation of the network security group shared by the VMs created in parallel
+var nsgMutex sync.Mutex
+
+// updateState applies the change to the state while no other VM is updating or saving it
+func updateState(change func()) {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+	change()
+}
+
+// clusterRef identifies the azure cluster in the state management files
+func clusterRef(clusterType, clusterName, region string) util.ClusterRef {
+	return util.NewClusterRef("azure", clusterType, clusterName, region)
+}
+
 func (config *AzureProvider) ConfigWriter(logging log.Logger, clusterType string) error {
-	return util.SaveState(logging, config.Config, "azure", clusterType, config.ClusterName+" "+config.Config.ResourceGroupName+" "+config.Region)
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+	return util.SaveState(logging, config.Config, clusterRef(clusterType, config.ClusterName, config.Region))
 }
 
-func isPresent(kind string, obj AzureProvider) bool {
-	path := util.GetPath(util.CLUSTER_PATH, "azure", kind, obj.ClusterName+" "+obj.Config.ResourceGroupName+" "+obj.Region, "info.json")
-	_, err := os.ReadFile(path)
-	if os.IsNotExist(err) {
-		return false
+// checkpoint records the completed step of the HA cluster creation in the state management file
+func (config *AzureProvider) checkpoint(logging log.Logger, step string) error {
+	updateState(func() {
+		config.Config.Checkpoints.Mark(step)
+	})
+	logging = logging.With("step", step)
+	if err := config.ConfigWriter(logging, "ha"); err != nil {
+		return err
 	}
-	return true
+	logging.Debug("checkpoint saved")
+	return nil
+}
+
+func isPresent(kind string, obj AzureProvider) bool {
+	return clusterRef(kind, obj.ClusterName, obj.Region).IsPresent()
 }
 
 func (config *AzureProvider) ConfigReader(logging log.Logger, clusterType string) error {
-	data, err := util.GetState(logging, "azure", clusterType, config.ClusterName+" "+config.Config.ResourceGroupName+" "+config.Region)
-	if err != nil {
+	var structData AzureStateCluster
+	if err := util.GetState(logging, &structData, clusterRef(clusterType, config.ClusterName, config.Region)); err != nil {
 		return err
 	}
-	// Convert the map to JSON
-	jsonData, _ := json.Marshal(data)
-
-	// Convert the JSON to a struct
-	var structData AzureStateCluster
-	json.Unmarshal(jsonData, &structData)
 
 	config.Config = &structData
 	config.ClusterName = config.Config.ClusterName
@@ -218,6 +260,23 @@ func setRequiredENV_VAR(logging log.Logger, ctx context.Context, cred *AzureProv
 	return nil
 }
 
+// resourceTags the ownership tags of the resource of the given role along with the tags of the user
+func (obj *AzureProvider) resourceTags(role string) map[string]*string {
+	clusterType := util.CLUSTER_TYPE_MANAGED
+	if obj.HACluster {
+		clusterType = util.CLUSTER_TYPE_HA
+	}
+	var userTags map[string]string
+	if obj.Config != nil {
+		userTags = obj.Config.Tags
+	}
+	tags := map[string]*string{}
+	for key, value := range util.ResourceTags(clusterRef(clusterType, obj.ClusterName, obj.Region), role, userTags) {
+		tags[key] = to.Ptr(value)
+	}
+	return tags
+}
+
 func getAzureManagedClusterClient(cred *AzureProvider) (*armcontainerservice.ManagedClustersClient, error) {
 
 	managedClustersClient, err := armcontainerservice.NewManagedClustersClient(cred.SubscriptionID, cred.AzureTokenCred, nil)
@@ -246,6 +305,7 @@ func (obj *AzureProvider) CreateResourceGroup(ctx context.Context, logging log.L
 		obj.Config.ResourceGroupName,
 		armresources.ResourceGroup{
 			Location: to.Ptr(obj.Region),
+			Tags:     obj.resourceTags(util.TAG_ROLE_CLUSTER),
 		},
 		nil)
 	if err != nil {
@@ -320,7 +380,7 @@ func (obj *AzureProvider) DeleteSubnet(ctx context.Context, logging log.Logger,
 	return nil
 }
 
-func (obj *AzureProvider) CreatePublicIP(ctx context.Context, logging log.Logger, publicIPName string) (*armnetwork.PublicIPAddress, error) {
+func (obj *AzureProvider) CreatePublicIP(ctx context.Context, logging log.Logger, publicIPName, role string) (*armnetwork.PublicIPAddress, error) {
 	publicIPAddressClient, err := armnetwork.NewPublicIPAddressesClient(obj.SubscriptionID, obj.AzureTokenCred, nil)
 	if err != nil {
 		return nil, err
@@ -328,6 +388,7 @@ func (obj *AzureProvider) CreatePublicIP(ctx context.Context, logging log.Logger
 
 	parameters := armnetwork.PublicIPAddress{
 		Location: to.Ptr(obj.Region),
+		Tags:     obj.resourceTags(role),
 		Properties: &armnetwork.PublicIPAddressPropertiesFormat{
 			PublicIPAllocationMethod: to.Ptr(armnetwork.IPAllocationMethodStatic), // Static or Dynamic
 		},
@@ -348,11 +409,17 @@ func (obj *AzureProvider) CreatePublicIP(ctx context.Context, logging log.Logger
 
 func (obj *AzureProvider) DeleteAllPublicIP(ctx context.Context, logging log.Logger) error {
 	for _, interfaceName := range obj.Config.InfoControlPlanes.PublicIPNames {
+		if len(interfaceName) == 0 {
+			continue
+		}
 		if err := obj.DeletePublicIP(ctx, logging, interfaceName); err != nil {
 			return err
 		}
 	}
 	for _, interfaceName := range obj.Config.InfoWorkerPlanes.PublicIPNames {
+		if len(interfaceName) == 0 {
+			continue
+		}
 		if err := obj.DeletePublicIP(ctx, logging, interfaceName); err != nil {
 			return err
 		}
@@ -411,40 +478,44 @@ func (obj *AzureProvider) UploadSSHKey(ctx context.Context) (err error) {
 	if err != nil {
 		return
 	}
-	path := util.GetPath(util.CLUSTER_PATH, "azure", "ha", obj.ClusterName+" "+obj.Config.ResourceGroupName+" "+obj.Region)
-	err = os.MkdirAll(path, 0755)
+	ref := clusterRef("ha", obj.ClusterName, obj.Region)
+	err = os.MkdirAll(ref.Path(), 0755)
 	if err != nil {
 		return
 	}
 
-	keyPairToUpload, err := util.CreateSSHKeyPair("azure", obj.ClusterName+" "+obj.Config.ResourceGroupName+" "+obj.Region)
+	keyPairToUpload, err := util.CreateSSHKeyPair("azure", ref.ID())
 	if err != nil {
 		return
 	}
 
 	_, err = sshClient.Create(ctx, obj.Config.ResourceGroupName, obj.ClusterName+"-ssh", armcompute.SSHPublicKeyResource{
 		Location:   to.Ptr(obj.Region),
+		Tags:       obj.resourceTags(util.TAG_ROLE_CLUSTER),
 		Properties: &armcompute.SSHPublicKeyResourceProperties{PublicKey: to.Ptr(keyPairToUpload)},
 	}, nil)
 	obj.Config.SSHKeyName = obj.ClusterName + "-ssh"
 
-	// ------- Setting the ssh configs only the public ips used will change
+	obj.setSSHPayload()
+	return
+}
+
+// setSSHPayload sets the ssh configs only the public ips used will change
+func (obj *AzureProvider) setSSHPayload() {
 	obj.SSH_Payload.UserName = "azureuser"
-	obj.SSH_Payload.PathPrivateKey = util.GetPath(util.SSH_PATH, "azure", "ha", obj.ClusterName+" "+obj.Config.ResourceGroupName+" "+obj.Region)
+	obj.SSH_Payload.PathPrivateKey = util.GetPath(util.SSH_PATH, "azure", "ha", clusterRef("ha", obj.ClusterName, obj.Region).ID())
 	obj.SSH_Payload.Output = ""
 	obj.SSH_Payload.PublicIP = ""
-	// ------
-
-	return
 }
 
-func (obj *AzureProvider) CreateNetworkInterface(ctx context.Context, logging log.Logger, resourceName, nicName string, subnetID string, publicIPID string, networkSecurityGroupID string) (*armnetwork.Interface, error) {
+func (obj *AzureProvider) CreateNetworkInterface(ctx context.Context, logging log.Logger, resourceName, nicName, role string, subnetID string, publicIPID string, networkSecurityGroupID string) (*armnetwork.Interface, error) {
 	nicClient, err := armnetwork.NewInterfacesClient(obj.SubscriptionID, obj.AzureTokenCred, nil)
 	if err != nil {
 		return nil, err
 	}
 	parameters := armnetwork.Interface{
 		Location: to.Ptr(obj.Region),
+		Tags:     obj.resourceTags(role),
 		Properties: &armnetwork.InterfacePropertiesFormat{
 			IPConfigurations: []*armnetwork.InterfaceIPConfiguration{
 				{
@@ -481,11 +552,17 @@ func (obj *AzureProvider) CreateNetworkInterface(ctx context.Context, logging lo
 
 func (obj *AzureProvider) DeleteAllNetworkInterface(ctx context.Context, logging log.Logger) error {
 	for _, interfaceName := range obj.Config.InfoControlPlanes.NetworkInterfaceNames {
+		if len(interfaceName) == 0 {
+			continue
+		}
 		if err := obj.DeleteNetworkInterface(ctx, logging, interfaceName); err != nil {
 			return err
 		}
 	}
 	for _, interfaceName := range obj.Config.InfoWorkerPlanes.NetworkInterfaceNames {
+		if len(interfaceName) == 0 {
+			continue
+		}
 		if err := obj.DeleteNetworkInterface(ctx, logging, interfaceName); err != nil {
 			return err
 		}
@@ -570,7 +647,7 @@ func (obj *AzureProvider) DeleteNSG(ctx context.Context, logging log.Logger, nsg
 	return nil
 }
 
-func (obj *AzureProvider) CreateNSG(ctx context.Context, logging log.Logger, nsgName string, securityRules []*armnetwork.SecurityRule) (*armnetwork.SecurityGroup, error) {
+func (obj *AzureProvider) CreateNSG(ctx context.Context, logging log.Logger, nsgName, role string, securityRules []*armnetwork.SecurityRule) (*armnetwork.SecurityGroup, error) {
 	nsgClient, err := armnetwork.NewSecurityGroupsClient(obj.SubscriptionID, obj.AzureTokenCred, nil)
 	if err != nil {
 		return nil, err
@@ -578,6 +655,7 @@ func (obj *AzureProvider) CreateNSG(ctx context.Context, logging log.Logger, nsg
 
 	parameters := armnetwork.SecurityGroup{
 		Location: to.Ptr(obj.Region),
+		Tags:     obj.resourceTags(role),
 		Properties: &armnetwork.SecurityGroupPropertiesFormat{
 			SecurityRules: securityRules,
 		},
@@ -626,6 +704,7 @@ func (obj *AzureProvider) CreateVirtualNetwork(ctx context.Context, logging log.
 
 	parameters := armnetwork.VirtualNetwork{
 		Location: to.Ptr(obj.Region),
+		Tags:     obj.resourceTags(util.TAG_ROLE_CLUSTER),
 		Properties: &armnetwork.VirtualNetworkPropertiesFormat{
 			AddressSpace: &armnetwork.AddressSpace{
 				AddressPrefixes: []*string{
@@ -651,13 +730,19 @@ func (obj *AzureProvider) CreateVirtualNetwork(ctx context.Context, logging log.
 	return &resp.VirtualNetwork, nil
 }
 
+// fetchKubeconfig copies the kubeconfig from the state store to the local path which is printed
+func (obj *AzureProvider) fetchKubeconfig(logging log.Logger, kind string) {
+	if _, err := util.FetchStateFile("azure", kind, clusterRef(kind, obj.ClusterName, obj.Region).ID(), "config"); err != nil {
+		logging.Warn("unable to fetch the kubeconfig", err.Error())
+	}
+}
+
 func (obj *AzureProvider) kubeconfigReader() ([]byte, error) {
-	clusterDirName := obj.ClusterName + " " + obj.Config.ResourceGroupName + " " + obj.Region
 	typeOfCluster := "managed"
 	if obj.HACluster {
 		typeOfCluster = "ha"
 	}
-	return os.ReadFile(util.GetPath(util.CLUSTER_PATH, "azure", typeOfCluster, clusterDirName, "config"))
+	return util.ReadStateFile("azure", typeOfCluster, clusterRef(typeOfCluster, obj.ClusterName, obj.Region).ID(), "config")
 }
 
 func (p printer) Printer(logging log.Logger, isHA bool, operation int) {
@@ -669,41 +754,35 @@ func (p printer) Printer(logging log.Logger, isHA bool, operation int) {
 	case 0:
 		logging.Note("To use this cluster set this environment variable")
 		if isHA {
-			logging.Print(fmt.Sprintf("%sKUBECONFIG=\"%s\"\n", preFix, util.GetPath(util.CLUSTER_PATH, "azure", "ha", p.ClusterName+" "+p.ResourceName+" "+p.Region, "config")))
+			logging.Print(fmt.Sprintf("%sKUBECONFIG=\"%s\"\n", preFix, clusterRef("ha", p.ClusterName, p.Region).Path("config")))
 		} else {
-			logging.Print(fmt.Sprintf("%sKUBECONFIG=\"%s\"\n", preFix, util.GetPath(util.CLUSTER_PATH, "azure", "managed", p.ClusterName+" "+p.ResourceName+" "+p.Region, "config")))
+			logging.Print(fmt.Sprintf("%sKUBECONFIG=\"%s\"\n", preFix, clusterRef("managed", p.ClusterName, p.Region).Path("config")))
 		}
 	case 1:
-		logging.Note("Use the following command to unset KUBECONFIG")
-		if runtime.GOOS == "windows" {
-			logging.Print(fmt.Sprintf("%sKUBECONFIG=\"\"\n", preFix))
+		if isHA {
+			util.ForgetKubeconfig(logging, clusterRef("ha", p.ClusterName, p.Region))
 		} else {
-			logging.Print("unset KUBECONFIG")
+			util.ForgetKubeconfig(logging, clusterRef("managed", p.ClusterName, p.Region))
 		}
 	}
 }
 
-func (obj *AzureProvider) CreateVM(ctx context.Context, logging log.Logger, vmName, networkInterfaceID, diskName, script string) (*armcompute.VirtualMachine, error) {
+func (obj *AzureProvider) CreateVM(ctx context.Context, logging log.Logger, vmName, role, networkInterfaceID, diskName, script string) (*armcompute.VirtualMachine, error) {
 	vmClient, err := armcompute.NewVirtualMachinesClient(obj.SubscriptionID, obj.AzureTokenCred, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	//require ssh key for authentication on linux
-	sshPublicKeyPath := util.GetPath(util.OTHER_PATH, "azure", "ha", obj.ClusterName+" "+obj.Config.ResourceGroupName+" "+obj.Region, "keypair.pub")
-	var sshBytes []byte
-	_, err = os.Stat(sshPublicKeyPath)
-	if err != nil {
-		return nil, err
-	}
-
-	sshBytes, err = os.ReadFile(sshPublicKeyPath)
+	sshBytes, err := util.ReadStateFile("azure", "ha", clusterRef("ha", obj.ClusterName, obj.Region).ID(), "keypair.pub")
 	if err != nil {
 		return nil, err
 	}
 
+	tags := obj.resourceTags(role)
 	parameters := armcompute.VirtualMachine{
 		Location: to.Ptr(obj.Region),
+		Tags:     tags,
 		Identity: &armcompute.VirtualMachineIdentity{
 			Type: to.Ptr(armcompute.ResourceIdentityTypeNone),
 		},
@@ -764,16 +843,40 @@ func (obj *AzureProvider) CreateVM(ctx context.Context, logging log.Logger, vmNa
 		return nil, err
 	}
 	logging.Info("Created network virtual machine", *resp.Name)
+
+	// the os disk is created along with the vm so it is tagged afterwards, the vm is kept when it fails
+	if err := obj.tagDisk(ctx, diskName, tags); err != nil {
+		logging.Warn("unable to tag the disk", diskName, err.Error())
+	}
 	return &resp.VirtualMachine, nil
 }
 
+func (obj *AzureProvider) tagDisk(ctx context.Context, diskName string, tags map[string]*string) error {
+	diskClient, err := armcompute.NewDisksClient(obj.SubscriptionID, obj.AzureTokenCred, nil)
+	if err != nil {
+		return err
+	}
+	pollerResponse, err := diskClient.BeginUpdate(ctx, obj.Config.ResourceGroupName, diskName, armcompute.DiskUpdate{Tags: tags}, nil)
+	if err != nil {
+		return err
+	}
+	_, err = pollerResponse.PollUntilDone(ctx, nil)
+	return err
+}
+
 func (obj *AzureProvider) DeleteAllVMs(ctx context.Context, logging log.Logger) error {
 	for _, instanceName := range obj.Config.InfoControlPlanes.Names {
+		if len(instanceName) == 0 {
+			continue
+		}
 		if err := obj.DeleteVM(ctx, logging, instanceName); err != nil {
 			return err
 		}
 	}
 	for _, instanceName := range obj.Config.InfoWorkerPlanes.Names {
+		if len(instanceName) == 0 {
+			continue
+		}
 		if err := obj.DeleteVM(ctx, logging, instanceName); err != nil {
 			return err
 		}
@@ -815,11 +918,17 @@ func (obj *AzureProvider) DeleteVM(ctx context.Context, logging log.Logger, vmNa
 
 func (obj *AzureProvider) DeleteAllDisks(ctx context.Context, logging log.Logger) error {
 	for _, diskName := range obj.Config.InfoControlPlanes.DiskNames {
+		if len(diskName) == 0 {
+			continue
+		}
 		if err := obj.DeleteDisk(ctx, logging, diskName); err != nil {
 			return err
 		}
 	}
 	for _, diskName := range obj.Config.InfoWorkerPlanes.DiskNames {
+		if len(diskName) == 0 {
+			continue
+		}
 		if err := obj.DeleteDisk(ctx, logging, diskName); err != nil {
 			return err
 		}
@@ -860,31 +969,184 @@ func (obj *AzureProvider) DeleteDisk(ctx context.Context, logging log.Logger, di
 
 // SaveKubeconfig stores the kubeconfig to state management file
 func (obj *AzureProvider) SaveKubeconfig(logging log.Logger, kubeconfig string) error {
-	folderName := obj.ClusterName + " " + obj.Config.ResourceGroupName + " " + obj.Region
 	kind := "managed"
 	if obj.HACluster {
 		kind = "ha"
 	}
-	err := os.MkdirAll(util.GetPath(util.CLUSTER_PATH, "azure", kind, folderName), 0755)
-	if err != nil && !os.IsExist(err) {
+	err := util.WriteStateFile("azure", []byte(kubeconfig), kind, clusterRef(kind, obj.ClusterName, obj.Region).ID(), "config")
+	if err != nil {
 		return err
 	}
+	logging.Info("💾 Kubeconfig", "")
+	return nil
+}
 
-	_, err = os.Create(util.GetPath(util.CLUSTER_PATH, "azure", kind, folderName, "config"))
-	if err != nil && !os.IsExist(err) {
-		return err
+// azureResourceKinds the kinds of the resources created in the resource group of the cluster by their azure type
+var azureResourceKinds = map[string]string{
+	"microsoft.compute/virtualmachines":          "vm",
+	"microsoft.compute/disks":                    "disk",
+	"microsoft.compute/sshpublickeys":            "ssh-key",
+	"microsoft.network/networkinterfaces":        "nic",
+	"microsoft.network/networksecuritygroups":    "nsg",
+	"microsoft.network/publicipaddresses":        "public-ip",
+	"microsoft.network/virtualnetworks":          "virtual-network",
+	"microsoft.containerservice/managedclusters": "aks-cluster",
+}
+
+// azureResource a resource found in the resource group of the cluster
+type azureResource struct {
+	Kind string
+	Name string
+	ID   string
+}
+
+// driftResource a resource recorded in the state, all of its fields are blanked when it is forgotten
+type driftResource struct {
+	kind   string
+	fields []*string
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
 	}
+	return *value
+}
 
-	file, err := os.OpenFile(util.GetPath(util.CLUSTER_PATH, "azure", kind, folderName, "config"), os.O_WRONLY, 0750)
-	if err != nil && !os.IsExist(err) {
-		return err
+func isNotFound(err error) bool {
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound
+}
+
+// listResourceGroup the resources in the resource group of the cluster along with the subnets of
+// its virtual network, the resource group is dedicated to the cluster
+func (obj *AzureProvider) listResourceGroup(ctx context.Context) ([]azureResource, error) {
+	client, err := armresources.NewClient(obj.SubscriptionID, obj.AzureTokenCred, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resources []azureResource
+	pager := client.NewListByResourceGroupPager(obj.Config.ResourceGroupName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range page.Value {
+			if resource.Name == nil || resource.Type == nil {
+				continue
+			}
+			kind, ok := azureResourceKinds[strings.ToLower(*resource.Type)]
+			if !ok {
+				continue
+			}
+			resources = append(resources, azureResource{Kind: kind, Name: *resource.Name, ID: stringValue(resource.ID)})
+		}
 	}
-	defer file.Close()
 
-	_, err = file.Write([]byte(kubeconfig))
+	if len(obj.Config.VirtualNetworkName) == 0 {
+		return resources, nil
+	}
+	subnetClient, err := armnetwork.NewSubnetsClient(obj.SubscriptionID, obj.AzureTokenCred, nil)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	subnets := subnetClient.NewListPager(obj.Config.ResourceGroupName, obj.Config.VirtualNetworkName, nil)
+	for subnets.More() {
+		page, err := subnets.NextPage(ctx)
+		if isNotFound(err) {
+			// the virtual network itself is missing
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, subnet := range page.Value {
+			if subnet.Name != nil {
+				resources = append(resources, azureResource{Kind: "subnet", Name: *subnet.Name, ID: stringValue(subnet.ID)})
+			}
+		}
+	}
+	return resources, nil
+}
+
+// recordedResources the resources of the HA cluster recorded in the state, the IPs of the VM
+// are forgotten along with it so that the VM is created again when the creation is resumed
+func (config *AzureStateCluster) recordedResources() []driftResource {
+	resources := []driftResource{
+		{kind: "virtual-network", fields: []*string{&config.VirtualNetworkName, &config.VirtualNetworkID}},
+		{kind: "subnet", fields: []*string{&config.SubnetName, &config.SubnetID}},
+		{kind: "ssh-key", fields: []*string{&config.SSHKeyName}},
+	}
+	for _, vms := range []*AzureStateVMs{&config.InfoControlPlanes, &config.InfoWorkerPlanes} {
+		resources = append(resources, driftResource{kind: "nsg", fields: []*string{&vms.NetworkSecurityGroupName, &vms.NetworkSecurityGroupID}})
+		for index := range vms.Names {
+			vm := driftResource{kind: "vm", fields: []*string{&vms.Names[index]}}
+			if index < len(vms.PrivateIPs) {
+				vm.fields = append(vm.fields, &vms.PrivateIPs[index])
+			}
+			if index < len(vms.PublicIPs) {
+				vm.fields = append(vm.fields, &vms.PublicIPs[index])
+			}
+			resources = append(resources, vm)
+		}
+		for index := range vms.DiskNames {
+			resources = append(resources, driftResource{kind: "disk", fields: []*string{&vms.DiskNames[index]}})
+		}
+		for index := range vms.PublicIPNames {
+			resources = append(resources, driftResource{kind: "public-ip", fields: []*string{&vms.PublicIPNames[index]}})
+		}
+		for index := range vms.NetworkInterfaceNames {
+			resources = append(resources, driftResource{kind: "nic", fields: []*string{&vms.NetworkInterfaceNames[index]}})
+		}
+	}
+	for _, vm := range []*AzureStateVM{&config.InfoLoadBalancer, &config.InfoDatabase} {
+		resources = append(resources,
+			driftResource{kind: "nsg", fields: []*string{&vm.NetworkSecurityGroupName, &vm.NetworkSecurityGroupID}},
+			driftResource{kind: "vm", fields: []*string{&vm.Name, &vm.PrivateIP, &vm.PublicIP}},
+			driftResource{kind: "disk", fields: []*string{&vm.DiskName}},
+			driftResource{kind: "public-ip", fields: []*string{&vm.PublicIPName}},
+			driftResource{kind: "nic", fields: []*string{&vm.NetworkInterfaceName}},
+		)
+	}
+	return resources
+}
+
+// checkDrift compares the resources recorded in the state of the HA cluster with the ones found in its resource group
+func (config *AzureStateCluster) checkDrift(report *util.DriftReport, found []azureResource) {
+	existing := map[string]bool{}
+	for _, resource := range found {
+		existing[resource.Kind+"/"+resource.Name] = true
+	}
+	recorded := map[string]bool{}
+	for _, resource := range config.recordedResources() {
+		name := *resource.fields[0]
+		if len(name) == 0 {
+			continue
+		}
+		report.Check(resource.kind, name, "", existing[resource.kind+"/"+name])
+		recorded[resource.kind+"/"+name] = true
+	}
+	for _, resource := range found {
+		if !recorded[resource.Kind+"/"+resource.Name] {
+			report.Unexpected(resource.Kind, resource.Name, resource.ID)
+		}
+	}
+}
+
+// forgetMissing removes the resources which are not found in azure from the state
+// the positions of the VMs are kept as they are the numbers of the nodes
+func (config *AzureStateCluster) forgetMissing(missing []util.Drift) {
+	names := map[string]bool{}
+	for _, drift := range missing {
+		names[drift.Kind+"/"+drift.Name] = true
+	}
+	for _, resource := range config.recordedResources() {
+		if !names[resource.kind+"/"+*resource.fields[0]] {
+			continue
+		}
+		for _, field := range resource.fields {
+			*field = ""
+		}
 	}
-	logging.Info("💾 Kubeconfig", "")
-	return nil
 }
@@ -7,20 +7,9 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
 	log "github.com/kubesimplify/ksctl/api/logger"
+	util "github.com/kubesimplify/ksctl/api/utils"
 )
 
-func scriptWP(privateIPlb, token string) string {
-	return fmt.Sprintf(`#!/bin/bash
-cat <<EOF > worker-setup.sh
-#!/bin/bash
-curl -sfL https://get.k3s.io | sh -s - agent --token %s --server https://%s:6443
-EOF
-
-sudo chmod +x worker-setup.sh
-sudo ./worker-setup.sh
-`, token, privateIPlb)
-}
-
 // TODO: Add more firewall rules
 func getWorkerPlaneFirewallRules() (securityRules []*armnetwork.SecurityRule) {
 	securityRules = append(securityRules, &armnetwork.SecurityRule{
@@ -70,39 +59,58 @@ func (obj *AzureProvider) createWorkerPlane(logger log.Logger, ctx context.Conte
 	}
 
 	vmName := fmt.Sprintf("%s-wp-%d", obj.ClusterName, indexOfNode)
+	index := indexOfNode - 1
+
+	// names are recorded before creating the resources so that they are deleted even if the creation fails
+	updateState(func() {
+		obj.Config.InfoWorkerPlanes.Names = setAt(obj.Config.InfoWorkerPlanes.Names, index, vmName)
+		obj.Config.InfoWorkerPlanes.DiskNames = setAt(obj.Config.InfoWorkerPlanes.DiskNames, index, vmName+"-disk")
+		obj.Config.InfoWorkerPlanes.PublicIPNames = setAt(obj.Config.InfoWorkerPlanes.PublicIPNames, index, vmName+"-pub-ip")
+		obj.Config.InfoWorkerPlanes.NetworkInterfaceNames = setAt(obj.Config.InfoWorkerPlanes.NetworkInterfaceNames, index, vmName+"-nic")
+	})
 
-	publicIP, err := obj.CreatePublicIP(ctx, logger, vmName+"-pub-ip")
+	publicIP, err := obj.CreatePublicIP(ctx, logger, vmName+"-pub-ip", util.NODE_ROLE_WORKER)
 	if err != nil {
 		return err
 	}
-	obj.Config.InfoWorkerPlanes.PublicIPNames = append(obj.Config.InfoWorkerPlanes.PublicIPNames, *publicIP.Name)
 
-	// network security group
+	// network security group is shared by the VMs which are created in parallel
+	nsgMutex.Lock()
 	if len(obj.Config.InfoWorkerPlanes.NetworkSecurityGroupName) == 0 {
-		nsg, err := obj.CreateNSG(ctx, logger, obj.ClusterName+"-wp-nsg", getWorkerPlaneFirewallRules())
+		nsg, err := obj.CreateNSG(ctx, logger, obj.ClusterName+"-wp-nsg", util.NODE_ROLE_WORKER, getWorkerPlaneFirewallRules())
 		if err != nil {
+			nsgMutex.Unlock()
 			return err
 		}
 
-		obj.Config.InfoWorkerPlanes.NetworkSecurityGroupName = *nsg.Name
-		obj.Config.InfoWorkerPlanes.NetworkSecurityGroupID = *nsg.ID
+		updateState(func() {
+			obj.Config.InfoWorkerPlanes.NetworkSecurityGroupName = *nsg.Name
+			obj.Config.InfoWorkerPlanes.NetworkSecurityGroupID = *nsg.ID
+		})
 	}
+	nsgMutex.Unlock()
 
-	networkInterface, err := obj.CreateNetworkInterface(ctx, logger, obj.Config.ResourceGroupName, vmName+"-nic", obj.Config.SubnetID, *publicIP.ID, obj.Config.InfoWorkerPlanes.NetworkSecurityGroupID)
+	networkInterface, err := obj.CreateNetworkInterface(ctx, logger, obj.Config.ResourceGroupName, vmName+"-nic", util.NODE_ROLE_WORKER, obj.Config.SubnetID, *publicIP.ID, obj.Config.InfoWorkerPlanes.NetworkSecurityGroupID)
 	if err != nil {
 		return err
 	}
-	obj.Config.InfoWorkerPlanes.NetworkInterfaceNames = append(obj.Config.InfoWorkerPlanes.NetworkInterfaceNames, *networkInterface.Name)
 
-	obj.Config.InfoWorkerPlanes.Names = append(obj.Config.InfoWorkerPlanes.Names, vmName)
-	obj.Config.InfoWorkerPlanes.DiskNames = append(obj.Config.InfoWorkerPlanes.DiskNames, vmName+"-disk")
-
-	_, err = obj.CreateVM(ctx, logger, vmName, *networkInterface.ID, vmName+"-disk", scriptWP(obj.Config.InfoLoadBalancer.PrivateIP, obj.Config.K3sToken))
+	dist, err := obj.distribution()
 	if err != nil {
 		return err
 	}
-	obj.Config.InfoWorkerPlanes.PublicIPs = append(obj.Config.InfoWorkerPlanes.PublicIPs, *publicIP.Properties.IPAddress)
-	obj.Config.InfoWorkerPlanes.PrivateIPs = append(obj.Config.InfoWorkerPlanes.PrivateIPs, *networkInterface.Properties.IPConfigurations[0].Properties.PrivateIPAddress)
+	// the custom data runs as root on the first boot
+	_, err = obj.CreateVM(ctx, logger, vmName, util.NODE_ROLE_WORKER, *networkInterface.ID, vmName+"-disk", dist.JoinWorker(util.DistributionConfig{
+		LoadBalancerPrivateIP: obj.Config.InfoLoadBalancer.PrivateIP,
+		Token:                 obj.Config.K3sToken,
+	}))
+	if err != nil {
+		return err
+	}
+	updateState(func() {
+		obj.Config.InfoWorkerPlanes.PublicIPs = setAt(obj.Config.InfoWorkerPlanes.PublicIPs, index, *publicIP.Properties.IPAddress)
+		obj.Config.InfoWorkerPlanes.PrivateIPs = setAt(obj.Config.InfoWorkerPlanes.PrivateIPs, index, *networkInterface.Properties.IPConfigurations[0].Properties.PrivateIPAddress)
+	})
 	logger.Info("💻 Booted Worker plane VM: ", vmName)
 	return nil
 }
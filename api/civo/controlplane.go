@@ -9,8 +9,10 @@ Credit to @civo
 package civo
 
 import (
+	"context"
 	"fmt"
-	"time"
+	"strings"
+	"sync"
 
 	log "github.com/kubesimplify/ksctl/api/logger"
 
@@ -18,138 +20,73 @@ import (
 	util "github.com/kubesimplify/ksctl/api/utils"
 )
 
-// scriptWithoutCP_1 script used to configure the control-plane-1 with no need of output inital
-func scriptWithoutCP_1(dbEndpoint, pubIPlb string) string {
-
-	return fmt.Sprintf(`#!/bin/bash
-export K3S_DATASTORE_ENDPOINT='%s'
-export INSTALL_K3S_EXEC='--tls-san %s'
-curl -sfL https://get.k3s.io | sh -s - server \
-	--node-taint CriticalAddonsOnly=true:NoExecute
-`, dbEndpoint, pubIPlb)
-
-	// NOTE: Feature to add other CNI like Cilium
-	// Add these tags for having different CNI
-	// also check out the default loadbalancer available
-
-	//	return fmt.Sprintf(`#!/bin/bash
-	// export K3S_DATASTORE_ENDPOINT='%s'
-	//	curl -sfL https://get.k3s.io | sh -s - server \
-	//		--flannel-backend=none \
-	//		--disable-network-policy \
-	//		--node-taint CriticalAddonsOnly=true:NoExecute \
-	//		--tls-san %s
-	//
-	// `, dbEndpoint, privateIPlb)
-}
-
-func scriptWithCP_1() string {
-	return `#!/bin/bash
-cat /var/lib/rancher/k3s/server/token
-`
-}
-
-func scriptCP_n(dbEndpoint, pubIPlb, token string) string {
-	return fmt.Sprintf(`#!/bin/bash
-export SECRET='%s'
-export K3S_DATASTORE_ENDPOINT='%s'
-export INSTALL_K3S_EXEC='--tls-san %s'
-curl -sfL https://get.k3s.io | sh -s - server \
-	--token=$SECRET \
-	--node-taint CriticalAddonsOnly=true:NoExecute
-`, token, dbEndpoint, pubIPlb)
-
-	// NOTE: Feature to add other CNI like Cilium
-	// Add these tags for having different CNI
-	// also check out the default loadbalancer available
-
-	//	return fmt.Sprintf(`#!/bin/bash
-	// export SECRET='%s'
-	// export K3S_DATASTORE_ENDPOINT='%s'
-	//
-	//	curl -sfL https://get.k3s.io | sh -s - server \
-	//		--token=$SECRET \
-	//		--node-taint CriticalAddonsOnly=true:NoExecute \
-	//		--flannel-backend=none \
-	//		--disable-network-policy \
-	//		--tls-san %s
-	//
-	// `, token, dbEndpoint, privateIPlb)
-}
-
-func scriptKUBECONFIG() string {
-	return `#!/bin/bash
-cat /etc/rancher/k3s/k3s.yaml`
-}
-
-func (obj *HAType) FetchKUBECONFIG(logging log.Logger, instanceCP *civogo.Instance) (string, error) {
+func (obj *HAType) FetchKUBECONFIG(ctx context.Context, logging log.Logger, instanceCP *civogo.Instance) (string, error) {
 	obj.SSH_Payload.PublicIP = instanceCP.PublicIP
 	obj.SSH_Payload.Output = ""
-	err := obj.SSH_Payload.SSHExecute(logging, util.EXEC_WITH_OUTPUT, scriptKUBECONFIG(), true)
+	err := obj.SSH_Payload.SSHExecute(ctx, logging, util.EXEC_WITH_OUTPUT, obj.Distribution.Kubeconfig(), true)
 
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 
 	return obj.SSH_Payload.Output, nil
 }
 
-func (obj *HAType) CreateControlPlane(logging log.Logger, number int) (*civogo.Instance, error) {
+// firewallMutex guards the creation of the firewall shared by the nodes created in parallel
+var firewallMutex sync.Mutex
 
+// CreateControlPlane creates the controlplane VM with the given number (starting from 1)
+// the instance saved in the state management file for that number is reused
+func (obj *HAType) CreateControlPlane(ctx context.Context, logging log.Logger, number int) (*civogo.Instance, error) {
 	name := fmt.Sprintf("%s-ksctl-cp", obj.ClusterName)
 
+	// the firewall is shared by the controlplanes which are created in parallel
+	firewallMutex.Lock()
 	if len(obj.CPFirewallID) == 0 {
 		firewall, err := obj.CreateFirewall(name)
 		if err != nil {
+			firewallMutex.Unlock()
 			return nil, err
 		}
 		obj.CPFirewallID = firewall.ID
+
 		// TODO: Add better firewall rules
 		err = obj.Configuration.ConfigWriterFirewallControlPlaneNodes(logging, firewall.ID)
 		if err != nil {
-			return nil, nil
+			firewallMutex.Unlock()
+			return nil, err
 		}
 	}
+	firewallMutex.Unlock()
+
 	name += fmt.Sprint(number)
 
-	instance, err := obj.CreateInstance(name, obj.CPFirewallID, obj.NodeSize, "", true)
-	if err != nil {
-		return nil, err
+	if instanceID := obj.Configuration.ControlPlaneID(number); len(instanceID) != 0 {
+		return obj.waitForInstance(ctx, logging, instanceID, name)
 	}
 
-	err = obj.Configuration.ConfigWriterInstanceControlPlaneNodes(logging, instance.ID)
+	instance, err := obj.CreateInstance(name, util.NODE_ROLE_CONTROLPLANE, obj.CPFirewallID, obj.NodeSize, "", true)
 	if err != nil {
-		return nil, nil
+		return nil, err
 	}
 
-	var retObject *civogo.Instance
-
-	for {
-		getInstance, err := obj.GetInstance(instance.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		if getInstance.Status == "ACTIVE" {
-			retObject = getInstance
-			logging.Info("💻 Booted Instance ", name)
-			return retObject, nil
-		}
-		logging.Info("🚧 Instance ", name)
-		time.Sleep(10 * time.Second)
+	err = obj.Configuration.ConfigWriterInstanceControlPlaneNodes(logging, number, instance.ID)
+	if err != nil {
+		return nil, err
 	}
 
+	return obj.waitForInstance(ctx, logging, instance.ID, name)
 }
 
-// GetTokenFromCP_1 used to extract the K3S_TOKEN from the first Controlplane node
-func (obj *HAType) GetTokenFromCP_1(logging log.Logger, instance *civogo.Instance) string {
+// GetTokenFromCP_1 used to extract the token the other nodes join with from the first Controlplane node
+func (obj *HAType) GetTokenFromCP_1(ctx context.Context, logging log.Logger, instance *civogo.Instance) string {
 	obj.SSH_Payload.PublicIP = instance.PublicIP
 	obj.SSH_Payload.Output = ""
-	err := obj.SSH_Payload.SSHExecute(logging, util.EXEC_WITH_OUTPUT, scriptWithCP_1(), true)
+	err := obj.SSH_Payload.SSHExecute(ctx, logging, util.EXEC_WITH_OUTPUT, obj.Distribution.Token(), true)
 	if err != nil {
 		return ""
 	}
-	token := obj.SSH_Payload.Output
+	token := strings.TrimSpace(obj.SSH_Payload.Output)
 	obj.SSH_Payload.Output = ""
 	err = obj.Configuration.ConfigWriterServerToken(logging, token)
 
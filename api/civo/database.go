@@ -9,12 +9,14 @@ Credit to @civo
 package civo
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"strings"
 	"time"
 
 	log "github.com/kubesimplify/ksctl/api/logger"
+	util "github.com/kubesimplify/ksctl/api/utils"
 )
 
 func generateDBPassword(passwordLen int) string {
@@ -74,7 +76,11 @@ systemctl restart mysql
 }
 
 // CreateDatabase return endpoint address if no error is encountered
-func (obj *HAType) CreateDatabase(logging log.Logger) (string, error) {
+// the network and firewall saved in the state management file are reused
+func (obj *HAType) CreateDatabase(ctx context.Context, logging log.Logger) (string, error) {
+	if len(obj.Configuration.DBEndpoint) != 0 {
+		return obj.Configuration.DBEndpoint, nil
+	}
 
 	errV := obj.CreateNetwork(logging, obj.ClusterName+"-ksctl")
 	if errV != nil {
@@ -83,21 +89,35 @@ func (obj *HAType) CreateDatabase(logging log.Logger) (string, error) {
 
 	name := obj.ClusterName + "-ksctl-db"
 
-	firewall, err := obj.CreateFirewall(name)
-	if err != nil {
-		return "", err
+	if len(obj.Configuration.NetworkIDs.FirewallIDDatabaseNode) == 0 {
+		firewall, err := obj.CreateFirewall(name)
+		if err != nil {
+			return "", err
+		}
+		obj.DBFirewallID = firewall.ID
+
+		err = obj.Configuration.ConfigWriterFirewallDatabaseNodes(logging, firewall.ID)
+		if err != nil {
+			return "", nil
+		}
+	} else {
+		obj.DBFirewallID = obj.Configuration.NetworkIDs.FirewallIDDatabaseNode
 	}
-	obj.DBFirewallID = firewall.ID
 
-	err = obj.Configuration.ConfigWriterFirewallDatabaseNodes(logging, firewall.ID)
-	if err != nil {
-		return "", nil
+	// the password of a database instance without the saved endpoint is lost so it is recreated
+	for _, instanceID := range obj.Configuration.InstanceIDs.DatabaseNode {
+		if err := obj.DeleteInstance(instanceID); err != nil {
+			return "", err
+		}
+		logging.Info("🔥 Deleted incomplete Database instance", instanceID)
 	}
+	obj.Configuration.InstanceIDs.DatabaseNode = nil
+
 	generatedPassword := generateDBPassword(20)
 
 	// FIXME: try to make DB as private instance as SECURITY CONCERN
 	// SOLUTION: maybe try to make instance private or restricted firewall rules
-	instance, err := obj.CreateInstance(name, firewall.ID, "g3.large", scriptDB(generatedPassword), true)
+	instance, err := obj.CreateInstance(name, util.NODE_ROLE_DATABASE, obj.DBFirewallID, "g3.large", scriptDB(generatedPassword), true)
 	if err != nil {
 		return "", err
 	}
@@ -107,25 +127,15 @@ func (obj *HAType) CreateDatabase(logging log.Logger) (string, error) {
 		return "", nil
 	}
 
-	for {
-		getInstance, err := obj.GetInstance(instance.ID)
-		if err != nil {
-			return "", err
-		}
-
-		if getInstance.Status == "ACTIVE" {
-
-			logging.Info("💻 Booted Instance", name)
-
-			logging.Info("✅ Configured Database", "")
-			endpoint := fmt.Sprintf("mysql://ksctl:%s@tcp(%s:3306)/ksctldb", generatedPassword, getInstance.PrivateIP)
-			err = obj.Configuration.ConfigWriterDBEndpoint(logging, endpoint)
-			if err != nil {
-				return "", err
-			}
-			return endpoint, nil
-		}
-		logging.Info("🚧 Instance ", name)
-		time.Sleep(10 * time.Second)
+	getInstance, err := obj.waitForInstance(ctx, logging, instance.ID, name)
+	if err != nil {
+		return "", err
+	}
+	logging.Info("✅ Configured Database", "")
+	endpoint := fmt.Sprintf("mysql://ksctl:%s@tcp(%s:3306)/ksctldb", generatedPassword, getInstance.PrivateIP)
+	err = obj.Configuration.ConfigWriterDBEndpoint(logging, endpoint)
+	if err != nil {
+		return "", err
 	}
+	return endpoint, nil
 }
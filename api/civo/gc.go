@@ -0,0 +1,214 @@
+/*
+Kubesimplify
+Credit to @civo
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+package civo
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/civo/civogo"
+	log "github.com/kubesimplify/ksctl/api/logger"
+	util "github.com/kubesimplify/ksctl/api/utils"
+)
+
+// civoMonthlyPrice the monthly price of the instance sizes used by the HA cluster in USD
+// the networks, firewalls and ssh keys are free
+var civoMonthlyPrice = map[string]float64{
+	"g3.xsmall":  5,
+	"g3.small":   10,
+	"g3.medium":  20,
+	"g3.large":   40,
+	"g3.xlarge":  80,
+	"g3.2xlarge": 160,
+}
+
+var (
+	// the firewalls of the HA cluster are named <name>-ksctl-<cp|wp|db|lb> and the instances
+	// the same with the number of the controlplane or the workernode appended
+	civoNodeName    = regexp.MustCompile(`^(.+)-ksctl-(cp|wp|db|lb)[0-9]*$`)
+	civoNetworkName = regexp.MustCompile(`^(.+)-ksctl$`)
+)
+
+// gcRetryInterval time between the deletions of the firewalls and networks which are still used by the instances being deleted
+var gcRetryInterval = 10 * time.Second
+
+// knownCivoIDs the IDs of the resources recorded in the state of every HA cluster
+// any state which cannot be read fails it as its resources would be collected otherwise
+func knownCivoIDs() (map[string]bool, error) {
+	known := map[string]bool{}
+	refs, err := util.IndexedClusters("civo", util.CLUSTER_TYPE_HA)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		config, err := GetConfig(ref.Name, ref.Region)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read configuration of %v: %v", ref.Name, err)
+		}
+		ids := append([]string{config.SSHID,
+			config.NetworkIDs.NetworkID,
+			config.NetworkIDs.FirewallIDControlPlaneNode,
+			config.NetworkIDs.FirewallIDWorkerNode,
+			config.NetworkIDs.FirewallIDLoadBalancerNode,
+			config.NetworkIDs.FirewallIDDatabaseNode,
+		}, config.InstanceIDs.ControlNodes...)
+		ids = append(ids, config.InstanceIDs.WorkerNodes...)
+		ids = append(ids, config.InstanceIDs.LoadBalancerNode...)
+		ids = append(ids, config.InstanceIDs.DatabaseNode...)
+		for _, id := range ids {
+			if len(id) != 0 {
+				known[id] = true
+			}
+		}
+	}
+	return known, nil
+}
+
+// civoOrphans the resources of the region named by ksctl whose IDs are not known
+func civoOrphans(region string, known map[string]bool, instances []civogo.Instance, firewalls []civogo.Firewall, networks []civogo.Network, keys []civogo.SSHKey) []util.OrphanedResource {
+	var orphans []util.OrphanedResource
+	for _, instance := range instances {
+		if match := civoNodeName.FindStringSubmatch(instance.Hostname); match != nil && !known[instance.ID] {
+			orphans = append(orphans, util.OrphanedResource{Kind: "instance", Name: instance.Hostname, ID: instance.ID, Region: region, Cluster: match[1], MonthlyCost: civoMonthlyPrice[instance.Size]})
+		}
+	}
+	for _, firewall := range firewalls {
+		if match := civoNodeName.FindStringSubmatch(firewall.Name); match != nil && !known[firewall.ID] {
+			orphans = append(orphans, util.OrphanedResource{Kind: "firewall", Name: firewall.Name, ID: firewall.ID, Region: region, Cluster: match[1]})
+		}
+	}
+	for _, network := range networks {
+		if match := civoNetworkName.FindStringSubmatch(network.Label); match != nil && !known[network.ID] {
+			orphans = append(orphans, util.OrphanedResource{Kind: "network", Name: network.Label, ID: network.ID, Region: region, Cluster: match[1]})
+		}
+	}
+	// the ssh keys are named <name>-<region>-ksctl-ha
+	suffix := "-" + strings.ToLower(region) + "-ksctl-ha"
+	for _, key := range keys {
+		if strings.HasSuffix(key.Name, suffix) && !known[key.ID] {
+			orphans = append(orphans, util.OrphanedResource{Kind: "ssh-key", Name: key.Name, ID: key.ID, Region: region, Cluster: strings.TrimSuffix(key.Name, suffix)})
+		}
+	}
+	return orphans
+}
+
+// civoOrphaned implements util.GarbageCollector
+// the resources of the clusters being created or deleted are skipped as they may not be recorded yet
+func civoOrphaned(ctx context.Context, logging log.Logger, region string) ([]util.OrphanedResource, error) {
+	regions := util.CIVO_REGIONS
+	if len(region) != 0 {
+		if !util.IsValidRegionCIVO(region) {
+			return nil, fmt.Errorf("region {%s} is invalid", region)
+		}
+		regions = []string{region}
+	}
+	known, err := knownCivoIDs()
+	if err != nil {
+		return nil, err
+	}
+	apiKey := fetchAPIKey(logging)
+
+	var orphans []util.OrphanedResource
+	for _, region := range regions {
+		client, err := civogo.NewClient(apiKey, region)
+		if err != nil {
+			return nil, err
+		}
+		instances, err := client.ListAllInstances()
+		if err != nil {
+			return nil, err
+		}
+		firewalls, err := client.ListFirewalls()
+		if err != nil {
+			return nil, err
+		}
+		networks, err := client.ListNetworks()
+		if err != nil {
+			return nil, err
+		}
+		keys, err := client.ListSSHKeys()
+		if err != nil {
+			return nil, err
+		}
+		for _, orphan := range civoOrphans(region, known, instances, firewalls, networks, keys) {
+			if util.IsLocked("civo", util.ClusterPayload{ClusterName: orphan.Cluster, Region: region, HACluster: true}) {
+				logging.Note("Skipping the resource of the cluster being changed", orphan.Name)
+				continue
+			}
+			orphans = append(orphans, orphan)
+		}
+	}
+	return orphans, nil
+}
+
+// civoDeleteOrphaned implements util.GarbageCollector
+func civoDeleteOrphaned(ctx context.Context, logging log.Logger, resources []util.OrphanedResource) error {
+	byRegion := map[string][]util.OrphanedResource{}
+	for _, resource := range resources {
+		byRegion[resource.Region] = append(byRegion[resource.Region], resource)
+	}
+	apiKey := fetchAPIKey(logging)
+	for region, resources := range byRegion {
+		client, err := civogo.NewClient(apiKey, region)
+		if err != nil {
+			return err
+		}
+		if err := deleteOrphans(ctx, logging, client, resources); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteOrphans deletes the instances first as the firewalls and the network cannot be deleted while they are used
+// every resource is attempted so that one failure doesn't leave the rest behind
+func deleteOrphans(ctx context.Context, logging log.Logger, client *civogo.Client, resources []util.OrphanedResource) error {
+	deleteResource := func(resource util.OrphanedResource) (err error) {
+		switch resource.Kind {
+		case "instance":
+			_, err = client.DeleteInstance(resource.ID)
+		case "firewall":
+			_, err = client.DeleteFirewall(resource.ID)
+		case "network":
+			_, err = client.DeleteNetwork(resource.ID)
+		case "ssh-key":
+			_, err = client.DeleteSSHKey(resource.ID)
+		default:
+			err = fmt.Errorf("unknown kind of resource: %s", resource.Kind)
+		}
+		return
+	}
+
+	var errV error
+	// the firewalls and the network are retried till the instances using them are gone
+	for _, kind := range []string{"instance", "ssh-key", "firewall", "network"} {
+		for _, resource := range resources {
+			if resource.Kind != kind {
+				continue
+			}
+			err := deleteResource(resource)
+			for retry := 1; err != nil && retry < util.MAX_RETRY_COUNT && (kind == "firewall" || kind == "network"); retry++ {
+				if ctxErr := util.Sleep(ctx, gcRetryInterval); ctxErr != nil {
+					return ctxErr
+				}
+				err = deleteResource(resource)
+			}
+			if err != nil {
+				errV = err
+				logging.Err(fmt.Sprintf("❌ unable to delete %s %s: %v", resource.Kind, resource.Name, err))
+				continue
+			}
+			logging.Info(fmt.Sprintf("✅ deleted %s", resource.Kind), resource.Name)
+		}
+	}
+	return errV
+}
@@ -1,6 +1,7 @@
 package civo
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -32,9 +33,9 @@ func isValidSizeHA(size string) bool {
 }
 
 // HelperExecNoOutputControlPlane helps with script execution without returning us the output
-func (obj *HAType) HelperExecNoOutputControlPlane(logging log.Logger, publicIP, script string, fastMode bool) error {
+func (obj *HAType) HelperExecNoOutputControlPlane(ctx context.Context, logging log.Logger, publicIP, script string, fastMode bool) error {
 	obj.SSH_Payload.PublicIP = publicIP
-	err := obj.SSH_Payload.SSHExecute(logging, util.EXEC_WITHOUT_OUTPUT, script, fastMode)
+	err := obj.SSH_Payload.SSHExecute(ctx, logging, util.EXEC_WITHOUT_OUTPUT, script, fastMode)
 	if err != nil {
 		return err
 	}
@@ -42,10 +43,10 @@ func (obj *HAType) HelperExecNoOutputControlPlane(logging log.Logger, publicIP,
 }
 
 // HelperExecOutputControlPlane helps with script execution and also returns the script output
-func (obj *HAType) HelperExecOutputControlPlane(logging log.Logger, publicIP, script string, fastMode bool) (string, error) {
+func (obj *HAType) HelperExecOutputControlPlane(ctx context.Context, logging log.Logger, publicIP, script string, fastMode bool) (string, error) {
 	obj.SSH_Payload.Output = ""
 	obj.SSH_Payload.PublicIP = publicIP
-	err := obj.SSH_Payload.SSHExecute(logging, util.EXEC_WITH_OUTPUT, script, fastMode)
+	err := obj.SSH_Payload.SSHExecute(ctx, logging, util.EXEC_WITH_OUTPUT, script, fastMode)
 	if err != nil {
 		return "", err
 	}
@@ -53,7 +54,9 @@ func (obj *HAType) HelperExecOutputControlPlane(logging log.Logger, publicIP, sc
 }
 
 // haCreateClusterHandler creates a HA type cluster
-func haCreateClusterHandler(logging log.Logger, name, region, nodeSize string, noCP, noWP int) error {
+// every completed step is saved as a checkpoint in the state management file
+// when resume is set the creation continues from the step which failed earlier
+func haCreateClusterHandler(ctx context.Context, logging log.Logger, name, region, nodeSize string, noCP, noWP int, resume bool, tags map[string]string, distribution, k8sVersion string) error {
 
 	if errV := validationOfArguments(name, region); errV != nil {
 		return errV
@@ -63,14 +66,24 @@ func haCreateClusterHandler(logging log.Logger, name, region, nodeSize string, n
 		return fmt.Errorf("invalid node size")
 	}
 
-	if isPresent("ha", name, region) {
-		return fmt.Errorf("duplicate cluster found")
+	present := isPresent("ha", name, region)
+	if present && !resume {
+		return fmt.Errorf("duplicate cluster found, use --resume to continue its creation")
 	}
 
 	if err := util.IsValidNoOfControlPlanes(noCP); err != nil {
 		return err
 	}
 
+	release, err := util.GetKubernetesRelease(k8sVersion)
+	if err != nil {
+		return err
+	}
+	dist, err := util.NewDistribution(distribution, release.Version)
+	if err != nil {
+		return err
+	}
+
 	apiToken := fetchAPIKey(logging)
 	if len(apiToken) == 0 {
 		return fmt.Errorf("Credentials are missing")
@@ -86,28 +99,52 @@ func haCreateClusterHandler(logging log.Logger, name, region, nodeSize string, n
 		return err
 	}
 
+	config := &JsonStore{
+		ClusterName: name,
+		Region:      region,
+		DBEndpoint:  "",
+		ServerToken: "",
+		InstanceIDs: InstanceID{},
+		NetworkIDs:  NetworkID{},
+
+		ClusterMetadata: util.NewClusterMetadata(nodeSize, 0),
+	}
+	config.Tags = tags
+	config.Distribution = dist.Name()
+	config.KubernetesVersion = release.Version
+	config.ControlPlaneNodes, config.WorkerNodes = noCP, noWP
+	if present {
+		savedConfig, err := GetConfig(name, region)
+		if err != nil {
+			return err
+		}
+		config = &savedConfig
+		if err := config.ValidateResume(nodeSize, noCP, noWP, len(config.InstanceIDs.ControlNodes), len(config.InstanceIDs.WorkerNodes)); err != nil {
+			return err
+		}
+		logging.Note(fmt.Sprintf("Resuming the cluster creation, completed steps: %v", config.Checkpoints))
+		// the nodes which are already installed decide the distribution and its version
+		if dist, err = util.NewDistribution(config.Distribution, config.KubernetesVersion); err != nil {
+			return err
+		}
+	}
+
 	var obj HACollection
 
 	obj = &HAType{
-		Client:       client,
-		NodeSize:     nodeSize,
-		ClusterName:  name,
-		DiskImgID:    diskImg.ID,
-		DBFirewallID: "",
-		LBFirewallID: "",
-		CPFirewallID: "",
-		WPFirewallID: "",
-		NetworkID:    "",
-		SSHID:        "",
-		Configuration: &JsonStore{
-			ClusterName: name,
-			Region:      region,
-			DBEndpoint:  "",
-			ServerToken: "",
-			InstanceIDs: InstanceID{},
-			NetworkIDs:  NetworkID{},
-		},
-		SSH_Payload: &util.SSHPayload{},
+		Client:        client,
+		NodeSize:      nodeSize,
+		ClusterName:   name,
+		DiskImgID:     diskImg.ID,
+		DBFirewallID:  config.NetworkIDs.FirewallIDDatabaseNode,
+		LBFirewallID:  config.NetworkIDs.FirewallIDLoadBalancerNode,
+		CPFirewallID:  config.NetworkIDs.FirewallIDControlPlaneNode,
+		WPFirewallID:  config.NetworkIDs.FirewallIDWorkerNode,
+		NetworkID:     config.NetworkIDs.NetworkID,
+		SSHID:         config.SSHID,
+		Configuration: config,
+		SSH_Payload:   &util.SSHPayload{},
+		Distribution:  dist,
 	}
 
 	// NOTE: Config Loadbalancer require the control planes privateIPs
@@ -116,79 +153,129 @@ func haCreateClusterHandler(logging log.Logger, name, region, nodeSize string, n
 	if err != nil {
 		return err
 	}
+	if err := config.ConfigWriterCheckpoint(logging, util.STEP_SSH_KEY); err != nil {
+		return err
+	}
 
-	mysqlEndpoint, err := obj.CreateDatabase(logging)
-	if err != nil {
+	// the database is only created for the distribution keeping its state in it
+	var mysqlEndpoint string
+	if dist.ExternalDatastore() {
+		mysqlEndpoint, err = obj.CreateDatabase(ctx, logging)
+		if err != nil {
+			return err
+		}
+		if err := config.ConfigWriterCheckpoint(logging, util.STEP_DATABASE); err != nil {
+			return err
+		}
+	} else if err := obj.CreateNetwork(logging, name+"-ksctl"); err != nil {
 		return err
 	}
 
-	loadBalancer, err := obj.CreateLoadbalancer(logging)
+	loadBalancer, err := obj.CreateLoadbalancer(ctx, logging)
 	if err != nil {
 		return err
 	}
+	if err := config.ConfigWriterCheckpoint(logging, util.STEP_LOADBALANCER); err != nil {
+		return err
+	}
 
 	var controlPlanes = make([](*civogo.Instance), noCP)
 
-	for i := 0; i < noCP; i++ {
-		controlPlanes[i], err = obj.CreateControlPlane(logging, i+1)
+	err = util.RunInParallel(noCP, util.MAX_PARALLEL_NODES, func(i int) (err error) {
+		controlPlanes[i], err = obj.CreateControlPlane(ctx, logging, i+1)
 		if err != nil {
-			return err
+			return fmt.Errorf("control-plane-%d: %v", i+1, err)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := config.ConfigWriterCheckpoint(logging, util.STEP_CONTROLPLANES); err != nil {
+		return err
 	}
 
 	// NOTE: Config the loadbalancer before controlplane is configured
 	var controlPlaneIPs = make([]string, noCP)
 	for i := 0; i < noCP; i++ {
-		controlPlaneIPs[i] = controlPlanes[i].PrivateIP + ":6443"
+		controlPlaneIPs[i] = controlPlanes[i].PrivateIP
 	}
 
-	err = obj.ConfigLoadBalancer(logging, loadBalancer, controlPlaneIPs)
-	if err != nil {
-		return err
+	if !config.Checkpoints.Done(util.STEP_LOADBALANCER_CONFIG) {
+		err = obj.ConfigLoadBalancer(ctx, logging, loadBalancer, controlPlaneIPs)
+		if err != nil {
+			return err
+		}
+		if err := config.ConfigWriterCheckpoint(logging, util.STEP_LOADBALANCER_CONFIG); err != nil {
+			return err
+		}
 	}
 
-	token := ""
+	token := config.ServerToken
+	if config.Checkpoints.Done(util.StepK3sControlPlane(1)) && !config.Checkpoints.Done(util.StepK3sControlPlane(noCP)) {
+		// on resume the token is fetched again as kubeadm keeps the certificates for the controlplanes for 2 hours
+		if token = obj.GetTokenFromCP_1(ctx, logging, controlPlanes[0]); len(token) == 0 {
+			return fmt.Errorf("🚨 Cannot retrieve %s token", dist.Name())
+		}
+	}
 	for i := 0; i < noCP; i++ {
+		if config.Checkpoints.Done(util.StepK3sControlPlane(i + 1)) {
+			continue
+		}
+		distConfig := util.DistributionConfig{
+			LoadBalancerPublicIP:  loadBalancer.PublicIP,
+			LoadBalancerPrivateIP: loadBalancer.PrivateIP,
+			DatastoreEndpoint:     mysqlEndpoint,
+			Token:                 token,
+		}
 		if i == 0 {
-			err = obj.HelperExecNoOutputControlPlane(logging, controlPlanes[i].PublicIP, scriptWithoutCP_1(mysqlEndpoint, loadBalancer.PublicIP), true)
+			err = obj.HelperExecNoOutputControlPlane(ctx, logging, controlPlanes[i].PublicIP, dist.InstallFirstControlPlane(distConfig), true)
 			if err != nil {
 				return err
 			}
 
-			token = obj.GetTokenFromCP_1(logging, controlPlanes[0])
+			token = obj.GetTokenFromCP_1(ctx, logging, controlPlanes[0])
 			if len(token) == 0 {
-				return fmt.Errorf("🚨 Cannot retrieve k3s token")
+				return fmt.Errorf("🚨 Cannot retrieve %s token", dist.Name())
 			}
 		} else {
-			err = obj.HelperExecNoOutputControlPlane(logging, controlPlanes[i].PublicIP, scriptCP_n(mysqlEndpoint, loadBalancer.PublicIP, token), true)
+			err = obj.HelperExecNoOutputControlPlane(ctx, logging, controlPlanes[i].PublicIP, dist.JoinControlPlane(distConfig), true)
 			if err != nil {
 				return err
 			}
 		}
+		if err := config.ConfigWriterCheckpoint(logging, util.StepK3sControlPlane(i+1)); err != nil {
+			return err
+		}
 		logging.Info("✅ Configured", fmt.Sprintf("control-plane-%d\n", i+1))
 	}
 
-	kubeconfig, err := obj.FetchKUBECONFIG(logging, controlPlanes[0])
-	if err != nil {
-		return fmt.Errorf("Cannot fetch kubeconfig\n" + err.Error())
+	if !config.Checkpoints.Done(util.STEP_KUBECONFIG) {
+		kubeconfig, err := obj.FetchKUBECONFIG(ctx, logging, controlPlanes[0])
+		if err != nil {
+			return fmt.Errorf("Cannot fetch kubeconfig\n" + err.Error())
+		}
+		err = obj.SaveKubeconfig(logging, haKubeconfig(kubeconfig, loadBalancer.PublicIP, name, region))
+		if err != nil {
+			return err
+		}
+		if err := config.ConfigWriterCheckpoint(logging, util.STEP_KUBECONFIG); err != nil {
+			return err
+		}
 	}
-	newKubeconfig := strings.Replace(kubeconfig, "127.0.0.1", loadBalancer.PublicIP, 1)
-
-	newKubeconfig = strings.Replace(newKubeconfig, "default", name+"-"+strings.ToLower(region)+"-ha-civo", -1)
 
-	err = obj.SaveKubeconfig(logging, newKubeconfig)
+	logging.Info("⛓  JOINING WORKER NODES", "")
+	err = util.RunInParallel(noWP, util.MAX_PARALLEL_NODES, func(i int) error {
+		if _, err := obj.CreateWorkerNode(ctx, logging, i+1, loadBalancer.PrivateIP, token); err != nil {
+			return fmt.Errorf("worker-node-%d: %v", i+1, err)
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-
-	logging.Info("⛓  JOINING WORKER NODES", "")
-	var workerPlanes = make([](*civogo.Instance), noWP)
-
-	for i := 0; i < noWP; i++ {
-		workerPlanes[i], err = obj.CreateWorkerNode(logging, i+1, loadBalancer.PrivateIP, token)
-		if err != nil {
-			return err
-		}
+	if err := config.ConfigWriterCheckpoint(logging, util.STEP_WORKERPLANES); err != nil {
+		return err
 	}
 
 	logging.Info("Created your HA Civo cluster!!🥳 🎉 ")
@@ -203,7 +290,7 @@ func haCreateClusterHandler(logging log.Logger, name, region, nodeSize string, n
 	return nil
 }
 
-func haDeleteClusterHandler(logging log.Logger, name, region string, showMsg bool) error {
+func haDeleteClusterHandler(ctx context.Context, logging log.Logger, name, region string, showMsg bool) error {
 
 	if errV := validationOfArguments(name, region); errV != nil {
 		return errV
@@ -270,10 +357,12 @@ THIS IS A DESTRUCTIVE STEP MAKE SURE IF YOU WANT TO DELETE THE CLUSTER '%s'
 		// dont delete the configs
 		return errR
 	}
-	time.Sleep(10 * time.Second)
+	if err := util.Sleep(ctx, 10*time.Second); err != nil {
+		return err
+	}
 
 	errR = nil
-	if err := obj.DeleteNetworks(logging); err != nil && !errors.Is(civogo.DatabaseNetworkNotFoundError, err) {
+	if err := obj.DeleteNetworks(ctx, logging); err != nil && !errors.Is(civogo.DatabaseNetworkNotFoundError, err) {
 		return err
 	}
 	errR = err
@@ -299,8 +388,57 @@ THIS IS A DESTRUCTIVE STEP MAKE SURE IF YOU WANT TO DELETE THE CLUSTER '%s'
 	return nil
 }
 
+// haKubeconfig the kubeconfig of the distribution points at the loadbalancer instead of the controlplane
+// and its entries are named after the cluster
+func haKubeconfig(kubeconfig, loadBalancerIP, name, region string) string {
+	kubeconfig = strings.Replace(kubeconfig, "127.0.0.1", loadBalancerIP, 1)
+	return strings.Replace(kubeconfig, "default", name+"-"+strings.ToLower(region)+"-ha-civo", -1)
+}
+
+// haRefreshKubeconfig fetches the kubeconfig of the HA cluster again from its first controlplane
+func haRefreshKubeconfig(ctx context.Context, logging log.Logger, name, region string) error {
+	config, err := GetConfig(name, region)
+	if err != nil {
+		return err
+	}
+	if len(config.ControlPlaneID(1)) == 0 || len(config.InstanceIDs.LoadBalancerNode) == 0 {
+		return fmt.Errorf("the cluster %s has no controlplane or loadbalancer", name)
+	}
+	dist, err := util.NewDistribution(config.Distribution, config.KubernetesVersion)
+	if err != nil {
+		return err
+	}
+	client, err := civogo.NewClient(fetchAPIKey(logging), region)
+	if err != nil {
+		return err
+	}
+	obj := &HAType{
+		Client:        client,
+		ClusterName:   name,
+		Configuration: &config,
+		SSH_Payload: &util.SSHPayload{
+			UserName:       "root",
+			PathPrivateKey: util.GetPath(util.SSH_PATH, "civo", "ha", clusterRef("ha", name, region).ID()),
+		},
+		Distribution: dist,
+	}
+	controlPlane, err := obj.GetInstance(config.ControlPlaneID(1))
+	if err != nil {
+		return err
+	}
+	loadBalancer, err := obj.GetInstance(config.InstanceIDs.LoadBalancerNode[0])
+	if err != nil {
+		return err
+	}
+	kubeconfig, err := obj.FetchKUBECONFIG(ctx, logging, controlPlane)
+	if err != nil {
+		return fmt.Errorf("Cannot fetch kubeconfig\n" + err.Error())
+	}
+	return obj.SaveKubeconfig(logging, haKubeconfig(kubeconfig, loadBalancer.PublicIP, name, region))
+}
+
 // AddMoreWorkerNodes adds more worker nodes to the existing HA cluster
-func (provider CivoProvider) AddMoreWorkerNodes(logging log.Logger) error {
+func (provider CivoProvider) AddMoreWorkerNodes(ctx context.Context, logging log.Logger) error {
 	name, region, nodeSize, noWP := provider.ClusterName, provider.Region, provider.Spec.Disk, provider.Spec.HAWorkerNodes
 
 	if errV := validationOfArguments(name, region); errV != nil {
@@ -329,6 +467,11 @@ func (provider CivoProvider) AddMoreWorkerNodes(logging log.Logger) error {
 	if err != nil {
 		return err
 	}
+	// the new nodes join with the distribution and the version the cluster was created with
+	dist, err := util.NewDistribution(config.Distribution, config.KubernetesVersion)
+	if err != nil {
+		return err
+	}
 	var obj HACollection
 
 	obj = &HAType{
@@ -338,20 +481,42 @@ func (provider CivoProvider) AddMoreWorkerNodes(logging log.Logger) error {
 		DiskImgID:     diskImg.ID,
 		WPFirewallID:  config.NetworkIDs.FirewallIDWorkerNode,
 		NetworkID:     config.NetworkIDs.NetworkID,
-		Configuration: &config}
+		Configuration: &config,
+		SSH_Payload: &util.SSHPayload{
+			UserName:       "root",
+			PathPrivateKey: util.GetPath(util.SSH_PATH, "civo", "ha", clusterRef("ha", name, region).ID()),
+		},
+		Distribution: dist}
 
 	logging.Info("JOINING Additional WORKER NODES", "")
 	lb, err := obj.GetInstance(config.InstanceIDs.LoadBalancerNode[0])
-	var workerPlanes = make([](*civogo.Instance), noWP)
+	if err != nil {
+		return err
+	}
+
+	// the bootstrap token of kubeadm expires so a new one is created on the first controlplane
+	token, err := util.JoinToken(dist, config.ServerToken, func() (string, error) {
+		controlPlane, err := obj.GetInstance(config.ControlPlaneID(1))
+		if err != nil {
+			return "", err
+		}
+		return obj.GetTokenFromCP_1(ctx, logging, controlPlane), nil
+	})
+	if err != nil {
+		return err
+	}
 
 	noOfWorkerNodes := len(config.InstanceIDs.WorkerNodes)
 
-	for i := 0; i < noWP; i++ {
-		workerPlanes[i], err = obj.CreateWorkerNode(logging, i+noOfWorkerNodes+1, lb.PrivateIP, config.ServerToken)
-		if err != nil {
-			logging.Err("Failed to add more nodes..")
-			return err
+	err = util.RunInParallel(noWP, util.MAX_PARALLEL_NODES, func(i int) error {
+		if _, err := obj.CreateWorkerNode(ctx, logging, i+noOfWorkerNodes+1, lb.PrivateIP, token); err != nil {
+			return fmt.Errorf("worker-node-%d: %v", i+noOfWorkerNodes+1, err)
 		}
+		return nil
+	})
+	if err != nil {
+		logging.Err("Failed to add more nodes..")
+		return err
 	}
 
 	logging.Info("Added more nodes 🥳 🎉 ")
@@ -359,7 +524,7 @@ func (provider CivoProvider) AddMoreWorkerNodes(logging log.Logger) error {
 }
 
 // DeleteSomeWorkerNodes deletes workerNodes from existing HA cluster
-func (provider CivoProvider) DeleteSomeWorkerNodes(logging log.Logger) error {
+func (provider CivoProvider) DeleteSomeWorkerNodes(ctx context.Context, logging log.Logger) error {
 	clusterName := provider.ClusterName
 	region := provider.Region
 	noWP := provider.Spec.HAWorkerNodes
@@ -384,18 +549,8 @@ then deletion will happen from 4, 3, 2, 1
 2) then delete before deleting the instance
 		kubectl delete node <node name>
 `))
-	fmt.Println("Enter your choice to continue..[y/N]")
-	choice := "n"
-	unsafe := false
-	fmt.Scanf("%s", &choice)
-	if strings.Compare("y", choice) == 0 ||
-		strings.Compare("yes", choice) == 0 ||
-		strings.Compare("Y", choice) == 0 {
-		unsafe = true
-	}
-
-	if !unsafe {
-		return nil
+	if err := util.ConfirmNodesDeletion(logging, provider.AutoApprove); err != nil {
+		return err
 	}
 
 	config, err := GetConfig(clusterName, region)
@@ -425,14 +580,16 @@ then deletion will happen from 4, 3, 2, 1
 	}
 
 	for i := 0; i < noWP; i++ {
-		err := obj.DeleteInstance(config.InstanceIDs.WorkerNodes[len(config.InstanceIDs.WorkerNodes)-1])
-		if err != nil {
-			return err
+		// the node which failed to be created has no instance
+		if instanceID := config.InstanceIDs.WorkerNodes[len(config.InstanceIDs.WorkerNodes)-1]; len(instanceID) != 0 {
+			if err := obj.DeleteInstance(instanceID); err != nil {
+				return err
+			}
 		}
 
 		config.InstanceIDs.WorkerNodes = config.InstanceIDs.WorkerNodes[:len(config.InstanceIDs.WorkerNodes)-1]
 
-		err = saveConfig(logging, clusterName+" "+region, config)
+		err = saveConfig(logging, config)
 		if err != nil {
 			return err
 		}
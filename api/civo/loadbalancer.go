@@ -9,8 +9,7 @@ Credit to @civo
 package civo
 
 import (
-	"fmt"
-	"time"
+	"context"
 
 	log "github.com/kubesimplify/ksctl/api/logger"
 
@@ -27,85 +26,58 @@ systemctl start haproxy && systemctl enable haproxy
 `
 }
 
-func configLBscript(controlPlaneIPs []string) string {
-	script := `cat <<EOF > /etc/haproxy/haproxy.cfg
-frontend kubernetes-frontend
-  bind *:6443
-  mode tcp
-  option tcplog
-  timeout client 10s
-  default_backend kubernetes-backend
-
-backend kubernetes-backend
-  timeout connect 10s
-  timeout server 10s
-  mode tcp
-  option tcp-check
-  balance roundrobin
-`
-
-	for index, controlPlaneIP := range controlPlaneIPs {
-		script += fmt.Sprintf(`  server k3sserver-%d %s check
-`, index+1, controlPlaneIP)
-	}
-
-	script += `EOF
+// configLBscript forwards the ports of the distribution to the controlplanes
+func configLBscript(controlPlaneIPs []string, ports []int) string {
+	return "cat <<EOF > /etc/haproxy/haproxy.cfg\n" + util.HAProxyConfig(controlPlaneIPs, ports) + `EOF
 
 systemctl restart haproxy
 `
-	return script
 }
 
-func (obj *HAType) ConfigLoadBalancer(logging log.Logger, instance *civogo.Instance, CPIPs []string) error {
-	getScript := configLBscript(CPIPs)
+func (obj *HAType) ConfigLoadBalancer(ctx context.Context, logging log.Logger, instance *civogo.Instance, CPIPs []string) error {
+	getScript := configLBscript(CPIPs, obj.Distribution.Ports())
 	obj.SSH_Payload.PublicIP = instance.PublicIP
-	err := obj.SSH_Payload.SSHExecute(logging, util.EXEC_WITHOUT_OUTPUT, getScript, true)
+	err := obj.SSH_Payload.SSHExecute(ctx, logging, util.EXEC_WITHOUT_OUTPUT, getScript, true)
 	if err == nil {
 		logging.Info("✅ Configured LoadBalancer", "")
 	}
 	return err
 }
 
-func (obj *HAType) CreateLoadbalancer(logging log.Logger) (*civogo.Instance, error) {
-
+// CreateLoadbalancer creates the loadbalancer VM
+// the firewall and instance saved in the state management file are reused
+func (obj *HAType) CreateLoadbalancer(ctx context.Context, logging log.Logger) (*civogo.Instance, error) {
 	name := obj.ClusterName + "-ksctl-lb"
-	firewall, err := obj.CreateFirewall(name)
-	if err != nil {
-		return nil, err
+
+	if len(obj.Configuration.NetworkIDs.FirewallIDLoadBalancerNode) == 0 {
+		firewall, err := obj.CreateFirewall(name)
+		if err != nil {
+			return nil, err
+		}
+		obj.LBFirewallID = firewall.ID
+
+		// TODO: More restrictive policies
+		err = obj.Configuration.ConfigWriterFirewallLoadBalancerNodes(logging, firewall.ID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		obj.LBFirewallID = obj.Configuration.NetworkIDs.FirewallIDLoadBalancerNode
 	}
-	obj.LBFirewallID = firewall.ID
 
-	// TODO: More restrictive policies
-	err = obj.Configuration.ConfigWriterFirewallLoadBalancerNodes(logging, firewall.ID)
-	if err != nil {
-		return nil, nil
+	if len(obj.Configuration.InstanceIDs.LoadBalancerNode) != 0 {
+		return obj.waitForInstance(ctx, logging, obj.Configuration.InstanceIDs.LoadBalancerNode[0], name)
 	}
 
-	instance, err := obj.CreateInstance(name, firewall.ID, "g3.medium", scriptLB(), true)
+	instance, err := obj.CreateInstance(name, util.NODE_ROLE_LOADBALANCER, obj.LBFirewallID, "g3.medium", scriptLB(), true)
 	if err != nil {
 		return nil, err
 	}
 
 	err = obj.Configuration.ConfigWriterInstanceLoadBalancer(logging, instance.ID)
 	if err != nil {
-		return nil, nil
+		return nil, err
 	}
 
-	var retObject *civogo.Instance
-
-	for {
-		getInstance, err := obj.GetInstance(instance.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		if getInstance.Status == "ACTIVE" {
-			retObject = getInstance
-
-			logging.Info("💻 Booted Instance ", name)
-			return retObject, nil
-		}
-		logging.Info("🚧 Instance ", name)
-		time.Sleep(10 * time.Second)
-	}
+	return obj.waitForInstance(ctx, logging, instance.ID, name)
 }
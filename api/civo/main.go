@@ -9,10 +9,13 @@ Credit to @civo
 package civo
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"runtime"
 
+	"github.com/civo/civogo"
 	log "github.com/kubesimplify/ksctl/api/logger"
 
 	util "github.com/kubesimplify/ksctl/api/utils"
@@ -26,6 +29,58 @@ type CivoProvider struct {
 	Spec        util.Machine `json:"spec"`
 	Application string       `json:"application"`
 	CNIPlugin   string       `json:"cni_plugin"`
+	// Resume continues the HA cluster creation from the saved checkpoints
+	Resume bool `json:"resume"`
+	// CleanupOnFailure deletes the partially created HA cluster when the creation fails
+	CleanupOnFailure bool `json:"cleanup_on_failure"`
+	// Tags given by the user for the resources of the cluster
+	Tags map[string]string `json:"tags"`
+	// Distribution of kubernetes of the HA cluster
+	Distribution string `json:"distribution"`
+	// KubernetesVersion the minor version of kubernetes of the cluster
+	KubernetesVersion string `json:"kubernetes_version"`
+	// AutoApprove the worker nodes are deleted without asking for the confirmation
+	AutoApprove bool `json:"auto_approve"`
+}
+
+func init() {
+	util.RegisterProvider("civo", func(payload util.ClusterPayload) util.Provider {
+		return &CivoProvider{
+			ClusterName: payload.ClusterName,
+			Region:      payload.Region,
+			HACluster:   payload.HACluster,
+			Spec:        payload.Spec,
+			Application: payload.Application,
+			CNIPlugin:   payload.CNIPlugin,
+
+			Resume:            payload.Resume,
+			CleanupOnFailure:  payload.CleanupOnFailure,
+			Tags:              payload.Tags,
+			Distribution:      payload.Distribution,
+			KubernetesVersion: payload.KubernetesVersion,
+			AutoApprove:       payload.AutoApprove,
+		}
+	})
+	util.RegisterCredentials("civo", util.CredentialType{
+		Fields: []util.CredentialField{
+			{Key: "token", Env: "CIVO_TOKEN", Prompt: "Enter your API-TOKEN-KEY", Secret: true},
+		},
+		Verify: verifyCredentials,
+	})
+	util.RegisterGarbageCollector("civo", util.GarbageCollector{
+		Orphaned: civoOrphaned,
+		Delete:   civoDeleteOrphaned,
+	})
+}
+
+// verifyCredentials lists the regions which is the cheapest call needing a valid api_token
+func verifyCredentials(ctx context.Context, cred map[string]string) error {
+	client, err := civogo.NewClient(cred["token"], "LON1")
+	if err != nil {
+		return err
+	}
+	_, err = client.ListRegions()
+	return err
 }
 
 // Credentials accept the api_token for CIVO auth and authorization from user
@@ -34,14 +89,11 @@ func Credentials(logger log.Logger) bool {
 	logger.Print("Enter your API-TOKEN-KEY 👇")
 	apikey, err := util.UserInputCredentials(logger)
 	if err != nil {
-		panic(err.Error())
-	}
-
-	apiStore := util.CivoCredential{
-		Token: apikey,
+		logger.Err(err.Error())
+		return false
 	}
 
-	err = util.SaveCred(logger, apiStore, "civo")
+	err = util.SetCred(context.Background(), logger, "civo", map[string]string{"token": apikey}, true)
 
 	if err != nil {
 		logger.Err(err.Error())
@@ -68,17 +120,20 @@ func fetchAPIKey(logger log.Logger) string {
 
 // isPresent Checks whether the cluster to create is already present
 func isPresent(offering, clusterName, Region string) bool {
-	_, err := os.ReadFile(util.GetPath(util.CLUSTER_PATH, "civo", offering, clusterName+" "+Region, "info.json"))
-	if os.IsNotExist(err) {
-		return false
+	return clusterRef(offering, clusterName, Region).IsPresent()
+}
+
+// fetchKubeconfig copies the kubeconfig from the state store to the local path which is printed
+func fetchKubeconfig(logging log.Logger, offering, clusterName, region string) {
+	if _, err := util.FetchStateFile("civo", offering, clusterRef(offering, clusterName, region).ID(), "config"); err != nil {
+		logging.Warn("unable to fetch the kubeconfig", err.Error())
 	}
-	return true
 }
 
 // cleanup called when error is encountered during creation during cluster creation
-func cleanup(logging log.Logger, provider CivoProvider) error {
+func cleanup(ctx context.Context, logging log.Logger, provider CivoProvider) error {
 	logging.Err("Cannot continue 😢")
-	return haDeleteClusterHandler(logging, provider.ClusterName, provider.Region, false)
+	return haDeleteClusterHandler(ctx, logging, provider.ClusterName, provider.Region, false)
 }
 
 // validationOfArguments is name and region specified valid
@@ -98,23 +153,41 @@ func validationOfArguments(name, region string) error {
 // CreateCluster calls the helper functions for cluster creation
 // based on the flag `HACluster` whether to delete managed cluster or HA type cluster
 // FIXME: Ingress or Loadbalancer is not working as expected!!
-func (provider CivoProvider) CreateCluster(logging log.Logger) error {
+func (provider CivoProvider) CreateCluster(ctx context.Context, logging log.Logger) error {
 	if provider.HACluster {
-		if err := haCreateClusterHandler(logging, provider.ClusterName, provider.Region, provider.Spec.Disk,
-			provider.Spec.HAControlPlaneNodes, provider.Spec.HAWorkerNodes); err != nil {
-			_ = cleanup(logging, provider)
+		// an existing cluster which is not being resumed must never be cleaned up
+		existing := isPresent("ha", provider.ClusterName, provider.Region) && !provider.Resume
+		if err := haCreateClusterHandler(ctx, logging, provider.ClusterName, provider.Region, provider.Spec.Disk,
+			provider.Spec.HAControlPlaneNodes, provider.Spec.HAWorkerNodes, provider.Resume, provider.Tags, provider.Distribution, provider.KubernetesVersion); err != nil {
+			if existing || !isPresent("ha", provider.ClusterName, provider.Region) {
+				return err
+			}
+			if ctx.Err() != nil {
+				// an interrupted creation keeps its state so that it can be resumed or deleted later
+				logging.Note("Cluster creation was interrupted, rerun with --resume to continue or use delete-cluster to remove it")
+			} else if provider.CleanupOnFailure {
+				_ = cleanup(ctx, logging, provider)
+			} else {
+				logging.Note("The created resources are kept, rerun with --resume to continue or use delete-cluster to remove them")
+			}
 			return err
 		}
 		return nil
 	}
 	payload := ClusterInfoInjecter(logging, provider.ClusterName, provider.Region, provider.Spec.Disk, provider.Spec.ManagedNodes, provider.Application, provider.CNIPlugin)
+	payload.Tags = provider.Tags
+	payload.KubernetesVersion = provider.KubernetesVersion
 	if isPresent("managed", provider.ClusterName, provider.Region) {
 		return fmt.Errorf("DUPLICATE Cluster")
 	}
-	err := managedCreateClusterHandler(logging, payload)
+	err := managedCreateClusterHandler(ctx, logging, payload)
 	if err != nil {
+		if ctx.Err() != nil {
+			logging.Note("Cluster creation was interrupted, use delete-cluster to remove it")
+			return err
+		}
 		logging.Err("CLEANUP TRIGGERED!: failed to create")
-		_ = managedDeleteClusterHandler(logging, provider.ClusterName, provider.Region, false)
+		_ = managedDeleteClusterHandler(ctx, logging, provider.ClusterName, provider.Region, false)
 		return err
 	}
 	return err
@@ -122,32 +195,240 @@ func (provider CivoProvider) CreateCluster(logging log.Logger) error {
 
 // DeleteCluster calls the helper functions for cluster deletion
 // based on the flag `HACluster` whether to delete managed cluster or HA type cluster
-func (provider CivoProvider) DeleteCluster(logging log.Logger) error {
+func (provider CivoProvider) DeleteCluster(ctx context.Context, logging log.Logger) error {
+	if provider.HACluster {
+		return haDeleteClusterHandler(ctx, logging, provider.ClusterName, provider.Region, true)
+	}
+	return managedDeleteClusterHandler(ctx, logging, provider.ClusterName, provider.Region, true)
+}
+
+// SwitchContext merges the kubeconfig of the cluster into the default kubeconfig and makes it the current context
+func (provider CivoProvider) SwitchContext(ctx context.Context, logging log.Logger) error {
+	kind := "managed"
+	if provider.HACluster {
+		kind = "ha"
+	}
+	if !isPresent(kind, provider.ClusterName, provider.Region) {
+		return fmt.Errorf("ERR Cluster not found")
+	}
+	fetchKubeconfig(logging, kind, provider.ClusterName, provider.Region)
+	return util.SwitchKubeconfig(logging, clusterRef(kind, provider.ClusterName, provider.Region))
+}
+
+// Kubeconfig implements util.Provider, the kubeconfig of the managed cluster is fetched from civo
+// and of the HA cluster from its first controlplane
+func (provider CivoProvider) Kubeconfig(ctx context.Context, logging log.Logger, refresh bool) ([]byte, error) {
+	kind := "managed"
+	if provider.HACluster {
+		kind = "ha"
+	}
+	if !isPresent(kind, provider.ClusterName, provider.Region) {
+		return nil, fmt.Errorf("ERR Cluster not found")
+	}
+	if refresh {
+		var err error
+		if provider.HACluster {
+			err = haRefreshKubeconfig(ctx, logging, provider.ClusterName, provider.Region)
+		} else {
+			err = managedRefreshKubeconfig(logging, provider.ClusterName, provider.Region)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return util.ReadStateFile("civo", kind, clusterRef(kind, provider.ClusterName, provider.Region).ID(), "config")
+}
+
+// Create implements util.Provider
+func (provider CivoProvider) Create(ctx context.Context, logging log.Logger) error {
+	return provider.CreateCluster(ctx, logging)
+}
+
+// Delete implements util.Provider
+func (provider CivoProvider) Delete(ctx context.Context, logging log.Logger) error {
+	return provider.DeleteCluster(ctx, logging)
+}
+
+// AddNodes implements util.Provider, only HA clusters are supported
+func (provider CivoProvider) AddNodes(ctx context.Context, logging log.Logger) error {
+	if !provider.HACluster {
+		return fmt.Errorf("adding nodes is only supported for HA cluster")
+	}
+	return provider.AddMoreWorkerNodes(ctx, logging)
+}
+
+// DeleteNodes implements util.Provider, only HA clusters are supported
+func (provider CivoProvider) DeleteNodes(ctx context.Context, logging log.Logger) error {
+	if !provider.HACluster {
+		return fmt.Errorf("deleting nodes is only supported for HA cluster")
+	}
+	return provider.DeleteSomeWorkerNodes(ctx, logging)
+}
+
+// List returns all the managed and HA clusters present in the state management files
+// the node counts, node size and creation time are read from their info.json
+func (provider CivoProvider) List(ctx context.Context, logging log.Logger) ([]util.ClusterInfo, error) {
+	var clusters []util.ClusterInfo
+
+	for _, clusterType := range []string{util.CLUSTER_TYPE_MANAGED, util.CLUSTER_TYPE_HA} {
+		refs, err := util.IndexedClusters("civo", clusterType)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			clusters = append(clusters, clusterInfo(clusterType, ref.Name, ref.Region))
+		}
+	}
+	return clusters, nil
+}
+
+// clusterInfo the summary of the cluster from its info.json, only the name and region
+// are known when the state cannot be read
+func clusterInfo(clusterType, clusterName, region string) util.ClusterInfo {
+	cluster := util.ClusterInfo{
+		ClusterName:    clusterName,
+		Region:         region,
+		Provider:       "civo",
+		Type:           clusterType,
+		KubeconfigPath: clusterRef(clusterType, clusterName, region).Path("config"),
+	}
+	if clusterType == util.CLUSTER_TYPE_MANAGED {
+		if config, err := GetConfigManaged(clusterName, region); err == nil {
+			cluster.NodeSize = config.NodeSize
+			cluster.WorkerNodes = config.ManagedNodes
+			cluster.CreatedAt = config.CreatedAt
+		}
+		return cluster
+	}
+	if config, err := GetConfig(clusterName, region); err == nil {
+		cluster.NodeSize = config.NodeSize
+		cluster.ControlPlaneNodes = util.NoOfNodes(config.InstanceIDs.ControlNodes)
+		cluster.WorkerNodes = util.NoOfNodes(config.InstanceIDs.WorkerNodes)
+		cluster.CreatedAt = config.CreatedAt
+	}
+	return cluster
+}
+
+// Describe implements util.Provider
+// civo state only records the IDs of the instances, their IPs are shown by the civo dashboard
+func (provider CivoProvider) Describe(ctx context.Context, logging log.Logger) (util.ClusterDescription, error) {
+	clusterType := util.CLUSTER_TYPE_MANAGED
 	if provider.HACluster {
-		return haDeleteClusterHandler(logging, provider.ClusterName, provider.Region, true)
+		clusterType = util.CLUSTER_TYPE_HA
 	}
-	return managedDeleteClusterHandler(logging, provider.ClusterName, provider.Region, true)
+	if !isPresent(clusterType, provider.ClusterName, provider.Region) {
+		return util.ClusterDescription{}, fmt.Errorf("cluster does not exists: %v", provider.ClusterName)
+	}
+	description := util.ClusterDescription{ClusterInfo: clusterInfo(clusterType, provider.ClusterName, provider.Region)}
+
+	if !provider.HACluster {
+		config, err := GetConfigManaged(provider.ClusterName, provider.Region)
+		if err != nil {
+			return description, fmt.Errorf("Unable to read configuration: %v", err)
+		}
+		description.Resources = []util.ClusterResource{{Kind: "kubernetes-cluster", Name: provider.ClusterName, ID: config.ClusterID}}
+		return description, nil
+	}
+
+	config, err := GetConfig(provider.ClusterName, provider.Region)
+	if err != nil {
+		return description, fmt.Errorf("Unable to read configuration: %v", err)
+	}
+	description.DatabaseEndpoint = util.RedactPassword(config.DBEndpoint)
+	for _, resource := range []util.ClusterResource{
+		{Kind: "network", ID: config.NetworkIDs.NetworkID},
+		{Kind: "firewall", Name: util.NODE_ROLE_CONTROLPLANE, ID: config.NetworkIDs.FirewallIDControlPlaneNode},
+		{Kind: "firewall", Name: util.NODE_ROLE_WORKER, ID: config.NetworkIDs.FirewallIDWorkerNode},
+		{Kind: "firewall", Name: util.NODE_ROLE_LOADBALANCER, ID: config.NetworkIDs.FirewallIDLoadBalancerNode},
+		{Kind: "firewall", Name: util.NODE_ROLE_DATABASE, ID: config.NetworkIDs.FirewallIDDatabaseNode},
+		{Kind: "ssh-key", ID: config.SSHID},
+	} {
+		if len(resource.ID) != 0 {
+			description.Resources = append(description.Resources, resource)
+		}
+	}
+
+	for _, nodes := range []struct {
+		role string
+		ids  []string
+	}{
+		{util.NODE_ROLE_LOADBALANCER, config.InstanceIDs.LoadBalancerNode},
+		{util.NODE_ROLE_DATABASE, config.InstanceIDs.DatabaseNode},
+		{util.NODE_ROLE_CONTROLPLANE, config.InstanceIDs.ControlNodes},
+		{util.NODE_ROLE_WORKER, config.InstanceIDs.WorkerNodes},
+	} {
+		for _, id := range nodes.ids {
+			if len(id) != 0 {
+				description.Nodes = append(description.Nodes, util.ClusterNode{Role: nodes.role, ID: id})
+			}
+		}
+	}
+	return description, nil
 }
 
-// SwitchContext provides the export command for switching to specific provider's cluster
-func (provider CivoProvider) SwitchContext(logging log.Logger) error {
-	switch provider.HACluster {
-	case true:
-		if isPresent("ha", provider.ClusterName, provider.Region) {
-			var printKubeconfig util.PrinterKubeconfigPATH
-			printKubeconfig = printer{ClusterName: provider.ClusterName, Region: provider.Region}
-			printKubeconfig.Printer(logging, true, 0)
-			return nil
+// CheckDrift implements util.Provider
+// the unexpected resources are the ones named after the cluster which are not in its state
+// they are left as they are, they are usually leaked by an interrupted operation
+func (provider CivoProvider) CheckDrift(ctx context.Context, logging log.Logger, fix bool) (util.DriftReport, error) {
+	clusterType := util.CLUSTER_TYPE_MANAGED
+	if provider.HACluster {
+		clusterType = util.CLUSTER_TYPE_HA
+	}
+	report := util.DriftReport{ClusterName: provider.ClusterName, Provider: "civo", Type: clusterType, Region: provider.Region}
+	if !isPresent(clusterType, provider.ClusterName, provider.Region) {
+		return report, fmt.Errorf("cluster does not exists: %v", provider.ClusterName)
+	}
+	client, err := civogo.NewClient(fetchAPIKey(logging), provider.Region)
+	if err != nil {
+		return report, err
+	}
+
+	if !provider.HACluster {
+		config, err := GetConfigManaged(provider.ClusterName, provider.Region)
+		if err != nil {
+			return report, fmt.Errorf("Unable to read configuration: %v", err)
+		}
+		found, err := managedClusterExists(client, config.ClusterID)
+		if err != nil {
+			return report, err
 		}
-	case false:
-		if isPresent("managed", provider.ClusterName, provider.Region) {
-			var printKubeconfig util.PrinterKubeconfigPATH
-			printKubeconfig = printer{ClusterName: provider.ClusterName, Region: provider.Region}
-			printKubeconfig.Printer(logging, false, 0)
-			return nil
+		if !report.Check("kubernetes-cluster", provider.ClusterName, config.ClusterID, found) && fix {
+			// nothing of the managed cluster is left
+			if err := util.DeleteClusterState(clusterRef(clusterType, provider.ClusterName, provider.Region)); err != nil {
+				return report, err
+			}
+			report.Fixed = true
 		}
+		return report, nil
+	}
+
+	config, err := GetConfig(provider.ClusterName, provider.Region)
+	if err != nil {
+		return report, fmt.Errorf("Unable to read configuration: %v", err)
 	}
-	return fmt.Errorf("ERR Cluster not found")
+	obj := &HAType{Client: client, ClusterName: provider.ClusterName, Configuration: &config}
+	if err := obj.CheckDrift(&report); err != nil {
+		return report, err
+	}
+	if fix && len(report.Missing()) != 0 {
+		if err := config.update(logging, func() { config.forgetMissing(report.Missing()) }); err != nil {
+			return report, err
+		}
+		report.Fixed = true
+	}
+	return report, nil
+}
+
+// managedClusterExists looks up the managed cluster by its ID
+func managedClusterExists(client *civogo.Client, clusterID string) (bool, error) {
+	if len(clusterID) == 0 {
+		return false, nil
+	}
+	_, err := client.GetKubernetesCluster(clusterID)
+	if errors.Is(err, civogo.DatabaseKubernetesClusterNotFoundError) {
+		return false, nil
+	}
+	return err == nil, err
 }
 
 // this will be made available to each create functional calls
@@ -169,16 +450,38 @@ func (p printer) Printer(logging log.Logger, isHA bool, operation int) {
 	case 0:
 		logging.Note("To use this cluster set this environment variable")
 		if isHA {
-			logging.Print(fmt.Sprintf("%sKUBECONFIG=\"%s\"\n", preFix, util.GetPath(util.CLUSTER_PATH, "civo", "ha", p.ClusterName+" "+p.Region, "config")))
+			logging.Print(fmt.Sprintf("%sKUBECONFIG=\"%s\"\n", preFix, clusterRef("ha", p.ClusterName, p.Region).Path("config")))
 		} else {
-			logging.Print(fmt.Sprintf("%sKUBECONFIG=\"%s\"\n", preFix, util.GetPath(util.CLUSTER_PATH, "civo", "managed", p.ClusterName+" "+p.Region, "config")))
+			logging.Print(fmt.Sprintf("%sKUBECONFIG=\"%s\"\n", preFix, clusterRef("managed", p.ClusterName, p.Region).Path("config")))
 		}
 	case 1:
-		logging.Note("Use the following command to unset KUBECONFIG")
-		if runtime.GOOS == "windows" {
-			logging.Print(fmt.Sprintf("%sKUBECONFIG=\"\"\n", preFix))
+		if isHA {
+			util.ForgetKubeconfig(logging, clusterRef("ha", p.ClusterName, p.Region))
 		} else {
-			logging.Print("unset KUBECONFIG")
+			util.ForgetKubeconfig(logging, clusterRef("managed", p.ClusterName, p.Region))
 		}
 	}
 }
+
+// IsPresent implements util.Provider
+func (provider CivoProvider) IsPresent() bool {
+	if provider.HACluster {
+		return isPresent("ha", provider.ClusterName, provider.Region)
+	}
+	return isPresent("managed", provider.ClusterName, provider.Region)
+}
+
+// NoOfWorkerNodes implements util.Provider, only HA clusters are supported
+func (provider CivoProvider) NoOfWorkerNodes(ctx context.Context, logging log.Logger) (int, error) {
+	if !provider.HACluster {
+		return 0, fmt.Errorf("worker nodes are only tracked for HA cluster")
+	}
+	if !isPresent("ha", provider.ClusterName, provider.Region) {
+		return 0, fmt.Errorf("cluster does not exists: %v", provider.ClusterName)
+	}
+	config, err := GetConfig(provider.ClusterName, provider.Region)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to read configuration: %v", err)
+	}
+	return len(config.InstanceIDs.WorkerNodes), nil
+}
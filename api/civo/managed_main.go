@@ -9,13 +9,12 @@ Credit to @civo
 package civo
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 
 	log "github.com/kubesimplify/ksctl/api/logger"
 
-	"os"
 	"strings"
 	"time"
 
@@ -24,80 +23,59 @@ import (
 	"github.com/civo/civogo"
 )
 
-// configWriterManaged stores the KUBECONFIG
-func configWriterManaged(logging log.Logger, kubeconfig, clusterN, region, clusterID string) error {
+// configWriterManaged stores the KUBECONFIG and the state of the cluster
+func configWriterManaged(logging log.Logger, kubeconfig, clusterN, region string, configStore ManagedConfig) error {
 	// create the necessary folders and files
-	clusterFolder := clusterN + " " + region
-	err := os.MkdirAll(util.GetPath(util.CLUSTER_PATH, "civo", "managed", clusterFolder), 0750)
+	ref := clusterRef("managed", clusterN, region)
+	err := util.WriteStateFile("civo", []byte(kubeconfig), "managed", ref.ID(), "config")
+	if err != nil {
+		return err
+	}
 
-	if err != nil && !os.IsExist(err) {
+	if err = saveConfigManaged(logging, ref, configStore); err != nil {
 		return err
 	}
-	_, err = os.Create(util.GetPath(util.CLUSTER_PATH, "civo", "managed", clusterFolder, "config"))
+
+	return nil
+}
+
+// managedRefreshKubeconfig fetches the kubeconfig of the managed cluster again from civo
+func managedRefreshKubeconfig(logging log.Logger, name, region string) error {
+	state, err := GetConfigManaged(name, region)
 	if err != nil {
 		return err
 	}
-	// write the contents to the req. files
-	file, err := os.OpenFile(util.GetPath(util.CLUSTER_PATH, "civo", "managed", clusterFolder, "config"), os.O_WRONLY, 0640)
+	client, err := civogo.NewClient(fetchAPIKey(logging), region)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	_, err = file.Write([]byte(kubeconfig))
+	cluster, err := client.GetKubernetesCluster(state.ClusterID)
 	if err != nil {
 		return err
 	}
-
-	if err = saveConfigManaged(logging, clusterFolder, ManagedConfig{ClusterID: clusterID, Region: region}); err != nil {
-		return err
+	if len(cluster.KubeConfig) == 0 {
+		return fmt.Errorf("the cluster %s is not ready, its status is %s", name, cluster.Status)
 	}
-
-	return nil
+	return configWriterManaged(logging, cluster.KubeConfig, name, region, state)
 }
 
 type ManagedConfig struct {
 	ClusterID string `json:"clusterid"`
 	Region    string `json:"region"`
+
+	// ClusterMetadata is kept in the envelope of the state, see util.ClusterState
+	util.ClusterMetadata `json:"-"`
 }
 
 // GetConfigManaged fetch the state management file
 func GetConfigManaged(clusterName, region string) (configStore ManagedConfig, err error) {
-
-	fileBytes, err := os.ReadFile(util.GetPath(util.CLUSTER_PATH, "civo", "managed", clusterName+" "+region, "info.json"))
-
-	if err != nil {
-		return
-	}
-
-	err = json.Unmarshal(fileBytes, &configStore)
-
-	if err != nil {
-		return
-	}
-
+	_, err = util.ReadState(&configStore, clusterRef("managed", clusterName, region))
 	return
 }
 
 // saveConfigManaged update/store the state to state management file
-func saveConfigManaged(logging log.Logger, clusterFolder string, configStore ManagedConfig) error {
-
-	storeBytes, err := json.Marshal(configStore)
-	if err != nil {
-		return err
-	}
-
-	err = os.Mkdir(util.GetPath(util.CLUSTER_PATH, "civo", "managed", clusterFolder), 0750)
-	if err != nil && !os.IsExist(err) {
-		return err
-	}
-
-	err = os.WriteFile(util.GetPath(util.CLUSTER_PATH, "civo", "managed", clusterFolder, "info.json"), storeBytes, 0640)
-	if err != nil {
-		return err
-	}
-	logging.Info("💾 configuration", "")
-	return nil
+func saveConfigManaged(logging log.Logger, ref util.ClusterRef, configStore ManagedConfig) error {
+	return util.SaveState(logging, &configStore, ref)
 }
 
 // ClusterInfoInjecter Serializes the information which is return as utils.CivoProvider for sending it to API
@@ -141,7 +119,7 @@ func isValidSizeManaged(size string) bool {
 }
 
 // CreateCluster creates managed CIVO cluster
-func managedCreateClusterHandler(logging log.Logger, civoConfig CivoProvider) error {
+func managedCreateClusterHandler(ctx context.Context, logging log.Logger, civoConfig CivoProvider) error {
 	if len(civoConfig.APIKey) == 0 {
 		return fmt.Errorf("CREDENTIALS NOT PRESENT")
 	}
@@ -158,6 +136,11 @@ func managedCreateClusterHandler(logging log.Logger, civoConfig CivoProvider) er
 		return fmt.Errorf("INVALID size of node")
 	}
 
+	release, err := util.GetManagedKubernetesRelease(civoConfig.KubernetesVersion)
+	if err != nil {
+		return err
+	}
+
 	client, err := civogo.NewClient(civoConfig.APIKey, civoConfig.Region)
 	if err != nil {
 		return err
@@ -169,13 +152,15 @@ func managedCreateClusterHandler(logging log.Logger, civoConfig CivoProvider) er
 	}
 
 	configK8s := &civogo.KubernetesClusterConfig{
-		Name:            civoConfig.ClusterName,
-		Region:          civoConfig.Region,
-		NumTargetNodes:  civoConfig.Spec.ManagedNodes,
-		TargetNodesSize: civoConfig.Spec.Disk,
-		NetworkID:       defaultNetwork.ID,
-		Applications:    civoConfig.Application,
-		CNIPlugin:       civoConfig.CNIPlugin,
+		Name:              civoConfig.ClusterName,
+		Region:            civoConfig.Region,
+		NumTargetNodes:    civoConfig.Spec.ManagedNodes,
+		TargetNodesSize:   civoConfig.Spec.Disk,
+		NetworkID:         defaultNetwork.ID,
+		Applications:      civoConfig.Application,
+		CNIPlugin:         civoConfig.CNIPlugin,
+		KubernetesVersion: release.Civo,
+		Tags:              strings.Join(util.TagList(util.ResourceTags(clusterRef("managed", civoConfig.ClusterName, civoConfig.Region), util.TAG_ROLE_CLUSTER, civoConfig.Tags)), " "),
 	}
 
 	resp, err := client.NewKubernetesClusters(configK8s)
@@ -189,13 +174,29 @@ func managedCreateClusterHandler(logging log.Logger, civoConfig CivoProvider) er
 		if errors.Is(err, civogo.UnknownError) {
 			return fmt.Errorf("UNKNOWN ERR")
 		}
+		return err
+	}
+
+	// the cluster id is saved before waiting so that an interrupted creation can be deleted later
+	state := ManagedConfig{
+		ClusterID:       resp.ID,
+		Region:          civoConfig.Region,
+		ClusterMetadata: util.NewClusterMetadata(civoConfig.Spec.Disk, civoConfig.Spec.ManagedNodes),
+	}
+	state.Tags = civoConfig.Tags
+	state.KubernetesVersion = release.Version
+	if err := saveConfigManaged(logging, clusterRef("managed", civoConfig.ClusterName, civoConfig.Region), state); err != nil {
+		return err
 	}
 	for {
 		// clusterDS fetches the current state of kubernetes cluster given its id
-		clusterDS, _ := client.GetKubernetesCluster(resp.ID)
+		clusterDS, err := client.GetKubernetesCluster(resp.ID)
+		if err != nil {
+			return err
+		}
 		if clusterDS.Ready {
 			logging.Info("💻 Booted Instance", civoConfig.ClusterName)
-			err := configWriterManaged(logging, clusterDS.KubeConfig, civoConfig.ClusterName, civoConfig.Region, resp.ID)
+			err := configWriterManaged(logging, clusterDS.KubeConfig, civoConfig.ClusterName, civoConfig.Region, state)
 			if err != nil {
 				return err
 			}
@@ -207,15 +208,17 @@ func managedCreateClusterHandler(logging log.Logger, civoConfig CivoProvider) er
 			break
 		}
 		logging.Info("🚧 Instance", clusterDS.Status)
-		time.Sleep(10 * time.Second)
+		if err := util.Sleep(ctx, 10*time.Second); err != nil {
+			return err
+		}
 	}
 	logging.Info("Created your managed civo cluster!!🥳 🎉 ")
 	return nil
 }
 
 // kubeconfigDeleter deletes all configs related to the provided cluster
-func kubeconfigDeleter(path string) error {
-	err := os.RemoveAll(path)
+func kubeconfigDeleter(ref util.ClusterRef) error {
+	err := util.DeleteClusterState(ref)
 	if err != nil {
 		return err
 	}
@@ -238,7 +241,7 @@ func deleteClusterWithID(logging log.Logger, clusterID, regionCode string) error
 }
 
 // DeleteCluster deletes cluster from the given name and region
-func managedDeleteClusterHandler(logging log.Logger, name, region string, showMsg bool) error {
+func managedDeleteClusterHandler(ctx context.Context, logging log.Logger, name, region string, showMsg bool) error {
 
 	// TODO: Add delete message
 	if showMsg {
@@ -269,7 +272,7 @@ func managedDeleteClusterHandler(logging log.Logger, name, region string, showMs
 		return err
 	}
 
-	if err := kubeconfigDeleter(util.GetPath(util.CLUSTER_PATH, "civo", "managed", name+" "+region)); err != nil {
+	if err := kubeconfigDeleter(clusterRef("managed", name, region)); err != nil {
 		return err
 	}
 
@@ -9,10 +9,11 @@ Credit to @civo
 package civo
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/kubesimplify/ksctl/api/logger"
@@ -41,7 +42,7 @@ type LoadBalancerRet struct {
 
 type HACollection interface {
 	DeleteInstances(log.Logger) error
-	DeleteNetworks(log.Logger) error
+	DeleteNetworks(context.Context, log.Logger) error
 
 	DeleteInstance(string) error
 	DeleteFirewall(string) error
@@ -52,23 +53,23 @@ type HACollection interface {
 
 	CreateFirewall(string) (*civogo.FirewallResult, error)
 	CreateNetwork(log.Logger, string) error
-	CreateInstance(string, string, string, string, bool) (*civogo.Instance, error)
+	CreateInstance(string, string, string, string, string, bool) (*civogo.Instance, error)
 
 	SaveKubeconfig(log.Logger, string) error
 
-	CreateLoadbalancer(log.Logger) (*civogo.Instance, error)
-	CreateControlPlane(log.Logger, int) (*civogo.Instance, error)
-	CreateWorkerNode(log.Logger, int, string, string) (*civogo.Instance, error)
-	CreateDatabase(log.Logger) (string, error)
-	GetTokenFromCP_1(log.Logger, *civogo.Instance) string
+	CreateLoadbalancer(context.Context, log.Logger) (*civogo.Instance, error)
+	CreateControlPlane(context.Context, log.Logger, int) (*civogo.Instance, error)
+	CreateWorkerNode(context.Context, log.Logger, int, string, string) (*civogo.Instance, error)
+	CreateDatabase(context.Context, log.Logger) (string, error)
+	GetTokenFromCP_1(context.Context, log.Logger, *civogo.Instance) string
 
 	UploadSSHKey(log.Logger) error
 	CreateSSHKeyPair(log.Logger, string) error
 	DeleteSSHKeyPair() error
-	ConfigLoadBalancer(log.Logger, *civogo.Instance, []string) error
-	FetchKUBECONFIG(log.Logger, *civogo.Instance) (string, error)
-	HelperExecNoOutputControlPlane(log.Logger, string, string, bool) error
-	HelperExecOutputControlPlane(log.Logger, string, string, bool) (string, error)
+	ConfigLoadBalancer(context.Context, log.Logger, *civogo.Instance, []string) error
+	FetchKUBECONFIG(context.Context, log.Logger, *civogo.Instance) (string, error)
+	HelperExecNoOutputControlPlane(context.Context, log.Logger, string, string, bool) error
+	HelperExecOutputControlPlane(context.Context, log.Logger, string, string, bool) (string, error)
 }
 
 type HAType struct {
@@ -84,6 +85,8 @@ type HAType struct {
 	SSHID         string           `json:"sshid"` // used to store the ssh id from CIVO
 	Configuration *JsonStore       `json:"configuration"`
 	SSH_Payload   *util.SSHPayload `json:"ssh___payload"`
+	// Distribution of kubernetes installed on the nodes
+	Distribution util.Distribution `json:"-"`
 }
 
 type InstanceID struct {
@@ -109,44 +112,37 @@ type JsonStore struct {
 	SSHID       string     `json:"ssh_id"`
 	InstanceIDs InstanceID `json:"instanceids"`
 	NetworkIDs  NetworkID  `json:"networkids"`
-}
-
-func GetConfig(clusterName, region string) (configStore JsonStore, err error) {
-
-	fileBytes, err := os.ReadFile(util.GetPath(util.CLUSTER_PATH, "civo", "ha", clusterName+" "+region, "info.json"))
-
-	if err != nil {
-		return
-	}
+	// Checkpoints steps of the cluster creation which are completed
+	Checkpoints util.Checkpoints `json:"checkpoints,omitempty"`
 
-	err = json.Unmarshal(fileBytes, &configStore)
+	// ClusterMetadata is kept in the envelope of the state, see util.ClusterState
+	util.ClusterMetadata `json:"-"`
+}
 
-	if err != nil {
-		return
-	}
+// clusterRef identifies the civo cluster in the state management files
+func clusterRef(clusterType, clusterName, region string) util.ClusterRef {
+	return util.NewClusterRef("civo", clusterType, clusterName, region)
+}
 
+func GetConfig(clusterName, region string) (configStore JsonStore, err error) {
+	_, err = util.ReadState(&configStore, clusterRef("ha", clusterName, region))
 	return
 }
 
-// TODO: Merge it with same function in utils package
-func saveConfig(logging log.Logger, clusterFolder string, configStore JsonStore) error {
-
-	storeBytes, err := json.Marshal(configStore)
-	if err != nil {
-		return err
-	}
+// configMutex serializes the updates of the state management file
+// as the nodes of the HA cluster are created in parallel
+var configMutex sync.Mutex
 
-	err = os.MkdirAll(util.GetPath(util.CLUSTER_PATH, "civo", "ha", clusterFolder), 0750)
-	if err != nil && !os.IsExist(err) {
-		return err
-	}
+// update applies the change to the configuration and saves it to state management file
+func (config *JsonStore) update(logging log.Logger, change func()) error {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	change()
+	return saveConfig(logging, *config)
+}
 
-	err = os.WriteFile(util.GetPath(util.CLUSTER_PATH, "civo", "ha", clusterFolder, "info.json"), storeBytes, 0640)
-	if err != nil {
-		return err
-	}
-	logging.Info("💾 configuration", "")
-	return nil
+func saveConfig(logging log.Logger, configStore JsonStore) error {
+	return util.SaveState(logging, &configStore, clusterRef("ha", configStore.ClusterName, configStore.Region))
 }
 
 type ConfigurationHandlers interface {
@@ -154,88 +150,147 @@ type ConfigurationHandlers interface {
 	ConfigWriterInstanceDatabase(log.Logger, string) error
 	ConfigWriterServerToken(log.Logger, string) error
 	ConfigWriterInstanceLoadBalancer(log.Logger, string) error
-	ConfigWriterInstanceControlPlaneNodes(log.Logger, string) error
-	ConfigWriterInstanceWorkerNodes(log.Logger, string) error
+	ConfigWriterInstanceControlPlaneNodes(log.Logger, int, string) error
+	ConfigWriterInstanceWorkerNodes(log.Logger, int, string) error
 	ConfigWriterFirewallLoadBalancerNodes(log.Logger, string) error
 	ConfigWriterFirewallControlPlaneNodes(log.Logger, string) error
 	ConfigWriterFirewallWorkerNodes(log.Logger, string) error
 	ConfigWriterFirewallDatabaseNodes(log.Logger, string) error
 	ConfigWriterNetworkID(log.Logger, string) error
 	ConfigWriterSSHID(log.Logger, string) error
+	ConfigWriterCheckpoint(log.Logger, string) error
 }
 
 // ConfigWriterDBEndpoint write Database endpoint to state management file
 func (config *JsonStore) ConfigWriterDBEndpoint(logging log.Logger, endpoint string) error {
-	config.DBEndpoint = endpoint
-	return saveConfig(logging, config.ClusterName+" "+config.Region, *config)
+	return config.update(logging, func() {
+		config.DBEndpoint = endpoint
+	})
 }
 
 // ConfigWriterSSHID write SSH keypairId which is uploaded to Civo to state management file
 func (config *JsonStore) ConfigWriterSSHID(logging log.Logger, keypair_id string) error {
-	config.SSHID = keypair_id
-	return saveConfig(logging, config.ClusterName+" "+config.Region, *config)
+	return config.update(logging, func() {
+		config.SSHID = keypair_id
+	})
 }
 
 // ConfigWriterNetworkID write NetworkID of created network Civo to state management file
 func (config *JsonStore) ConfigWriterNetworkID(logging log.Logger, netID string) error {
-	config.NetworkIDs.NetworkID = netID
-	return saveConfig(logging, config.ClusterName+" "+config.Region, *config)
+	return config.update(logging, func() {
+		config.NetworkIDs.NetworkID = netID
+	})
 }
 
 // ConfigWriterFirewallControlPlaneNodes write firewall_id of all controlplane's firewall to state management file
 func (config *JsonStore) ConfigWriterFirewallControlPlaneNodes(logging log.Logger, fwID string) error {
-	config.NetworkIDs.FirewallIDControlPlaneNode = fwID
-	return saveConfig(logging, config.ClusterName+" "+config.Region, *config)
+	return config.update(logging, func() {
+		config.NetworkIDs.FirewallIDControlPlaneNode = fwID
+	})
 }
 
 // ConfigWriterFirewallWorkerNodes write firewall_id of all workernode's firewall to state management file
 func (config *JsonStore) ConfigWriterFirewallWorkerNodes(logging log.Logger, fwID string) error {
-	config.NetworkIDs.FirewallIDWorkerNode = fwID
-	return saveConfig(logging, config.ClusterName+" "+config.Region, *config)
+	return config.update(logging, func() {
+		config.NetworkIDs.FirewallIDWorkerNode = fwID
+	})
 }
 
 // ConfigWriterFirewallLoadBalancerNodes write firewall_id of loadbalancer firewall to state management file
 // TODO: Add more fine grained firewall rules
 func (config *JsonStore) ConfigWriterFirewallLoadBalancerNodes(logging log.Logger, fwID string) error {
-	config.NetworkIDs.FirewallIDLoadBalancerNode = fwID
-	return saveConfig(logging, config.ClusterName+" "+config.Region, *config)
+	return config.update(logging, func() {
+		config.NetworkIDs.FirewallIDLoadBalancerNode = fwID
+	})
 }
 
 // ConfigWriterFirewallDatabaseNodes write firewall_id of database firewall to state management file
 // TODO: Add more restrictive firewall rules
 func (config *JsonStore) ConfigWriterFirewallDatabaseNodes(logging log.Logger, fwID string) error {
-	config.NetworkIDs.FirewallIDDatabaseNode = fwID
-	return saveConfig(logging, config.ClusterName+" "+config.Region, *config)
+	return config.update(logging, func() {
+		config.NetworkIDs.FirewallIDDatabaseNode = fwID
+	})
 }
 
 // ConfigWriterServerToken write the K3S_TOKEN to the state management file
 func (config *JsonStore) ConfigWriterServerToken(logging log.Logger, token string) error {
-	config.ServerToken = token
-	return saveConfig(logging, config.ClusterName+" "+config.Region, *config)
+	return config.update(logging, func() {
+		config.ServerToken = token
+	})
 }
 
 // ConfigWriterInstanceDatabase write the instance_id of database VM to state management file
 func (config *JsonStore) ConfigWriterInstanceDatabase(logging log.Logger, instanceID string) error {
-	config.InstanceIDs.DatabaseNode = append(config.InstanceIDs.DatabaseNode, instanceID)
-	return saveConfig(logging, config.ClusterName+" "+config.Region, *config)
+	return config.update(logging, func() {
+		config.InstanceIDs.DatabaseNode = append(config.InstanceIDs.DatabaseNode, instanceID)
+	})
 }
 
 // ConfigWriterInstanceLoadBalancer write the instance_id of loadbalancer VM to state management file
 func (config *JsonStore) ConfigWriterInstanceLoadBalancer(logging log.Logger, instanceID string) error {
-	config.InstanceIDs.LoadBalancerNode = append(config.InstanceIDs.LoadBalancerNode, instanceID)
-	return saveConfig(logging, config.ClusterName+" "+config.Region, *config)
+	return config.update(logging, func() {
+		config.InstanceIDs.LoadBalancerNode = append(config.InstanceIDs.LoadBalancerNode, instanceID)
+	})
 }
 
-// ConfigWriterInstanceControlPlaneNodes write the instance_id of controlplane VMs to state management file
-func (config *JsonStore) ConfigWriterInstanceControlPlaneNodes(logging log.Logger, instanceID string) error {
-	config.InstanceIDs.ControlNodes = append(config.InstanceIDs.ControlNodes, instanceID)
-	return saveConfig(logging, config.ClusterName+" "+config.Region, *config)
+// ConfigWriterInstanceControlPlaneNodes write the instance_id of controlplane VM with the given number (starting from 1) to state management file
+// the ids are kept in the order of the node number as the nodes are created in parallel
+func (config *JsonStore) ConfigWriterInstanceControlPlaneNodes(logging log.Logger, number int, instanceID string) error {
+	return config.update(logging, func() {
+		config.InstanceIDs.ControlNodes = setInstanceID(config.InstanceIDs.ControlNodes, number, instanceID)
+	})
 }
 
-// ConfigWriterInstanceWorkerNodes write the instance_id of workernode VMs to state management file
-func (config *JsonStore) ConfigWriterInstanceWorkerNodes(logging log.Logger, instanceID string) error {
-	config.InstanceIDs.WorkerNodes = append(config.InstanceIDs.WorkerNodes, instanceID)
-	return saveConfig(logging, config.ClusterName+" "+config.Region, *config)
+// ConfigWriterInstanceWorkerNodes write the instance_id of workernode VM with the given number (starting from 1) to state management file
+// the ids are kept in the order of the node number as the nodes are created in parallel
+func (config *JsonStore) ConfigWriterInstanceWorkerNodes(logging log.Logger, number int, instanceID string) error {
+	return config.update(logging, func() {
+		config.InstanceIDs.WorkerNodes = setInstanceID(config.InstanceIDs.WorkerNodes, number, instanceID)
+	})
+}
+
+// setInstanceID places the instance_id at the position of the node number (starting from 1)
+// the positions of the nodes which are not yet created are left empty
+func setInstanceID(instanceIDs []string, number int, instanceID string) []string {
+	for len(instanceIDs) < number {
+		instanceIDs = append(instanceIDs, "")
+	}
+	instanceIDs[number-1] = instanceID
+	return instanceIDs
+}
+
+// instanceID returns the saved instance_id of the node number (starting from 1), empty if it is not created
+func instanceID(instanceIDs []string, number int) string {
+	if len(instanceIDs) < number {
+		return ""
+	}
+	return instanceIDs[number-1]
+}
+
+// ControlPlaneID returns the saved instance_id of the controlplane with the given number
+func (config *JsonStore) ControlPlaneID(number int) string {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	return instanceID(config.InstanceIDs.ControlNodes, number)
+}
+
+// WorkerNodeID returns the saved instance_id of the workernode with the given number
+func (config *JsonStore) WorkerNodeID(number int) string {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	return instanceID(config.InstanceIDs.WorkerNodes, number)
+}
+
+// ConfigWriterCheckpoint write the completed step of cluster creation to state management file
+func (config *JsonStore) ConfigWriterCheckpoint(logging log.Logger, step string) error {
+	logging = logging.With("step", step)
+	if err := config.update(logging, func() {
+		config.Checkpoints.Mark(step)
+	}); err != nil {
+		return err
+	}
+	logging.Debug("checkpoint saved")
+	return nil
 }
 
 // DeleteInstances deletes all the VMs
@@ -249,6 +304,10 @@ func (obj *HAType) DeleteInstances(logging log.Logger) error {
 	var errV error
 	// if some controlplanes are down then there will be errors i.e if controlplanes are deleted
 	for index, instanceID := range instances.ControlNodes {
+		if len(instanceID) == 0 {
+			// node which failed to be created
+			continue
+		}
 		if err := obj.DeleteInstance(instanceID); err != nil {
 			errV = err
 			logging.Err(fmt.Sprintf("❌ [%d/%d] deleted controlplane instances", index+1, len(instances.ControlNodes)))
@@ -262,6 +321,10 @@ func (obj *HAType) DeleteInstances(logging log.Logger) error {
 
 	errV = nil
 	for index, instanceID := range instances.WorkerNodes {
+		if len(instanceID) == 0 {
+			// node which failed to be created
+			continue
+		}
 		if err := obj.DeleteInstance(instanceID); err != nil {
 			errV = err
 			logging.Err(fmt.Sprintf("❌ [%d/%d] deleted workerplane instances", index+1, len(instances.WorkerNodes)))
@@ -275,6 +338,10 @@ func (obj *HAType) DeleteInstances(logging log.Logger) error {
 
 	errV = nil
 	for index, instanceID := range instances.LoadBalancerNode {
+		if len(instanceID) == 0 {
+			// removed from the state by check-drift
+			continue
+		}
 		if err := obj.DeleteInstance(instanceID); err != nil {
 			errV = err
 			logging.Err(fmt.Sprintf("❌ [%d/%d] deleted loadbalancer instances", index+1, len(instances.LoadBalancerNode)))
@@ -288,6 +355,10 @@ func (obj *HAType) DeleteInstances(logging log.Logger) error {
 
 	errV = nil
 	for index, instanceID := range instances.DatabaseNode {
+		if len(instanceID) == 0 {
+			// removed from the state by check-drift
+			continue
+		}
 		if err := obj.DeleteInstance(instanceID); err != nil {
 			errV = err
 			logging.Err(fmt.Sprintf("❌ [%d/%d] deleted database instances", index+1, len(instances.DatabaseNode)))
@@ -300,7 +371,7 @@ func (obj *HAType) DeleteInstances(logging log.Logger) error {
 
 // DeleteNetworks deletes all network related objects
 // deletes all firewalls, and the network
-func (obj *HAType) DeleteNetworks(logging log.Logger) error {
+func (obj *HAType) DeleteNetworks(ctx context.Context, logging log.Logger) error {
 	networks, err := ExtractNetworks(obj.ClusterName, obj.Client.Region)
 	if err != nil {
 		return err
@@ -314,7 +385,9 @@ func (obj *HAType) DeleteNetworks(logging log.Logger) error {
 		logging.Info(fmt.Sprintf("✅ deleted controlplane firewall"), networks.FirewallIDControlPlaneNode)
 	}
 
-	time.Sleep(5 * time.Second)
+	if err := util.Sleep(ctx, 5*time.Second); err != nil {
+		return err
+	}
 
 	if len(networks.FirewallIDWorkerNode) != 0 {
 		err = obj.DeleteFirewall(networks.FirewallIDWorkerNode)
@@ -324,7 +397,9 @@ func (obj *HAType) DeleteNetworks(logging log.Logger) error {
 		logging.Info(fmt.Sprintf("✅ deleted workerplane firewall"), networks.FirewallIDWorkerNode)
 	}
 
-	time.Sleep(5 * time.Second)
+	if err := util.Sleep(ctx, 5*time.Second); err != nil {
+		return err
+	}
 
 	if len(networks.FirewallIDDatabaseNode) != 0 {
 		err = obj.DeleteFirewall(networks.FirewallIDDatabaseNode)
@@ -334,7 +409,9 @@ func (obj *HAType) DeleteNetworks(logging log.Logger) error {
 		logging.Info(fmt.Sprintf("✅ deleted database firewall"), networks.FirewallIDDatabaseNode)
 	}
 
-	time.Sleep(5 * time.Second)
+	if err := util.Sleep(ctx, 5*time.Second); err != nil {
+		return err
+	}
 
 	if len(networks.FirewallIDLoadBalancerNode) != 0 {
 		err = obj.DeleteFirewall(networks.FirewallIDLoadBalancerNode)
@@ -344,6 +421,10 @@ func (obj *HAType) DeleteNetworks(logging log.Logger) error {
 		logging.Info(fmt.Sprintf("✅ deleted loadbalancer firewall"), networks.FirewallIDLoadBalancerNode)
 	}
 
+	if len(networks.NetworkID) == 0 {
+		return nil
+	}
+
 	err = nil
 	retry := 0
 	retryTimeout := 2
@@ -353,7 +434,9 @@ func (obj *HAType) DeleteNetworks(logging log.Logger) error {
 			break
 		}
 		retry++
-		time.Sleep(time.Duration(retryTimeout) * time.Second)
+		if err := util.Sleep(ctx, time.Duration(retryTimeout)*time.Second); err != nil {
+			return err
+		}
 		retryTimeout *= 2
 		logging.Warn(fmt.Sprintln("❗ RETRYING ", err))
 	}
@@ -367,6 +450,135 @@ func (obj *HAType) DeleteNetworks(logging log.Logger) error {
 	return nil
 }
 
+// CheckDrift looks up the instances, firewalls, network and ssh key recorded in the configuration
+// civo is queried once per kind of resource and the ones named after the cluster which are
+// not recorded are reported as unexpected
+func (obj *HAType) CheckDrift(report *util.DriftReport) error {
+	config := obj.Configuration
+	prefix := obj.ClusterName + "-ksctl-"
+
+	instances, err := obj.Client.ListAllInstances()
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for _, instance := range instances {
+		existing[instance.ID] = true
+	}
+	recorded := map[string]bool{}
+	for _, nodes := range []struct {
+		role string
+		ids  []string
+	}{
+		{util.NODE_ROLE_LOADBALANCER, config.InstanceIDs.LoadBalancerNode},
+		{util.NODE_ROLE_DATABASE, config.InstanceIDs.DatabaseNode},
+		{util.NODE_ROLE_CONTROLPLANE, config.InstanceIDs.ControlNodes},
+		{util.NODE_ROLE_WORKER, config.InstanceIDs.WorkerNodes},
+	} {
+		for _, id := range nodes.ids {
+			if len(id) != 0 {
+				// the role is reported as the name, the empty ID is of the node which failed to be created
+				report.Check("instance", nodes.role, id, existing[id])
+				recorded[id] = true
+			}
+		}
+	}
+	for _, instance := range instances {
+		if strings.HasPrefix(instance.Hostname, prefix) && !recorded[instance.ID] {
+			report.Unexpected("instance", instance.Hostname, instance.ID)
+		}
+	}
+
+	firewalls, err := obj.Client.ListFirewalls()
+	if err != nil {
+		return err
+	}
+	existing, recorded = map[string]bool{}, map[string]bool{}
+	for _, firewall := range firewalls {
+		existing[firewall.ID] = true
+	}
+	for _, firewall := range []struct {
+		role string
+		id   string
+	}{
+		{util.NODE_ROLE_CONTROLPLANE, config.NetworkIDs.FirewallIDControlPlaneNode},
+		{util.NODE_ROLE_WORKER, config.NetworkIDs.FirewallIDWorkerNode},
+		{util.NODE_ROLE_LOADBALANCER, config.NetworkIDs.FirewallIDLoadBalancerNode},
+		{util.NODE_ROLE_DATABASE, config.NetworkIDs.FirewallIDDatabaseNode},
+	} {
+		if len(firewall.id) != 0 {
+			report.Check("firewall", firewall.role, firewall.id, existing[firewall.id])
+			recorded[firewall.id] = true
+		}
+	}
+	for _, firewall := range firewalls {
+		if strings.HasPrefix(firewall.Name, prefix) && !recorded[firewall.ID] {
+			report.Unexpected("firewall", firewall.Name, firewall.ID)
+		}
+	}
+
+	networks, err := obj.Client.ListNetworks()
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, network := range networks {
+		switch {
+		case network.ID == config.NetworkIDs.NetworkID:
+			found = true
+		case network.Label == obj.ClusterName+"-ksctl":
+			report.Unexpected("network", network.Label, network.ID)
+		}
+	}
+	report.Check("network", "", config.NetworkIDs.NetworkID, found)
+
+	keys, err := obj.Client.ListSSHKeys()
+	if err != nil {
+		return err
+	}
+	found = false
+	for _, key := range keys {
+		if key.ID == config.SSHID {
+			found = true
+		}
+	}
+	report.Check("ssh-key", "", config.SSHID, found)
+	return nil
+}
+
+// forgetMissing removes the resources which are not found in civo from the configuration
+// the positions of the nodes are kept as they are the numbers of the nodes
+func (config *JsonStore) forgetMissing(missing []util.Drift) {
+	ids := map[string]bool{}
+	for _, drift := range missing {
+		ids[drift.ID] = true
+	}
+	for _, id := range []*string{
+		&config.NetworkIDs.NetworkID,
+		&config.NetworkIDs.FirewallIDControlPlaneNode,
+		&config.NetworkIDs.FirewallIDWorkerNode,
+		&config.NetworkIDs.FirewallIDLoadBalancerNode,
+		&config.NetworkIDs.FirewallIDDatabaseNode,
+		&config.SSHID,
+	} {
+		if ids[*id] {
+			*id = ""
+		}
+	}
+	for _, nodes := range [][]string{
+		config.InstanceIDs.ControlNodes,
+		config.InstanceIDs.WorkerNodes,
+		config.InstanceIDs.LoadBalancerNode,
+		config.InstanceIDs.DatabaseNode,
+	} {
+		for index := range nodes {
+			if ids[nodes[index]] {
+				nodes[index] = ""
+			}
+		}
+	}
+}
+
 // DeleteInstance delete a VM with instance_id
 func (obj *HAType) DeleteInstance(instanceID string) error {
 	_, err := obj.Client.DeleteInstance(instanceID)
@@ -387,6 +599,9 @@ func (obj *HAType) DeleteNetwork(networkID string) error {
 
 // DeleteSSHKeyPair delete the SSH Keypair in CIVO
 func (obj *HAType) DeleteSSHKeyPair() error {
+	if len(obj.SSHID) == 0 {
+		return nil
+	}
 	_, err := obj.Client.DeleteSSHKey(obj.SSHID)
 	return err
 }
@@ -403,11 +618,32 @@ func (obj *HAType) GetInstance(instanceID string) (inst *civogo.Instance, err er
 	return
 }
 
+// waitForInstance waits till the instance is ACTIVE and returns it
+// it stops waiting when the context is cancelled
+func (obj *HAType) waitForInstance(ctx context.Context, logging log.Logger, instanceID, name string) (*civogo.Instance, error) {
+	for {
+		getInstance, err := obj.GetInstance(instanceID)
+		if err != nil {
+			return nil, err
+		}
+		if getInstance.Status == "ACTIVE" {
+			logging.Info("💻 Booted Instance", name)
+			return getInstance, nil
+		}
+		logging.Info("🚧 Instance", name)
+		if err := util.Sleep(ctx, 10*time.Second); err != nil {
+			return nil, err
+		}
+	}
+}
+
 // CreateInstance create a instance with provided Configuration
 // NOTE: initializationScript: if "" -> no default VM script when it is ready to serve
 // else -> provide the script to run when the VM is ready (no need to SSH into to exec script)
 // mention the `#!/bin/bash` for scripts
-func (obj *HAType) CreateInstance(instanceName, firewallID, NodeSize, initializationScript string, public bool) (inst *civogo.Instance, err error) {
+// the instance is tagged with the ownership tags of its role, civo doesn't support tags on
+// the firewalls, networks and ssh keys so they are only known by their names
+func (obj *HAType) CreateInstance(instanceName, role, firewallID, NodeSize, initializationScript string, public bool) (inst *civogo.Instance, err error) {
 	publicIP := "create"
 	if !public {
 		publicIP = "none"
@@ -424,6 +660,7 @@ func (obj *HAType) CreateInstance(instanceName, firewallID, NodeSize, initializa
 		Script:           initializationScript,
 		SSHKeyID:         obj.SSHID,
 		PublicIPRequired: publicIP,
+		Tags:             util.TagList(util.ResourceTags(clusterRef("ha", obj.ClusterName, obj.Client.Region), role, obj.Configuration.Tags)),
 	}
 
 	inst, err = obj.Client.CreateInstance(instanceConfig)
@@ -445,7 +682,12 @@ func (obj *HAType) CreateFirewall(firewallName string) (firew *civogo.FirewallRe
 }
 
 // CreateNetwork creates network with provided name
+// if the network is already saved in the state management file it is reused
 func (obj *HAType) CreateNetwork(logging log.Logger, networkName string) error {
+	if netID := obj.Configuration.NetworkIDs.NetworkID; len(netID) != 0 {
+		obj.NetworkID = netID
+		return nil
+	}
 	net, err := obj.Client.NewNetwork(networkName)
 	if err != nil {
 		return err
@@ -467,24 +709,7 @@ func (obj *HAType) CreateSSHKeyPair(logging log.Logger, publicKey string) error
 
 // SaveKubeconfig stores the kubeconfig to state management file
 func (obj *HAType) SaveKubeconfig(logging log.Logger, kubeconfig string) error {
-	folderName := obj.ClusterName + " " + obj.Client.Region
-	err := os.MkdirAll(util.GetPath(util.CLUSTER_PATH, "civo", "ha", folderName), 0644)
-	if err != nil && !os.IsExist(err) {
-		return err
-	}
-
-	_, err = os.Create(util.GetPath(util.CLUSTER_PATH, "civo", "ha", folderName, "config"))
-	if err != nil && !os.IsExist(err) {
-		return err
-	}
-
-	file, err := os.OpenFile(util.GetPath(util.CLUSTER_PATH, "civo", "ha", folderName, "config"), os.O_WRONLY, 0750)
-	if err != nil && !os.IsExist(err) {
-		return err
-	}
-	defer file.Close()
-
-	_, err = file.Write([]byte(kubeconfig))
+	err := util.WriteStateFile("civo", []byte(kubeconfig), "ha", clusterRef("ha", obj.ClusterName, obj.Client.Region).ID(), "config")
 	if err != nil {
 		return err
 	}
@@ -520,26 +745,32 @@ func ExtractNetworks(clusterName, region string) (instIDs NetworkID, err error)
 // WARNING: it is a destructive method
 // removes all the info related to cluster (i.e. stat management file, configs and related info)
 func DeleteAllPaths(clusterName, region string) error {
-	return os.RemoveAll(util.GetPath(util.CLUSTER_PATH, "civo", "ha", clusterName+" "+region))
+	return util.DeleteClusterState(clusterRef("ha", clusterName, region))
 }
 
 // UploadSSHKey it creates a ssh keypair saves to state management file and uploads it to CIVO
 func (ha *HAType) UploadSSHKey(logging log.Logger) (err error) {
-	path := util.GetPath(util.OTHER_PATH, "civo", "ha", ha.ClusterName+" "+ha.Client.Region)
-	err = os.MkdirAll(path, 0755)
-	if err != nil {
-		return
-	}
-	keyPairToUpload, err := util.CreateSSHKeyPair("civo", ha.ClusterName+" "+ha.Client.Region)
+	ref := clusterRef("ha", ha.ClusterName, ha.Client.Region)
+	err = os.MkdirAll(ref.Path(), 0755)
 	if err != nil {
 		return
 	}
+	// already uploaded keypair is reused when resuming the cluster creation
+	if len(ha.Configuration.SSHID) == 0 {
+		var keyPairToUpload string
+		keyPairToUpload, err = util.CreateSSHKeyPair("civo", ref.ID())
+		if err != nil {
+			return
+		}
 
-	err = ha.CreateSSHKeyPair(logging, keyPairToUpload)
+		err = ha.CreateSSHKeyPair(logging, keyPairToUpload)
+	} else {
+		ha.SSHID = ha.Configuration.SSHID
+	}
 
 	// ------- Setting the ssh configs only the public ips used will change
 	ha.SSH_Payload.UserName = "root"
-	ha.SSH_Payload.PathPrivateKey = util.GetPath(util.SSH_PATH, "civo", "ha", ha.ClusterName+" "+ha.Client.Region)
+	ha.SSH_Payload.PathPrivateKey = util.GetPath(util.SSH_PATH, "civo", "ha", ref.ID())
 	ha.SSH_Payload.Output = ""
 	ha.SSH_Payload.PublicIP = ""
 	// ------
@@ -9,64 +9,57 @@ Credit to @civo
 package civo
 
 import (
+	"context"
 	"fmt"
-	"time"
 
 	log "github.com/kubesimplify/ksctl/api/logger"
+	util "github.com/kubesimplify/ksctl/api/utils"
 
 	"github.com/civo/civogo"
 )
 
-func scriptWP(privateIPlb, token string) string {
-	return fmt.Sprintf(`#!/bin/bash
-export SECRET='%s'
-curl -sfL https://get.k3s.io | sh -s - agent --token=$SECRET --server https://%s:6443
-`, token, privateIPlb)
-}
-
-func (obj *HAType) CreateWorkerNode(logging log.Logger, number int, privateIPlb, token string) (*civogo.Instance, error) {
-
+// CreateWorkerNode creates the workernode VM with the given number (starting from 1)
+// the instance saved in the state management file for that number is reused
+func (obj *HAType) CreateWorkerNode(ctx context.Context, logging log.Logger, number int, privateIPlb, token string) (*civogo.Instance, error) {
 	name := fmt.Sprintf("%s-ksctl-wp", obj.ClusterName)
 
+	// the firewall is shared by the workernodes which are created in parallel
+	firewallMutex.Lock()
 	if len(obj.WPFirewallID) == 0 {
 		firewall, err := obj.CreateFirewall(name)
 		if err != nil {
+			firewallMutex.Unlock()
 			return nil, err
 		}
+
 		// TODO: More restrictive firewalls
 		obj.WPFirewallID = firewall.ID
 		err = obj.Configuration.ConfigWriterFirewallWorkerNodes(logging, firewall.ID)
 		if err != nil {
-			return nil, nil
+			firewallMutex.Unlock()
+			return nil, err
 		}
 	}
+	firewallMutex.Unlock()
+
 	name += fmt.Sprint(number)
 
-	instance, err := obj.CreateInstance(name, obj.WPFirewallID, obj.NodeSize, scriptWP(privateIPlb, token), false)
-	if err != nil {
-		return nil, err
+	if instanceID := obj.Configuration.WorkerNodeID(number); len(instanceID) != 0 {
+		return obj.waitForInstance(ctx, logging, instanceID, name)
 	}
 
-	err = obj.Configuration.ConfigWriterInstanceWorkerNodes(logging, instance.ID)
+	instance, err := obj.CreateInstance(name, util.NODE_ROLE_WORKER, obj.WPFirewallID, obj.NodeSize, obj.Distribution.JoinWorker(util.DistributionConfig{
+		LoadBalancerPrivateIP: privateIPlb,
+		Token:                 token,
+	}), false)
 	if err != nil {
-		return nil, nil
+		return nil, err
 	}
 
-	var retObject *civogo.Instance
-
-	for {
-		getInstance, err := obj.GetInstance(instance.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		if getInstance.Status == "ACTIVE" {
-			retObject = getInstance
-			logging.Info("💻 Booted Instance", name)
-			return retObject, nil
-		}
-		logging.Info("🚧 Instance", name)
-		time.Sleep(10 * time.Second)
+	err = obj.Configuration.ConfigWriterInstanceWorkerNodes(logging, number, instance.ID)
+	if err != nil {
+		return nil, err
 	}
 
+	return obj.waitForInstance(ctx, logging, instance.ID, name)
 }
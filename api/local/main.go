@@ -9,9 +9,12 @@ Credit to @kubernetes.io, @kind
 package local
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"runtime"
+	"strconv"
 	"time"
 
 	log "github.com/kubesimplify/ksctl/api/logger"
@@ -21,6 +24,23 @@ import (
 	"sigs.k8s.io/kind/pkg/errors"
 )
 
+// LocalProvider kind based cluster running on the local docker daemon
+// its state is always kept in ~/.ksctl/config as the cluster exists only on this machine
+type LocalProvider struct {
+	util.LocalProvider
+	// KubernetesVersion the minor version of kubernetes of the kind node image
+	KubernetesVersion string
+}
+
+func init() {
+	util.RegisterProvider("local", func(payload util.ClusterPayload) util.Provider {
+		return &LocalProvider{
+			LocalProvider:     ClusterInfoInjecter(payload.ClusterName, payload.Spec.ManagedNodes),
+			KubernetesVersion: payload.KubernetesVersion,
+		}
+	})
+}
+
 func generateConfig(noWorker, noControl int) ([]byte, error) {
 	if noWorker >= 0 && noControl == 0 {
 		return nil, fmt.Errorf("invalid config request control node cannot be 0")
@@ -74,40 +94,53 @@ nodes:
 	return cluster.CreateWithRawConfig(raw), nil
 }
 
+// clusterRef identifies the local cluster, its files are kept in ~/.ksctl/config/local/local/<id>
+func clusterRef(clusterName string) util.ClusterRef {
+	return util.NewClusterRef("local", util.CLUSTER_TYPE_LOCAL, clusterName, "")
+}
+
 func isPresent(cluster string) bool {
-	_, err := os.ReadFile(util.GetPath(util.OTHER_PATH, "local", cluster, "info"))
+	_, err := os.ReadFile(clusterRef(cluster).Path("info"))
 	if os.IsNotExist(err) {
 		return false
 	}
 	return true
 }
 
-func createNecessaryConfigs(clusterName string) (string, error) {
-	err := os.Mkdir(util.GetPath(util.OTHER_PATH, "local", clusterName), 0750)
-	if err != nil && !os.IsExist(err) {
+// createNecessaryConfigs creates the kubeconfig and the info file which holds the metadata of the cluster
+func createNecessaryConfigs(clusterName string, noOfNodes int, kubernetesVersion string) (string, error) {
+	ref := clusterRef(clusterName)
+	err := os.MkdirAll(ref.Path(), 0750)
+	if err != nil {
 		return "", err
 	}
 
-	_, err = os.Create(util.GetPath(util.OTHER_PATH, "local", clusterName, "config"))
+	_, err = os.Create(ref.Path("config"))
 	if err != nil {
 		// TODO: if error happens here do clean up the dir created above
 		return "", err
 	}
-	_, err = os.Create(util.GetPath(util.OTHER_PATH, "local", clusterName, "info"))
+	_, err = os.Create(ref.Path("info"))
 	if err != nil {
 		return "", err
 	}
 
-	err = os.WriteFile(
-		fmt.Sprintf(util.GetPath(util.OTHER_PATH, "local", clusterName, "info")),
-		[]byte(fmt.Sprintf("%s", clusterName)),
-		0640)
+	state := &localState{ClusterMetadata: util.NewClusterMetadata("", noOfNodes)}
+	state.KubernetesVersion = kubernetesVersion
+	rawInfo, err := util.EncodeState(state, ref)
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(ref.Path("info"), rawInfo, 0640)
 
 	if err != nil {
 		return "", err
 	}
+	if err := util.RegisterCluster(ref); err != nil {
+		return "", err
+	}
 
-	return util.GetPath(util.OTHER_PATH, "local", clusterName, "config"), nil
+	return ref.Path("config"), nil
 }
 
 func ClusterInfoInjecter(clusterName string, noOfNodes int) util.LocalProvider {
@@ -124,7 +157,8 @@ func ClusterInfoInjecter(clusterName string, noOfNodes int) util.LocalProvider {
 	return spec
 }
 
-func CreateCluster(logging log.Logger, localConfig util.LocalProvider) error {
+// CreateCluster creates the kind cluster with the requested no of nodes
+func (localConfig LocalProvider) CreateCluster(ctx context.Context, logging log.Logger) error {
 
 	provider := cluster.NewProvider(
 	//cluster.ProviderWithLogger(logg), // TODO: try to add these
@@ -135,32 +169,51 @@ func CreateCluster(logging log.Logger, localConfig util.LocalProvider) error {
 	if err != nil {
 		return err
 	}
+	release, err := util.GetKubernetesRelease(localConfig.KubernetesVersion)
+	if err != nil {
+		return err
+	}
 	if isPresent(localConfig.ClusterName) {
 		return fmt.Errorf("🚩 DUPLICATE cluster creation")
 	}
 
+	// the wait for the control plane to be ready is bounded by the deadline of the context
 	Wait := 50 * time.Second
-	if err := provider.Create(
-		localConfig.ClusterName,
-		withConfig,
-		cluster.CreateWithNodeImage("kindest/node:v1.25.2@sha256:9be91e9e9cdf116809841fc77ebdb8845443c4c72fe5218f3ae9eb57fdb4bace"),
-		// cluster.CreateWithRetain(flags.Retain),
-		cluster.CreateWithWaitForReady(Wait),
-		cluster.CreateWithKubeconfigPath(func() string {
-			path, err := createNecessaryConfigs(localConfig.ClusterName)
-			if err != nil {
-				logging.Err("Cannot continue 😢")
-				_ = DeleteCluster(logging, localConfig.ClusterName)
-				panic(err)
-			}
-			return path
-		}()),
-		cluster.CreateWithDisplayUsage(true),
-		cluster.CreateWithDisplaySalutation(true),
-	); err != nil {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < Wait {
+		Wait = time.Until(deadline)
+	}
+	kubeconfigPath, err := createNecessaryConfigs(localConfig.ClusterName, localConfig.Spec.ManagedNodes, release.Version)
+	if err != nil {
 		logging.Err("Cannot continue 😢")
-		_ = DeleteCluster(logging, localConfig.ClusterName)
-		return errors.Wrap(err, "failed to create cluster")
+		_ = localConfig.DeleteCluster(ctx, logging)
+		return err
+	}
+
+	// kind does not accept a context so the creation is waited upon till the context is cancelled
+	created := make(chan error, 1)
+	go func() {
+		created <- provider.Create(
+			localConfig.ClusterName,
+			withConfig,
+			cluster.CreateWithNodeImage(release.KindImage),
+			// cluster.CreateWithRetain(flags.Retain),
+			cluster.CreateWithWaitForReady(Wait),
+			cluster.CreateWithKubeconfigPath(kubeconfigPath),
+			cluster.CreateWithDisplayUsage(true),
+			cluster.CreateWithDisplaySalutation(true),
+		)
+	}()
+
+	select {
+	case <-ctx.Done():
+		logging.Note("Cluster creation was interrupted, use delete-cluster to remove it")
+		return ctx.Err()
+	case err := <-created:
+		if err != nil {
+			logging.Err("Cannot continue 😢")
+			_ = localConfig.DeleteCluster(ctx, logging)
+			return errors.Wrap(err, "failed to create cluster")
+		}
 	}
 
 	var printKubeconfig util.PrinterKubeconfigPATH
@@ -172,7 +225,7 @@ func CreateCluster(logging log.Logger, localConfig util.LocalProvider) error {
 	return nil
 }
 
-func (p printer) Printer(logging log.Logger,isHA bool, operation int) {
+func (p printer) Printer(logging log.Logger, isHA bool, operation int) {
 	preFix := "export "
 	if runtime.GOOS == "windows" {
 		preFix = "$Env:"
@@ -180,18 +233,9 @@ func (p printer) Printer(logging log.Logger,isHA bool, operation int) {
 	switch operation {
 	case 0:
 		logging.Note("To use this cluster set this environment variable")
-		if isHA {
-			logging.Print(fmt.Sprintf("%sKUBECONFIG=\"%s\"\n", preFix, util.GetPath(util.CLUSTER_PATH, "local", p.ClusterName, "config")))
-		} else {
-			logging.Print(fmt.Sprintf("%sKUBECONFIG=\"%s\"\n", preFix, util.GetPath(util.CLUSTER_PATH, "local", p.ClusterName, "config")))
-		}
+		logging.Print(fmt.Sprintf("%sKUBECONFIG=\"%s\"\n", preFix, clusterRef(p.ClusterName).Path("config")))
 	case 1:
-		logging.Note("Use the following command to unset KUBECONFIG")
-		if runtime.GOOS == "windows" {
-		    logging.Print(fmt.Sprintf("%sKUBECONFIG=\"\"\n", preFix))
-		} else {
-			logging.Print("unset KUBECONFIG")
-		}
+		util.ForgetKubeconfig(logging, clusterRef(p.ClusterName))
 	}
 }
 
@@ -203,20 +247,26 @@ func deleteConfigs(path string) error {
 	return nil
 }
 
-func DeleteCluster(logging log.Logger, name string) error {
+// DeleteCluster deletes the kind cluster and its configs
+func (localConfig LocalProvider) DeleteCluster(ctx context.Context, logging log.Logger) error {
+	name := localConfig.ClusterName
 	provider := cluster.NewProvider(
 	// cluster.ProviderWithLogger(logger),	// TODO: try to add these
 	// runtime.GetDefault(logger),
 	)
-	_, err := os.ReadFile(util.GetPath(util.CLUSTER_PATH, "local", name, "info"))
+	ref := clusterRef(name)
+	_, err := os.ReadFile(ref.Path("info"))
 	if err != nil {
 		return fmt.Errorf("NO matching cluster found")
 	}
 
-	if err := provider.Delete(name, util.GetPath(util.CLUSTER_PATH, "local", name, "config")); err != nil {
+	if err := provider.Delete(name, ref.Path("config")); err != nil {
 		return fmt.Errorf("FAIL to delete cluster %q", err)
 	}
-	if err := deleteConfigs(util.GetPath(util.CLUSTER_PATH, "local", name)); err != nil {
+	if err := deleteConfigs(ref.Path()); err != nil {
+		return err
+	}
+	if err := util.UnregisterCluster(ref); err != nil {
 		return err
 	}
 	var printKubeconfig util.PrinterKubeconfigPATH
@@ -229,14 +279,224 @@ type printer struct {
 	ClusterName string
 }
 
-// SwitchContext TODO: Add description
-func SwitchContext(logging log.Logger, clusterName string) error {
-	if isPresent(clusterName) {
-		// TODO: ISSUE #5
-		var printKubeconfig util.PrinterKubeconfigPATH
-		printKubeconfig = printer{ClusterName: clusterName}
-		printKubeconfig.Printer(logging, false, 0)
-		return nil
+// SwitchContext merges the kubeconfig of the local cluster into the default kubeconfig and makes it the current context
+func (localConfig LocalProvider) SwitchContext(ctx context.Context, logging log.Logger) error {
+	if !isPresent(localConfig.ClusterName) {
+		return fmt.Errorf("ERR Cluster not found")
+	}
+	return util.SwitchKubeconfig(logging, clusterRef(localConfig.ClusterName))
+}
+
+// Kubeconfig implements util.Provider, the kubeconfig is exported again by kind
+func (localConfig LocalProvider) Kubeconfig(ctx context.Context, logging log.Logger, refresh bool) ([]byte, error) {
+	if !isPresent(localConfig.ClusterName) {
+		return nil, fmt.Errorf("ERR Cluster not found")
+	}
+	ref := clusterRef(localConfig.ClusterName)
+	if refresh {
+		kubeconfig, err := cluster.NewProvider().KubeConfig(localConfig.ClusterName, false)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(ref.Path("config"), []byte(kubeconfig), 0600); err != nil {
+			return nil, err
+		}
+	}
+	return os.ReadFile(ref.Path("config"))
+}
+
+// Create implements util.Provider
+func (localConfig LocalProvider) Create(ctx context.Context, logging log.Logger) error {
+	return localConfig.CreateCluster(ctx, logging)
+}
+
+// Delete implements util.Provider
+func (localConfig LocalProvider) Delete(ctx context.Context, logging log.Logger) error {
+	return localConfig.DeleteCluster(ctx, logging)
+}
+
+// AddNodes implements util.Provider, not supported for local clusters
+func (localConfig LocalProvider) AddNodes(ctx context.Context, logging log.Logger) error {
+	return fmt.Errorf("adding nodes is not supported for local cluster")
+}
+
+// DeleteNodes implements util.Provider, not supported for local clusters
+func (localConfig LocalProvider) DeleteNodes(ctx context.Context, logging log.Logger) error {
+	return fmt.Errorf("deleting nodes is not supported for local cluster")
+}
+
+// List returns all the local clusters present in the state management files
+func (localConfig LocalProvider) List(ctx context.Context, logging log.Logger) ([]util.ClusterInfo, error) {
+	var clusters []util.ClusterInfo
+
+	refs, err := util.IndexedClusters("local", util.CLUSTER_TYPE_LOCAL)
+	if err != nil {
+		return nil, err
 	}
-	return fmt.Errorf("ERR Cluster not found")
+	for _, ref := range refs {
+		clusters = append(clusters, clusterInfo(ref.Name))
+	}
+	return clusters, nil
+}
+
+// Describe implements util.Provider
+// the nodes are the docker containers named by kind e.g. demo-control-plane, demo-worker, demo-worker2
+func (localConfig LocalProvider) Describe(ctx context.Context, logging log.Logger) (util.ClusterDescription, error) {
+	if !isPresent(localConfig.ClusterName) {
+		return util.ClusterDescription{}, fmt.Errorf("cluster does not exists: %v", localConfig.ClusterName)
+	}
+	description := util.ClusterDescription{ClusterInfo: clusterInfo(localConfig.ClusterName)}
+	if description.ControlPlaneNodes == 0 {
+		return description, nil
+	}
+	description.Nodes = kindNodes(localConfig.ClusterName, description.WorkerNodes)
+	return description, nil
+}
+
+// kindNodes the nodes of the kind cluster with the single control plane
+func kindNodes(clusterName string, workerNodes int) []util.ClusterNode {
+	nodes := []util.ClusterNode{{Role: util.NODE_ROLE_CONTROLPLANE, Name: clusterName + "-control-plane"}}
+	for worker := 1; worker <= workerNodes; worker++ {
+		name := clusterName + "-worker"
+		if worker > 1 {
+			name += strconv.Itoa(worker)
+		}
+		nodes = append(nodes, util.ClusterNode{Role: util.NODE_ROLE_WORKER, Name: name})
+	}
+	return nodes
+}
+
+// CheckDrift implements util.Provider
+// the containers of the cluster cannot be recreated so only the state of the deleted cluster is removed on fix
+func (localConfig LocalProvider) CheckDrift(ctx context.Context, logging log.Logger, fix bool) (util.DriftReport, error) {
+	name := localConfig.ClusterName
+	report := util.DriftReport{ClusterName: name, Provider: "local", Type: util.CLUSTER_TYPE_LOCAL}
+	if !isPresent(name) {
+		return report, fmt.Errorf("cluster does not exists: %v", name)
+	}
+
+	provider := cluster.NewProvider()
+	clusters, err := provider.List()
+	if err != nil {
+		return report, err
+	}
+	var containers []string
+	for _, existing := range clusters {
+		if existing != name {
+			continue
+		}
+		nodes, err := provider.ListNodes(name)
+		if err != nil {
+			return report, err
+		}
+		for _, node := range nodes {
+			containers = append(containers, node.String())
+		}
+	}
+
+	if !checkDrift(&report, clusterInfo(name), clusters, containers) && fix {
+		ref := clusterRef(name)
+		if err := deleteConfigs(ref.Path()); err != nil {
+			return report, err
+		}
+		if err := util.UnregisterCluster(ref); err != nil {
+			return report, err
+		}
+		report.Fixed = true
+	}
+	return report, nil
+}
+
+// checkDrift compares the kind cluster and its node containers with the ones recorded in the info file
+// returns whether the kind cluster exists
+func checkDrift(report *util.DriftReport, info util.ClusterInfo, clusters, containers []string) bool {
+	found := false
+	for _, existing := range clusters {
+		if existing == info.ClusterName {
+			found = true
+		}
+	}
+	if !report.Check("kind-cluster", info.ClusterName, "", found) || info.ControlPlaneNodes == 0 {
+		return found
+	}
+
+	existing := map[string]bool{}
+	for _, container := range containers {
+		existing[container] = true
+	}
+	recorded := map[string]bool{}
+	for _, node := range kindNodes(info.ClusterName, info.WorkerNodes) {
+		report.Check("node", node.Name, "", existing[node.Name])
+		recorded[node.Name] = true
+	}
+	for _, container := range containers {
+		if !recorded[container] {
+			report.Unexpected("node", container, "")
+		}
+	}
+	return found
+}
+
+// localState the status of the local cluster is kept by kind, only the metadata is saved in the info file
+type localState struct {
+	util.ClusterMetadata `json:"-"`
+}
+
+// readState decodes the info file and saves it in the current schema when it was written by an older version
+// the older versions didn't record the creation time so the modification time of the file is kept as one
+func readState(name string, state *localState) error {
+	ref := clusterRef(name)
+	infoPath := ref.Path("info")
+	raw, err := os.ReadFile(infoPath)
+	if err != nil {
+		return err
+	}
+	clusterState, migrated, err := util.DecodeState(raw, state, ref)
+	if err != nil || !migrated {
+		return err
+	}
+	if len(clusterState.CreatedAt) == 0 {
+		if stat, err := os.Stat(infoPath); err == nil {
+			clusterState.CreatedAt = stat.ModTime().UTC().Format(time.RFC3339)
+			state.CreatedAt = clusterState.CreatedAt
+		}
+	}
+	upgraded, err := json.Marshal(clusterState)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(infoPath, upgraded, 0640)
+}
+
+// clusterInfo reads the metadata stored in the info file of the cluster
+func clusterInfo(name string) util.ClusterInfo {
+	info := util.ClusterInfo{
+		ClusterName:    name,
+		Region:         "N/A",
+		Provider:       "local",
+		Type:           util.CLUSTER_TYPE_LOCAL,
+		KubeconfigPath: clusterRef(name).Path("config"),
+	}
+
+	var state localState
+	if err := readState(name, &state); err != nil {
+		return info
+	}
+	info.CreatedAt = state.CreatedAt
+	if state.ManagedNodes > 0 {
+		// the kind config always has a single control plane node
+		info.ControlPlaneNodes = 1
+		info.WorkerNodes = state.ManagedNodes - 1
+	}
+	return info
+}
+
+// IsPresent implements util.Provider
+func (localConfig LocalProvider) IsPresent() bool {
+	return isPresent(localConfig.ClusterName)
+}
+
+// NoOfWorkerNodes implements util.Provider, not supported for local clusters
+func (localConfig LocalProvider) NoOfWorkerNodes(ctx context.Context, logging log.Logger) (int, error) {
+	return 0, fmt.Errorf("worker nodes are only tracked for HA cluster")
 }
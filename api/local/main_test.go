@@ -1,10 +1,12 @@
 package local
 
 import (
+	"context"
 	"os"
 	"strings"
 	"testing"
 
+	log "github.com/kubesimplify/ksctl/api/logger"
 	util "github.com/kubesimplify/ksctl/api/utils"
 	"github.com/stretchr/testify/assert"
 )
@@ -78,11 +80,11 @@ func TestIsPresent(t *testing.T) {
 	assert.Equal(t, false, present, "with no clusters returns true! (false +ve)")
 
 	// create the folder
-	err := os.Mkdir(util.GetPath(util.OTHER_PATH, "local", "demo"), 0755)
+	err := os.MkdirAll(clusterRef("demo").Path(), 0755)
 	if err != nil {
 		t.Fatal(err)
 	}
-	_, err = os.Create(util.GetPath(util.OTHER_PATH, "local", "demo", "info"))
+	_, err = os.Create(clusterRef("demo").Path("info"))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -90,3 +92,73 @@ func TestIsPresent(t *testing.T) {
 	present = isPresent("demo")
 	assert.Equal(t, true, present, "Failed to detect the cluster (false -ve)")
 }
+
+func TestClusterInfo(t *testing.T) {
+	cleanup()
+	clusters, err := LocalProvider{}.List(context.Background(), log.Logger{})
+	assert.Nil(t, err, "missing state directory must not fail")
+	assert.Empty(t, clusters)
+
+	setup()
+	t.Cleanup(func() {
+		cleanup()
+	})
+
+	_, err = createNecessaryConfigs("demo", 3, "1.26")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clusters, err = LocalProvider{}.List(context.Background(), log.Logger{})
+	assert.Nil(t, err)
+	assert.Len(t, clusters, 1)
+
+	info := clusterInfo("demo")
+	assert.Equal(t, "local", info.Provider)
+	assert.Equal(t, util.CLUSTER_TYPE_LOCAL, info.Type)
+	assert.Equal(t, 1, info.ControlPlaneNodes)
+	assert.Equal(t, 2, info.WorkerNodes)
+	assert.NotEmpty(t, info.CreatedAt)
+	assert.Equal(t, clusterRef("demo").Path("config"), info.KubeconfigPath)
+
+	description, err := LocalProvider{LocalProvider: ClusterInfoInjecter("demo", 0)}.Describe(context.Background(), log.Logger{})
+	assert.Nil(t, err)
+	assert.Equal(t, []util.ClusterNode{
+		{Role: util.NODE_ROLE_CONTROLPLANE, Name: "demo-control-plane"},
+		{Role: util.NODE_ROLE_WORKER, Name: "demo-worker"},
+		{Role: util.NODE_ROLE_WORKER, Name: "demo-worker2"},
+	}, description.Nodes)
+
+	// older clusters have only the name stored in the info file
+	err = os.WriteFile(clusterRef("demo").Path("info"), []byte("demo"), 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info = clusterInfo("demo")
+	assert.Equal(t, 0, info.WorkerNodes)
+	assert.NotEmpty(t, info.CreatedAt)
+
+	// and are saved in the current schema keeping the time of creation
+	raw, err := os.ReadFile(clusterRef("demo").Path("info"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, string(raw), `"schemaVersion":1`)
+	assert.Equal(t, info.CreatedAt, clusterInfo("demo").CreatedAt)
+}
+
+func TestCheckDrift(t *testing.T) {
+	info := util.ClusterInfo{ClusterName: "demo", ControlPlaneNodes: 1, WorkerNodes: 2}
+
+	report := util.DriftReport{}
+	assert.True(t, checkDrift(&report, info, []string{"other", "demo"}, []string{"demo-control-plane", "demo-worker2", "demo-worker3"}))
+	assert.Equal(t, 4, report.Checked)
+	assert.Equal(t, []util.Drift{
+		{Kind: "node", Name: "demo-worker", Status: util.DRIFT_MISSING},
+		{Kind: "node", Name: "demo-worker3", Status: util.DRIFT_UNEXPECTED},
+	}, report.Drifts)
+
+	// the nodes of the deleted cluster are not checked
+	report = util.DriftReport{}
+	assert.False(t, checkDrift(&report, info, []string{"other"}, nil))
+	assert.Equal(t, []util.Drift{{Kind: "kind-cluster", Name: "demo", Status: util.DRIFT_MISSING}}, report.Drifts)
+}
@@ -1,14 +1,60 @@
 package logger
 
+import "io"
+
+// Level is the severity of the log message, the zero value is LevelInfo
+type Level int
+
+const (
+	LevelDebug Level = iota - 1
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+const (
+	FORMAT_TEXT = "text"
+	FORMAT_JSON = "json"
+)
+
+// Logger writes leveled messages as coloured text or as json lines
+// the zero value writes coloured text of level info and above to stdout
 type Logger struct {
+	// Verbose adds the timestamp to the text format and enables the debug messages
 	Verbose bool
+
+	// Level is the minimum level of the messages which are written
+	Level Level
+
+	// Format is either FORMAT_TEXT (default) or FORMAT_JSON
+	Format string
+
+	// NoColor disables the ANSI colours of the text format
+	NoColor bool
+
+	// Out is where the messages are written, defaults to stdout (stderr when Verbose)
+	Out io.Writer
+
+	// Sinks also receive every message without the colours, e.g. the log file of the operation
+	Sinks []io.Writer
+
+	// fields are the key-value pairs added to every message
+	fields []string
 }
 
 type LogFactory interface {
 	// will accept a string to be highlignted
+	Debug(...string)
 	Info(...string)
 	Warn(...string)
 	Print(...string)
 	Err(...string)
-    Note(...string)
+	Note(...string)
+}
+
+var _ LogFactory = &Logger{}
+
+// IsValidFormat whether the format is supported
+func IsValidFormat(format string) bool {
+	return format == "" || format == FORMAT_TEXT || format == FORMAT_JSON
 }
@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestLevels(t *testing.T) {
+	var out bytes.Buffer
+	logging := Logger{Out: &out, NoColor: true, Level: LevelWarn}
+
+	logging.Debug("debug")
+	logging.Info("info")
+	logging.Note("note")
+	logging.Warn("warn")
+	logging.Err("error")
+	assert.Equal(t, "[WARN] warn\n[ERR] error\n", out.String())
+
+	out.Reset()
+	logging = Logger{Out: &out, NoColor: true}
+	logging.Debug("debug")
+	logging.Info("info")
+	assert.Equal(t, "[INFO] info\n", out.String())
+
+	out.Reset()
+	logging.Level = LevelDebug
+	logging.Debug("debug")
+	assert.Equal(t, "[DEBUG] debug\n", out.String())
+}
+
+func TestTextFormat(t *testing.T) {
+	var out bytes.Buffer
+	logging := Logger{Out: &out}
+	logging.Info("💻 Booted Instance", "demo-cp-1")
+	assert.Equal(t, GREEN+"[INFO] 💻 Booted Instance demo-cp-1"+RESET+"\n", out.String())
+
+	out.Reset()
+	logging.Print("plain")
+	assert.Equal(t, "[MSG]  plain\n", out.String())
+
+	out.Reset()
+	logging.NoColor = true
+	withFields := logging.With("cluster", "demo", "provider", "civo")
+	withFields.Note("created\n")
+	assert.Equal(t, "[NOTE] created cluster=demo provider=civo\n", out.String())
+
+	out.Reset()
+	logging.Note("parent")
+	assert.Equal(t, "[NOTE] parent\n", out.String(), "With must not change the parent logger")
+}
+
+func TestJSONFormat(t *testing.T) {
+	var out bytes.Buffer
+	logging := Logger{Out: &out, Format: FORMAT_JSON}.With("cluster", "demo", "step", "database")
+	logging.Err("failed \"quoted\"")
+
+	assert.Assert(t, strings.HasPrefix(out.String(), `{"time":`))
+	var line map[string]string
+	assert.NilError(t, json.Unmarshal(out.Bytes(), &line))
+	assert.Equal(t, "error", line["level"])
+	assert.Equal(t, `failed "quoted"`, line["msg"])
+	assert.Equal(t, "demo", line["cluster"])
+	assert.Equal(t, "database", line["step"])
+}
+
+func TestSinks(t *testing.T) {
+	var out, file bytes.Buffer
+	logging := Logger{Out: &out, Sinks: []io.Writer{&file}}
+	logging.Warn("disk is", "full")
+
+	assert.Equal(t, YELLOW+"[WARN] disk is full"+RESET+"\n", out.String())
+	assert.Equal(t, "[WARN] disk is full\n", file.String())
+}
+
+func TestIsValidFormat(t *testing.T) {
+	assert.Assert(t, IsValidFormat(""))
+	assert.Assert(t, IsValidFormat(FORMAT_TEXT))
+	assert.Assert(t, IsValidFormat(FORMAT_JSON))
+	assert.Assert(t, !IsValidFormat("yaml"))
+}
+
+func TestRenderTable(t *testing.T) {
+	table := RenderTable(
+		[]string{"NAME", "PROVIDER", "REGION"},
+		[][]string{
+			{"demo", "civo", "LON1"},
+			{"démo-ha", "aws", ""},
+			{"short"},
+		})
+	expected := "NAME      PROVIDER   REGION\n" +
+		"demo      civo       LON1\n" +
+		"démo-ha   aws\n" +
+		"short\n"
+	assert.Equal(t, expected, table)
+
+	assert.Equal(t, "NAME   REGION\n", RenderTable([]string{"NAME", "REGION"}, nil))
+}
+
+func TestTable(t *testing.T) {
+	var out bytes.Buffer
+	logging := Logger{Out: &out, Level: LevelError, Format: FORMAT_JSON}
+	logging.Table([]string{"NAME"}, [][]string{{"demo"}})
+	assert.Equal(t, "NAME\ndemo\n", out.String())
+}
@@ -1,9 +1,13 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
-	"log"
+	"io"
+	"os"
 	"strings"
+	"time"
 )
 
 const (
@@ -17,59 +21,136 @@ const (
 	RESET      = "\033[0m"
 )
 
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+// With returns a copy of the logger which adds the key-value pairs to every message
+// e.g. logging.With("cluster", name, "region", region)
+func (logger Logger) With(keyValues ...string) Logger {
+	if len(keyValues)%2 != 0 {
+		keyValues = append(keyValues, "")
+	}
+	fields := make([]string, 0, len(logger.fields)+len(keyValues))
+	logger.fields = append(append(fields, logger.fields...), keyValues...)
+	return logger
+}
+
+// Debug debugging message, written only when Verbose or the Level is LevelDebug
+func (logger *Logger) Debug(message ...string) {
+	logger.write(LevelDebug, "DEBUG", WHITE, message)
+}
+
 // Info information message to stdout
 // in green colour
 func (logger *Logger) Info(message ...string) {
-
-    outputMsg := strings.Join(message, " ")
-
-	if logger.Verbose {
-		log.Printf("%s[INFO] %v%s", GREEN, outputMsg, RESET)
-	} else {
-		fmt.Printf("%s[INFO] %v%s\n", GREEN, outputMsg, RESET)
-	}
+	logger.write(LevelInfo, "INFO", GREEN, message)
 }
 
 // Print plan text stdout
 func (logger *Logger) Print(message ...string) {
-    outputMsg := strings.Join(message, " ")
-	if logger.Verbose {
-		log.Println("[MSG] ", outputMsg)
-	} else {
-		fmt.Println("[MSG] ", outputMsg)
-	}
+	logger.write(LevelInfo, "MSG", "", message)
 }
 
 // Note note taking message to stdout
 // in blue colour
 func (logger *Logger) Note(message ...string) {
-
-    outputMsg := strings.Join(message, " ")
-	if logger.Verbose {
-		log.Printf("%s[NOTE] %v%s", BLUE_BOLD, outputMsg, RESET)
-	} else {
-		fmt.Printf("%s[NOTE] %v%s\n", BLUE_BOLD, outputMsg, RESET)
-	}
+	logger.write(LevelInfo, "NOTE", BLUE_BOLD, message)
 }
 
 // Warn warning message to stdout
 // in yellow colour
 func (logger *Logger) Warn(message ...string) {
-    outputMsg := strings.Join(message, " ")
-	if logger.Verbose {
-		log.Printf("%s[WARN] %v%s", YELLOW, outputMsg, RESET)
-	} else {
-		fmt.Printf("%s[WARN] %v%s\n", YELLOW, outputMsg, RESET)
-	}
+	logger.write(LevelWarn, "WARN", YELLOW, message)
 }
 
 // Err error message to stdout
 // in red color
 func (logger *Logger) Err(message ...string) {
-    outputMsg := strings.Join(message, " ")
+	logger.write(LevelError, "ERR", RED, message)
+}
+
+func (logger *Logger) enabled(level Level) bool {
+	if logger.Verbose {
+		return true
+	}
+	return level >= logger.Level
+}
+
+// write formats the message once for the console and once without colours for the sinks
+// every line is written with a single call so that the messages of the parallel tasks are not mixed
+func (logger *Logger) write(level Level, tag, colour string, message []string) {
+	if !logger.enabled(level) {
+		return
+	}
+	now := time.Now()
+	outputMsg := strings.TrimRight(strings.Join(message, " "), "\n")
+
+	out := logger.Out
+	if out == nil {
+		out = os.Stdout
+		if logger.Verbose {
+			out = os.Stderr
+		}
+	}
+	if logger.NoColor {
+		colour = ""
+	}
+	_, _ = io.WriteString(out, logger.format(now, level, tag, colour, outputMsg))
+
+	if len(logger.Sinks) == 0 {
+		return
+	}
+	line := logger.format(now, level, tag, "", outputMsg)
+	for _, sink := range logger.Sinks {
+		_, _ = io.WriteString(sink, line)
+	}
+}
+
+func (logger *Logger) format(now time.Time, level Level, tag, colour, msg string) string {
+	if logger.Format == FORMAT_JSON {
+		return jsonLine(now, level, msg, logger.fields)
+	}
+
+	var line strings.Builder
 	if logger.Verbose {
-		log.Printf("%s[ERR] %v%s", RED, outputMsg, RESET)
+		line.WriteString(now.Format("2006/01/02 15:04:05 "))
+	}
+	line.WriteString(colour)
+	if tag == "MSG" {
+		line.WriteString("[MSG]  ")
 	} else {
-		fmt.Printf("%s[ERR] %v%s\n", RED, outputMsg, RESET)
+		fmt.Fprintf(&line, "[%s] ", tag)
+	}
+	line.WriteString(msg)
+	for i := 0; i < len(logger.fields); i += 2 {
+		fmt.Fprintf(&line, " %s=%s", logger.fields[i], logger.fields[i+1])
+	}
+	if len(colour) != 0 {
+		line.WriteString(RESET)
+	}
+	line.WriteString("\n")
+	return line.String()
+}
+
+// jsonLine is a single json object with the time, level, msg and the fields in the order they were added
+func jsonLine(now time.Time, level Level, msg string, fields []string) string {
+	var line bytes.Buffer
+	keyValues := append([]string{"time", now.Format(time.RFC3339), "level", levelNames[level], "msg", msg}, fields...)
+	line.WriteString("{")
+	for i := 0; i < len(keyValues); i += 2 {
+		if i != 0 {
+			line.WriteString(",")
+		}
+		key, _ := json.Marshal(keyValues[i])
+		value, _ := json.Marshal(keyValues[i+1])
+		line.Write(key)
+		line.WriteString(":")
+		line.Write(value)
 	}
+	line.WriteString("}\n")
+	return line.String()
 }
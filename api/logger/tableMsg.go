@@ -1 +1,56 @@
 package logger
+
+import (
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// RenderTable aligns the rows under the headers separated by three spaces like kubectl does
+// the width of the columns is counted in runes so that the emojis and the non-ASCII names line up
+func RenderTable(headers []string, rows [][]string) string {
+	widths := make([]int, len(headers))
+	for i, header := range headers {
+		widths[i] = utf8.RuneCountInString(header)
+	}
+	for _, row := range rows {
+		for i := 0; i < len(row) && i < len(widths); i++ {
+			if width := utf8.RuneCountInString(row[i]); width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+
+	var table strings.Builder
+	writeRow := func(row []string) {
+		var line strings.Builder
+		for i := range widths {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			line.WriteString(cell)
+			if i != len(widths)-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)+3))
+			}
+		}
+		table.WriteString(strings.TrimRight(line.String(), " "))
+		table.WriteString("\n")
+	}
+	writeRow(headers)
+	for _, row := range rows {
+		writeRow(row)
+	}
+	return table.String()
+}
+
+// Table writes the table to the output of the logger, it is not affected by the level and the format
+// as it is the result of the command and not a log message
+func (logger *Logger) Table(headers []string, rows [][]string) {
+	out := logger.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	_, _ = io.WriteString(out, RenderTable(headers, rows))
+}
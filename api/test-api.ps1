@@ -39,3 +39,10 @@ Write-Output "+-------------------------+"
 
 Set-Location azure
 go test . -v && Set-Location -
+
+Write-Output "+-------------------------+"
+Write-Output "|   Testing (api/aws)     |"
+Write-Output "+-------------------------+"
+
+Set-Location aws
+go test . -v && Set-Location -
@@ -31,4 +31,11 @@ echo "+-------------------------+"
 cd azure/
 go test . -v && cd -
 
+echo "+-------------------------+"
+echo "|   Testing (api/aws)     |"
+echo "+-------------------------+"
+
+cd aws/
+go test . -v && cd -
+
 rm -rvf ${HOME}/.ksctl
@@ -0,0 +1,48 @@
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+package utils
+
+import "fmt"
+
+// steps of the HA cluster creation which are recorded in the state management file
+// so that a failed creation can be resumed from the step which failed
+const (
+	STEP_SSH_KEY             = "ssh-key"
+	STEP_DATABASE            = "database"
+	STEP_LOADBALANCER        = "loadbalancer"
+	STEP_CONTROLPLANES       = "controlplanes"
+	STEP_LOADBALANCER_CONFIG = "loadbalancer-config"
+	STEP_KUBECONFIG          = "kubeconfig"
+	STEP_WORKERPLANES        = "workerplanes"
+)
+
+// StepK3sControlPlane step of installing k3s on the control plane with the given number (starting from 1)
+func StepK3sControlPlane(number int) string {
+	return fmt.Sprintf("k3s-controlplane-%d", number)
+}
+
+// Checkpoints the steps which are already completed
+type Checkpoints []string
+
+// Done whether the step is already completed
+func (checkpoints Checkpoints) Done(step string) bool {
+	for _, done := range checkpoints {
+		if done == step {
+			return true
+		}
+	}
+	return false
+}
+
+// Mark records the step as completed
+func (checkpoints *Checkpoints) Mark(step string) {
+	if checkpoints.Done(step) {
+		return
+	}
+	*checkpoints = append(*checkpoints, step)
+}
@@ -0,0 +1,314 @@
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kubesimplify/ksctl/api/logger"
+)
+
+// INDEX_FILE maps the IDs of the clusters of a provider to their names, kept as <provider>/index.json
+const INDEX_FILE = "index.json"
+
+// indexLockTTL the index is updated in milliseconds so an older lock was left by a killed process
+const indexLockTTL = time.Minute
+
+// ClusterRef identifies a cluster, its state management files are kept in <provider>/<type>/<id>
+type ClusterRef struct {
+	Provider string `json:"provider"`
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Region   string `json:"region,omitempty"`
+}
+
+func NewClusterRef(provider, clusterType, name, region string) ClusterRef {
+	return ClusterRef{Provider: provider, Type: clusterType, Name: name, Region: region}
+}
+
+// ID is generated from the provider, type, name and region so it stays the same for the lifetime
+// of the cluster and is safe to use as a directory name on every OS e.g. demo-1a2b3c4d
+func (ref ClusterRef) ID() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{ref.Provider, ref.Type, ref.Name, ref.Region}, "/")))
+	return ref.Name + "-" + hex.EncodeToString(sum[:4])
+}
+
+// PayloadRef the cluster of the payload given to the provider registered by the name
+func PayloadRef(provider string, payload ClusterPayload) ClusterRef {
+	switch {
+	case provider == "local":
+		return NewClusterRef(provider, CLUSTER_TYPE_LOCAL, payload.ClusterName, "")
+	case payload.HACluster:
+		return NewClusterRef(provider, CLUSTER_TYPE_HA, payload.ClusterName, payload.Region)
+	}
+	return NewClusterRef(provider, CLUSTER_TYPE_MANAGED, payload.ClusterName, payload.Region)
+}
+
+// Path of a file of the cluster on the local disk e.g. the kubeconfig
+func (ref ClusterRef) Path(file ...string) string {
+	return GetPath(OTHER_PATH, ref.Provider, append([]string{ref.Type, ref.ID()}, file...)...)
+}
+
+// IsPresent whether the state management file of the cluster is in the store
+func (ref ClusterRef) IsPresent() bool {
+	return IsStatePresent(ref.Provider, ref.Type, ref.ID(), "info.json")
+}
+
+type clusterIndex struct {
+	Clusters map[string]ClusterRef `json:"clusters"`
+}
+
+// indexStore the local clusters are never kept in the state store so is their index
+func indexStore(provider string) StateStore {
+	if provider == "local" {
+		return LocalStore{}
+	}
+	return stateStore
+}
+
+func readIndex(provider string) (clusterIndex, error) {
+	index := clusterIndex{Clusters: map[string]ClusterRef{}}
+	raw, err := indexStore(provider).Read(stateContext, StateKey(provider, INDEX_FILE))
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return index, err
+	}
+	if err := json.Unmarshal(raw, &index); err != nil {
+		return index, fmt.Errorf("invalid index of the %s clusters: %v", provider, err)
+	}
+	if index.Clusters == nil {
+		index.Clusters = map[string]ClusterRef{}
+	}
+	return index, nil
+}
+
+// updateIndex applies the change to the index while holding its lock
+// as the clusters of a provider can be created and deleted concurrently
+func updateIndex(provider string, change func(clusters map[string]ClusterRef)) error {
+	store := indexStore(provider)
+	lock, err := acquireLock(stateContext, logger.Logger{Level: logger.LevelError}, store,
+		StateKey("locks", provider, INDEX_FILE+".lock"), provider+" index", "update-index", indexLockTTL, indexLockTTL)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	index, err := readIndex(provider)
+	if err != nil {
+		return err
+	}
+	change(index.Clusters)
+	raw, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return store.Write(stateContext, StateKey(provider, INDEX_FILE), raw)
+}
+
+// RegisterCluster adds the cluster to the index of its provider
+func RegisterCluster(ref ClusterRef) error {
+	index, err := readIndex(ref.Provider)
+	if err != nil {
+		return err
+	}
+	if _, ok := index.Clusters[ref.ID()]; ok {
+		return nil
+	}
+	return updateIndex(ref.Provider, func(clusters map[string]ClusterRef) {
+		clusters[ref.ID()] = ref
+	})
+}
+
+// UnregisterCluster removes the cluster from the index of its provider
+func UnregisterCluster(ref ClusterRef) error {
+	return updateIndex(ref.Provider, func(clusters map[string]ClusterRef) {
+		delete(clusters, ref.ID())
+	})
+}
+
+// IndexedClusters returns the clusters of the type found in the index of the provider sorted by the name and region
+func IndexedClusters(provider, clusterType string) ([]ClusterRef, error) {
+	index, err := readIndex(provider)
+	if err != nil {
+		return nil, err
+	}
+	var refs []ClusterRef
+	for _, ref := range index.Clusters {
+		if ref.Type == clusterType {
+			refs = append(refs, ref)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Name != refs[j].Name {
+			return refs[i].Name < refs[j].Name
+		}
+		return refs[i].Region < refs[j].Region
+	})
+	return refs, nil
+}
+
+// FindClusters the clusters of the name in the indexes of the registered providers sorted by the provider, type and region
+// the empty provider, type or region matches every cluster
+func FindClusters(name, provider, clusterType, region string) ([]ClusterRef, error) {
+	var refs []ClusterRef
+	for _, registered := range RegisteredProviders() {
+		if len(provider) != 0 && provider != registered {
+			continue
+		}
+		index, err := readIndex(registered)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range index.Clusters {
+			if ref.Name != name ||
+				(len(clusterType) != 0 && ref.Type != clusterType) ||
+				(len(region) != 0 && !strings.EqualFold(ref.Region, region)) {
+				continue
+			}
+			refs = append(refs, ref)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Provider != refs[j].Provider {
+			return refs[i].Provider < refs[j].Provider
+		}
+		if refs[i].Type != refs[j].Type {
+			return refs[i].Type < refs[j].Type
+		}
+		return refs[i].Region < refs[j].Region
+	})
+	return refs, nil
+}
+
+// DeleteClusterState removes the state management files of the cluster and its entry in the index
+func DeleteClusterState(ref ClusterRef) error {
+	if err := DeleteStateDir(ref.Provider, ref.Type, ref.ID()); err != nil {
+		return err
+	}
+	return UnregisterCluster(ref)
+}
+
+// legacyClusterFiles are the files kept in the directories of the clusters before they had an ID
+var legacyClusterFiles = []string{"info.json", "config", "keypair", "keypair.pub"}
+
+// MigrateLayout moves the state of the clusters created by the older versions from the
+// "name region" (civo, aws), "name resourcegroup region" (azure) and "name" (local) directories
+// to the ID ones and builds the index, the providers which already have an index are skipped
+func MigrateLayout(logging logger.Logger) error {
+	if err := migrateLocalLayout(logging); err != nil {
+		return err
+	}
+	for _, provider := range []string{"civo", "azure", "aws"} {
+		if _, err := stateStore.Read(stateContext, StateKey(provider, INDEX_FILE)); !os.IsNotExist(err) {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		var refs []ClusterRef
+		for _, clusterType := range []string{CLUSTER_TYPE_HA, CLUSTER_TYPE_MANAGED} {
+			dirs, err := ListStateDir(provider, clusterType)
+			if err != nil {
+				return err
+			}
+			for _, dir := range dirs {
+				fields := strings.Fields(dir)
+				if len(fields) < 2 {
+					continue
+				}
+				ref := NewClusterRef(provider, clusterType, fields[0], fields[len(fields)-1])
+				if err := moveClusterDir(ref, dir); err != nil {
+					return fmt.Errorf("unable to migrate the state of the cluster %s: %v", dir, err)
+				}
+				logging.Note("Moved the state of the cluster", dir, "to", ref.Path())
+				refs = append(refs, ref)
+			}
+		}
+
+		if err := updateIndex(provider, func(clusters map[string]ClusterRef) {
+			for _, ref := range refs {
+				clusters[ref.ID()] = ref
+			}
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func moveClusterDir(ref ClusterRef, dir string) error {
+	for _, file := range legacyClusterFiles {
+		data, err := ReadStateFile(ref.Provider, ref.Type, dir, file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := WriteStateFile(ref.Provider, data, ref.Type, ref.ID(), file); err != nil {
+			return err
+		}
+	}
+	return DeleteStateDir(ref.Provider, ref.Type, dir)
+}
+
+// migrateLocalLayout the local clusters were kept in local/<name> of the local disk
+func migrateLocalLayout(logging logger.Logger) error {
+	if _, err := os.Stat(getPaths("local", INDEX_FILE)); !os.IsNotExist(err) {
+		return err
+	}
+	// the cluster named local is in the way of the type directory so it is moved aside first
+	if _, err := os.Stat(getPaths("local", CLUSTER_TYPE_LOCAL, "info")); err == nil {
+		if err := os.Rename(getPaths("local", CLUSTER_TYPE_LOCAL), getPaths("local", "."+CLUSTER_TYPE_LOCAL)); err != nil {
+			return err
+		}
+	}
+	entries, err := os.ReadDir(getPaths("local"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var refs []ClusterRef
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		legacyDir := getPaths("local", entry.Name())
+		if _, err := os.Stat(filepath.Join(legacyDir, "info")); err != nil {
+			// the type directory of the current layout
+			continue
+		}
+		name := strings.TrimPrefix(entry.Name(), ".")
+		ref := NewClusterRef("local", CLUSTER_TYPE_LOCAL, name, "")
+		if err := os.MkdirAll(getPaths("local", CLUSTER_TYPE_LOCAL), 0750); err != nil {
+			return err
+		}
+		if err := os.Rename(legacyDir, ref.Path()); err != nil {
+			return fmt.Errorf("unable to migrate the state of the cluster %s: %v", name, err)
+		}
+		logging.Note("Moved the state of the cluster", name, "to", ref.Path())
+		refs = append(refs, ref)
+	}
+
+	return updateIndex("local", func(clusters map[string]ClusterRef) {
+		for _, ref := range refs {
+			clusters[ref.ID()] = ref
+		}
+	})
+}
@@ -0,0 +1,26 @@
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+package utils
+
+import (
+	"context"
+	"time"
+)
+
+// Sleep pauses for the duration or till the ctx is cancelled, whichever happens first
+// it returns the error of the ctx when cancelled so that the polling loops can abort
+func Sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,441 @@
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kubesimplify/ksctl/api/logger"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	// CRED_PASSPHRASE_ENV the passphrase of the credentials for the non interactive runs e.g. CI
+	CRED_PASSPHRASE_ENV = "KSCTL_PASSPHRASE"
+	// CRED_KEY_FILE_ENV the file whose content is used as the passphrase of the credentials
+	CRED_KEY_FILE_ENV = "KSCTL_KEY_FILE"
+
+	credEncryptionVersion = 1
+	credKDF               = "scrypt"
+	credCipher            = "aes-256-gcm"
+)
+
+// scrypt parameters recommended for interactive logins
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	credKeyLen   = 32
+	credSaltSize = 16
+)
+
+// encryptedCred is the content of ~/.ksctl/cred/<provider>.json once encrypted
+// the key is derived from the passphrase with scrypt and a random salt per file
+type encryptedCred struct {
+	Encrypted int    `json:"ksctl_encrypted"`
+	KDF       string `json:"kdf"`
+	Cipher    string `json:"cipher"`
+	Salt      []byte `json:"salt"`
+	Nonce     []byte `json:"nonce"`
+	Data      []byte `json:"data"`
+}
+
+// ErrNoPassphrase is returned when the credentials cannot be encrypted or decrypted
+// as neither the passphrase nor the key file is provided and there is no terminal to ask for it
+var ErrNoPassphrase = fmt.Errorf("no passphrase for the credentials, set %s or %s", CRED_PASSPHRASE_ENV, CRED_KEY_FILE_ENV)
+
+var (
+	credKeyFile string
+
+	// passphrase is asked at most once per run
+	passphraseMutex sync.Mutex
+	passphrase      []byte
+)
+
+// SetCredentialKeyFile the key file used instead of the passphrase, it overrides KSCTL_KEY_FILE
+func SetCredentialKeyFile(path string) {
+	passphraseMutex.Lock()
+	defer passphraseMutex.Unlock()
+	credKeyFile = path
+	passphrase = nil
+}
+
+// credPassphrase in the order of KSCTL_PASSPHRASE, the key file and the prompt on the terminal
+func credPassphrase() ([]byte, error) {
+	passphraseMutex.Lock()
+	defer passphraseMutex.Unlock()
+	if passphrase != nil {
+		return passphrase, nil
+	}
+
+	if value, ok := os.LookupEnv(CRED_PASSPHRASE_ENV); ok && len(value) != 0 {
+		passphrase = []byte(value)
+		return passphrase, nil
+	}
+	keyFile := credKeyFile
+	if len(keyFile) == 0 {
+		keyFile = os.Getenv(CRED_KEY_FILE_ENV)
+	}
+	if len(keyFile) != 0 {
+		raw, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the key file of the credentials: %v", err)
+		}
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 {
+			return nil, fmt.Errorf("key file %s of the credentials is empty", keyFile)
+		}
+		passphrase = raw
+		return passphrase, nil
+	}
+	if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, ErrNoPassphrase
+	}
+	fmt.Print("    Enter the passphrase of the credentials-> ")
+	raw, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, ErrNoPassphrase
+	}
+	passphrase = raw
+	return passphrase, nil
+}
+
+func credKey(salt []byte) ([]byte, error) {
+	secret, err := credPassphrase()
+	if err != nil {
+		return nil, err
+	}
+	return scrypt.Key(secret, salt, scryptN, scryptR, scryptP, credKeyLen)
+}
+
+func credAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptCred seals the credentials, the name of the provider and profile is authenticated so the files cannot be swapped
+func encryptCred(name string, plain []byte) ([]byte, error) {
+	salt := make([]byte, credSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, err := credKey(salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := credAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return json.Marshal(encryptedCred{
+		Encrypted: credEncryptionVersion,
+		KDF:       credKDF,
+		Cipher:    credCipher,
+		Salt:      salt,
+		Nonce:     nonce,
+		Data:      aead.Seal(nil, nonce, plain, []byte(name)),
+	})
+}
+
+// isEncryptedCred whether the content of the credential file is encrypted
+func isEncryptedCred(raw []byte) (encryptedCred, bool) {
+	var cred encryptedCred
+	if err := json.Unmarshal(raw, &cred); err != nil || cred.Encrypted == 0 {
+		return cred, false
+	}
+	return cred, true
+}
+
+func decryptCred(name string, cred encryptedCred) ([]byte, error) {
+	if cred.Encrypted != credEncryptionVersion || cred.KDF != credKDF || cred.Cipher != credCipher {
+		return nil, fmt.Errorf("credentials of %s are encrypted with an unsupported scheme, upgrade ksctl to use them", name)
+	}
+	key, err := credKey(cred.Salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := credAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(cred.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("credentials of %s are corrupted", name)
+	}
+	plain, err := aead.Open(nil, cred.Nonce, cred.Data, []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt the credentials of %s: wrong passphrase or key file", name)
+	}
+	return plain, nil
+}
+
+// writeCred encrypts and saves the credentials of the profile, only the user can read the file
+func writeCred(provider, profile string, plain []byte) error {
+	sealed, err := encryptCred(credName(provider, profile), plain)
+	if err != nil {
+		return err
+	}
+	path := GetPath(CREDENTIAL_PATH, provider, profile)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, sealed, 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of the existing file
+	return os.Chmod(path, 0600)
+}
+
+// readCred returns the decrypted credentials of the profile
+// the plaintext files saved by the older versions are returned as they are
+func readCred(logging logger.Logger, provider, profile string) ([]byte, error) {
+	raw, err := os.ReadFile(GetPath(CREDENTIAL_PATH, provider, profile))
+	if err != nil {
+		return nil, err
+	}
+	name := credName(provider, profile)
+	cred, ok := isEncryptedCred(raw)
+	if !ok {
+		logging.Warn(fmt.Sprintf("credentials of %s are not encrypted, run `ksctl cred encrypt`", name))
+		return raw, nil
+	}
+	return decryptCred(name, cred)
+}
+
+// EncryptCredentials encrypts the plaintext credential files saved by the older versions of ksctl
+// and returns the providers (provider/profile for the named profiles) whose credentials were encrypted
+func EncryptCredentials(logging logger.Logger) ([]string, error) {
+	files, err := filepath.Glob(GetPath(CREDENTIAL_PATH, "*"))
+	if err != nil {
+		return nil, err
+	}
+	profileFiles, err := filepath.Glob(GetPath(CREDENTIAL_PATH, "*", "*"))
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, profileFiles...)
+	sort.Strings(files)
+
+	var encrypted []string
+	var failed []string
+	for _, file := range files {
+		provider, profile := strings.TrimSuffix(filepath.Base(file), ".json"), DEFAULT_PROFILE
+		if dir := filepath.Dir(file); dir != filepath.Dir(GetPath(CREDENTIAL_PATH, "")) {
+			provider, profile = filepath.Base(dir), provider
+		}
+		name := credName(provider, profile)
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return encrypted, err
+		}
+		if _, ok := isEncryptedCred(raw); ok {
+			logging.Debug("credentials are already encrypted", name)
+			continue
+		}
+		if !json.Valid(raw) {
+			logging.Warn("skipping the invalid credentials", file)
+			failed = append(failed, name)
+			continue
+		}
+		if err := writeCred(provider, profile, raw); err != nil {
+			if errors.Is(err, ErrNoPassphrase) {
+				return encrypted, err
+			}
+			logging.Err(fmt.Sprintf("unable to encrypt the credentials of %s: %v", name, err))
+			failed = append(failed, name)
+			continue
+		}
+		logging.Info("🔒 credentials", name)
+		encrypted = append(encrypted, name)
+	}
+	if len(failed) != 0 {
+		return encrypted, fmt.Errorf("unable to encrypt the credentials of %s", strings.Join(failed, ", "))
+	}
+	return encrypted, nil
+}
+
+// CredentialField is a value of the credentials of a provider, the secrets are never printed
+type CredentialField struct {
+	// Key in the credential file, the flag of `ksctl cred set` is the key with - instead of _
+	Key string
+	// Env is the environment variable the value is read from
+	Env    string
+	Prompt string
+	Secret bool
+	// Optional fields can be left empty
+	Optional bool
+}
+
+// Flag the name of the flag of the field
+func (field CredentialField) Flag() string {
+	return strings.ReplaceAll(field.Key, "_", "-")
+}
+
+// CredentialVerifier checks the credentials against the provider with a cheap read only call
+type CredentialVerifier func(ctx context.Context, cred map[string]string) error
+
+// CredentialType describes the credentials of a provider
+type CredentialType struct {
+	Fields []CredentialField
+	Verify CredentialVerifier
+}
+
+var credentialTypes = map[string]CredentialType{}
+
+// RegisterCredentials makes the credentials of the provider manageable by `ksctl cred`
+// it is meant to be called from the init() of the provider package
+func RegisterCredentials(provider string, credType CredentialType) {
+	if _, dup := credentialTypes[provider]; dup {
+		panic("credentials registered twice: " + provider)
+	}
+	credentialTypes[provider] = credType
+}
+
+// GetCredentialType returns the registered credentials of the provider
+func GetCredentialType(provider string) (CredentialType, error) {
+	credType, ok := credentialTypes[strings.ToLower(provider)]
+	if !ok {
+		return credType, fmt.Errorf("invalid provider: %s", provider)
+	}
+	return credType, nil
+}
+
+// CredentialProviders returns the providers with registered credentials in sorted order
+func CredentialProviders() []string {
+	names := make([]string, 0, len(credentialTypes))
+	for name := range credentialTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetCred verifies the credentials against the provider, unless verify is false, and saves them to the active profile
+func SetCred(ctx context.Context, logging logger.Logger, provider string, cred map[string]string, verify bool) error {
+	credType, err := GetCredentialType(provider)
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for _, field := range credType.Fields {
+		if len(cred[field.Key]) == 0 && !field.Optional {
+			missing = append(missing, field.Key)
+		}
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("missing %s of the %s credentials", strings.Join(missing, ", "), provider)
+	}
+	if verify && credType.Verify != nil {
+		if err := credType.Verify(ctx, cred); err != nil {
+			return fmt.Errorf("invalid %s credentials: %v", provider, err)
+		}
+		logging.Info("✅ credentials", provider)
+	}
+	return SaveCred(logging, cred, provider)
+}
+
+// VerifyCred verifies the saved credentials of the active profile of the provider
+func VerifyCred(ctx context.Context, logging logger.Logger, provider string) error {
+	credType, err := GetCredentialType(provider)
+	if err != nil {
+		return err
+	}
+	cred, err := GetCred(logging, provider)
+	if err != nil {
+		return err
+	}
+	if credType.Verify == nil {
+		return fmt.Errorf("verification of the %s credentials is not supported", provider)
+	}
+	if err := credType.Verify(ctx, cred); err != nil {
+		return fmt.Errorf("invalid %s credentials: %v", provider, err)
+	}
+	return nil
+}
+
+// RemoveCred deletes the credentials of the profile, the default profile of the provider is reset when it is the removed one
+func RemoveCred(provider, profile string) error {
+	if err := os.Remove(GetPath(CREDENTIAL_PATH, provider, profile)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("profile %s of %s not found", profile, provider)
+		}
+		return err
+	}
+	settings, err := readProfileSettings()
+	if err != nil || settings.Defaults[provider] != profile {
+		return err
+	}
+	delete(settings.Defaults, provider)
+	return writeProfileSettings(settings)
+}
+
+// CredentialStatus reports a saved profile without its secrets
+type CredentialStatus struct {
+	Provider  string `json:"provider"`
+	Profile   string `json:"profile"`
+	Default   bool   `json:"default"`
+	Encrypted bool   `json:"encrypted"`
+	// Env is true when the environment variables of the provider are set, `ksctl cred set` reads them
+	Env bool `json:"env"`
+}
+
+// ListCred returns the saved profiles of every provider, they are not decrypted
+func ListCred() ([]CredentialStatus, error) {
+	var statuses []CredentialStatus
+	for _, provider := range CredentialProviders() {
+		profiles, err := Profiles(provider)
+		if err != nil {
+			return nil, err
+		}
+		env := true
+		for _, field := range credentialTypes[provider].Fields {
+			if !field.Optional {
+				env = env && len(os.Getenv(field.Env)) != 0
+			}
+		}
+		defaultProfile := DefaultProfile(provider)
+		for _, profile := range profiles {
+			raw, err := os.ReadFile(GetPath(CREDENTIAL_PATH, provider, profile))
+			if err != nil {
+				return nil, err
+			}
+			_, encrypted := isEncryptedCred(raw)
+			statuses = append(statuses, CredentialStatus{
+				Provider:  provider,
+				Profile:   profile,
+				Default:   profile == defaultProfile,
+				Encrypted: encrypted,
+				Env:       env,
+			})
+		}
+	}
+	return statuses, nil
+}
@@ -0,0 +1,368 @@
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	DISTRIBUTION_K3S     = "k3s"
+	DISTRIBUTION_RKE2    = "rke2"
+	DISTRIBUTION_KUBEADM = "kubeadm"
+)
+
+// Distribution supplies the scripts which install kubernetes on the nodes of the HA clusters
+// the scripts run as root, the providers whose ssh user is not root run them with sudo
+type Distribution interface {
+	Name() string
+	// ExternalDatastore whether the controlplanes keep the state of the cluster in the database
+	// created by ksctl, the other distributions run etcd on the controlplanes
+	ExternalDatastore() bool
+	// Ports of the controlplanes which the loadbalancer forwards
+	Ports() []int
+
+	// InstallFirstControlPlane script installing the first controlplane which creates the cluster
+	InstallFirstControlPlane(DistributionConfig) string
+	// Token script printing the secret the other nodes join the cluster with, run on the first controlplane
+	Token() string
+	// TokenExpires whether the token saved in the state stops being accepted after a while
+	TokenExpires() bool
+	// JoinControlPlane script joining the other controlplanes through the loadbalancer
+	JoinControlPlane(DistributionConfig) string
+	// JoinWorker script joining the worker nodes through the loadbalancer, it is run on the first boot
+	JoinWorker(DistributionConfig) string
+	// Kubeconfig script printing the admin kubeconfig with the server https://127.0.0.1:6443 and
+	// the cluster, user and context named default, the providers point it at the loadbalancer
+	Kubeconfig() string
+}
+
+// DistributionConfig what the scripts of the distribution know about the cluster
+type DistributionConfig struct {
+	// LoadBalancerPublicIP is added to the certificate of the apiserver
+	LoadBalancerPublicIP string
+	// LoadBalancerPrivateIP the nodes join the cluster through it
+	LoadBalancerPrivateIP string
+	// DatastoreEndpoint of the database of the cluster when the distribution uses it
+	DatastoreEndpoint string
+	// Token printed by the Token script, empty for the first controlplane
+	Token string
+}
+
+// JoinToken the token the nodes added to the existing cluster join with
+// it is the saved token unless it expires, then fetch prints a new one on the first controlplane
+func JoinToken(dist Distribution, saved string, fetch func() (string, error)) (string, error) {
+	if !dist.TokenExpires() && len(saved) != 0 {
+		return saved, nil
+	}
+	token, err := fetch()
+	if err != nil {
+		return "", err
+	}
+	if len(token) == 0 {
+		return "", fmt.Errorf("🚨 Cannot retrieve %s token", dist.Name())
+	}
+	return token, nil
+}
+
+// Distributions the names accepted by NewDistribution
+func Distributions() []string {
+	return []string{DISTRIBUTION_K3S, DISTRIBUTION_RKE2, DISTRIBUTION_KUBEADM}
+}
+
+// NewDistribution returns the distribution of the name installing the kubernetes version,
+// k3s when the name is empty as the clusters created before the distribution was recorded in their state use k3s
+// k3s and rke2 install their latest version when the version is empty as it wasn't recorded either
+func NewDistribution(name, version string) (Distribution, error) {
+	var release KubernetesRelease
+	if len(version) != 0 {
+		var err error
+		if release, err = GetKubernetesRelease(version); err != nil {
+			return nil, err
+		}
+	}
+	switch strings.ToLower(name) {
+	case "", DISTRIBUTION_K3S:
+		return k3s{version: release.K3s}, nil
+	case DISTRIBUTION_RKE2:
+		return rke2{version: release.RKE2}, nil
+	case DISTRIBUTION_KUBEADM:
+		if len(release.Kubeadm) == 0 {
+			release, _ = GetKubernetesRelease(DEFAULT_KUBERNETES_VERSION)
+		}
+		return kubeadm{version: release.Kubeadm, minorVersion: "v" + release.Version}, nil
+	}
+	return nil, fmt.Errorf("invalid distribution: %s, expected one of %s", name, strings.Join(Distributions(), ", "))
+}
+
+// k3s keeps the state of the cluster in the mysql database of the cluster
+type k3s struct {
+	// version of k3s e.g. v1.26.4+k3s1, the latest one when empty
+	version string
+}
+
+// install the exports pinning the version of k3s followed by the installer
+func (dist k3s) install() string {
+	if len(dist.version) == 0 {
+		return "curl -sfL https://get.k3s.io | sh -s - "
+	}
+	return fmt.Sprintf("export INSTALL_K3S_VERSION='%s'\ncurl -sfL https://get.k3s.io | sh -s - ", dist.version)
+}
+
+func (k3s) Name() string            { return DISTRIBUTION_K3S }
+func (k3s) ExternalDatastore() bool { return true }
+func (k3s) Ports() []int            { return []int{6443} }
+func (k3s) TokenExpires() bool      { return false }
+
+func (dist k3s) InstallFirstControlPlane(config DistributionConfig) string {
+	return fmt.Sprintf(`#!/bin/bash
+export K3S_DATASTORE_ENDPOINT='%s'
+export INSTALL_K3S_EXEC='--tls-san %s'
+%sserver \
+	--node-taint CriticalAddonsOnly=true:NoExecute
+`, config.DatastoreEndpoint, config.LoadBalancerPublicIP, dist.install())
+}
+
+func (k3s) Token() string {
+	return `#!/bin/bash
+cat /var/lib/rancher/k3s/server/token
+`
+}
+
+func (dist k3s) JoinControlPlane(config DistributionConfig) string {
+	return fmt.Sprintf(`#!/bin/bash
+export SECRET='%s'
+export K3S_DATASTORE_ENDPOINT='%s'
+export INSTALL_K3S_EXEC='--tls-san %s'
+%sserver \
+	--token=$SECRET \
+	--node-taint CriticalAddonsOnly=true:NoExecute
+`, config.Token, config.DatastoreEndpoint, config.LoadBalancerPublicIP, dist.install())
+}
+
+func (dist k3s) JoinWorker(config DistributionConfig) string {
+	return fmt.Sprintf(`#!/bin/bash
+export SECRET='%s'
+%sagent --token=$SECRET --server https://%s:6443
+`, config.Token, dist.install(), config.LoadBalancerPrivateIP)
+}
+
+func (k3s) Kubeconfig() string {
+	return `#!/bin/bash
+cat /etc/rancher/k3s/k3s.yaml`
+}
+
+// rke2 runs etcd on the controlplanes, the nodes register through its supervisor port 9345
+type rke2 struct {
+	// version of rke2 e.g. v1.26.4+rke2r1, the latest one when empty
+	version string
+}
+
+// install runs the installer of the type of node, server or agent
+func (dist rke2) install(installType string) string {
+	env := "INSTALL_RKE2_TYPE=" + installType
+	if len(dist.version) != 0 {
+		env += fmt.Sprintf(" INSTALL_RKE2_VERSION='%s'", dist.version)
+	}
+	return "curl -sfL https://get.rke2.io | " + env + " sh -\n"
+}
+
+func (rke2) Name() string            { return DISTRIBUTION_RKE2 }
+func (rke2) ExternalDatastore() bool { return false }
+func (rke2) Ports() []int            { return []int{6443, 9345} }
+func (rke2) TokenExpires() bool      { return false }
+
+// rke2Config the config.yaml of rke2, the first controlplane has no server to join
+func rke2Config(config DistributionConfig, server bool) string {
+	script := "mkdir -p /etc/rancher/rke2\ncat <<EOF > /etc/rancher/rke2/config.yaml\n"
+	if len(config.Token) != 0 {
+		script += fmt.Sprintf("server: https://%s:9345\ntoken: %s\n", config.LoadBalancerPrivateIP, config.Token)
+	}
+	if server {
+		script += fmt.Sprintf("tls-san:\n  - %s\n  - %s\nnode-taint:\n  - CriticalAddonsOnly=true:NoExecute\n",
+			config.LoadBalancerPublicIP, config.LoadBalancerPrivateIP)
+	}
+	return script + "EOF\n"
+}
+
+func (dist rke2) InstallFirstControlPlane(config DistributionConfig) string {
+	config.Token = ""
+	return `#!/bin/bash
+` + rke2Config(config, true) + dist.install("server") + `systemctl enable rke2-server.service
+systemctl start rke2-server.service
+`
+}
+
+func (rke2) Token() string {
+	return `#!/bin/bash
+cat /var/lib/rancher/rke2/server/node-token
+`
+}
+
+func (dist rke2) JoinControlPlane(config DistributionConfig) string {
+	return `#!/bin/bash
+` + rke2Config(config, true) + dist.install("server") + `systemctl enable rke2-server.service
+systemctl start rke2-server.service
+`
+}
+
+func (dist rke2) JoinWorker(config DistributionConfig) string {
+	return `#!/bin/bash
+` + rke2Config(config, false) + dist.install("agent") + `systemctl enable rke2-agent.service
+systemctl start rke2-agent.service
+`
+}
+
+func (rke2) Kubeconfig() string {
+	return `#!/bin/bash
+cat /etc/rancher/rke2/rke2.yaml`
+}
+
+// kubeadm runs stacked etcd on the controlplanes with containerd and flannel
+// its token is the bootstrap token, the hash of the CA and the key of the uploaded certificates
+// the uploaded certificates expire after 2 hours, the Token script uploads them again
+type kubeadm struct {
+	// version of kubernetes e.g. v1.26.4
+	version string
+	// minorVersion of the packages of pkgs.k8s.io e.g. v1.26
+	minorVersion string
+}
+
+func (kubeadm) Name() string            { return DISTRIBUTION_KUBEADM }
+func (kubeadm) ExternalDatastore() bool { return false }
+func (kubeadm) Ports() []int            { return []int{6443} }
+func (kubeadm) TokenExpires() bool      { return true }
+
+// prerequisites installs containerd and the packages of kubelet, kubeadm and kubectl of the version as documented by kubeadm
+func (dist kubeadm) prerequisites() string {
+	return fmt.Sprintf(`#!/bin/bash
+set -e
+swapoff -a
+sed -i '/ swap / s/^/#/' /etc/fstab
+
+cat <<EOF > /etc/modules-load.d/k8s.conf
+overlay
+br_netfilter
+EOF
+modprobe overlay
+modprobe br_netfilter
+
+cat <<EOF > /etc/sysctl.d/k8s.conf
+net.bridge.bridge-nf-call-iptables  = 1
+net.bridge.bridge-nf-call-ip6tables = 1
+net.ipv4.ip_forward                 = 1
+EOF
+sysctl --system
+
+export DEBIAN_FRONTEND=noninteractive
+apt-get update
+apt-get install -y containerd apt-transport-https ca-certificates curl gpg
+mkdir -p /etc/containerd
+containerd config default | sed 's/SystemdCgroup = false/SystemdCgroup = true/' > /etc/containerd/config.toml
+systemctl restart containerd
+
+mkdir -p /etc/apt/keyrings
+curl -fsSL https://pkgs.k8s.io/core:/stable:/%s/deb/Release.key | gpg --dearmor --yes -o /etc/apt/keyrings/kubernetes-apt-keyring.gpg
+echo 'deb [signed-by=/etc/apt/keyrings/kubernetes-apt-keyring.gpg] https://pkgs.k8s.io/core:/stable:/%s/deb/ /' > /etc/apt/sources.list.d/kubernetes.list
+apt-get update
+apt-get install -y kubelet=%[3]s-* kubeadm=%[3]s-* kubectl=%[3]s-*
+apt-mark hold kubelet kubeadm kubectl
+`, dist.minorVersion, dist.minorVersion, strings.TrimPrefix(dist.version, "v"))
+}
+
+// kubeadmToken splits the token printed by the Token script
+func kubeadmToken(token string) (bootstrapToken, caCertHash, certificateKey string) {
+	fields := append(strings.Fields(token), "", "", "")
+	return fields[0], fields[1], fields[2]
+}
+
+func (dist kubeadm) InstallFirstControlPlane(config DistributionConfig) string {
+	return dist.prerequisites() + fmt.Sprintf(`
+kubeadm certs certificate-key > /etc/kubernetes/ksctl-certificate-key
+kubeadm init \
+	--control-plane-endpoint %s:6443 \
+	--apiserver-cert-extra-sans %s \
+	--pod-network-cidr 10.244.0.0/16 \
+	--kubernetes-version %s \
+	--upload-certs \
+	--certificate-key $(cat /etc/kubernetes/ksctl-certificate-key)
+kubectl --kubeconfig /etc/kubernetes/admin.conf apply -f %s
+`, config.LoadBalancerPrivateIP, config.LoadBalancerPublicIP, dist.version, FLANNEL_MANIFEST)
+}
+
+// Token the certificates are uploaded again so that they are valid for 2 more hours, the bootstrap
+// token expires along with them and the ones created by the earlier runs are deleted as the token is saved in the state
+func (kubeadm) Token() string {
+	return `#!/bin/bash
+set -e
+KEY=$(cat /etc/kubernetes/ksctl-certificate-key)
+kubeadm init phase upload-certs --upload-certs --certificate-key $KEY > /dev/null
+kubeadm token list | awk '/ksctl-join/ {print $1}' | xargs -r -n1 kubeadm token delete > /dev/null
+TOKEN=$(kubeadm token create --ttl 2h --description ksctl-join)
+HASH=$(openssl x509 -pubkey -in /etc/kubernetes/pki/ca.crt | openssl rsa -pubin -outform der 2>/dev/null | sha256sum | cut -d' ' -f1)
+echo "$TOKEN sha256:$HASH $KEY"
+`
+}
+
+func (dist kubeadm) JoinControlPlane(config DistributionConfig) string {
+	token, hash, key := kubeadmToken(config.Token)
+	return dist.prerequisites() + fmt.Sprintf(`
+kubeadm join %s:6443 \
+	--token %s \
+	--discovery-token-ca-cert-hash %s \
+	--control-plane \
+	--certificate-key %s
+`, config.LoadBalancerPrivateIP, token, hash, key)
+}
+
+func (dist kubeadm) JoinWorker(config DistributionConfig) string {
+	token, hash, _ := kubeadmToken(config.Token)
+	return dist.prerequisites() + fmt.Sprintf(`
+kubeadm join %s:6443 \
+	--token %s \
+	--discovery-token-ca-cert-hash %s
+`, config.LoadBalancerPrivateIP, token, hash)
+}
+
+// Kubeconfig the admin.conf points at the private IP of the loadbalancer and names everything kubernetes
+func (kubeadm) Kubeconfig() string {
+	return `#!/bin/bash
+sed -e 's#server: https://.*#server: https://127.0.0.1:6443#' \
+	-e 's/kubernetes-admin@kubernetes/default/' \
+	-e 's/kubernetes-admin/default/' \
+	-e 's/: kubernetes$/: default/' \
+	/etc/kubernetes/admin.conf`
+}
+
+// HAProxyConfig the haproxy.cfg of the loadbalancer of the HA cluster
+// every port of the distribution is forwarded to the same port of the controlplanes
+func HAProxyConfig(controlPlaneIPs []string, ports []int) string {
+	var config strings.Builder
+	for _, port := range ports {
+		fmt.Fprintf(&config, `frontend kubernetes-frontend-%d
+  bind *:%d
+  mode tcp
+  option tcplog
+  timeout client 10s
+  default_backend kubernetes-backend-%d
+
+backend kubernetes-backend-%d
+  timeout connect 10s
+  timeout server 10s
+  mode tcp
+  option tcp-check
+  balance roundrobin
+`, port, port, port, port)
+		for index, controlPlaneIP := range controlPlaneIPs {
+			fmt.Fprintf(&config, "  server controlplane-%d %s:%d check\n", index+1, controlPlaneIP, port)
+		}
+		config.WriteString("\n")
+	}
+	return config.String()
+}
@@ -0,0 +1,70 @@
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+package utils
+
+const (
+	// DRIFT_MISSING the resource is recorded in the state management file but is not found in the provider
+	DRIFT_MISSING = "missing"
+	// DRIFT_UNEXPECTED the resource follows the naming of the cluster but is not recorded in its state
+	DRIFT_UNEXPECTED = "unexpected"
+)
+
+// Drift a resource of the cluster whose state differs from the provider
+type Drift struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name,omitempty"`
+	ID     string `json:"id,omitempty"`
+	Status string `json:"status"`
+}
+
+// DriftReport the result of comparing the resources recorded in the state of the cluster with the provider
+type DriftReport struct {
+	ClusterName string `json:"cluster_name"`
+	Provider    string `json:"provider"`
+	Type        string `json:"type"`
+	Region      string `json:"region,omitempty"`
+	// Checked is the number of the recorded resources looked up in the provider
+	Checked int     `json:"checked"`
+	Drifts  []Drift `json:"drifts,omitempty"`
+	// Fixed is true when the missing resources were removed from the state
+	Fixed bool `json:"fixed"`
+}
+
+// Check records the resource as missing when it is not found, the resource which is
+// neither named nor has an ID was never created so it is not checked
+func (report *DriftReport) Check(kind, name, id string, found bool) bool {
+	if len(name) == 0 && len(id) == 0 {
+		return true
+	}
+	report.Checked++
+	if !found {
+		report.Drifts = append(report.Drifts, Drift{Kind: kind, Name: name, ID: id, Status: DRIFT_MISSING})
+	}
+	return found
+}
+
+// Unexpected records the resource found in the provider which is not in the state
+func (report *DriftReport) Unexpected(kind, name, id string) {
+	report.Drifts = append(report.Drifts, Drift{Kind: kind, Name: name, ID: id, Status: DRIFT_UNEXPECTED})
+}
+
+// Missing returns the recorded resources which are not found in the provider
+func (report DriftReport) Missing() []Drift {
+	var missing []Drift
+	for _, drift := range report.Drifts {
+		if drift.Status == DRIFT_MISSING {
+			missing = append(missing, drift)
+		}
+	}
+	return missing
+}
+
+// HasDrift whether the state of the cluster differs from the provider
+func (report DriftReport) HasDrift() bool {
+	return len(report.Drifts) != 0
+}
@@ -0,0 +1,82 @@
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/kubesimplify/ksctl/api/logger"
+)
+
+// OrphanedResource a resource following the naming of ksctl which no state management file points at
+// it is left by a failed creation or a partial deletion
+type OrphanedResource struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	ID     string `json:"id,omitempty"`
+	Region string `json:"region"`
+	// Cluster is the name of the cluster the resource is named after
+	Cluster string `json:"cluster"`
+	// MonthlyCost is the estimated cost in USD, 0 when it is free or not known
+	MonthlyCost float64 `json:"monthly_cost"`
+}
+
+// GarbageCollector finds and deletes the orphaned resources of a provider
+type GarbageCollector struct {
+	// Orphaned lists the orphaned resources in the region, in every region when it is empty
+	Orphaned func(ctx context.Context, logging logger.Logger, region string) ([]OrphanedResource, error)
+	// Delete deletes the resources returned by Orphaned
+	Delete func(ctx context.Context, logging logger.Logger, resources []OrphanedResource) error
+}
+
+var (
+	garbageCollectors      = map[string]GarbageCollector{}
+	garbageCollectorsMutex sync.Mutex
+)
+
+// RegisterGarbageCollector makes the garbage collector of the provider available to `ksctl gc`
+func RegisterGarbageCollector(provider string, collector GarbageCollector) {
+	garbageCollectorsMutex.Lock()
+	defer garbageCollectorsMutex.Unlock()
+	garbageCollectors[provider] = collector
+}
+
+// GetGarbageCollector returns the registered garbage collector of the provider
+func GetGarbageCollector(provider string) (GarbageCollector, error) {
+	garbageCollectorsMutex.Lock()
+	defer garbageCollectorsMutex.Unlock()
+	collector, ok := garbageCollectors[provider]
+	if !ok {
+		return GarbageCollector{}, fmt.Errorf("garbage collection is not supported for the provider: %s", provider)
+	}
+	return collector, nil
+}
+
+// GarbageCollectedProviders returns the names of the providers with a garbage collector in sorted order
+func GarbageCollectedProviders() []string {
+	garbageCollectorsMutex.Lock()
+	defer garbageCollectorsMutex.Unlock()
+	names := make([]string, 0, len(garbageCollectors))
+	for name := range garbageCollectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MonthlyCost the estimated cost of the resources in USD
+func MonthlyCost(resources []OrphanedResource) float64 {
+	total := 0.0
+	for _, resource := range resources {
+		total += resource.MonthlyCost
+	}
+	return total
+}
@@ -0,0 +1,450 @@
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kubesimplify/ksctl/api/logger"
+	"sigs.k8s.io/yaml"
+)
+
+// kubeconfig keeps every field of the file so that the entries not owned by ksctl are written back as they were
+type kubeconfig map[string]interface{}
+
+// the lists of the kubeconfig with the key of the entry in every item e.g. clusters[].cluster
+var kubeconfigLists = map[string]string{"clusters": "cluster", "users": "user", "contexts": "context"}
+
+// DefaultKubeconfigPath the kubeconfig used by kubectl when KUBECONFIG is not set
+func DefaultKubeconfigPath() string {
+	return filepath.Join(GetUserName(), ".kube", "config")
+}
+
+// KubeconfigContextName the name of the context, cluster and user of the cluster in the merged kubeconfig
+// the provider and the ID keep it unique as the kubeconfigs of the clusters all use the same names e.g. default
+func KubeconfigContextName(ref ClusterRef) string {
+	return "ksctl-" + ref.Provider + "-" + ref.ID()
+}
+
+// KubeconfigPath the kubeconfig of the cluster on the local disk, it is fetched from the state store when needed
+// the local clusters are never kept in the state store
+func KubeconfigPath(ref ClusterRef) (string, error) {
+	if ref.Provider != "local" {
+		return FetchStateFile(ref.Provider, ref.Type, ref.ID(), "config")
+	}
+	path := ref.Path("config")
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func readKubeconfig(path string) (kubeconfig, error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return kubeconfig{"apiVersion": "v1", "kind": "Config"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	config := kubeconfig{}
+	if err := yaml.Unmarshal(raw, &config); err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// writeKubeconfig replaces the file in one step so that kubectl never reads a partial kubeconfig
+func writeKubeconfig(path string, config kubeconfig) error {
+	raw, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".ksctl.tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (config kubeconfig) list(name string) []interface{} {
+	list, _ := config[name].([]interface{})
+	return list
+}
+
+// entry returns the body of the named item of the list e.g. the cluster of clusters[name]
+func (config kubeconfig) entry(list, name string) map[string]interface{} {
+	for _, item := range config.list(list) {
+		if item, ok := item.(map[string]interface{}); ok && item["name"] == name {
+			body, _ := item[kubeconfigLists[list]].(map[string]interface{})
+			return body
+		}
+	}
+	return nil
+}
+
+// setEntry replaces the named item of the list, it is appended when not present
+func (config kubeconfig) setEntry(list, name string, body map[string]interface{}) {
+	item := map[string]interface{}{"name": name, kubeconfigLists[list]: body}
+	items := config.list(list)
+	for i, existing := range items {
+		if existing, ok := existing.(map[string]interface{}); ok && existing["name"] == name {
+			items[i] = item
+			return
+		}
+	}
+	config[list] = append(items, item)
+}
+
+// removeEntries removes the items of the list whose names match, it reports whether any was removed
+func (config kubeconfig) removeEntries(list string, match func(name string) bool) bool {
+	items := config.list(list)
+	kept := items[:0:0]
+	for _, existing := range items {
+		if existing, ok := existing.(map[string]interface{}); ok {
+			if name, _ := existing["name"].(string); match(name) {
+				continue
+			}
+		}
+		kept = append(kept, existing)
+	}
+	if len(kept) == len(items) {
+		return false
+	}
+	config[list] = kept
+	return true
+}
+
+// currentContext the context used by the kubeconfig with its cluster and user
+// the first context is used when the current one is not set
+func (config kubeconfig) currentContext() (name string, context, cluster, user map[string]interface{}, err error) {
+	name, _ = config["current-context"].(string)
+	if len(name) == 0 {
+		if contexts := config.list("contexts"); len(contexts) != 0 {
+			if item, ok := contexts[0].(map[string]interface{}); ok {
+				name, _ = item["name"].(string)
+			}
+		}
+	}
+	context = config.entry("contexts", name)
+	if context == nil {
+		return "", nil, nil, nil, fmt.Errorf("no context found in the kubeconfig")
+	}
+	clusterName, _ := context["cluster"].(string)
+	userName, _ := context["user"].(string)
+	cluster, user = config.entry("clusters", clusterName), config.entry("users", userName)
+	if cluster == nil || user == nil {
+		return "", nil, nil, nil, fmt.Errorf("the context %s of the kubeconfig has no cluster or user", name)
+	}
+	return name, context, cluster, user, nil
+}
+
+// MergeKubeconfig adds the cluster, user and context of the kubeconfig of a cluster to the kubeconfig at path
+// all named after the given name and makes it the current context, the older entries of the name are replaced
+func MergeKubeconfig(path, name string, clusterKubeconfig []byte) error {
+	source := kubeconfig{}
+	if err := yaml.Unmarshal(clusterKubeconfig, &source); err != nil {
+		return fmt.Errorf("invalid kubeconfig of %s: %w", name, err)
+	}
+	_, current, cluster, user, err := source.currentContext()
+	if err != nil {
+		return fmt.Errorf("invalid kubeconfig of %s: %w", name, err)
+	}
+
+	config, err := readKubeconfig(path)
+	if err != nil {
+		return err
+	}
+	merged := map[string]interface{}{"cluster": name, "user": name}
+	if namespace, ok := current["namespace"]; ok {
+		merged["namespace"] = namespace
+	}
+	config.setEntry("clusters", name, cluster)
+	config.setEntry("users", name, user)
+	config.setEntry("contexts", name, merged)
+	config["current-context"] = name
+	return writeKubeconfig(path, config)
+}
+
+// RemoveKubeconfig removes the clusters, users and contexts of the cluster from the kubeconfig at path
+// including the ones of its scoped kubeconfigs, the current context is cleared when it was one of them
+// and the missing file is left as it is
+func RemoveKubeconfig(path string, ref ClusterRef) (bool, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	config, err := readKubeconfig(path)
+	if err != nil {
+		return false, err
+	}
+	name := KubeconfigContextName(ref)
+	ofCluster := func(entry string) bool {
+		return entry == name || strings.HasPrefix(entry, name+"-")
+	}
+	removed := false
+	for list := range kubeconfigLists {
+		if config.removeEntries(list, ofCluster) {
+			removed = true
+		}
+	}
+	if current, _ := config["current-context"].(string); ofCluster(current) {
+		config["current-context"] = ""
+		removed = true
+	}
+	if !removed {
+		return false, nil
+	}
+	return true, writeKubeconfig(path, config)
+}
+
+// SwitchKubeconfig merges the kubeconfig saved for the cluster into the default kubeconfig
+// and switches kubectl to the cluster
+func SwitchKubeconfig(logging logger.Logger, ref ClusterRef) error {
+	raw, err := os.ReadFile(ref.Path("config"))
+	if err != nil {
+		return fmt.Errorf("unable to read the kubeconfig of the cluster %s: %w", ref.Name, err)
+	}
+	path := DefaultKubeconfigPath()
+	name := KubeconfigContextName(ref)
+	if err := MergeKubeconfig(path, name, raw); err != nil {
+		return err
+	}
+	logging.Info("Switched to the context "+name, path)
+	if env := os.Getenv("KUBECONFIG"); len(env) != 0 && env != path {
+		logging.Warn("KUBECONFIG is set so kubectl doesn't use "+path, "unset KUBECONFIG to use the cluster")
+	}
+	return nil
+}
+
+// ForgetKubeconfig removes the entries of the deleted cluster from the default kubeconfig
+// a failure only warns as the cluster is already deleted
+func ForgetKubeconfig(logging logger.Logger, ref ClusterRef) {
+	path := DefaultKubeconfigPath()
+	removed, err := RemoveKubeconfig(path, ref)
+	if err != nil {
+		logging.Warn("Unable to remove the cluster from the kubeconfig", err.Error())
+		return
+	}
+	if removed {
+		logging.Info("Removed the context "+KubeconfigContextName(ref), path)
+	}
+}
+
+// KubeconfigScope the service account which a scoped kubeconfig authenticates as
+// it gets the cluster role only in its namespace so that it can be handed to the users of the cluster
+type KubeconfigScope struct {
+	User      string
+	Namespace string
+	// Role the cluster role bound in the namespace e.g. edit or view
+	Role string
+	// Duration the token is valid for, the api server may shorten it
+	Duration time.Duration
+}
+
+var kubernetesNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// Validate the names are used for the service account and the namespace so they must be DNS labels
+func (scope KubeconfigScope) Validate() error {
+	for field, value := range map[string]string{"user": scope.User, "namespace": scope.Namespace, "role": scope.Role} {
+		if len(value) == 0 || len(value) > 63 || !kubernetesNameRegex.MatchString(value) {
+			return fmt.Errorf("invalid %s %q, it must be a lowercase DNS label", field, value)
+		}
+	}
+	// the api server doesn't issue the tokens for less than 10 minutes
+	if scope.Duration < 10*time.Minute {
+		return fmt.Errorf("invalid duration %v, it must be at least 10m", scope.Duration)
+	}
+	return nil
+}
+
+// kubeAPI the api server of the cluster reached with the credentials of a kubeconfig
+type kubeAPI struct {
+	server string
+	token  string
+	client *http.Client
+}
+
+var errAlreadyExists = errors.New("already exists")
+
+// newKubeAPI only the certificates and the tokens are supported as ksctl doesn't run the exec plugins e.g. of EKS
+func newKubeAPI(cluster, user map[string]interface{}) (*kubeAPI, error) {
+	server, _ := cluster["server"].(string)
+	if len(server) == 0 {
+		return nil, fmt.Errorf("the kubeconfig has no server")
+	}
+	tlsConfig := &tls.Config{}
+	if insecure, _ := cluster["insecure-skip-tls-verify"].(bool); insecure {
+		tlsConfig.InsecureSkipVerify = true
+	}
+	if data, _ := cluster["certificate-authority-data"].(string); len(data) != 0 {
+		ca, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate-authority-data: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("invalid certificate-authority-data")
+		}
+	}
+
+	api := &kubeAPI{server: strings.TrimSuffix(server, "/")}
+	api.token, _ = user["token"].(string)
+	certData, _ := user["client-certificate-data"].(string)
+	keyData, _ := user["client-key-data"].(string)
+	if len(certData) != 0 && len(keyData) != 0 {
+		cert, err := base64.StdEncoding.DecodeString(certData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client-certificate-data: %w", err)
+		}
+		key, err := base64.StdEncoding.DecodeString(keyData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client-key-data: %w", err)
+		}
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{pair}
+	}
+	if len(api.token) == 0 && len(tlsConfig.Certificates) == 0 {
+		return nil, fmt.Errorf("the kubeconfig has no token or client certificate, other kinds of credentials are not supported")
+	}
+	api.client = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}, Timeout: 30 * time.Second}
+	return api, nil
+}
+
+// create posts the object to the path, errAlreadyExists is returned when it exists
+func (api *kubeAPI) create(ctx context.Context, path string, object, result interface{}) error {
+	body, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, api.server+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if len(api.token) != 0 {
+		req.Header.Set("Authorization", "Bearer "+api.token)
+	}
+	resp, err := api.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	switch {
+	case resp.StatusCode == http.StatusConflict:
+		return errAlreadyExists
+	case resp.StatusCode/100 != 2:
+		var status struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(raw, &status) != nil || len(status.Message) == 0 {
+			status.Message = http.StatusText(resp.StatusCode)
+		}
+		return fmt.Errorf("POST %s failed: %s", path, status.Message)
+	case result != nil:
+		return json.Unmarshal(raw, result)
+	}
+	return nil
+}
+
+// ScopedKubeconfig creates the service account of the scope bound to its role in its namespace
+// with the admin kubeconfig and returns a kubeconfig with a token of the service account
+// the namespace, service account and role binding which exist are reused
+func ScopedKubeconfig(ctx context.Context, adminKubeconfig []byte, scope KubeconfigScope) ([]byte, error) {
+	if err := scope.Validate(); err != nil {
+		return nil, err
+	}
+	admin := kubeconfig{}
+	if err := yaml.Unmarshal(adminKubeconfig, &admin); err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+	_, current, cluster, user, err := admin.currentContext()
+	if err != nil {
+		return nil, err
+	}
+	api, err := newKubeAPI(cluster, user)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := []struct {
+		path   string
+		object map[string]interface{}
+	}{
+		{"/api/v1/namespaces", map[string]interface{}{
+			"apiVersion": "v1", "kind": "Namespace",
+			"metadata": map[string]interface{}{"name": scope.Namespace},
+		}},
+		{"/api/v1/namespaces/" + scope.Namespace + "/serviceaccounts", map[string]interface{}{
+			"apiVersion": "v1", "kind": "ServiceAccount",
+			"metadata": map[string]interface{}{"name": scope.User, "namespace": scope.Namespace},
+		}},
+		{"/apis/rbac.authorization.k8s.io/v1/namespaces/" + scope.Namespace + "/rolebindings", map[string]interface{}{
+			"apiVersion": "rbac.authorization.k8s.io/v1", "kind": "RoleBinding",
+			"metadata": map[string]interface{}{"name": "ksctl-" + scope.User + "-" + scope.Role, "namespace": scope.Namespace},
+			"roleRef":  map[string]interface{}{"apiGroup": "rbac.authorization.k8s.io", "kind": "ClusterRole", "name": scope.Role},
+			"subjects": []interface{}{map[string]interface{}{"kind": "ServiceAccount", "name": scope.User, "namespace": scope.Namespace}},
+		}},
+	}
+	for _, object := range objects {
+		if err := api.create(ctx, object.path, object.object, nil); err != nil && !errors.Is(err, errAlreadyExists) {
+			return nil, err
+		}
+	}
+
+	var tokenRequest struct {
+		Status struct {
+			Token string `json:"token"`
+		} `json:"status"`
+	}
+	err = api.create(ctx, "/api/v1/namespaces/"+scope.Namespace+"/serviceaccounts/"+scope.User+"/token", map[string]interface{}{
+		"apiVersion": "authentication.k8s.io/v1", "kind": "TokenRequest",
+		"spec": map[string]interface{}{"expirationSeconds": int64(scope.Duration / time.Second)},
+	}, &tokenRequest)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokenRequest.Status.Token) == 0 {
+		return nil, fmt.Errorf("no token was issued for the service account %s", scope.User)
+	}
+
+	clusterName, _ := current["cluster"].(string)
+	name := scope.User + "@" + clusterName
+	return yaml.Marshal(kubeconfig{
+		"apiVersion":      "v1",
+		"kind":            "Config",
+		"clusters":        []interface{}{map[string]interface{}{"name": clusterName, "cluster": cluster}},
+		"users":           []interface{}{map[string]interface{}{"name": scope.User, "user": map[string]interface{}{"token": tokenRequest.Status.Token}}},
+		"contexts":        []interface{}{map[string]interface{}{"name": name, "context": map[string]interface{}{"cluster": clusterName, "user": scope.User, "namespace": scope.Namespace}}},
+		"current-context": name,
+	})
+}
@@ -0,0 +1,246 @@
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/kubesimplify/ksctl/api/logger"
+)
+
+// LOCK_POLL_INTERVAL is how often a held lock is checked while waiting for it
+const LOCK_POLL_INTERVAL = 2 * time.Second
+
+// LockInfo is saved in the lock of the cluster so that the user knows who is holding it
+type LockInfo struct {
+	Owner     string `json:"owner"`
+	PID       int    `json:"pid"`
+	Operation string `json:"operation"`
+	CreatedAt string `json:"created_at"`
+}
+
+func (info LockInfo) String() string {
+	return fmt.Sprintf("%s (pid %d) is running %s since %s", info.Owner, info.PID, info.Operation, info.CreatedAt)
+}
+
+// LockedError is returned when the cluster is locked by another operation
+type LockedError struct {
+	Cluster string
+	Info    LockInfo
+}
+
+func (err *LockedError) Error() string {
+	return fmt.Sprintf("cluster %s is locked: %s, run `ksctl unlock` if it is no longer running", err.Cluster, err.Info)
+}
+
+// lockWait is how long the mutating operations wait for the lock held by another operation, 0 fails immediately
+var lockWait time.Duration
+
+// SetLockWait changes how long the mutating operations wait for the lock of the cluster
+func SetLockWait(wait time.Duration) {
+	lockWait = wait
+}
+
+// lockKey the locks are kept apart from the state of the clusters so that deleting a cluster keeps its lock
+// e.g. locks/civo/ha/demo-1a2b3c4d.lock
+func lockKey(provider string, payload ClusterPayload) string {
+	clusterType := CLUSTER_TYPE_MANAGED
+	if payload.HACluster {
+		clusterType = CLUSTER_TYPE_HA
+	}
+	ref := NewClusterRef(provider, clusterType, payload.ClusterName, payload.Region)
+	return StateKey("locks", provider, clusterType, ref.ID()+".lock")
+}
+
+func lockOwner() string {
+	name := "unknown"
+	if current, err := user.Current(); err == nil {
+		name = current.Username
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return name + "@" + host
+}
+
+// ClusterLock is held by a mutating operation on the cluster until Unlock is called
+type ClusterLock struct {
+	store StateStore
+	key   string
+}
+
+// LockCluster acquires the advisory lock of the cluster in the state store
+// it fails with a LockedError when the lock is held and the wait is 0 otherwise polls until the wait expires
+func LockCluster(ctx context.Context, logging logger.Logger, provider string, payload ClusterPayload, operation string, wait time.Duration) (*ClusterLock, error) {
+	return acquireLock(ctx, logging, stateStore, lockKey(provider, payload), payload.ClusterName, operation, wait, 0)
+}
+
+// acquireLock creates the lock in the store, the lock older than staleAfter is taken over, 0 never does it
+func acquireLock(ctx context.Context, logging logger.Logger, store StateStore, key, cluster, operation string, wait, staleAfter time.Duration) (*ClusterLock, error) {
+	raw, err := json.Marshal(LockInfo{
+		Owner:     lockOwner(),
+		PID:       os.Getpid(),
+		Operation: operation,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(wait)
+	waiting := false
+	for {
+		err := store.Create(ctx, key, raw)
+		if err == nil {
+			return &ClusterLock{store: store, key: key}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("unable to lock the cluster: %w", err)
+		}
+
+		info, err := readLock(ctx, store, key)
+		if os.IsNotExist(err) {
+			// released between the two calls
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if createdAt, err := time.Parse(time.RFC3339, info.CreatedAt); err == nil && staleAfter > 0 && time.Since(createdAt) > staleAfter {
+			logging.Warn("Taking over the stale lock", info.String())
+			if err := store.Delete(ctx, key); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		locked := &LockedError{Cluster: cluster, Info: info}
+		if !time.Now().Before(deadline) {
+			return nil, locked
+		}
+		if !waiting {
+			logging.Warn("Waiting for the lock", locked.Error())
+			waiting = true
+		}
+		if err := Sleep(ctx, LOCK_POLL_INTERVAL); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func readLock(ctx context.Context, store StateStore, key string) (LockInfo, error) {
+	var info LockInfo
+	raw, err := store.Read(ctx, key)
+	if err != nil {
+		return info, err
+	}
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return info, fmt.Errorf("invalid lock of the cluster: %v", err)
+	}
+	return info, nil
+}
+
+// Unlock releases the lock, it is safe to call more than once
+func (lock *ClusterLock) Unlock() error {
+	if lock == nil || len(lock.key) == 0 {
+		return nil
+	}
+	// not bound to the ctx of the operation as the lock must be released after it is cancelled
+	err := lock.store.Delete(context.Background(), lock.key)
+	lock.key = ""
+	return err
+}
+
+// ForceUnlock removes the lock of the cluster irrespective of its owner and returns who was holding it
+// it is the escape hatch when the operation holding the lock was killed
+func ForceUnlock(provider string, payload ClusterPayload) (LockInfo, error) {
+	key := lockKey(provider, payload)
+	info, err := readLock(stateContext, stateStore, key)
+	if os.IsNotExist(err) {
+		return info, fmt.Errorf("cluster %s is not locked", payload.ClusterName)
+	}
+	// a corrupted lock is removed as well
+	if err := stateStore.Delete(stateContext, key); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// IsLocked whether an operation is running on the cluster, the resources it creates may not be recorded yet
+// the lock which cannot be read is considered held
+func IsLocked(provider string, payload ClusterPayload) bool {
+	_, err := stateStore.Read(stateContext, lockKey(provider, payload))
+	return !os.IsNotExist(err)
+}
+
+// lockedProvider holds the lock of the cluster during the mutating operations of the provider
+type lockedProvider struct {
+	Provider
+	name    string
+	payload ClusterPayload
+}
+
+func (provider lockedProvider) withLock(ctx context.Context, logging logger.Logger, operation string, run func(context.Context, logger.Logger) error) error {
+	lock, err := LockCluster(ctx, logging, provider.name, provider.payload, operation, lockWait)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := lock.Unlock(); err != nil {
+			logging.Warn("Unable to release the lock of the cluster", err.Error())
+		}
+	}()
+	return run(ctx, logging)
+}
+
+func (provider lockedProvider) Create(ctx context.Context, logging logger.Logger) error {
+	return provider.withLock(ctx, logging, "create", provider.Provider.Create)
+}
+
+func (provider lockedProvider) Delete(ctx context.Context, logging logger.Logger) error {
+	return provider.withLock(ctx, logging, "delete", provider.Provider.Delete)
+}
+
+func (provider lockedProvider) AddNodes(ctx context.Context, logging logger.Logger) error {
+	return provider.withLock(ctx, logging, "add-nodes", provider.Provider.AddNodes)
+}
+
+func (provider lockedProvider) DeleteNodes(ctx context.Context, logging logger.Logger) error {
+	return provider.withLock(ctx, logging, "delete-nodes", provider.Provider.DeleteNodes)
+}
+
+// CheckDrift holds the lock only when the state is rewritten
+func (provider lockedProvider) CheckDrift(ctx context.Context, logging logger.Logger, fix bool) (DriftReport, error) {
+	if !fix {
+		return provider.Provider.CheckDrift(ctx, logging, false)
+	}
+	var report DriftReport
+	err := provider.withLock(ctx, logging, "check-drift", func(ctx context.Context, logging logger.Logger) (err error) {
+		report, err = provider.Provider.CheckDrift(ctx, logging, true)
+		return
+	})
+	return report, err
+}
+
+// Kubeconfig holds the lock only when the kubeconfig in the state is replaced
+func (provider lockedProvider) Kubeconfig(ctx context.Context, logging logger.Logger, refresh bool) ([]byte, error) {
+	if !refresh {
+		return provider.Provider.Kubeconfig(ctx, logging, false)
+	}
+	var kubeconfig []byte
+	err := provider.withLock(ctx, logging, "refresh-kubeconfig", func(ctx context.Context, logging logger.Logger) (err error) {
+		kubeconfig, err = provider.Provider.Kubeconfig(ctx, logging, true)
+		return
+	})
+	return kubeconfig, err
+}
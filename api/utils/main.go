@@ -9,6 +9,7 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
@@ -18,6 +19,7 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
@@ -55,7 +57,7 @@ type SSHPayload struct {
 }
 
 type SSHCollection interface {
-	SSHExecute(int, *string, bool)
+	SSHExecute(context.Context, logger.Logger, int, string, bool) error
 }
 
 type LocalProvider struct {
@@ -87,6 +89,7 @@ const (
 	CLUSTER_PATH         = int(1)
 	SSH_PATH             = int(2)
 	OTHER_PATH           = int(3)
+	LOG_PATH             = int(4)
 	EXEC_WITH_OUTPUT     = int(1)
 	EXEC_WITHOUT_OUTPUT  = int(0)
 )
@@ -104,20 +107,17 @@ type PrinterKubeconfigPATH interface {
 	Printer(logger.Logger, bool, int)
 }
 
-type CivoHandlers interface {
-	CreateCluster() error
-	DeleteCluster() error
-	SwitchContext(logger.Logger) error
-	AddMoreWorkerNodes() error
-	DeleteSomeWorkerNodes() error
-}
+// CIVO_REGIONS the region codes supported for CIVO
+var CIVO_REGIONS = []string{"FRA1", "NYC1", "PHX1", "LON1"}
 
 // IsValidRegionCIVO validates the region code for CIVO
 func IsValidRegionCIVO(reg string) bool {
-	return strings.Compare(reg, "FRA1") == 0 ||
-		strings.Compare(reg, "NYC1") == 0 ||
-		strings.Compare(reg, "PHX1") == 0 ||
-		strings.Compare(reg, "LON1") == 0
+	for _, region := range CIVO_REGIONS {
+		if strings.Compare(reg, region) == 0 {
+			return true
+		}
+	}
+	return false
 }
 
 func IsValidName(clusterName string) bool {
@@ -126,6 +126,11 @@ func IsValidName(clusterName string) bool {
 	return matched
 }
 
+// ksctlPath joins the elements under ~/.ksctl with the separator of the OS
+func ksctlPath(elem ...string) string {
+	return filepath.Join(append([]string{GetUserName(), ".ksctl"}, elem...)...)
+}
+
 // getKubeconfig returns the path to clusters specific to provider
 func getKubeconfig(provider string, params ...string) string {
 	if strings.Compare(provider, "civo") != 0 &&
@@ -134,29 +139,16 @@ func getKubeconfig(provider string, params ...string) string {
 		strings.Compare(provider, "aws") != 0 {
 		return ""
 	}
-	var ret strings.Builder
-
-	if runtime.GOOS == "windows" {
-		ret.WriteString(fmt.Sprintf("%s\\.ksctl\\config\\%s", GetUserName(), provider))
-		for _, item := range params {
-			ret.WriteString("\\" + item)
-		}
-	} else {
-		ret.WriteString(fmt.Sprintf("%s/.ksctl/config/%s", GetUserName(), provider))
-		for _, item := range params {
-			ret.WriteString("/" + item)
-		}
-	}
-	return ret.String()
+	return getPaths(provider, params...)
 }
 
 // getCredentials generate the path to the credentials of different providers
-func getCredentials(provider string) string {
-	if runtime.GOOS == "windows" {
-		return fmt.Sprintf("%s\\.ksctl\\cred\\%s.json", GetUserName(), provider)
-	} else {
-		return fmt.Sprintf("%s/.ksctl/cred/%s.json", GetUserName(), provider)
+// the profile is optional, the default profile is kept in <provider>.json
+func getCredentials(provider string, profile ...string) string {
+	if len(profile) == 0 || credName(provider, profile[0]) == provider {
+		return ksctlPath("cred", provider+".json")
 	}
+	return ksctlPath("cred", provider, profile[0]+".json")
 }
 
 // GetPath use this in every function and differentiate the logic by using if-else
@@ -167,18 +159,22 @@ func GetPath(flag int, provider string, subfolders ...string) string {
 	case CLUSTER_PATH:
 		return getKubeconfig(provider, subfolders...)
 	case CREDENTIAL_PATH:
-		return getCredentials(provider)
+		return getCredentials(provider, subfolders...)
 	case OTHER_PATH:
 		return getPaths(provider, subfolders...)
+	case LOG_PATH:
+		return getLogPath(subfolders...)
 	default:
 		return ""
 	}
 }
 
+// SaveCred encrypts the credentials of the active profile of the provider and saves them in ~/.ksctl/cred
 func SaveCred(logging logger.Logger, config interface{}, provider string) error {
 	if strings.Compare(provider, "civo") != 0 &&
 		strings.Compare(provider, "azure") != 0 &&
-		strings.Compare(provider, "aws") != 0 {
+		strings.Compare(provider, "aws") != 0 &&
+		strings.Compare(provider, "s3") != 0 {
 		return fmt.Errorf("Invalid Provider (given): Unable to save configuration")
 	}
 
@@ -186,37 +182,7 @@ func SaveCred(logging logger.Logger, config interface{}, provider string) error
 	if err != nil {
 		return err
 	}
-	_, err = os.Create(GetPath(CREDENTIAL_PATH, provider))
-	if err != nil && !os.IsExist(err) {
-		return err
-	}
-
-	err = os.WriteFile(GetPath(CREDENTIAL_PATH, provider), storeBytes, 0640)
-	if err != nil {
-		return err
-	}
-	logging.Info("💾 configuration", "")
-	return nil
-}
-
-func SaveState(logging logger.Logger, config interface{}, provider, clusterType string, clusterDir string) error {
-	if strings.Compare(provider, "civo") != 0 &&
-		strings.Compare(provider, "azure") != 0 &&
-		strings.Compare(provider, "aws") != 0 {
-		return fmt.Errorf("invalid Provider (given): Unable to save configuration")
-	}
-	storeBytes, err := json.Marshal(config)
-	if err != nil {
-		return err
-	}
-	if err := os.MkdirAll(GetPath(CLUSTER_PATH, provider, clusterType, clusterDir), 0755); err != nil && !os.IsExist(err) {
-		return err
-	}
-	_, err = os.Create(GetPath(CLUSTER_PATH, provider, clusterType, clusterDir, "info.json"))
-	if err != nil && !os.IsExist(err) {
-		return err
-	}
-	err = os.WriteFile(GetPath(CLUSTER_PATH, provider, clusterType, clusterDir, "info.json"), storeBytes, 0640)
+	err = writeCred(provider, ActiveProfile(provider), storeBytes)
 	if err != nil {
 		return err
 	}
@@ -224,27 +190,15 @@ func SaveState(logging logger.Logger, config interface{}, provider, clusterType
 	return nil
 }
 
+// GetCred returns the decrypted credentials of the active profile of the provider
 func GetCred(logging logger.Logger, provider string) (i map[string]string, err error) {
 
-	fileBytes, err := os.ReadFile(GetPath(CREDENTIAL_PATH, provider))
-
-	if err != nil {
-		return
+	profile := ActiveProfile(provider)
+	fileBytes, err := readCred(logging, provider, profile)
+	if os.IsNotExist(err) && profile != DEFAULT_PROFILE {
+		err = fmt.Errorf("profile %s of %s not found, save its credentials with `ksctl cred add --provider %s --profile %s`", profile, provider, provider, profile)
 	}
 
-	err = json.Unmarshal(fileBytes, &i)
-
-	if err != nil {
-		return
-	}
-	logging.Info("🔄 configuration", "")
-
-	return
-}
-
-func GetState(logging logger.Logger, provider, clusterType, clusterDir string) (i map[string]interface{}, err error) {
-	fileBytes, err := os.ReadFile(GetPath(CLUSTER_PATH, provider, clusterType, clusterDir, "info.json"))
-
 	if err != nil {
 		return
 	}
@@ -255,46 +209,25 @@ func GetState(logging logger.Logger, provider, clusterType, clusterDir string) (
 		return
 	}
 	logging.Info("🔄 configuration", "")
+
 	return
 }
 
 // getSSHPath generate the SSH keypair location and subsequent fetch
 func getSSHPath(provider string, params ...string) string {
-	var ret strings.Builder
-
-	if runtime.GOOS == "windows" {
-		ret.WriteString(fmt.Sprintf("%s\\.ksctl\\config\\%s", GetUserName(), provider))
-		for _, item := range params {
-			ret.WriteString("\\" + item)
-		}
-		ret.WriteString("\\keypair")
-	} else {
-		ret.WriteString(fmt.Sprintf("%s/.ksctl/config/%s", GetUserName(), provider))
-		for _, item := range params {
-			ret.WriteString("/" + item)
-		}
-		ret.WriteString("/keypair")
-	}
-	return ret.String()
+	return filepath.Join(getPaths(provider, params...), "keypair")
 }
 
 // getPaths to generate path irrespective of the cluster
 // its a free flowing (Provider field has not much significance)
 func getPaths(provider string, params ...string) string {
-	var ret strings.Builder
+	return ksctlPath(append([]string{"config", provider}, params...)...)
+}
 
-	if runtime.GOOS == "windows" {
-		ret.WriteString(fmt.Sprintf("%s\\.ksctl\\config\\%s", GetUserName(), provider))
-		for _, item := range params {
-			ret.WriteString("\\" + item)
-		}
-	} else {
-		ret.WriteString(fmt.Sprintf("%s/.ksctl/config/%s", GetUserName(), provider))
-		for _, item := range params {
-			ret.WriteString("/" + item)
-		}
-	}
-	return ret.String()
+// getLogPath to generate the path of the log files of the operations
+// the provider is not used as a single operation can span across them
+func getLogPath(params ...string) string {
+	return ksctlPath(append([]string{"logs"}, params...)...)
 }
 
 func CreateSSHKeyPair(provider, clusterDir string) (string, error) {
@@ -310,10 +243,17 @@ func CreateSSHKeyPair(provider, clusterDir string) (string, error) {
 
 	fmt.Println(string(out))
 
-	keyPairToUpload := GetPath(OTHER_PATH, provider, "ha", clusterDir, "keypair.pub")
-	fileBytePub, err := os.ReadFile(keyPairToUpload)
-	if err != nil {
-		return "", err
+	// the keypair is generated locally and then saved to the state store
+	var fileBytePub []byte
+	for _, name := range []string{"keypair", "keypair.pub"} {
+		raw, err := os.ReadFile(GetPath(OTHER_PATH, provider, "ha", clusterDir, name))
+		if err != nil {
+			return "", err
+		}
+		if err := WriteStateFile(provider, raw, "ha", clusterDir, name); err != nil {
+			return "", err
+		}
+		fileBytePub = raw
 	}
 
 	return string(fileBytePub), nil
@@ -380,9 +320,24 @@ func returnServerPublicKeys(publicIP string) (string, error) {
 	return fingerprint[1], nil
 }
 
-func (sshPayload *SSHPayload) SSHExecute(logging logger.Logger, flag int, script string, fastMode bool) error {
+// readPrivateKey reads the ssh private key, it is fetched from the state store
+// when the cluster was created from another machine
+func readPrivateKey(ctx context.Context, privateKeyPath string) ([]byte, error) {
+	raw, err := os.ReadFile(privateKeyPath)
+	if !os.IsNotExist(err) {
+		return raw, err
+	}
+	key, relErr := filepath.Rel(getPaths(""), privateKeyPath)
+	if relErr != nil || strings.HasPrefix(key, "..") {
+		return raw, err
+	}
+	return stateStore.Read(ctx, filepath.ToSlash(key))
+}
 
-	privateKeyBytes, err := os.ReadFile(sshPayload.PathPrivateKey)
+// SSHExecute runs the script on the VM, the retries and the running script are stopped when the ctx is cancelled
+func (sshPayload *SSHPayload) SSHExecute(ctx context.Context, logging logger.Logger, flag int, script string, fastMode bool) error {
+
+	privateKeyBytes, err := readPrivateKey(ctx, sshPayload.PathPrivateKey)
 	if err != nil {
 		return err
 	}
@@ -420,7 +375,9 @@ func (sshPayload *SSHPayload) SSHExecute(logging logger.Logger, flag int, script
 			})}
 
 	if !fastMode {
-		time.Sleep(SSH_PAUSE_IN_SECONDS * time.Second)
+		if err := Sleep(ctx, SSH_PAUSE_IN_SECONDS*time.Second); err != nil {
+			return err
+		}
 	}
 
 	var conn *ssh.Client
@@ -433,7 +390,10 @@ func (sshPayload *SSHPayload) SSHExecute(logging logger.Logger, flag int, script
 		} else {
 			logging.Err(fmt.Sprintln("RETRYING", err))
 		}
-		time.Sleep(10 * time.Second) // waiting for ssh to get started
+		// waiting for ssh to get started
+		if err := Sleep(ctx, 10*time.Second); err != nil {
+			return err
+		}
 		currRetryCounter++
 	}
 	if currRetryCounter == MAX_RETRY_COUNT {
@@ -443,6 +403,17 @@ func (sshPayload *SSHPayload) SSHExecute(logging logger.Logger, flag int, script
 	logging.Info("🤖 Exec Scripts", "")
 	defer conn.Close()
 
+	// closing the connection stops the running script
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
 	session, err := conn.NewSession()
 
 	if err != nil {
@@ -460,6 +431,9 @@ func (sshPayload *SSHPayload) SSHExecute(logging logger.Logger, flag int, script
 	if flag == EXEC_WITH_OUTPUT {
 		sshPayload.Output = buff.String()
 	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 	if err != nil {
 		return err
 	}
@@ -482,6 +456,26 @@ func UserInputCredentials(logging logger.Logger) (string, error) {
 	return strings.TrimSpace(string(bytePassword)), nil
 }
 
+// confirmInput the answers of the user to the confirmations
+var confirmInput io.Reader = os.Stdin
+
+// ConfirmNodesDeletion asks the user to confirm the deletion of the worker nodes unless autoApprove is set
+// the deletion is refused with an error when the answer is not yes so that it isn't reported as done
+func ConfirmNodesDeletion(logging logger.Logger, autoApprove bool) error {
+	if autoApprove {
+		return nil
+	}
+	logging.Print("Enter your choice to continue..[y/N]")
+	choice := "n"
+	fmt.Fscanf(confirmInput, "%s", &choice)
+	if strings.Compare("y", choice) == 0 ||
+		strings.Compare("yes", choice) == 0 ||
+		strings.Compare("Y", choice) == 0 {
+		return nil
+	}
+	return fmt.Errorf("deletion of the worker nodes is not confirmed")
+}
+
 func IsValidNoOfControlPlanes(noCP int) error {
 	if noCP < 3 || (noCP)&1 == 0 {
 		return fmt.Errorf("no of controlplanes must be >= 3 and should be odd number")
@@ -0,0 +1,62 @@
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// MAX_PARALLEL_NODES maximum number of nodes which are provisioned at the same time
+const MAX_PARALLEL_NODES = 4
+
+// Errors the failures of the tasks which were run in parallel
+type Errors []error
+
+func (errs Errors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d task(s) failed: %s", len(errs), strings.Join(msgs, "; "))
+}
+
+// RunInParallel calls task for every index from 0 to noOfTasks-1 with atmost maxParallel of them running at once
+// every task is run even if some of them fail, the errors are returned together as Errors in the order of the index
+func RunInParallel(noOfTasks, maxParallel int, task func(index int) error) error {
+	if maxParallel < 1 {
+		maxParallel = 1
+	}
+
+	failures := make([]error, noOfTasks)
+	slots := make(chan struct{}, maxParallel)
+	var wg sync.WaitGroup
+
+	for i := 0; i < noOfTasks; i++ {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(index int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			failures[index] = task(index)
+		}(i)
+	}
+	wg.Wait()
+
+	var errs Errors
+	for _, err := range failures {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
@@ -0,0 +1,158 @@
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DEFAULT_PROFILE the credentials saved before the profiles were added, kept in ~/.ksctl/cred/<provider>.json
+// the other profiles are kept in ~/.ksctl/cred/<provider>/<profile>.json
+const DEFAULT_PROFILE = "default"
+
+// PROFILES_FILE holds the default profile of every provider, kept as ~/.ksctl/profiles.json
+const PROFILES_FILE = "profiles.json"
+
+type profileSettings struct {
+	Defaults map[string]string `json:"defaults"`
+}
+
+var (
+	// activeProfiles the profile of the credentials used by every provider in this run
+	activeProfilesMutex sync.Mutex
+	activeProfiles      = map[string]string{}
+)
+
+// IsValidProfile the profile is used as a file name, so it follows the rules of the cluster name
+func IsValidProfile(profile string) bool {
+	return IsValidName(profile)
+}
+
+// UseProfile selects the profile whose credentials are read and saved for the provider
+func UseProfile(provider, profile string) {
+	activeProfilesMutex.Lock()
+	defer activeProfilesMutex.Unlock()
+	activeProfiles[provider] = profile
+}
+
+// ActiveProfile the profile selected for the provider, the default profile when none is
+func ActiveProfile(provider string) string {
+	activeProfilesMutex.Lock()
+	defer activeProfilesMutex.Unlock()
+	if profile := activeProfiles[provider]; len(profile) != 0 {
+		return profile
+	}
+	return DEFAULT_PROFILE
+}
+
+// credName identifies the credentials of the profile, the default profile keeps the name of the provider
+func credName(provider, profile string) string {
+	if len(profile) == 0 || profile == DEFAULT_PROFILE {
+		return provider
+	}
+	return provider + "/" + profile
+}
+
+func readProfileSettings() (profileSettings, error) {
+	settings := profileSettings{Defaults: map[string]string{}}
+	raw, err := os.ReadFile(ksctlPath(PROFILES_FILE))
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, err
+	}
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return settings, fmt.Errorf("invalid %s: %v", PROFILES_FILE, err)
+	}
+	if settings.Defaults == nil {
+		settings.Defaults = map[string]string{}
+	}
+	return settings, nil
+}
+
+// DefaultProfile the profile used by the provider when neither the flag nor the state of the cluster has one
+func DefaultProfile(provider string) string {
+	settings, err := readProfileSettings()
+	if err != nil || len(settings.Defaults[provider]) == 0 {
+		return DEFAULT_PROFILE
+	}
+	return settings.Defaults[provider]
+}
+
+// SetDefaultProfile changes the default profile of the provider, its credentials must be saved already
+func SetDefaultProfile(provider, profile string) error {
+	if !IsValidProfile(profile) {
+		return fmt.Errorf("invalid profile: %s", profile)
+	}
+	if _, err := os.Stat(GetPath(CREDENTIAL_PATH, provider, profile)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("profile %s of %s not found, save its credentials with `ksctl cred add`", profile, provider)
+		}
+		return err
+	}
+	settings, err := readProfileSettings()
+	if err != nil {
+		return err
+	}
+	settings.Defaults[provider] = profile
+	return writeProfileSettings(settings)
+}
+
+func writeProfileSettings(settings profileSettings) error {
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(ksctlPath(), 0750); err != nil {
+		return err
+	}
+	return os.WriteFile(ksctlPath(PROFILES_FILE), raw, 0640)
+}
+
+// Profiles returns the profiles of the provider whose credentials are saved in sorted order
+func Profiles(provider string) ([]string, error) {
+	var profiles []string
+	if _, err := os.Stat(GetPath(CREDENTIAL_PATH, provider)); err == nil {
+		profiles = append(profiles, DEFAULT_PROFILE)
+	}
+	files, err := filepath.Glob(GetPath(CREDENTIAL_PATH, provider, "*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		profiles = append(profiles, strings.TrimSuffix(filepath.Base(file), ".json"))
+	}
+	sort.Strings(profiles)
+	return profiles, nil
+}
+
+// resolveProfile the profile given for the operation, otherwise the one recorded in the state
+// of the existing cluster so that it is managed with the account it was created with, otherwise the default one
+func resolveProfile(name string, payload ClusterPayload) string {
+	if len(payload.Profile) != 0 {
+		return payload.Profile
+	}
+	clusterType := CLUSTER_TYPE_MANAGED
+	if payload.HACluster {
+		clusterType = CLUSTER_TYPE_HA
+	}
+	ref := NewClusterRef(name, clusterType, payload.ClusterName, payload.Region)
+	if raw, err := ReadStateFile(name, clusterType, ref.ID(), "info.json"); err == nil {
+		if state, _, err := DecodeState(raw, nil, ref); err == nil && len(state.Spec.Profile) != 0 {
+			return state.Spec.Profile
+		}
+	}
+	return DefaultProfile(name)
+}
@@ -0,0 +1,222 @@
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kubesimplify/ksctl/api/logger"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	SPEC_API_VERSION = "ksctl.kubesimplify.com/v1alpha1"
+	SPEC_KIND        = "Cluster"
+)
+
+// ClusterSpec is the declarative definition of a cluster which can be kept in git
+// it can be written either in YAML or JSON
+//
+//	apiVersion: ksctl.kubesimplify.com/v1alpha1
+//	kind: Cluster
+//	metadata:
+//	  name: demo
+//	spec:
+//	  provider: civo
+//	  region: LON1
+//	  ha: true
+//	  nodeSize: g3.small
+//	  nodePools:
+//	    controlPlane: 3
+//	    worker: 2
+//	  tags:
+//	    team: platform
+//	  distribution: k3s
+//	  kubernetesVersion: "1.26"
+type ClusterSpec struct {
+	APIVersion string          `json:"apiVersion"`
+	Kind       string          `json:"kind"`
+	Metadata   SpecMetadata    `json:"metadata"`
+	Spec       ClusterSpecBody `json:"spec"`
+}
+
+type SpecMetadata struct {
+	Name string `json:"name"`
+}
+
+type ClusterSpecBody struct {
+	Provider  string    `json:"provider"`
+	Region    string    `json:"region,omitempty"`
+	HA        bool      `json:"ha,omitempty"`
+	NodeSize  string    `json:"nodeSize,omitempty"`
+	NodePools NodePools `json:"nodePools"`
+	CNI       string    `json:"cni,omitempty"`
+	Apps      []string  `json:"apps,omitempty"`
+	// Profile of the credentials, the default profile of the provider when empty
+	Profile string `json:"profile,omitempty"`
+	// Tags put on the resources of the cluster along with the ownership tags
+	Tags map[string]string `json:"tags,omitempty"`
+	// Distribution of kubernetes of the HA cluster of civo or azure, k3s by default
+	Distribution string `json:"distribution,omitempty"`
+	// KubernetesVersion the minor version of kubernetes of the cluster, by default the managed clusters
+	// use the default version of the provider and the others DEFAULT_KUBERNETES_VERSION
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+}
+
+// NodePools number of nodes in each pool
+// managed is used by the managed clusters, controlPlane and worker by the HA clusters
+type NodePools struct {
+	Managed      int `json:"managed,omitempty"`
+	ControlPlane int `json:"controlPlane,omitempty"`
+	Worker       int `json:"worker,omitempty"`
+}
+
+// LoadClusterSpec reads and validates the cluster spec file
+func LoadClusterSpec(path string) (*ClusterSpec, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spec ClusterSpec
+	if err := yaml.UnmarshalStrict(raw, &spec); err != nil {
+		return nil, fmt.Errorf("invalid cluster spec %s: %v", path, err)
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// Validate checks the fields which are common to all the providers
+// the provider specific validations happen when the cluster is created
+func (spec *ClusterSpec) Validate() error {
+	if spec.APIVersion != SPEC_API_VERSION {
+		return fmt.Errorf("unsupported apiVersion: %q, expected %q", spec.APIVersion, SPEC_API_VERSION)
+	}
+	if spec.Kind != SPEC_KIND {
+		return fmt.Errorf("unsupported kind: %q, expected %q", spec.Kind, SPEC_KIND)
+	}
+	if !IsValidName(spec.Metadata.Name) {
+		return fmt.Errorf("invalid cluster name: %v", spec.Metadata.Name)
+	}
+	if _, ok := providers[strings.ToLower(spec.Spec.Provider)]; !ok {
+		return fmt.Errorf("invalid provider: %s", spec.Spec.Provider)
+	}
+
+	if err := ValidateTags(spec.Spec.Tags); err != nil {
+		return err
+	}
+
+	if len(spec.Spec.Distribution) != 0 {
+		if _, err := NewDistribution(spec.Spec.Distribution, ""); err != nil {
+			return err
+		}
+		provider := strings.ToLower(spec.Spec.Provider)
+		if !spec.Spec.HA || (provider != "civo" && provider != "azure") {
+			return fmt.Errorf("distribution is only used by the HA cluster of civo and azure")
+		}
+	}
+
+	if _, err := GetKubernetesRelease(spec.Spec.KubernetesVersion); err != nil {
+		return err
+	}
+
+	pools := spec.Spec.NodePools
+	if spec.Spec.HA {
+		if pools.Managed != 0 {
+			return fmt.Errorf("nodePools.managed is not used by HA cluster, use nodePools.controlPlane and nodePools.worker")
+		}
+		if err := IsValidNoOfControlPlanes(pools.ControlPlane); err != nil {
+			return err
+		}
+		if pools.Worker < 0 {
+			return fmt.Errorf("nodePools.worker cannot be negative")
+		}
+	} else {
+		if pools.ControlPlane != 0 || pools.Worker != 0 {
+			return fmt.Errorf("nodePools.controlPlane and nodePools.worker are only used by HA cluster, use nodePools.managed")
+		}
+		if pools.Managed < 1 {
+			return fmt.Errorf("nodePools.managed must be atleast 1")
+		}
+	}
+	return nil
+}
+
+// Payload maps the spec onto the values used by the providers
+func (spec *ClusterSpec) Payload() ClusterPayload {
+	return ClusterPayload{
+		ClusterName: spec.Metadata.Name,
+		Region:      spec.Spec.Region,
+		HACluster:   spec.Spec.HA,
+		Spec: Machine{
+			Disk:                spec.Spec.NodeSize,
+			ManagedNodes:        spec.Spec.NodePools.Managed,
+			HAControlPlaneNodes: spec.Spec.NodePools.ControlPlane,
+			HAWorkerNodes:       spec.Spec.NodePools.Worker,
+		},
+		Application:       strings.Join(spec.Spec.Apps, ","),
+		CNIPlugin:         spec.Spec.CNI,
+		Profile:           spec.Spec.Profile,
+		Tags:              spec.Spec.Tags,
+		Distribution:      spec.Spec.Distribution,
+		KubernetesVersion: spec.Spec.KubernetesVersion,
+	}
+}
+
+// ApplySpec creates the cluster if it is absent otherwise reconciles the
+// number of worker nodes of the HA cluster to the one given in the spec
+// the deletion of the worker nodes is confirmed by the user unless autoApprove is set
+func ApplySpec(ctx context.Context, logging logger.Logger, spec *ClusterSpec, autoApprove bool) error {
+	payload := spec.Payload()
+	payload.AutoApprove = autoApprove
+	provider, err := GetProvider(spec.Spec.Provider, payload)
+	if err != nil {
+		return err
+	}
+
+	if !provider.IsPresent() {
+		logging.Info("Cluster not found, creating", spec.Metadata.Name)
+		return provider.Create(ctx, logging)
+	}
+
+	if !spec.Spec.HA {
+		logging.Note("managed cluster already exists, only the worker nodes of HA cluster are reconciled")
+		return nil
+	}
+
+	current, err := provider.NoOfWorkerNodes(ctx, logging)
+	if err != nil {
+		return err
+	}
+	desired := spec.Spec.NodePools.Worker
+
+	switch {
+	case desired > current:
+		logging.Info("Adding worker nodes", fmt.Sprintf("%d -> %d", current, desired))
+		payload.Spec.HAWorkerNodes = desired - current
+		provider, err = GetProvider(spec.Spec.Provider, payload)
+		if err != nil {
+			return err
+		}
+		return provider.AddNodes(ctx, logging)
+	case desired < current:
+		logging.Info("Deleting worker nodes", fmt.Sprintf("%d -> %d", current, desired))
+		payload.Spec.HAWorkerNodes = current - desired
+		provider, err = GetProvider(spec.Spec.Provider, payload)
+		if err != nil {
+			return err
+		}
+		return provider.DeleteNodes(ctx, logging)
+	}
+	logging.Info("Cluster is up to date", spec.Metadata.Name)
+	return nil
+}
@@ -0,0 +1,273 @@
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/kubesimplify/ksctl/api/logger"
+)
+
+// STATE_SCHEMA_VERSION is the version of the info.json written by this version of ksctl
+// bump it along with a migration appended to stateMigrations whenever the envelope changes
+const STATE_SCHEMA_VERSION = 1
+
+// ClusterState is the envelope of the state management file (info.json) of every cluster
+// the provider specific resources are kept in the Status
+type ClusterState struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	Provider      string          `json:"provider"`
+	Type          string          `json:"type"`
+	CreatedAt     string          `json:"createdAt,omitempty"`
+	Spec          StateSpec       `json:"spec"`
+	Status        json.RawMessage `json:"status"`
+}
+
+// StateSpec the values provided by the user when the cluster was created
+type StateSpec struct {
+	ClusterName  string `json:"clusterName"`
+	Region       string `json:"region,omitempty"`
+	NodeSize     string `json:"nodeSize,omitempty"`
+	ManagedNodes int    `json:"managedNodes,omitempty"`
+	// Profile of the credentials the cluster is managed with
+	Profile string `json:"profile,omitempty"`
+	// Tags given by the user which are put on the resources of the cluster
+	Tags map[string]string `json:"tags,omitempty"`
+	// Distribution of kubernetes of the HA cluster, empty for the clusters using k3s created by the older versions
+	Distribution string `json:"distribution,omitempty"`
+	// KubernetesVersion the minor version of kubernetes, empty for the clusters created by the older versions
+	// and for the managed clusters using the default version of the provider
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+	// ControlPlaneNodes and WorkerNodes requested when the HA cluster was created
+	ControlPlaneNodes int `json:"controlPlaneNodes,omitempty"`
+	WorkerNodes       int `json:"workerNodes,omitempty"`
+}
+
+// Metadata lets the envelope fill the ClusterMetadata embedded in the status of the providers
+func (metadata *ClusterMetadata) Metadata() *ClusterMetadata {
+	return metadata
+}
+
+type metadataHolder interface {
+	Metadata() *ClusterMetadata
+}
+
+// asMetadataHolder the Metadata method has a pointer receiver, so the status passed by value is copied
+func asMetadataHolder(status interface{}) (metadataHolder, bool) {
+	value := reflect.ValueOf(status)
+	if !value.IsValid() || (value.Kind() == reflect.Ptr && value.IsNil()) {
+		return nil, false
+	}
+	if holder, ok := status.(metadataHolder); ok {
+		return holder, true
+	}
+	copied := reflect.New(value.Type())
+	copied.Elem().Set(value)
+	holder, ok := copied.Interface().(metadataHolder)
+	return holder, ok
+}
+
+// stateMigrations[i] upgrades the state from the schema version i to i+1
+var stateMigrations = []func(ClusterRef, map[string]interface{}) (map[string]interface{}, error){
+	migrateStateV0,
+}
+
+// migrateStateV0 wraps the unversioned state, which is the provider specific struct with the
+// cluster metadata inlined (civo.JsonStore, civo.ManagedConfig, azure.AzureStateCluster, aws.AwsStateCluster)
+func migrateStateV0(ref ClusterRef, state map[string]interface{}) (map[string]interface{}, error) {
+	name, region := ref.Name, ref.Region
+	for _, field := range []string{"clustername", "cluster_name"} {
+		if value, ok := state[field].(string); ok && len(value) != 0 {
+			name = value
+		}
+	}
+	if value, ok := state["region"].(string); ok && len(value) != 0 {
+		region = value
+	}
+
+	spec := map[string]interface{}{"clusterName": name, "region": region}
+	envelope := map[string]interface{}{
+		"schemaVersion": 1,
+		"provider":      ref.Provider,
+		"type":          ref.Type,
+		"spec":          spec,
+	}
+	if value, ok := state["node_size"]; ok {
+		spec["nodeSize"] = value
+		delete(state, "node_size")
+	}
+	if value, ok := state["managed_nodes"]; ok {
+		spec["managedNodes"] = value
+		delete(state, "managed_nodes")
+	}
+	if value, ok := state["created_at"]; ok {
+		envelope["createdAt"] = value
+		delete(state, "created_at")
+	}
+	envelope["status"] = state
+	return envelope, nil
+}
+
+// migrateState upgrades the raw state to STATE_SCHEMA_VERSION, migrated is false when it is already at it
+func migrateState(ref ClusterRef, raw []byte) (state ClusterState, migrated bool, err error) {
+	raw = bytes.TrimSpace(raw)
+	if ref.Provider == "local" && !bytes.HasPrefix(raw, []byte("{")) {
+		// the first versions saved only the name of the local cluster in plain text
+		raw = []byte("{}")
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return state, false, fmt.Errorf("invalid state of the cluster %s: %v", ref.Name, err)
+	}
+
+	version := 0
+	if value, ok := fields["schemaVersion"].(float64); ok {
+		version = int(value)
+	}
+	if version > STATE_SCHEMA_VERSION {
+		return state, false, fmt.Errorf("state of the cluster %s has schema version %d, upgrade ksctl to use it", ref.Name, version)
+	}
+	for ; version < STATE_SCHEMA_VERSION; version++ {
+		if fields, err = stateMigrations[version](ref, fields); err != nil {
+			return state, false, fmt.Errorf("unable to migrate the state of the cluster %s to schema version %d: %v", ref.Name, version+1, err)
+		}
+		migrated = true
+	}
+
+	if migrated {
+		if raw, err = json.Marshal(fields); err != nil {
+			return state, false, err
+		}
+	}
+	err = json.Unmarshal(raw, &state)
+	return state, migrated, err
+}
+
+// EncodeState wraps the provider specific status into the envelope
+// the embedded ClusterMetadata of the status is moved to the spec of the envelope
+func EncodeState(status interface{}, ref ClusterRef) ([]byte, error) {
+	rawStatus, err := json.Marshal(status)
+	if err != nil {
+		return nil, err
+	}
+	state := ClusterState{
+		SchemaVersion: STATE_SCHEMA_VERSION,
+		Provider:      ref.Provider,
+		Type:          ref.Type,
+		Spec:          StateSpec{ClusterName: ref.Name, Region: ref.Region},
+		Status:        rawStatus,
+	}
+	if holder, ok := asMetadataHolder(status); ok {
+		metadata := holder.Metadata()
+		if len(metadata.Profile) == 0 && ref.Provider != "local" {
+			// the cluster is created with the active profile, the local clusters don't use any credentials
+			metadata.Profile = ActiveProfile(ref.Provider)
+		}
+		state.CreatedAt = metadata.CreatedAt
+		state.Spec.NodeSize = metadata.NodeSize
+		state.Spec.ManagedNodes = metadata.ManagedNodes
+		state.Spec.Profile = metadata.Profile
+		state.Spec.Tags = metadata.Tags
+		state.Spec.Distribution = metadata.Distribution
+		state.Spec.KubernetesVersion = metadata.KubernetesVersion
+		state.Spec.ControlPlaneNodes = metadata.ControlPlaneNodes
+		state.Spec.WorkerNodes = metadata.WorkerNodes
+	}
+	return json.Marshal(state)
+}
+
+// DecodeState upgrades the raw state to the current schema and unmarshals its status
+// migrated reports whether the raw state has to be saved again in the current schema
+func DecodeState(raw []byte, status interface{}, ref ClusterRef) (state ClusterState, migrated bool, err error) {
+	state, migrated, err = migrateState(ref, raw)
+	if err != nil {
+		return
+	}
+	if state.Provider != ref.Provider || state.Type != ref.Type {
+		return state, false, fmt.Errorf("state of the cluster %s belongs to %s %s cluster", ref.Name, state.Provider, state.Type)
+	}
+	if status == nil {
+		return
+	}
+	if len(state.Status) != 0 && string(state.Status) != "null" {
+		if err = json.Unmarshal(state.Status, status); err != nil {
+			return state, false, fmt.Errorf("invalid status of the cluster %s: %v", ref.Name, err)
+		}
+	}
+	if holder, ok := status.(metadataHolder); ok {
+		*holder.Metadata() = ClusterMetadata{
+			NodeSize:          state.Spec.NodeSize,
+			ManagedNodes:      state.Spec.ManagedNodes,
+			CreatedAt:         state.CreatedAt,
+			Profile:           state.Spec.Profile,
+			Tags:              state.Spec.Tags,
+			Distribution:      state.Spec.Distribution,
+			KubernetesVersion: state.Spec.KubernetesVersion,
+			ControlPlaneNodes: state.Spec.ControlPlaneNodes,
+			WorkerNodes:       state.Spec.WorkerNodes,
+		}
+	}
+	return
+}
+
+// SaveState writes the status of the cluster to its state management file and adds it to the index
+func SaveState(logging logger.Logger, status interface{}, ref ClusterRef) error {
+	if strings.Compare(ref.Provider, "civo") != 0 &&
+		strings.Compare(ref.Provider, "azure") != 0 &&
+		strings.Compare(ref.Provider, "aws") != 0 {
+		return fmt.Errorf("invalid Provider (given): Unable to save configuration")
+	}
+	storeBytes, err := EncodeState(status, ref)
+	if err != nil {
+		return err
+	}
+	if err := WriteStateFile(ref.Provider, storeBytes, ref.Type, ref.ID(), "info.json"); err != nil {
+		return err
+	}
+	if err := RegisterCluster(ref); err != nil {
+		return err
+	}
+	logging.Info("💾 configuration", "")
+	return nil
+}
+
+// ReadState reads the state management file of the cluster into the status
+// the files of the older versions of ksctl are upgraded and saved in the current schema
+func ReadState(status interface{}, ref ClusterRef) (ClusterState, error) {
+	raw, err := ReadStateFile(ref.Provider, ref.Type, ref.ID(), "info.json")
+	if err != nil {
+		return ClusterState{}, err
+	}
+	state, migrated, err := DecodeState(raw, status, ref)
+	if err != nil {
+		return state, err
+	}
+	if migrated {
+		upgraded, err := json.Marshal(state)
+		if err != nil {
+			return state, err
+		}
+		if err := WriteStateFile(ref.Provider, upgraded, ref.Type, ref.ID(), "info.json"); err != nil {
+			return state, fmt.Errorf("unable to save the migrated state of the cluster %s: %v", ref.Name, err)
+		}
+	}
+	return state, nil
+}
+
+// GetState is ReadState which logs the successful read
+func GetState(logging logger.Logger, status interface{}, ref ClusterRef) error {
+	if _, err := ReadState(status, ref); err != nil {
+		return err
+	}
+	logging.Info("🔄 configuration", "")
+	return nil
+}
@@ -0,0 +1,467 @@
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/kubesimplify/ksctl/api/logger"
+)
+
+const (
+	STATE_STORE_LOCAL = "local"
+	STATE_STORE_S3    = "s3"
+)
+
+// StateStore keeps the state management files of the clusters e.g. info.json, the kubeconfig and the ssh keypair
+// the keys are slash separated paths relative to ~/.ksctl/config e.g. "civo/ha/demo LON1/info.json"
+// the requests to a remote store are stopped when the ctx is cancelled
+type StateStore interface {
+	// Read returns an error satisfying os.IsNotExist when the key is absent
+	Read(ctx context.Context, key string) ([]byte, error)
+	Write(ctx context.Context, key string, data []byte) error
+	// Create writes the key only when it is absent otherwise returns an error satisfying os.IsExist
+	Create(ctx context.Context, key string, data []byte) error
+	// Delete removes the key along with every key under it
+	Delete(ctx context.Context, key string) error
+	// List returns the names of the directories directly under the key, nothing when the key is absent
+	List(ctx context.Context, key string) ([]string, error)
+}
+
+var stateStore StateStore = LocalStore{}
+
+// stateContext is the ctx of the operation used by the helpers below which are called deep in the providers
+var stateContext = context.Background()
+
+// SetStateStore changes the store used by all the providers, it is meant to be called once before any operation
+func SetStateStore(store StateStore) {
+	stateStore = store
+}
+
+// GetStateStore returns the store used by all the providers
+func GetStateStore() StateStore {
+	return stateStore
+}
+
+// SetStateContext binds the state of the providers to the ctx of the operation so that the
+// --timeout and the interrupt stop its requests to the store, it is meant to be called once per operation
+func SetStateContext(ctx context.Context) {
+	stateContext = ctx
+}
+
+// StateKey joins the provider and the path into the key of the store
+// e.g. StateKey("civo", "ha", "demo LON1", "info.json")
+func StateKey(provider string, path ...string) string {
+	return strings.Join(append([]string{provider}, path...), "/")
+}
+
+func ReadStateFile(provider string, path ...string) ([]byte, error) {
+	return stateStore.Read(stateContext, StateKey(provider, path...))
+}
+
+func WriteStateFile(provider string, data []byte, path ...string) error {
+	return stateStore.Write(stateContext, StateKey(provider, path...), data)
+}
+
+func DeleteStateDir(provider string, path ...string) error {
+	return stateStore.Delete(stateContext, StateKey(provider, path...))
+}
+
+func ListStateDir(provider string, path ...string) ([]string, error) {
+	return stateStore.List(stateContext, StateKey(provider, path...))
+}
+
+// IsStatePresent whether the state file is in the store, the errors other than a missing file
+// are treated as present so that an existing cluster is never overwritten
+func IsStatePresent(provider string, path ...string) bool {
+	_, err := ReadStateFile(provider, path...)
+	return !os.IsNotExist(err)
+}
+
+// FetchStateFile makes sure the state file is on the local disk and returns its path
+// the kubeconfig and the ssh private key are used from there by kubectl and ssh
+func FetchStateFile(provider string, path ...string) (string, error) {
+	if _, err := ReadStateFile(provider, path...); err != nil {
+		return "", err
+	}
+	return GetPath(OTHER_PATH, provider, path...), nil
+}
+
+// LocalStore keeps the state in ~/.ksctl/config of the machine
+type LocalStore struct{}
+
+func (LocalStore) path(key string) string {
+	parts := strings.Split(key, "/")
+	return getPaths(parts[0], parts[1:]...)
+}
+
+func (store LocalStore) Read(_ context.Context, key string) ([]byte, error) {
+	return os.ReadFile(store.path(key))
+}
+
+func (store LocalStore) Write(_ context.Context, key string, data []byte) error {
+	filePath := store.path(key)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	// the private key is refused by ssh when others can read it
+	perm := os.FileMode(0640)
+	if path.Base(key) == "keypair" {
+		perm = 0600
+	}
+	return os.WriteFile(filePath, data, perm)
+}
+
+func (store LocalStore) Create(_ context.Context, key string, data []byte) error {
+	filePath := store.path(key)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (store LocalStore) Delete(_ context.Context, key string) error {
+	return os.RemoveAll(store.path(key))
+}
+
+func (store LocalStore) List(_ context.Context, key string) ([]string, error) {
+	entries, err := os.ReadDir(store.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// S3Config the settings of the S3 compatible object store, saved as ~/.ksctl/cred/s3.json
+// the KSCTL_S3_* environment variables take precedence over the saved values
+type S3Config struct {
+	// Endpoint e.g. https://s3.us-east-1.amazonaws.com or http://localhost:9000 for MinIO
+	Endpoint string `json:"endpoint"`
+	Bucket   string `json:"bucket"`
+	Region   string `json:"region"`
+	// Prefix of the keys so that a bucket can be shared, e.g. team-a/
+	Prefix          string `json:"prefix"`
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+}
+
+// S3Store keeps the state in a bucket of an S3 compatible object store so that it is shared by the team
+// a copy of every file which is read or written is kept in ~/.ksctl/config for kubectl and ssh
+type S3Store struct {
+	config S3Config
+	client *s3.Client
+	cache  LocalStore
+}
+
+// NewS3Store validates the config, the objects are addressed path-style as endpoint/bucket/key
+// which every S3 compatible store supports
+func NewS3Store(config S3Config) (*S3Store, error) {
+	if len(config.Endpoint) == 0 || len(config.Bucket) == 0 {
+		return nil, fmt.Errorf("endpoint and bucket of the s3 state store are required")
+	}
+	if len(config.AccessKeyID) == 0 || len(config.SecretAccessKey) == 0 {
+		return nil, fmt.Errorf("access key id and secret access key of the s3 state store are required")
+	}
+	if _, err := url.ParseRequestURI(config.Endpoint); err != nil {
+		return nil, fmt.Errorf("invalid endpoint of the s3 state store: %v", err)
+	}
+	if len(config.Region) == 0 {
+		config.Region = "us-east-1"
+	}
+	config.Endpoint = strings.TrimRight(config.Endpoint, "/")
+	if len(config.Prefix) != 0 && !strings.HasSuffix(config.Prefix, "/") {
+		config.Prefix += "/"
+	}
+	return &S3Store{
+		config: config,
+		client: s3.New(s3.Options{
+			Region:           config.Region,
+			Credentials:      aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider(config.AccessKeyID, config.SecretAccessKey, "")),
+			EndpointResolver: s3.EndpointResolverFromURL(config.Endpoint),
+			UsePathStyle:     true,
+			HTTPClient:       awshttp.NewBuildableClient().WithTimeout(30 * time.Second),
+		}),
+	}, nil
+}
+
+func (store *S3Store) Read(ctx context.Context, key string) ([]byte, error) {
+	resp, err := store.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(store.config.Bucket),
+		Key:    aws.String(store.config.Prefix + key),
+	})
+	if s3StatusCode(err) == http.StatusNotFound {
+		return nil, &fs.PathError{Op: "read", Path: key, Err: fs.ErrNotExist}
+	}
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return data, store.cache.Write(ctx, key, data)
+}
+
+func (store *S3Store) Write(ctx context.Context, key string, data []byte) error {
+	_, err := store.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(store.config.Bucket),
+		Key:    aws.String(store.config.Prefix + key),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		return s3Error(err)
+	}
+	return store.cache.Write(ctx, key, data)
+}
+
+// Create uses the conditional write of S3 which fails when the object already exists
+func (store *S3Store) Create(ctx context.Context, key string, data []byte) error {
+	_, err := store.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(store.config.Bucket),
+		Key:    aws.String(store.config.Prefix + key),
+		Body:   bytes.NewReader(data),
+	}, func(options *s3.Options) {
+		options.APIOptions = append(options.APIOptions, smithyhttp.AddHeaderValue("If-None-Match", "*"))
+	})
+	// 409 is returned when a conflicting write is in progress
+	if status := s3StatusCode(err); status == http.StatusPreconditionFailed || status == http.StatusConflict {
+		return &fs.PathError{Op: "create", Path: key, Err: fs.ErrExist}
+	}
+	if err != nil {
+		return s3Error(err)
+	}
+	return store.cache.Write(ctx, key, data)
+}
+
+func (store *S3Store) Delete(ctx context.Context, key string) error {
+	keys, _, err := store.list(ctx, store.config.Prefix+key+"/", false)
+	if err != nil {
+		return err
+	}
+	for _, object := range append(keys, store.config.Prefix+key) {
+		_, err := store.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(store.config.Bucket),
+			Key:    aws.String(object),
+		})
+		if err != nil && s3StatusCode(err) != http.StatusNotFound {
+			return s3Error(err)
+		}
+	}
+	return store.cache.Delete(ctx, key)
+}
+
+func (store *S3Store) List(ctx context.Context, key string) ([]string, error) {
+	prefix := store.config.Prefix + key + "/"
+	_, dirs, err := store.list(ctx, prefix, true)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		names = append(names, strings.TrimSuffix(strings.TrimPrefix(dir, prefix), "/"))
+	}
+	return names, nil
+}
+
+// list returns the keys and, when grouped by the delimiter, the directories under the prefix
+func (store *S3Store) list(ctx context.Context, prefix string, delimited bool) (keys, dirs []string, err error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(store.config.Bucket),
+		Prefix: aws.String(prefix),
+	}
+	if delimited {
+		input.Delimiter = aws.String("/")
+	}
+	pages := s3.NewListObjectsV2Paginator(store.client, input)
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, nil, s3Error(err)
+		}
+		for _, content := range page.Contents {
+			keys = append(keys, aws.ToString(content.Key))
+		}
+		for _, commonPrefix := range page.CommonPrefixes {
+			dirs = append(dirs, aws.ToString(commonPrefix.Prefix))
+		}
+	}
+	return keys, dirs, nil
+}
+
+// s3StatusCode the http status of the response of the failed request, 0 when there was no response
+func s3StatusCode(err error) int {
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.HTTPStatusCode()
+	}
+	return 0
+}
+
+// s3Error the error returned by the object store
+func s3Error(err error) error {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return fmt.Errorf("s3 state store: %s: %s", apiErr.ErrorCode(), apiErr.ErrorMessage())
+	}
+	return fmt.Errorf("s3 state store: %w", err)
+}
+
+// LoadS3Config reads ~/.ksctl/cred/s3.json and applies the KSCTL_S3_* environment variables over it
+func LoadS3Config() (S3Config, error) {
+	var config S3Config
+	raw, err := readCred(logger.Logger{Level: logger.LevelError}, "s3", DEFAULT_PROFILE)
+	if err != nil && !os.IsNotExist(err) {
+		return config, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(raw, &config); err != nil {
+			return config, fmt.Errorf("invalid s3 state store config: %v", err)
+		}
+	}
+	for env, field := range map[string]*string{
+		"KSCTL_S3_ENDPOINT":          &config.Endpoint,
+		"KSCTL_S3_BUCKET":            &config.Bucket,
+		"KSCTL_S3_REGION":            &config.Region,
+		"KSCTL_S3_PREFIX":            &config.Prefix,
+		"KSCTL_S3_ACCESS_KEY_ID":     &config.AccessKeyID,
+		"KSCTL_S3_SECRET_ACCESS_KEY": &config.SecretAccessKey,
+	} {
+		if value, ok := os.LookupEnv(env); ok {
+			*field = value
+		}
+	}
+	return config, nil
+}
+
+// S3Credentials accepts the settings of the s3 state store from the user
+func S3Credentials(logging logger.Logger) bool {
+	var config S3Config
+	for _, field := range []struct {
+		prompt string
+		value  *string
+	}{
+		{"Enter the ENDPOINT e.g. https://s3.us-east-1.amazonaws.com 👇", &config.Endpoint},
+		{"Enter the BUCKET 👇", &config.Bucket},
+		{"Enter the REGION e.g. us-east-1 👇", &config.Region},
+	} {
+		logging.Print(field.prompt)
+		if _, err := fmt.Scanln(field.value); err != nil {
+			logging.Err(err.Error())
+			return false
+		}
+	}
+
+	logging.Print("Enter your ACCESS KEY ID 👇")
+	accessKey, err := UserInputCredentials(logging)
+	if err != nil {
+		logging.Err(err.Error())
+		return false
+	}
+	logging.Print("Enter your SECRET ACCESS KEY 👇")
+	secret, err := UserInputCredentials(logging)
+	if err != nil {
+		logging.Err(err.Error())
+		return false
+	}
+	config.AccessKeyID, config.SecretAccessKey = accessKey, secret
+
+	if err := SetCred(context.Background(), logging, "s3", map[string]string{
+		"endpoint":          config.Endpoint,
+		"bucket":            config.Bucket,
+		"region":            config.Region,
+		"access_key_id":     config.AccessKeyID,
+		"secret_access_key": config.SecretAccessKey,
+	}, true); err != nil {
+		logging.Err(err.Error())
+		return false
+	}
+	return true
+}
+
+func init() {
+	RegisterCredentials("s3", CredentialType{
+		Fields: []CredentialField{
+			{Key: "endpoint", Env: "KSCTL_S3_ENDPOINT", Prompt: "Enter the ENDPOINT e.g. https://s3.us-east-1.amazonaws.com"},
+			{Key: "bucket", Env: "KSCTL_S3_BUCKET", Prompt: "Enter the BUCKET"},
+			{Key: "region", Env: "KSCTL_S3_REGION", Prompt: "Enter the REGION e.g. us-east-1"},
+			{Key: "prefix", Env: "KSCTL_S3_PREFIX", Prompt: "Enter the PREFIX of the keys", Optional: true},
+			{Key: "access_key_id", Env: "KSCTL_S3_ACCESS_KEY_ID", Prompt: "Enter your ACCESS KEY ID", Secret: true},
+			{Key: "secret_access_key", Env: "KSCTL_S3_SECRET_ACCESS_KEY", Prompt: "Enter your SECRET ACCESS KEY", Secret: true},
+		},
+		Verify: verifyS3Credentials,
+	})
+}
+
+// verifyS3Credentials lists the keys under the prefix which needs the read access to the bucket
+func verifyS3Credentials(ctx context.Context, cred map[string]string) error {
+	store, err := NewS3Store(S3Config{
+		Endpoint:        cred["endpoint"],
+		Bucket:          cred["bucket"],
+		Region:          cred["region"],
+		Prefix:          cred["prefix"],
+		AccessKeyID:     cred["access_key_id"],
+		SecretAccessKey: cred["secret_access_key"],
+	})
+	if err != nil {
+		return err
+	}
+	_, _, err = store.list(ctx, store.config.Prefix, true)
+	return err
+}
+
+// NewStateStore returns the store by its name, STATE_STORE_LOCAL (default) or STATE_STORE_S3
+func NewStateStore(name string) (StateStore, error) {
+	switch name {
+	case "", STATE_STORE_LOCAL:
+		return LocalStore{}, nil
+	case STATE_STORE_S3:
+		config, err := LoadS3Config()
+		if err != nil {
+			return nil, err
+		}
+		return NewS3Store(config)
+	}
+	return nil, fmt.Errorf("invalid state store %q, use %s or %s", name, STATE_STORE_LOCAL, STATE_STORE_S3)
+}
@@ -0,0 +1,89 @@
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// the ownership tags put on every resource created by ksctl, the user tags cannot use the ksctl- prefix
+const (
+	TAG_CLUSTER_ID   = "ksctl-cluster-id"
+	TAG_CLUSTER_NAME = "ksctl-cluster"
+	TAG_ROLE         = "ksctl-role"
+	TAG_VERSION      = "ksctl-version"
+	TAG_CREATOR      = "ksctl-creator"
+
+	// TAG_ROLE_CLUSTER the role of the resources shared by the nodes e.g. network, resource group
+	TAG_ROLE_CLUSTER = "cluster"
+)
+
+// KsctlVersion is recorded in the ownership tags, the cli sets it to its build version
+var KsctlVersion = "dev"
+
+var (
+	tagKeyRegex   = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:-]{0,127}$`)
+	tagValueRegex = regexp.MustCompile(`^[A-Za-z0-9_.:/@+=-]{0,256}$`)
+
+	invalidTagValueChars = regexp.MustCompile(`[^A-Za-z0-9_.:/@+=-]`)
+)
+
+// ValidateTags checks the tags given by the user against the limits of all the providers
+// the values cannot have spaces as civo keeps the tags of an instance in a space separated list
+func ValidateTags(tags map[string]string) error {
+	for key, value := range tags {
+		if !tagKeyRegex.MatchString(key) {
+			return fmt.Errorf("invalid key of the tag %q", key)
+		}
+		if strings.HasPrefix(strings.ToLower(key), "ksctl-") {
+			return fmt.Errorf("the key of the tag %q is reserved, the ksctl- prefix is used by the ownership tags", key)
+		}
+		if !tagValueRegex.MatchString(value) {
+			return fmt.Errorf("invalid value of the tag %q", key+"="+value)
+		}
+	}
+	return nil
+}
+
+// ResourceTags the ownership tags of a resource of the cluster along with the tags given by the user
+// the creator is the user and host running ksctl
+func ResourceTags(ref ClusterRef, role string, userTags map[string]string) map[string]string {
+	tags := make(map[string]string, len(userTags)+5)
+	for key, value := range userTags {
+		tags[key] = value
+	}
+	tags[TAG_CLUSTER_ID] = ref.ID()
+	tags[TAG_CLUSTER_NAME] = ref.Name
+	tags[TAG_ROLE] = role
+	tags[TAG_VERSION] = KsctlVersion
+	tags[TAG_CREATOR] = tagValue(lockOwner())
+	return tags
+}
+
+// tagValue replaces the characters not allowed in the values of the tags by - and truncates it
+// e.g. the DOMAIN\user of windows becomes DOMAIN-user
+func tagValue(value string) string {
+	value = invalidTagValueChars.ReplaceAllString(value, "-")
+	if len(value) > 256 {
+		value = value[:256]
+	}
+	return value
+}
+
+// TagList the tags as sorted key=value pairs, for the providers whose tags are plain strings
+func TagList(tags map[string]string) []string {
+	list := make([]string, 0, len(tags))
+	for key, value := range tags {
+		list = append(list, key+"="+value)
+	}
+	sort.Strings(list)
+	return list
+}
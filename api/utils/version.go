@@ -0,0 +1,106 @@
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DEFAULT_KUBERNETES_VERSION is installed when the user doesn't pick a version
+const DEFAULT_KUBERNETES_VERSION = "1.26"
+
+// KubernetesRelease the version of a minor release of kubernetes as named by every provider and distribution
+type KubernetesRelease struct {
+	// Version the minor version given by the user e.g. 1.26
+	Version string
+	// K3s the INSTALL_K3S_VERSION of the HA clusters
+	K3s string
+	// RKE2 the INSTALL_RKE2_VERSION of the HA clusters
+	RKE2 string
+	// Kubeadm the --kubernetes-version of kubeadm, its packages come from the repository of the minor version
+	Kubeadm string
+	// KindImage the node image of the local cluster, built for the version of kind used by ksctl
+	KindImage string
+	// Civo the kubernetes version of the civo managed cluster
+	Civo string
+	// AKS the kubernetes version of the azure managed cluster, AKS picks the latest patch of the minor version
+	AKS string
+	// EKS the kubernetes version of the aws managed cluster
+	EKS string
+}
+
+// FLANNEL_MANIFEST the CNI applied by kubeadm, pinned so that the clusters of a version are alike
+const FLANNEL_MANIFEST = "https://github.com/flannel-io/flannel/releases/download/v0.21.5/kube-flannel.yml"
+
+// kubernetesReleases the node images of kind v0.17.0 which ksctl is built with limit the minor versions
+// the patch versions of the distributions are the ones released along with the versions of civo
+var kubernetesReleases = []KubernetesRelease{
+	{
+		Version:   "1.24",
+		K3s:       "v1.24.13+k3s1",
+		RKE2:      "v1.24.13+rke2r1",
+		Kubeadm:   "v1.24.13",
+		KindImage: "kindest/node:v1.24.7@sha256:577c630ce8e509131eab1aea12c022190978dd2f745aac5eb1fe65c0807eb315",
+		Civo:      "1.24.4-k3s1",
+		AKS:       "1.24",
+		EKS:       "1.24",
+	},
+	{
+		Version:   "1.25",
+		K3s:       "v1.25.9+k3s1",
+		RKE2:      "v1.25.9+rke2r1",
+		Kubeadm:   "v1.25.9",
+		KindImage: "kindest/node:v1.25.3@sha256:f52781bc0d7a19fb6c405c2af83abfeb311f130707a0e219175677e366cc45d1",
+		Civo:      "1.25.0-k3s1",
+		AKS:       "1.25",
+		EKS:       "1.25",
+	},
+	{
+		Version:   "1.26",
+		K3s:       "v1.26.4+k3s1",
+		RKE2:      "v1.26.4+rke2r1",
+		Kubeadm:   "v1.26.4",
+		KindImage: "kindest/node:v1.26.0@sha256:691e24bd2417609db7e589e1a479b902d2e209892a10ce375fab60a8407c7352",
+		Civo:      "1.26.4-k3s1",
+		AKS:       "1.26",
+		EKS:       "1.26",
+	},
+}
+
+// GetManagedKubernetesRelease returns the release of the version of the managed cluster
+// the release is empty when the version is empty so that the provider picks its default version
+func GetManagedKubernetesRelease(version string) (KubernetesRelease, error) {
+	if len(version) == 0 {
+		return KubernetesRelease{}, nil
+	}
+	return GetKubernetesRelease(version)
+}
+
+// KubernetesVersions the versions accepted by GetKubernetesRelease
+func KubernetesVersions() []string {
+	versions := make([]string, 0, len(kubernetesReleases))
+	for _, release := range kubernetesReleases {
+		versions = append(versions, release.Version)
+	}
+	return versions
+}
+
+// GetKubernetesRelease returns the release of the minor version e.g. 1.26 or v1.26
+// the default version is returned when the version is empty
+func GetKubernetesRelease(version string) (KubernetesRelease, error) {
+	if len(version) == 0 {
+		version = DEFAULT_KUBERNETES_VERSION
+	}
+	for _, release := range kubernetesReleases {
+		if strings.TrimPrefix(version, "v") == release.Version {
+			return release, nil
+		}
+	}
+	return KubernetesRelease{}, fmt.Errorf("invalid kubernetes version: %s, expected one of %s", version, strings.Join(KubernetesVersions(), ", "))
+}
@@ -17,7 +17,8 @@ mkdir -p ${HOME}/.ksctl/config/civo/ha
 mkdir -p ${HOME}/.ksctl/config/azure/ha
 mkdir ${HOME}/.ksctl/config/azure/managed
 mkdir ${HOME}/.ksctl/config/civo/managed
-mkdir ${HOME}/.ksctl/config/aws
+mkdir -p ${HOME}/.ksctl/config/aws/ha
+mkdir -p ${HOME}/.ksctl/config/aws/managed
 mkdir ${HOME}/.ksctl/config/local
 
 echo -e "\033[1;32mINSTALL COMPLETE\033[0m\n"
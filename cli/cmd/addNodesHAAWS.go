@@ -0,0 +1,64 @@
+package cmd
+
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+import (
+	util "github.com/kubesimplify/ksctl/api/utils"
+	"github.com/spf13/cobra"
+)
+
+var addMoreWorkerNodesHAAws = &cobra.Command{
+	Use:   "add-nodes",
+	Short: "Use to add more worker nodes in HA AWS k3s cluster",
+	Long: `It is used to add nodes to worker nodes in cluster with the given name from user. For example:
+
+ksctl create-cluster ha-aws add-nodes <arguments to aws cloud provider>
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := newLogger(cmd, true)
+
+		provider, err := newProvider("ha-aws", util.ClusterPayload{
+			ClusterName: awshncclustername,
+			Region:      awshncregion,
+			Spec: util.Machine{
+				Disk:          awshncnodesize,
+				HAWorkerNodes: awshncwp,
+			},
+		})
+		if err != nil {
+			logger.Err(err.Error())
+			return
+		}
+		ctx, cancel := newContext()
+		defer cancel()
+		err = provider.AddNodes(ctx, logger)
+		if err != nil {
+			logger.Err(err.Error())
+			return
+		}
+		logger.Info("ADDED WORKKER NODE(s)")
+	},
+}
+
+var (
+	// aw hc -> add workernodes to ha-aws
+	awshncregion      string
+	awshncclustername string
+	awshncnodesize    string
+	awshncwp          int
+)
+
+func init() {
+	createClusterHAAws.AddCommand(addMoreWorkerNodesHAAws)
+	addMoreWorkerNodesHAAws.Flags().StringVarP(&awshncclustername, "name", "n", "", "Cluster name")
+	addMoreWorkerNodesHAAws.Flags().StringVarP(&awshncnodesize, "node-size", "s", "t2.medium", "Node size")
+	addMoreWorkerNodesHAAws.Flags().StringVarP(&awshncregion, "region", "r", "", "Region")
+	addMoreWorkerNodesHAAws.Flags().IntVarP(&awshncwp, "worker-nodes", "w", 1, "no of worker nodes to be added")
+	addMoreWorkerNodesHAAws.Flags().BoolP("verbose", "v", true, "for verbose output")
+	addMoreWorkerNodesHAAws.MarkFlagRequired("name")
+	addMoreWorkerNodesHAAws.MarkFlagRequired("region")
+}
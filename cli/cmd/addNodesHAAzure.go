@@ -7,10 +7,7 @@ Kubesimplify
 				Avinesh Tripathi <avineshtripathi1@gmail.com>
 */
 import (
-	log "github.com/kubesimplify/ksctl/api/logger"
-
-	"github.com/kubesimplify/ksctl/api/azure"
-	"github.com/kubesimplify/ksctl/api/utils"
+	util "github.com/kubesimplify/ksctl/api/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -22,22 +19,23 @@ var addMoreWorkerNodesHAAzure = &cobra.Command{
 ksctl create-cluster ha-civo add-nodes <arguments to civo cloud provider>
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		isSet := cmd.Flags().Lookup("verbose").Changed
-		logger := log.Logger{Verbose: true}
-		if !isSet {
-			logger.Verbose = false
-		}
+		logger := newLogger(cmd, true)
 
-		payload := azure.AzureProvider{
+		provider, err := newProvider("ha-azure", util.ClusterPayload{
 			ClusterName: azhncclustername,
 			Region:      azhncregion,
-			HACluster:   true,
-			Spec: utils.Machine{
+			Spec: util.Machine{
 				Disk:          azhncnodesize,
 				HAWorkerNodes: azhncwp,
 			},
+		})
+		if err != nil {
+			logger.Err(err.Error())
+			return
 		}
-		err := payload.AddMoreWorkerNodes(logger)
+		ctx, cancel := newContext()
+		defer cancel()
+		err = provider.AddNodes(ctx, logger)
 		if err != nil {
 			logger.Err(err.Error())
 			return
@@ -7,10 +7,7 @@ Kubesimplify
 				Avinesh Tripathi <avineshtripathi1@gmail.com>
 */
 import (
-	log "github.com/kubesimplify/ksctl/api/logger"
-
-	"github.com/kubesimplify/ksctl/api/civo"
-	"github.com/kubesimplify/ksctl/api/utils"
+	util "github.com/kubesimplify/ksctl/api/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -22,21 +19,22 @@ var addMoreWorkerNodesHACivo = &cobra.Command{
 ksctl create-cluster ha-civo add-nodes <arguments to civo cloud provider>
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		isSet := cmd.Flags().Lookup("verbose").Changed
-		logger := log.Logger{Verbose: true}
-		if !isSet {
-			logger.Verbose = false
-		}
-		payload := civo.CivoProvider{
+		logger := newLogger(cmd, true)
+		provider, err := newProvider("ha-civo", util.ClusterPayload{
 			ClusterName: awhcclustername,
 			Region:      awhcregion,
-			HACluster:   true,
-			Spec: utils.Machine{
+			Spec: util.Machine{
 				Disk:          awhcnodesize,
 				HAWorkerNodes: awhcnowp,
 			},
+		})
+		if err != nil {
+			logger.Err(err.Error())
+			return
 		}
-		err := payload.AddMoreWorkerNodes(logger)
+		ctx, cancel := newContext()
+		defer cancel()
+		err = provider.AddNodes(ctx, logger)
 		if err != nil {
 			logger.Err(err.Error())
 			return
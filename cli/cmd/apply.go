@@ -0,0 +1,72 @@
+package cmd
+
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+import (
+	util "github.com/kubesimplify/ksctl/api/utils"
+	"github.com/spf13/cobra"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Use to create or update a cluster from the spec file",
+	Long: `It is used to create the cluster described in the spec file, if the cluster
+already exists the worker nodes of the HA cluster are added or deleted to match the spec. For example:
+
+ksctl apply -f cluster.yaml
+
+the deletion of the worker nodes asks for a confirmation, use --yes to skip it e.g. in CI
+
+apiVersion: ksctl.kubesimplify.com/v1alpha1
+kind: Cluster
+metadata:
+  name: demo
+spec:
+  provider: civo        # civo, azure, aws, local
+  region: LON1
+  ha: true
+  nodeSize: g3.small
+  kubernetesVersion: "1.26"
+  nodePools:
+    controlPlane: 3     # HA cluster
+    worker: 2           # HA cluster
+    # managed: 2        # managed cluster
+  cni: flannel          # managed civo cluster
+  apps:                 # managed civo cluster
+  - argo-cd
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := newLogger(cmd, true)
+
+		spec, err := util.LoadClusterSpec(applyFile)
+		if err != nil {
+			logger.Err(err.Error())
+			return
+		}
+		ctx, cancel := newContext()
+		defer cancel()
+		if err := util.ApplySpec(ctx, logger, spec, applyYes); err != nil {
+			logger.Err(err.Error())
+			return
+		}
+		logger.Info("APPLIED CLUSTER SPEC")
+	},
+}
+
+var (
+	applyFile string
+	applyYes  bool
+)
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+	applyCmd.Flags().StringVarP(&applyFile, "file", "f", "", "Path to the cluster spec file (YAML or JSON)")
+	applyCmd.Flags().BoolVarP(&applyYes, "yes", "y", false, "Delete the worker nodes without asking for a confirmation")
+	applyCmd.Flags().BoolP("verbose", "v", true, "for verbose output")
+	applyCmd.MarkFlagRequired("file")
+}
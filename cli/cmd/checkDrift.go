@@ -0,0 +1,135 @@
+package cmd
+
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kubesimplify/ksctl/api/logger"
+	util "github.com/kubesimplify/ksctl/api/utils"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+var checkDriftCmd = &cobra.Command{
+	Use:   "check-drift <clustername>",
+	Short: "Use to compare the state of a cluster with its provider",
+	Long: `It is used to find the resources of a cluster recorded in the state management files which
+were removed outside of ksctl, e.g. from the dashboard of the provider, and the resources of the
+cluster which are not recorded. With --fix the missing resources are removed from the state so
+that the later operations don't fail on them, the unexpected ones are only reported. For example:
+
+ksctl check-drift <clustername> -p <civo,local,ha-civo,ha-azure,azure,ha-aws,aws> -r <region>
+ksctl check-drift demo -p ha-azure -r eastus --fix
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		logging := newLogger(cmd, cdFix).With("cluster", args[0])
+		switch cdOutput {
+		case OUTPUT_TABLE, OUTPUT_JSON, OUTPUT_YAML:
+		default:
+			logging.Err(fmt.Sprintf("invalid --output %q, use %s, %s or %s", cdOutput, OUTPUT_TABLE, OUTPUT_JSON, OUTPUT_YAML))
+			return
+		}
+		if cdProvider != "local" && len(cdRegion) == 0 {
+			logging.Err("Region is Required")
+			return
+		}
+		provider, err := newProvider(cdProvider, util.ClusterPayload{
+			ClusterName: args[0],
+			Region:      cdRegion,
+		})
+		if err != nil {
+			logging.Err(err.Error())
+			return
+		}
+
+		ctx, cancel := newContext()
+		defer cancel()
+		stateLogging := logging
+		if !stateLogging.Verbose {
+			stateLogging.Level = logger.LevelWarn
+		}
+		report, err := provider.CheckDrift(ctx, stateLogging, cdFix)
+		if err != nil {
+			logging.Err(err.Error())
+			return
+		}
+		if err := printDriftReport(logging, report, cdOutput); err != nil {
+			logging.Err(err.Error())
+		}
+	},
+}
+
+var (
+	cdRegion   string
+	cdProvider string
+	cdOutput   string
+	cdFix      bool
+)
+
+// printDriftReport prints the drifted resources, the notes are left out of the json and yaml output
+func printDriftReport(logging logger.Logger, report util.DriftReport, output string) error {
+	switch output {
+	case OUTPUT_JSON:
+		raw, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("JSON Convertion failed: %w", err)
+		}
+		fmt.Println(string(raw))
+		return nil
+
+	case OUTPUT_YAML:
+		raw, err := yaml.Marshal(report)
+		if err != nil {
+			return fmt.Errorf("YAML Convertion failed: %w", err)
+		}
+		fmt.Print(string(raw))
+		return nil
+	}
+
+	if !report.HasDrift() {
+		logging.Info("No drift found", fmt.Sprintf("checked %d resources", report.Checked))
+		return nil
+	}
+
+	orDash := func(value string) string {
+		if len(value) == 0 {
+			return "-"
+		}
+		return value
+	}
+	rows := make([][]string, 0, len(report.Drifts))
+	for _, drift := range report.Drifts {
+		rows = append(rows, []string{drift.Kind, orDash(drift.Name), orDash(drift.ID), drift.Status})
+	}
+	logging.Table([]string{"KIND", "NAME", "ID", "STATUS"}, rows)
+
+	missing := len(report.Missing())
+	switch {
+	case report.Fixed:
+		logging.Info("Removed the missing resources from the state", fmt.Sprintf("%d of %d checked", missing, report.Checked))
+	case missing != 0:
+		logging.Note(fmt.Sprintf("%d of %d resources are missing, rerun with --fix to remove them from the state", missing, report.Checked))
+	}
+	if unexpected := len(report.Drifts) - missing; unexpected != 0 {
+		logging.Note(fmt.Sprintf("%d resources are not recorded in the state, remove them from the provider if they are not in use", unexpected))
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(checkDriftCmd)
+	checkDriftCmd.Flags().StringVarP(&cdRegion, "region", "r", "", "Region")
+	checkDriftCmd.Flags().StringVarP(&cdProvider, "provider", "p", "", "Provider")
+	checkDriftCmd.Flags().StringVarP(&cdOutput, "output", "o", OUTPUT_TABLE, "Output format: table, json or yaml")
+	checkDriftCmd.Flags().BoolVar(&cdFix, "fix", false, "Remove the missing resources from the state")
+	checkDriftCmd.Flags().BoolP("verbose", "v", false, "for verbose output")
+	checkDriftCmd.MarkFlagRequired("provider")
+}
@@ -7,6 +7,9 @@ Kubesimplify
 				Avinesh Tripathi <avineshtripathi1@gmail.com>
 */
 import (
+	"strings"
+
+	util "github.com/kubesimplify/ksctl/api/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -21,6 +24,38 @@ ksctl create-cluster ["azure", "gcp", "aws", "local"]
 `,
 }
 
+// ctags the --tags of the create-cluster commands of the cloud providers
+var ctags map[string]string
+
+// addTagsFlag adds --tags to the create-cluster command of a cloud provider
+func addTagsFlag(cmd *cobra.Command) {
+	cmd.Flags().StringToStringVar(&ctags, "tags", nil, "tags put on every resource of the cluster along with the ownership tags e.g. team=platform,env=dev")
+}
+
+// cdistribution the --distribution of the create-cluster commands of the HA clusters
+var cdistribution string
+
+// addDistributionFlag adds --distribution to the create-cluster command of a HA cluster
+func addDistributionFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&cdistribution, "distribution", util.DISTRIBUTION_K3S, "distribution of kubernetes installed on the nodes ("+strings.Join(util.Distributions(), ", ")+")")
+}
+
+// ck8sVersion the --k8s-version of the create-cluster commands
+var ck8sVersion string
+
+// addK8sVersionFlag adds --k8s-version to the create-cluster command
+func addK8sVersionFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&ck8sVersion, "k8s-version", "", "minor version of kubernetes ("+strings.Join(util.KubernetesVersions(), ", ")+"), the managed clusters use the default version of the provider and the others "+util.DEFAULT_KUBERNETES_VERSION+" when it isn't given")
+}
+
+// k8sVersion the --k8s-version of the command, empty when the user didn't set it
+func k8sVersion(cmd *cobra.Command) string {
+	if !cmd.Flags().Changed("k8s-version") {
+		return ""
+	}
+	return ck8sVersion
+}
+
 func init() {
 	rootCmd.AddCommand(createClusterCmd)
 }
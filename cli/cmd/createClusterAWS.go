@@ -6,19 +6,61 @@ Kubesimplify
 				Anurag Kumar <contact.anurag7@gmail.com>
 				Avinesh Tripathi <avineshtripathi1@gmail.com>
 */
-import "github.com/spf13/cobra"
+import (
+	util "github.com/kubesimplify/ksctl/api/utils"
+	"github.com/spf13/cobra"
+)
 
 var createClusterAws = &cobra.Command{
 	Use:   "aws",
 	Short: "Use to create a EKS cluster in AWS",
 	Long: `It is used to create cluster with the given name from user. For example:
 
-ksctl create-cluster aws <arguments to civo cloud provider>
+ksctl create-cluster aws <arguments to aws cloud provider>
 `,
-	Run: func(cmd *cobra.Command, args []string) {},
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := newLogger(cmd, true)
+
+		provider, err := newProvider("aws", util.ClusterPayload{
+			ClusterName: awsmcclusterName,
+			Region:      awsmcregion,
+			Spec: util.Machine{
+				ManagedNodes: awsmcnodeCount,
+				Disk:         awsmcsize,
+			},
+			Tags:              ctags,
+			KubernetesVersion: k8sVersion(cmd),
+		})
+		if err != nil {
+			logger.Err(err.Error())
+			return
+		}
+		ctx, cancel := newContext()
+		defer cancel()
+		err = provider.Create(ctx, logger)
+		if err != nil {
+			logger.Err(err.Error())
+			return
+		}
+		logger.Info("CREATED CLUSTER")
+	},
 }
 
+var (
+	awsmcclusterName string
+	awsmcnodeCount   int
+	awsmcsize        string
+	awsmcregion      string
+)
+
 func init() {
 	createClusterCmd.AddCommand(createClusterAws)
+	addTagsFlag(createClusterAws)
+	addK8sVersionFlag(createClusterAws)
+	createClusterAws.Flags().StringVarP(&awsmcclusterName, "name", "n", "", "Cluster name")
+	createClusterAws.Flags().StringVarP(&awsmcsize, "node-size", "s", "t2.medium", "Node size")
+	createClusterAws.Flags().StringVarP(&awsmcregion, "region", "r", "us-east-1", "Region")
+	createClusterAws.Flags().IntVarP(&awsmcnodeCount, "nodes", "N", 1, "Number of Nodes")
 	createClusterAws.Flags().BoolP("verbose", "v", true, "for verbose output")
+	createClusterAws.MarkFlagRequired("name")
 }
@@ -8,9 +8,6 @@ Avinesh Tripathi <avineshtripathi1@gmail.com>
 package cmd
 
 import (
-	log "github.com/kubesimplify/ksctl/api/logger"
-
-	"github.com/kubesimplify/ksctl/api/azure"
 	util "github.com/kubesimplify/ksctl/api/utils"
 	"github.com/spf13/cobra"
 )
@@ -23,22 +20,25 @@ var createClusterAzure = &cobra.Command{
 	ksctl create-cluster azure <arguments to civo cloud provider>
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		isSet := cmd.Flags().Lookup("verbose").Changed
-		logger := log.Logger{Verbose: true}
-		if !isSet {
-			logger.Verbose = false
-		}
+		logger := newLogger(cmd, true)
 
-		payload := &azure.AzureProvider{
+		provider, err := newProvider("azure", util.ClusterPayload{
 			ClusterName: azmcclusterName,
-			HACluster:   false,
 			Region:      azmcregion,
 			Spec: util.Machine{
 				ManagedNodes: azmcnodeCount,
 				Disk:         azmcsize,
 			},
+			Tags:              ctags,
+			KubernetesVersion: k8sVersion(cmd),
+		})
+		if err != nil {
+			logger.Err(err.Error())
+			return
 		}
-		err := payload.CreateCluster(logger)
+		ctx, cancel := newContext()
+		defer cancel()
+		err = provider.Create(ctx, logger)
 		if err != nil {
 			logger.Err(err.Error())
 			return
@@ -56,6 +56,8 @@ var (
 
 func init() {
 	createClusterCmd.AddCommand(createClusterAzure)
+	addTagsFlag(createClusterAzure)
+	addK8sVersionFlag(createClusterAzure)
 	createClusterAzure.Flags().StringVarP(&azmcclusterName, "name", "n", "", "Cluster name")
 	createClusterAzure.Flags().StringVarP(&azmcsize, "node-size", "s", "Standard_DS2_v2", "Node size")
 	createClusterAzure.Flags().StringVarP(&azmcregion, "region", "r", "eastus", "Region")
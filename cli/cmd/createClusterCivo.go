@@ -7,10 +7,6 @@ Kubesimplify
 				Avinesh Tripathi <avineshtripathi1@gmail.com>
 */
 import (
-	log "github.com/kubesimplify/ksctl/api/logger"
-
-	"github.com/kubesimplify/ksctl/api/civo"
-	"github.com/kubesimplify/ksctl/api/utils"
 	util "github.com/kubesimplify/ksctl/api/utils"
 	"github.com/spf13/cobra"
 )
@@ -23,24 +19,27 @@ var createClusterCivo = &cobra.Command{
 ksctl create-cluster civo <arguments to civo cloud provider>
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		isSet := cmd.Flags().Lookup("verbose").Changed
-		logger := log.Logger{Verbose: true}
-		if !isSet {
-			logger.Verbose = false
-		}
+		logger := newLogger(cmd, true)
 
-		payload := civo.CivoProvider{
+		provider, err := newProvider("civo", util.ClusterPayload{
 			ClusterName: cclusterName,
 			Region:      cregion,
 			Application: apps,
 			CNIPlugin:   cni,
-			HACluster:   false,
-			Spec: utils.Machine{
+			Spec: util.Machine{
 				Disk:         cspec.Disk,
 				ManagedNodes: cspec.ManagedNodes,
 			},
+			Tags:              ctags,
+			KubernetesVersion: k8sVersion(cmd),
+		})
+		if err != nil {
+			logger.Err(err.Error())
+			return
 		}
-		err := payload.CreateCluster(logger)
+		ctx, cancel := newContext()
+		defer cancel()
+		err = provider.Create(ctx, logger)
 		if err != nil {
 			logger.Err(err.Error())
 			return
@@ -59,6 +58,8 @@ var (
 
 func init() {
 	createClusterCmd.AddCommand(createClusterCivo)
+	addTagsFlag(createClusterCivo)
+	addK8sVersionFlag(createClusterCivo)
 	createClusterCivo.Flags().StringVarP(&cclusterName, "name", "n", "", "Cluster name")
 	createClusterCivo.Flags().StringVarP(&cspec.Disk, "nodeSize", "s", "g4s.kube.xsmall", "Node size")
 	createClusterCivo.Flags().StringVarP(&cregion, "region", "r", "", "Region")
@@ -0,0 +1,70 @@
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+Anurag Kumar <contact.anurag7@gmail.com>
+Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+package cmd
+
+import (
+	util "github.com/kubesimplify/ksctl/api/utils"
+	"github.com/spf13/cobra"
+)
+
+var createClusterHAAws = &cobra.Command{
+	Use:   "ha-aws",
+	Short: "Use to create a HA k3s cluster in AWS",
+	Long: `It is used to create cluster with the given name from user. For example:
+
+	ksctl create-cluster ha-aws <arguments to aws cloud provider>
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := newLogger(cmd, true)
+
+		provider, err := newProvider("ha-aws", util.ClusterPayload{
+			ClusterName: awshcclusterName,
+			Region:      awshcregion,
+			Spec: util.Machine{
+				Disk:                awshcsize,
+				HAControlPlaneNodes: awshcnodeCCP,
+				HAWorkerNodes:       awshcnodeCWP,
+			},
+			Tags:              ctags,
+			KubernetesVersion: k8sVersion(cmd),
+		})
+		if err != nil {
+			logger.Err(err.Error())
+			return
+		}
+		ctx, cancel := newContext()
+		defer cancel()
+		err = provider.Create(ctx, logger)
+		if err != nil {
+			logger.Err(err.Error())
+			return
+		}
+		logger.Info("CREATED CLUSTER")
+	},
+}
+
+var (
+	awshcclusterName string
+	awshcnodeCWP     int
+	awshcnodeCCP     int
+	awshcsize        string
+	awshcregion      string
+)
+
+func init() {
+	createClusterCmd.AddCommand(createClusterHAAws)
+	addTagsFlag(createClusterHAAws)
+	addK8sVersionFlag(createClusterHAAws)
+	createClusterHAAws.Flags().StringVarP(&awshcclusterName, "name", "n", "", "Cluster name")
+	createClusterHAAws.Flags().StringVarP(&awshcsize, "node-size", "s", "t2.medium", "Node size")
+	createClusterHAAws.Flags().StringVarP(&awshcregion, "region", "r", "us-east-1", "Region")
+	createClusterHAAws.Flags().IntVarP(&awshcnodeCWP, "worker-nodes", "w", 1, "Number of worker Nodes")
+	createClusterHAAws.Flags().IntVarP(&awshcnodeCCP, "control-nodes", "c", 3, "Number of control Nodes")
+	createClusterHAAws.Flags().BoolP("verbose", "v", true, "for verbose output")
+	createClusterHAAws.MarkFlagRequired("name")
+}
@@ -8,38 +8,47 @@ Avinesh Tripathi <avineshtripathi1@gmail.com>
 package cmd
 
 import (
-	log "github.com/kubesimplify/ksctl/api/logger"
-
-	"github.com/kubesimplify/ksctl/api/azure"
 	util "github.com/kubesimplify/ksctl/api/utils"
 	"github.com/spf13/cobra"
 )
 
 var createClusterHAAzure = &cobra.Command{
 	Use:   "ha-azure",
-	Short: "Use to create a HA k3s cluster in Azure",
+	Short: "Use to create a HA cluster in Azure",
 	Long: `It is used to create cluster with the given name from user. For example:
 
 	ksctl create-cluster ha-azure <arguments to civo cloud provider>
+
+	kubernetes is installed with k3s, --distribution rke2 or kubeadm installs it with them instead
+	ksctl create-cluster ha-azure -n demo --distribution kubeadm
+
+	if the creation fails the created resources are kept, to continue from the failed step
+	ksctl create-cluster ha-azure <same arguments> --resume
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		isSet := cmd.Flags().Lookup("verbose").Changed
-		logger := log.Logger{Verbose: true}
-		if !isSet {
-			logger.Verbose = false
-		}
+		logger := newLogger(cmd, true)
 
-		payload := &azure.AzureProvider{
+		provider, err := newProvider("ha-azure", util.ClusterPayload{
 			ClusterName: azhcclusterName,
-			HACluster:   true,
 			Region:      azhcregion,
 			Spec: util.Machine{
 				Disk:                azhcsize,
 				HAControlPlaneNodes: azhcnodeCCP,
 				HAWorkerNodes:       azhcnodeCWP,
 			},
+			Resume:            azhcresume,
+			CleanupOnFailure:  azhccleanup,
+			Tags:              ctags,
+			KubernetesVersion: k8sVersion(cmd),
+			Distribution:      cdistribution,
+		})
+		if err != nil {
+			logger.Err(err.Error())
+			return
 		}
-		err := payload.CreateCluster(logger)
+		ctx, cancel := newContext()
+		defer cancel()
+		err = provider.Create(ctx, logger)
 		if err != nil {
 			logger.Err(err.Error())
 			return
@@ -54,15 +63,22 @@ var (
 	azhcnodeCCP     int
 	azhcsize        string
 	azhcregion      string
+	azhcresume      bool
+	azhccleanup     bool
 )
 
 func init() {
 	createClusterCmd.AddCommand(createClusterHAAzure)
+	addTagsFlag(createClusterHAAzure)
+	addK8sVersionFlag(createClusterHAAzure)
+	addDistributionFlag(createClusterHAAzure)
 	createClusterHAAzure.Flags().StringVarP(&azhcclusterName, "name", "n", "", "Cluster name")
 	createClusterHAAzure.Flags().StringVarP(&azhcsize, "node-size", "s", "Standard_F2s", "Node size")
 	createClusterHAAzure.Flags().StringVarP(&azhcregion, "region", "r", "eastus", "Region")
 	createClusterHAAzure.Flags().IntVarP(&azhcnodeCWP, "worker-nodes", "w", 1, "Number of worker Nodes")
 	createClusterHAAzure.Flags().IntVarP(&azhcnodeCCP, "control-nodes", "c", 3, "Number of control Nodes")
+	createClusterHAAzure.Flags().BoolVar(&azhcresume, "resume", false, "continue the failed creation of the cluster from the saved state")
+	createClusterHAAzure.Flags().BoolVar(&azhccleanup, "cleanup-on-failure", false, "delete the created resources if the creation fails")
 	createClusterHAAzure.Flags().BoolP("verbose", "v", true, "for verbose output")
 	createClusterHAAzure.MarkFlagRequired("name")
 }
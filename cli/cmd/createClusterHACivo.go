@@ -7,37 +7,46 @@ Kubesimplify
 				Avinesh Tripathi <avineshtripathi1@gmail.com>
 */
 import (
-	log "github.com/kubesimplify/ksctl/api/logger"
-
-	"github.com/kubesimplify/ksctl/api/civo"
-	"github.com/kubesimplify/ksctl/api/utils"
+	util "github.com/kubesimplify/ksctl/api/utils"
 	"github.com/spf13/cobra"
 )
 
 var createClusterHACivo = &cobra.Command{
 	Use:   "ha-civo",
-	Short: "Use to create a HA CIVO k3s cluster",
+	Short: "Use to create a HA CIVO cluster",
 	Long: `It is used to create cluster with the given name from user. For example:
 
 ksctl create-cluster ha-civo <arguments to civo cloud provider>
+
+kubernetes is installed with k3s, --distribution rke2 or kubeadm installs it with them instead
+ksctl create-cluster ha-civo -n demo --distribution rke2
+
+if the creation fails the created resources are kept, to continue from the failed step
+ksctl create-cluster ha-civo <same arguments> --resume
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		isSet := cmd.Flags().Lookup("verbose").Changed
-		logger := log.Logger{Verbose: true}
-		if !isSet {
-			logger.Verbose = false
-		}
-		payload := civo.CivoProvider{
+		logger := newLogger(cmd, true)
+		provider, err := newProvider("ha-civo", util.ClusterPayload{
 			ClusterName: chcclustername,
 			Region:      chcregion,
-			HACluster:   true,
-			Spec: utils.Machine{
+			Spec: util.Machine{
 				Disk:                chcnodesize,
 				HAControlPlaneNodes: chcnocp,
 				HAWorkerNodes:       chcnowp,
 			},
+			Resume:            chcresume,
+			CleanupOnFailure:  chccleanup,
+			Tags:              ctags,
+			KubernetesVersion: k8sVersion(cmd),
+			Distribution:      cdistribution,
+		})
+		if err != nil {
+			logger.Err(err.Error())
+			return
 		}
-		err := payload.CreateCluster(logger)
+		ctx, cancel := newContext()
+		defer cancel()
+		err = provider.Create(ctx, logger)
 		if err != nil {
 			logger.Err(err.Error())
 			return
@@ -53,15 +62,22 @@ var (
 	chcnodesize    string
 	chcnocp        int
 	chcnowp        int
+	chcresume      bool
+	chccleanup     bool
 )
 
 func init() {
 	createClusterCmd.AddCommand(createClusterHACivo)
+	addTagsFlag(createClusterHACivo)
+	addK8sVersionFlag(createClusterHACivo)
+	addDistributionFlag(createClusterHACivo)
 	createClusterHACivo.Flags().StringVarP(&chcnodesize, "nodeSize", "s", "g3.small", "Node size")
 	createClusterHACivo.Flags().StringVarP(&chcclustername, "name", "n", "", "Cluster name")
 	createClusterHACivo.Flags().StringVarP(&chcregion, "region", "r", "LON1", "Region")
 	createClusterHACivo.Flags().IntVarP(&chcnocp, "control-nodes", "c", 3, "no of control plane nodes")
 	createClusterHACivo.Flags().IntVarP(&chcnowp, "worker-nodes", "w", 1, "no of worker nodes")
+	createClusterHACivo.Flags().BoolVar(&chcresume, "resume", false, "continue the failed creation of the cluster from the saved state")
+	createClusterHACivo.Flags().BoolVar(&chccleanup, "cleanup-on-failure", false, "delete the created resources if the creation fails")
 	createClusterHACivo.Flags().BoolP("verbose", "v", true, "for verbose output")
 	createClusterHACivo.MarkFlagRequired("name")
 }
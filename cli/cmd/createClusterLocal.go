@@ -7,9 +7,6 @@ Kubesimplify
 				Avinesh Tripathi <avineshtripathi1@gmail.com>
 */
 import (
-	log "github.com/kubesimplify/ksctl/api/logger"
-
-	"github.com/kubesimplify/ksctl/api/local"
 	util "github.com/kubesimplify/ksctl/api/utils"
 	"github.com/spf13/cobra"
 )
@@ -22,15 +19,23 @@ var createClusterLocal = &cobra.Command{
 ksctl create-cluster local <arguments to civo cloud provider>
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		isSet := cmd.Flags().Lookup("verbose").Changed
-		logger := log.Logger{Verbose: true}
-		if !isSet {
-			logger.Verbose = false
-		}
+		logger := newLogger(cmd, true)
 
-		cargo := local.ClusterInfoInjecter(clocalclusterName, clocalspec.ManagedNodes)
+		provider, err := newProvider("local", util.ClusterPayload{
+			ClusterName: clocalclusterName,
+			Spec: util.Machine{
+				ManagedNodes: clocalspec.ManagedNodes,
+			},
+			KubernetesVersion: k8sVersion(cmd),
+		})
+		if err != nil {
+			logger.Err(err.Error())
+			return
+		}
 		logger.Info("Building cluster", "")
-		if err := local.CreateCluster(logger, cargo); err != nil {
+		ctx, cancel := newContext()
+		defer cancel()
+		if err := provider.Create(ctx, logger); err != nil {
 			logger.Err(err.Error())
 			return
 		}
@@ -45,6 +50,7 @@ var (
 
 func init() {
 	createClusterCmd.AddCommand(createClusterLocal)
+	addK8sVersionFlag(createClusterLocal)
 	createClusterLocal.Flags().StringVarP(&clocalclusterName, "name", "n", "demo", "Cluster name")
 	createClusterLocal.Flags().IntVarP(&clocalspec.ManagedNodes, "nodes", "N", 1, "Number of Nodes")
 	createClusterLocal.Flags().BoolP("verbose", "v", true, "Verbose output")
@@ -8,20 +8,28 @@ Kubesimplify
 */
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
-
-	log "github.com/kubesimplify/ksctl/api/logger"
+	"io"
+	"os"
+	"strconv"
+	"strings"
 
 	eks "github.com/kubesimplify/ksctl/api/aws"
 	aks "github.com/kubesimplify/ksctl/api/azure"
 	"github.com/kubesimplify/ksctl/api/civo"
+	log "github.com/kubesimplify/ksctl/api/logger"
+	util "github.com/kubesimplify/ksctl/api/utils"
 	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 const (
 	AWS   = 1
 	AZURE = 2
 	CIVO  = 3
+	S3    = 4
 )
 
 // initCmd represents the init command
@@ -30,50 +38,356 @@ var credCmd = &cobra.Command{
 	Short: "Login with your Cloud-provider Credentials",
 	Long: `login with your cloud provider credentials
 `,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logging := log.Logger{Format: logFormat, NoColor: noColor}
 		isSuccess := false
 		fmt.Println(`
 1> AWS (EKS)
 2> Azure (AKS)
-3> Civo (K3s)`)
+3> Civo (K3s)
+4> S3 compatible state store`)
 		choice := 0
-		_, err := fmt.Scanf("%d", &choice)
-		if err != nil {
-			panic(err.Error())
+		if _, err := fmt.Scanf("%d", &choice); err != nil {
+			choice = 0
 		}
 		switch choice {
-		case 1, 2, 3:
+		case 1, 2, 3, 4:
 			fmt.Println("Enter your credentials")
 		default:
-			err := fmt.Errorf("given Choice is Invalid")
-			fmt.Println(err.Error())
-			return
+			return credFailure(cmd, logging, fmt.Errorf("given Choice is Invalid"))
 		}
 
 		isSuccess = storeCredentials(cmd, choice)
 		if !isSuccess {
-			fmt.Println("Login Failed")
+			return credFailure(cmd, logging, fmt.Errorf("Login Failed"))
 		}
 		fmt.Println("Login Success")
+		return nil
+	},
+}
+
+// credFailure logs the error of the cred command and returns it so that ksctl exits with status 1
+// cobra doesn't print it again along with the usage as the flags and the arguments were valid
+func credFailure(cmd *cobra.Command, logging log.Logger, err error) error {
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	logging.Err(err.Error())
+	return err
+}
+
+// keyFile is used instead of the passphrase to encrypt and decrypt the credentials
+var keyFile string
+
+var credEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt the credentials saved by the older versions",
+	Long: `The credentials are encrypted with a key derived from the passphrase, which is asked on the terminal
+or read from the KSCTL_PASSPHRASE environment variable, or from the key file (--key-file or KSCTL_KEY_FILE).
+The credentials saved in plaintext by the older versions of ksctl are encrypted by this command. For example:
+
+KSCTL_PASSPHRASE=<passphrase> ksctl cred encrypt
+ksctl cred encrypt --key-file ~/.ksctl.key
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// newLogger would take the name of the subcommand as the provider
+		logging := log.Logger{Format: logFormat, NoColor: noColor, Verbose: cmd.Flags().Changed("verbose")}
+		encrypted, err := util.EncryptCredentials(logging)
+		if err != nil {
+			return credFailure(cmd, logging, err)
+		}
+		if len(encrypted) == 0 {
+			logging.Note("No plaintext credentials found")
+			return nil
+		}
+		logging.Info("ENCRYPTED CREDENTIALS", strings.Join(encrypted, ", "))
+		return nil
+	},
+}
+
+var credAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Save the credentials of a profile",
+	Long: `Every provider can have several named profiles of credentials e.g. one per account or subscription.
+The profile is selected by --profile on the cluster commands, the clusters remember the profile they were
+created with and the default profile is used otherwise. For example:
+
+ksctl cred add --provider <civo,azure,aws> --profile prod
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logging := log.Logger{Format: logFormat, NoColor: noColor}
+		choice, ok := credProviders[strings.ToLower(credProvider)]
+		if !ok {
+			return credFailure(cmd, logging, fmt.Errorf("invalid provider: %s", credProvider))
+		}
+		if len(profile) == 0 {
+			profile = util.DEFAULT_PROFILE
+		}
+		if !util.IsValidProfile(profile) {
+			return credFailure(cmd, logging, fmt.Errorf("invalid profile: %s", profile))
+		}
+		if !storeCredentials(cmd, choice) {
+			return credFailure(cmd, logging, fmt.Errorf("Login Failed"))
+		}
+		logging.Info("SAVED PROFILE", credProvider+"/"+profile)
+		return nil
+	},
+}
+
+var credDefaultCmd = &cobra.Command{
+	Use:   "default",
+	Short: "Change the default profile of a provider",
+	Long: `The default profile is used by the new clusters when --profile is not given. For example:
+
+ksctl cred default --provider <civo,azure,aws> --profile prod
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logging := log.Logger{Format: logFormat, NoColor: noColor}
+		if _, ok := credProviders[strings.ToLower(credProvider)]; !ok {
+			return credFailure(cmd, logging, fmt.Errorf("invalid provider: %s", credProvider))
+		}
+		if len(profile) == 0 {
+			return credFailure(cmd, logging, fmt.Errorf("Profile is Required"))
+		}
+		if err := util.SetDefaultProfile(strings.ToLower(credProvider), profile); err != nil {
+			return credFailure(cmd, logging, err)
+		}
+		logging.Info("DEFAULT PROFILE", credProvider+"/"+profile)
+		return nil
+	},
+}
+
+var credSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Verify and save the credentials without the interactive prompts",
+	Long: `Every value of the credentials is taken from its flag, otherwise from --stdin, otherwise from its
+environment variable and it is asked on the terminal when none of them has it. The credentials are verified
+against the provider before they are saved unless --skip-verify is given. --stdin reads a JSON object or
+key=value lines. For example:
+
+ksctl cred set --provider civo --token <token>
+CIVO_TOKEN=<token> ksctl cred set --provider civo --profile prod
+vault kv get -format=json -field=data secret/azure | ksctl cred set --provider azure --stdin
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logging := newLogger(cmd, false)
+		provider := strings.ToLower(credProvider)
+		credType, err := util.GetCredentialType(provider)
+		if err != nil {
+			return credFailure(cmd, logging, err)
+		}
+		if len(profile) == 0 {
+			profile = util.DEFAULT_PROFILE
+		}
+		if !util.IsValidProfile(profile) {
+			return credFailure(cmd, logging, fmt.Errorf("invalid profile: %s", profile))
+		}
+
+		var fromStdin map[string]string
+		if credStdin {
+			if fromStdin, err = readCredentials(os.Stdin); err != nil {
+				return credFailure(cmd, logging, err)
+			}
+		}
+
+		cred := map[string]string{}
+		for _, field := range credType.Fields {
+			value := ""
+			switch {
+			case cmd.Flags().Changed(field.Flag()):
+				value = *credFlags[field.Key]
+			case len(fromStdin[field.Key]) != 0:
+				value = fromStdin[field.Key]
+			case len(os.Getenv(field.Env)) != 0:
+				value = os.Getenv(field.Env)
+			case !field.Optional && !credStdin && terminal.IsTerminal(int(os.Stdin.Fd())):
+				logging.Print(field.Prompt + " 👇")
+				if value, err = util.UserInputCredentials(logging); err != nil {
+					return credFailure(cmd, logging, err)
+				}
+			}
+			if len(value) != 0 {
+				cred[field.Key] = strings.TrimSpace(value)
+			}
+		}
+
+		ctx, cancel := newContext()
+		defer cancel()
+		util.UseProfile(provider, profile)
+		if err := util.SetCred(ctx, logging, provider, cred, !credSkipVerify); err != nil {
+			return credFailure(cmd, logging, err)
+		}
+		logging.Info("SAVED PROFILE", provider+"/"+profile)
+		return nil
+	},
+}
+
+// readCredentials parses the credentials given on the stdin as a JSON object or key=value lines
+// the keys are the names of the fields or of their flags
+func readCredentials(in io.Reader) (map[string]string, error) {
+	raw, err := io.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	raw = bytes.TrimSpace(raw)
+	cred := map[string]string{}
+	if bytes.HasPrefix(raw, []byte("{")) {
+		if err := json.Unmarshal(raw, &cred); err != nil {
+			return nil, fmt.Errorf("invalid credentials on the stdin: %v", err)
+		}
+	} else {
+		for _, line := range strings.Split(string(raw), "\n") {
+			line = strings.TrimSpace(line)
+			if len(line) == 0 || strings.HasPrefix(line, "#") {
+				continue
+			}
+			key, value, found := strings.Cut(line, "=")
+			if !found {
+				return nil, fmt.Errorf("invalid credentials on the stdin: expected key=value, got %s", key)
+			}
+			cred[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	normalized := make(map[string]string, len(cred))
+	for key, value := range cred {
+		normalized[strings.ReplaceAll(strings.ToLower(key), "-", "_")] = value
+	}
+	return normalized, nil
+}
+
+var credListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the saved credentials without revealing them",
+	Long: `Lists the saved profiles of every provider, whether they are the default one, encrypted at rest
+and whether the environment variables of the provider are set. The credentials are not decrypted. For example:
+
+ksctl cred list
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logging := log.Logger{Format: logFormat, NoColor: noColor}
+		statuses, err := util.ListCred()
+		if err != nil {
+			return credFailure(cmd, logging, err)
+		}
+		if len(statuses) == 0 {
+			logging.Note("No credentials found, save them with `ksctl cred set`")
+			return nil
+		}
+		var rows [][]string
+		for _, status := range statuses {
+			rows = append(rows, []string{status.Provider, status.Profile,
+				strconv.FormatBool(status.Default), strconv.FormatBool(status.Encrypted), strconv.FormatBool(status.Env)})
+		}
+		logging.Table([]string{"PROVIDER", "PROFILE", "DEFAULT", "ENCRYPTED", "ENV"}, rows)
+		return nil
+	},
+}
+
+var credVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the saved credentials against the providers",
+	Long: `Verifies every saved profile, or the ones of the --provider and --profile, with a cheap read only call
+to the provider. For example:
+
+ksctl cred verify
+ksctl cred verify --provider azure --profile prod
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logging := log.Logger{Format: logFormat, NoColor: noColor}
+		statuses, err := util.ListCred()
+		if err != nil {
+			return credFailure(cmd, logging, err)
+		}
+		ctx, cancel := newContext()
+		defer cancel()
+		provider := strings.ToLower(credProvider)
+		var rows [][]string
+		failed := false
+		for _, status := range statuses {
+			if (len(provider) != 0 && status.Provider != provider) || (len(profile) != 0 && status.Profile != profile) {
+				continue
+			}
+			util.UseProfile(status.Provider, status.Profile)
+			result := "valid"
+			if err := util.VerifyCred(ctx, logging, status.Provider); err != nil {
+				result = err.Error()
+				failed = true
+			}
+			rows = append(rows, []string{status.Provider, status.Profile, result})
+		}
+		if len(rows) == 0 {
+			return credFailure(cmd, logging, fmt.Errorf("No credentials found to verify"))
+		}
+		logging.Table([]string{"PROVIDER", "PROFILE", "STATUS"}, rows)
+		if failed {
+			return credFailure(cmd, logging, fmt.Errorf("Some credentials are invalid"))
+		}
+		return nil
+	},
+}
 
+var credRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Remove the saved credentials of a profile",
+	Long: `Removes the credentials of the profile, the default profile is removed when --profile is not given.
+For example:
+
+ksctl cred remove --provider civo --profile prod
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logging := log.Logger{Format: logFormat, NoColor: noColor}
+		provider := strings.ToLower(credProvider)
+		if _, err := util.GetCredentialType(provider); err != nil {
+			return credFailure(cmd, logging, err)
+		}
+		if len(profile) == 0 {
+			profile = util.DEFAULT_PROFILE
+		}
+		if !util.IsValidProfile(profile) {
+			return credFailure(cmd, logging, fmt.Errorf("invalid profile: %s", profile))
+		}
+		if err := util.RemoveCred(provider, profile); err != nil {
+			return credFailure(cmd, logging, err)
+		}
+		logging.Info("REMOVED PROFILE", provider+"/"+profile)
+		return nil
 	},
 }
 
+var (
+	// credFlags the values of the flags of cred set by the key of the field
+	credFlags      = map[string]*string{}
+	credStdin      bool
+	credSkipVerify bool
+)
+
+// credProviders the providers which support the profiles
+var credProviders = map[string]int{"aws": AWS, "azure": AZURE, "civo": CIVO}
+
+// credProvider is the --provider of the cred subcommands
+var credProvider string
+
 func storeCredentials(cmd *cobra.Command, provider int) bool {
-	isSet := cmd.Flags().Lookup("verbose").Changed
-	logger := log.Logger{Verbose: true}
-	if !isSet {
-		logger.Verbose = false
+	// the credentials are not written to the log file
+	logger := newLogger(cmd, false)
+
+	if len(profile) != 0 {
+		for name, choice := range credProviders {
+			if choice == provider {
+				util.UseProfile(name, profile)
+			}
+		}
 	}
 
-	//TODO: Verify the Credentials
+	// the credentials are verified against the provider before they are saved
 	switch provider {
 	case AWS:
-		return eks.Credentials()
+		return eks.Credentials(logger)
 	case CIVO:
 		return civo.Credentials(logger)
 	case AZURE:
 		return aks.Credentials(logger)
+	case S3:
+		return util.S3Credentials(logger)
 	default:
 		return false
 	}
@@ -81,6 +395,49 @@ func storeCredentials(cmd *cobra.Command, provider int) bool {
 
 func init() {
 	rootCmd.AddCommand(credCmd)
+	credCmd.Annotations = map[string]string{noStateStore: ""}
 	credCmd.Flags().BoolP("verbose", "v", true, "for verbose output")
 
+	credCmd.AddCommand(credAddCmd)
+	credAddCmd.Annotations = map[string]string{noStateStore: ""}
+	credAddCmd.Flags().StringVarP(&credProvider, "provider", "p", "", "Provider (civo, azure or aws)")
+	credAddCmd.MarkFlagRequired("provider")
+
+	credCmd.AddCommand(credDefaultCmd)
+	credDefaultCmd.Annotations = map[string]string{noStateStore: ""}
+	credDefaultCmd.Flags().StringVarP(&credProvider, "provider", "p", "", "Provider (civo, azure or aws)")
+	credDefaultCmd.MarkFlagRequired("provider")
+
+	credCmd.AddCommand(credSetCmd)
+	credSetCmd.Annotations = map[string]string{noStateStore: ""}
+	credSetCmd.Flags().StringVarP(&credProvider, "provider", "p", "", "Provider ("+strings.Join(util.CredentialProviders(), ", ")+")")
+	credSetCmd.Flags().BoolVar(&credStdin, "stdin", false, "read the credentials from the stdin as a JSON object or key=value lines")
+	credSetCmd.Flags().BoolVar(&credSkipVerify, "skip-verify", false, "save the credentials without verifying them")
+	for _, provider := range util.CredentialProviders() {
+		credType, _ := util.GetCredentialType(provider)
+		for _, field := range credType.Fields {
+			if _, ok := credFlags[field.Key]; ok {
+				continue
+			}
+			credFlags[field.Key] = credSetCmd.Flags().String(field.Flag(), "", fmt.Sprintf("%s of the credentials, defaults to $%s", field.Key, field.Env))
+		}
+	}
+	credSetCmd.MarkFlagRequired("provider")
+
+	credCmd.AddCommand(credListCmd)
+	credListCmd.Annotations = map[string]string{noStateStore: ""}
+
+	credCmd.AddCommand(credVerifyCmd)
+	credVerifyCmd.Annotations = map[string]string{noStateStore: ""}
+	credVerifyCmd.Flags().StringVarP(&credProvider, "provider", "p", "", "Provider ("+strings.Join(util.CredentialProviders(), ", ")+")")
+
+	credCmd.AddCommand(credRemoveCmd)
+	credRemoveCmd.Annotations = map[string]string{noStateStore: ""}
+	credRemoveCmd.Flags().StringVarP(&credProvider, "provider", "p", "", "Provider ("+strings.Join(util.CredentialProviders(), ", ")+")")
+	credRemoveCmd.MarkFlagRequired("provider")
+
+	credCmd.AddCommand(credEncryptCmd)
+	credEncryptCmd.Annotations = map[string]string{noStateStore: ""}
+	credEncryptCmd.Flags().BoolP("verbose", "v", false, "for verbose output")
+
 }
@@ -0,0 +1,55 @@
+package cmd
+
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+import (
+	util "github.com/kubesimplify/ksctl/api/utils"
+	"github.com/spf13/cobra"
+)
+
+var deleteClusterAws = &cobra.Command{
+	Use:   "aws",
+	Short: "Use to delete a EKS cluster in AWS",
+	Long: `It is used to delete cluster with the given name from user. For example:
+
+ksctl delete-cluster aws <arguments to aws cloud provider>
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := newLogger(cmd, true)
+
+		provider, err := newProvider("aws", util.ClusterPayload{
+			ClusterName: awsdcclusterName,
+			Region:      awsdcregion,
+		})
+		if err != nil {
+			logger.Err(err.Error())
+			return
+		}
+		ctx, cancel := newContext()
+		defer cancel()
+		err = provider.Delete(ctx, logger)
+		if err != nil {
+			logger.Err(err.Error())
+			return
+		}
+		logger.Info("DELETED CLUSTER")
+	},
+}
+
+var (
+	awsdcclusterName string
+	awsdcregion      string
+)
+
+func init() {
+	deleteClusterCmd.AddCommand(deleteClusterAws)
+	deleteClusterAws.Flags().StringVarP(&awsdcclusterName, "name", "n", "", "Cluster name")
+	deleteClusterAws.Flags().StringVarP(&awsdcregion, "region", "r", "us-east-1", "Region")
+	deleteClusterAws.Flags().BoolP("verbose", "v", true, "for verbose output")
+	deleteClusterAws.MarkFlagRequired("name")
+	deleteClusterAws.MarkFlagRequired("region")
+}
@@ -7,9 +7,7 @@ Kubesimplify
 				Avinesh Tripathi <avineshtripathi1@gmail.com>
 */
 import (
-	log "github.com/kubesimplify/ksctl/api/logger"
-
-	"github.com/kubesimplify/ksctl/api/azure"
+	util "github.com/kubesimplify/ksctl/api/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -21,18 +19,19 @@ var deleteClusterAzure = &cobra.Command{
 ksctl create-cluster azure <arguments to civo cloud provider>
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		isSet := cmd.Flags().Lookup("verbose").Changed
-		logger := log.Logger{Verbose: true}
-		if !isSet {
-			logger.Verbose = false
-		}
+		logger := newLogger(cmd, true)
 
-		payload := &azure.AzureProvider{
+		provider, err := newProvider("azure", util.ClusterPayload{
 			ClusterName: azdcclusterName,
-			HACluster:   false,
 			Region:      azdcregion,
+		})
+		if err != nil {
+			logger.Err(err.Error())
+			return
 		}
-		err := payload.DeleteCluster(logger)
+		ctx, cancel := newContext()
+		defer cancel()
+		err = provider.Delete(ctx, logger)
 		if err != nil {
 			logger.Err(err.Error())
 			return
@@ -8,9 +8,7 @@ Kubesimplify
 */
 
 import (
-	log "github.com/kubesimplify/ksctl/api/logger"
-
-	"github.com/kubesimplify/ksctl/api/civo"
+	util "github.com/kubesimplify/ksctl/api/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -22,18 +20,19 @@ var deleteClusterCivo = &cobra.Command{
 ksctl delete-cluster civo
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		isSet := cmd.Flags().Lookup("verbose").Changed
-		logger := log.Logger{Verbose: true}
-		if !isSet {
-			logger.Verbose = false
-		}
+		logger := newLogger(cmd, true)
 
-		payload := civo.CivoProvider{
+		provider, err := newProvider("civo", util.ClusterPayload{
 			ClusterName: dclusterName,
 			Region:      dregion,
-			HACluster:   false,
+		})
+		if err != nil {
+			logger.Err(err.Error())
+			return
 		}
-		err := payload.DeleteCluster(logger)
+		ctx, cancel := newContext()
+		defer cancel()
+		err = provider.Delete(ctx, logger)
 		if err != nil {
 			logger.Err(err.Error())
 			return
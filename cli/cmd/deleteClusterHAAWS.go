@@ -0,0 +1,56 @@
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+Anurag Kumar <contact.anurag7@gmail.com>
+Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+package cmd
+
+import (
+	util "github.com/kubesimplify/ksctl/api/utils"
+	"github.com/spf13/cobra"
+)
+
+var deleteClusterHAAws = &cobra.Command{
+	Use:   "ha-aws",
+	Short: "Use to delete a HA k3s cluster in AWS",
+	Long: `It is used to delete cluster with the given name from user. For example:
+
+	ksctl delete-cluster ha-aws <arguments to aws cloud provider>
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := newLogger(cmd, true)
+
+		provider, err := newProvider("ha-aws", util.ClusterPayload{
+			ClusterName: awshdclusterName,
+			Region:      awshdregion,
+		})
+		if err != nil {
+			logger.Err(err.Error())
+			return
+		}
+		ctx, cancel := newContext()
+		defer cancel()
+		err = provider.Delete(ctx, logger)
+		if err != nil {
+			logger.Err(err.Error())
+			return
+		}
+		logger.Info("DELETED CLUSTER")
+	},
+}
+
+var (
+	awshdclusterName string
+	awshdregion      string
+)
+
+func init() {
+	deleteClusterCmd.AddCommand(deleteClusterHAAws)
+	deleteClusterHAAws.Flags().StringVarP(&awshdclusterName, "name", "n", "", "Cluster name")
+	deleteClusterHAAws.Flags().StringVarP(&awshdregion, "region", "r", "us-east-1", "Region")
+	deleteClusterHAAws.Flags().BoolP("verbose", "v", true, "for verbose output")
+	deleteClusterHAAws.MarkFlagRequired("name")
+	deleteClusterHAAws.MarkFlagRequired("region")
+}
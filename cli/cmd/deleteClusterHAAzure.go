@@ -8,9 +8,7 @@ Avinesh Tripathi <avineshtripathi1@gmail.com>
 package cmd
 
 import (
-	log "github.com/kubesimplify/ksctl/api/logger"
-
-	"github.com/kubesimplify/ksctl/api/azure"
+	util "github.com/kubesimplify/ksctl/api/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -22,18 +20,19 @@ var deleteClusterHAAzure = &cobra.Command{
 	ksctl delete-cluster ha-azure <arguments to civo cloud provider>
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		isSet := cmd.Flags().Lookup("verbose").Changed
-		logger := log.Logger{Verbose: true}
-		if !isSet {
-			logger.Verbose = false
-		}
+		logger := newLogger(cmd, true)
 
-		payload := &azure.AzureProvider{
+		provider, err := newProvider("ha-azure", util.ClusterPayload{
 			ClusterName: azhdclusterName,
-			HACluster:   true,
 			Region:      azhdregion,
+		})
+		if err != nil {
+			logger.Err(err.Error())
+			return
 		}
-		err := payload.DeleteCluster(logger)
+		ctx, cancel := newContext()
+		defer cancel()
+		err = provider.Delete(ctx, logger)
 		if err != nil {
 			logger.Err(err.Error())
 			return
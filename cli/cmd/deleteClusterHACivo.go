@@ -7,9 +7,7 @@ Kubesimplify
 				Avinesh Tripathi <avineshtripathi1@gmail.com>
 */
 import (
-	log "github.com/kubesimplify/ksctl/api/logger"
-
-	"github.com/kubesimplify/ksctl/api/civo"
+	util "github.com/kubesimplify/ksctl/api/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -21,17 +19,18 @@ var deleteClusterHACivo = &cobra.Command{
 ksctl delete-cluster ha-civo <arguments to civo cloud provider>
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		isSet := cmd.Flags().Lookup("verbose").Changed
-		logger := log.Logger{Verbose: true}
-		if !isSet {
-			logger.Verbose = false
-		}
-		payload := civo.CivoProvider{
+		logger := newLogger(cmd, true)
+		provider, err := newProvider("ha-civo", util.ClusterPayload{
 			ClusterName: dhcclustername,
 			Region:      dhcregion,
-			HACluster:   true,
+		})
+		if err != nil {
+			logger.Err(err.Error())
+			return
 		}
-		err := payload.DeleteCluster(logger)
+		ctx, cancel := newContext()
+		defer cancel()
+		err = provider.Delete(ctx, logger)
 		if err != nil {
 			logger.Err(err.Error())
 			return
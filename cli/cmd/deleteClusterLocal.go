@@ -7,9 +7,7 @@ Kubesimplify
 				Avinesh Tripathi <avineshtripathi1@gmail.com>
 */
 import (
-	log "github.com/kubesimplify/ksctl/api/logger"
-
-	"github.com/kubesimplify/ksctl/api/local"
+	util "github.com/kubesimplify/ksctl/api/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -21,13 +19,18 @@ var deleteClusterLocal = &cobra.Command{
 ksctl delete-cluster local <arguments to local/Docker provider>
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		isSet := cmd.Flags().Lookup("verbose").Changed
-		logger := log.Logger{Verbose: true}
-		if !isSet {
-			logger.Verbose = false
-		}
+		logger := newLogger(cmd, true)
 
-		if err := local.DeleteCluster(logger, dlocalclusterName); err != nil {
+		provider, err := newProvider("local", util.ClusterPayload{
+			ClusterName: dlocalclusterName,
+		})
+		if err != nil {
+			logger.Err(err.Error())
+			return
+		}
+		ctx, cancel := newContext()
+		defer cancel()
+		if err := provider.Delete(ctx, logger); err != nil {
 			logger.Err(err.Error())
 			return
 		}
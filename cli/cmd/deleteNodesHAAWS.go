@@ -0,0 +1,61 @@
+package cmd
+
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+import (
+	util "github.com/kubesimplify/ksctl/api/utils"
+	"github.com/spf13/cobra"
+)
+
+var deleteNodesHAAws = &cobra.Command{
+	Use:   "delete-nodes",
+	Short: "Use to delete a HA AWS k3s cluster",
+	Long: `It is used to delete cluster with the given name from user. For example:
+
+ksctl delete-cluster ha-aws delete-nodes <arguments to aws cloud provider>
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := newLogger(cmd, true)
+
+		provider, err := newProvider("ha-aws", util.ClusterPayload{
+			ClusterName: awsdhdclustername,
+			Region:      awsdhdregion,
+			Spec: util.Machine{
+				HAWorkerNodes: awsdhdwp,
+			},
+		})
+		if err != nil {
+			logger.Err(err.Error())
+			return
+		}
+		ctx, cancel := newContext()
+		defer cancel()
+		err = provider.DeleteNodes(ctx, logger)
+		if err != nil {
+			logger.Err(err.Error())
+			return
+		}
+		logger.Info("DELETED WorkerNode(s)")
+	},
+}
+
+var (
+	// dw hc -> delete worker-nodes to ha-aws
+	awsdhdregion      string
+	awsdhdclustername string
+	awsdhdwp          int
+)
+
+func init() {
+	deleteClusterHAAws.AddCommand(deleteNodesHAAws)
+	deleteNodesHAAws.Flags().StringVarP(&awsdhdclustername, "name", "n", "", "Cluster name")
+	deleteNodesHAAws.Flags().StringVarP(&awsdhdregion, "region", "r", "us-east-1", "Region")
+	deleteNodesHAAws.Flags().IntVarP(&awsdhdwp, "worker-nodes", "w", 1, "no of worker nodes to delete")
+	deleteNodesHAAws.Flags().BoolP("verbose", "v", true, "for verbose output")
+	deleteNodesHAAws.MarkFlagRequired("name")
+	deleteNodesHAAws.MarkFlagRequired("region")
+}
@@ -7,10 +7,7 @@ Kubesimplify
 				Avinesh Tripathi <avineshtripathi1@gmail.com>
 */
 import (
-	log "github.com/kubesimplify/ksctl/api/logger"
-
-	"github.com/kubesimplify/ksctl/api/civo"
-	"github.com/kubesimplify/ksctl/api/utils"
+	util "github.com/kubesimplify/ksctl/api/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -22,21 +19,22 @@ var deleteNodesHACivo = &cobra.Command{
 ksctl delete-cluster ha-civo delete-nodes <arguments to civo cloud provider>
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		isSet := cmd.Flags().Lookup("verbose").Changed
-		logger := log.Logger{Verbose: true}
-		if !isSet {
-			logger.Verbose = false
-		}
+		logger := newLogger(cmd, true)
 
-		payload := civo.CivoProvider{
+		provider, err := newProvider("ha-civo", util.ClusterPayload{
 			ClusterName: dwhcclustername,
 			Region:      dwhcregion,
-			HACluster:   true,
-			Spec: utils.Machine{
+			Spec: util.Machine{
 				HAWorkerNodes: dwhcwp,
 			},
+		})
+		if err != nil {
+			logger.Err(err.Error())
+			return
 		}
-		err := payload.DeleteSomeWorkerNodes(logger)
+		ctx, cancel := newContext()
+		defer cancel()
+		err = provider.DeleteNodes(ctx, logger)
 		if err != nil {
 			logger.Err(err.Error())
 			return
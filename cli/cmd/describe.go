@@ -0,0 +1,158 @@
+package cmd
+
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/kubesimplify/ksctl/api/logger"
+	util "github.com/kubesimplify/ksctl/api/utils"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+var describeCmd = &cobra.Command{
+	Use:   "describe",
+	Short: "Use to show the details of a resource",
+	Long: `It is used to show the details of a resource. For example:
+
+ksctl describe cluster -p ha-civo -n demo -r LON1
+`,
+}
+
+var describeClusterCmd = &cobra.Command{
+	Use:   "cluster",
+	Short: "Use to show every resource of a cluster",
+	Long: `It is used to show the network, firewalls, database and nodes of a cluster
+recorded in the state management files. For example:
+
+ksctl describe cluster -p <civo,local,ha-civo,ha-azure,azure,ha-aws,aws> -n <clustername> -r <region>
+ksctl describe cluster -p ha-aws -n demo -r us-east-1 -o json
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		logging := newLogger(cmd, false)
+		switch dOutput {
+		case OUTPUT_TABLE, OUTPUT_JSON, OUTPUT_YAML:
+		default:
+			logging.Err(fmt.Sprintf("invalid --output %q, use %s, %s or %s", dOutput, OUTPUT_TABLE, OUTPUT_JSON, OUTPUT_YAML))
+			return
+		}
+		if dProvider != "local" && len(dRegion) == 0 {
+			logging.Err("Region is Required")
+			return
+		}
+		provider, err := newProvider(dProvider, util.ClusterPayload{
+			ClusterName: dClusterName,
+			Region:      dRegion,
+		})
+		if err != nil {
+			logging.Err(err.Error())
+			return
+		}
+
+		ctx, cancel := newContext()
+		defer cancel()
+		stateLogging := logging
+		if !stateLogging.Verbose {
+			stateLogging.Level = logger.LevelWarn
+		}
+		description, err := provider.Describe(ctx, stateLogging)
+		if err != nil {
+			logging.Err(err.Error())
+			return
+		}
+		if err := printDescription(description, dOutput); err != nil {
+			logging.Err(err.Error())
+		}
+	},
+}
+
+var (
+	dClusterName string
+	dRegion      string
+	dProvider    string
+	dOutput      string
+)
+
+// printDescription prints the summary of the cluster followed by the tables of its resources and nodes
+func printDescription(description util.ClusterDescription, output string) error {
+	switch output {
+	case OUTPUT_JSON:
+		raw, err := json.MarshalIndent(description, "", "  ")
+		if err != nil {
+			return fmt.Errorf("JSON Convertion failed: %w", err)
+		}
+		fmt.Println(string(raw))
+		return nil
+
+	case OUTPUT_YAML:
+		raw, err := yaml.Marshal(description)
+		if err != nil {
+			return fmt.Errorf("YAML Convertion failed: %w", err)
+		}
+		fmt.Print(string(raw))
+		return nil
+	}
+
+	orDash := func(value string) string {
+		if len(value) == 0 {
+			return "-"
+		}
+		return value
+	}
+	summary := [][]string{
+		{"Name:", description.ClusterName},
+		{"Provider:", description.Provider},
+		{"Type:", orDash(description.Type)},
+		{"Region:", orDash(description.Region)},
+		{"Node Size:", orDash(description.NodeSize)},
+		{"Control Planes:", strconv.Itoa(description.ControlPlaneNodes)},
+		{"Workers:", strconv.Itoa(description.WorkerNodes)},
+		{"Created:", orDash(description.CreatedAt)},
+		{"Kubeconfig:", orDash(description.KubeconfigPath)},
+	}
+	if len(description.DatabaseEndpoint) != 0 {
+		summary = append(summary, []string{"Database Endpoint:", description.DatabaseEndpoint})
+	}
+	for _, line := range summary {
+		fmt.Printf("%-19s%s\n", line[0], line[1])
+	}
+
+	table := logger.Logger{}
+	if len(description.Resources) != 0 {
+		rows := make([][]string, 0, len(description.Resources))
+		for _, resource := range description.Resources {
+			rows = append(rows, []string{resource.Kind, orDash(resource.Name), orDash(resource.ID)})
+		}
+		fmt.Println("\nResources:")
+		table.Table([]string{"KIND", "NAME", "ID"}, rows)
+	}
+	if len(description.Nodes) != 0 {
+		rows := make([][]string, 0, len(description.Nodes))
+		for _, node := range description.Nodes {
+			rows = append(rows, []string{node.Role, orDash(node.Name), orDash(node.ID), orDash(node.PublicIP), orDash(node.PrivateIP)})
+		}
+		fmt.Println("\nNodes:")
+		table.Table([]string{"ROLE", "NAME", "ID", "PUBLIC-IP", "PRIVATE-IP"}, rows)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(describeCmd)
+	describeCmd.AddCommand(describeClusterCmd)
+	describeClusterCmd.Flags().StringVarP(&dClusterName, "name", "n", "", "Cluster name")
+	describeClusterCmd.Flags().StringVarP(&dRegion, "region", "r", "", "Region")
+	describeClusterCmd.Flags().StringVarP(&dProvider, "provider", "p", "", "Provider")
+	describeClusterCmd.Flags().StringVarP(&dOutput, "output", "o", OUTPUT_TABLE, "Output format: table, json or yaml")
+	describeClusterCmd.Flags().BoolP("verbose", "v", false, "for verbose output")
+	describeClusterCmd.MarkFlagRequired("name")
+	describeClusterCmd.MarkFlagRequired("provider")
+}
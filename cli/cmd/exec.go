@@ -0,0 +1,123 @@
+package cmd
+
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+
+	util "github.com/kubesimplify/ksctl/api/utils"
+	"github.com/spf13/cobra"
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec <clustername> -- <command> [args...]",
+	Short: "Use to run a command with the kubeconfig of a cluster",
+	Long: `It is used to run a command with KUBECONFIG pointing at the kubeconfig of the cluster, the
+exit code of the command is returned. The cluster is looked up by its name in the clusters of every
+provider, --provider and --region choose between the clusters with the same name. For example:
+
+ksctl exec demo -- kubectl get pods -A
+ksctl exec demo -p ha-civo -r LON1 -- helm list
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if cmd.ArgsLenAtDash() != 1 || len(args) < 2 {
+			return fmt.Errorf("expected the cluster name and the command after --")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		logging := newLogger(cmd, false).With("cluster", args[0])
+		// the output of the command is not mixed with the logs
+		logging.Out = os.Stderr
+		ref, kubeconfig, err := findKubeconfig(args[0], execProvider, execRegion)
+		if err != nil {
+			logging.Err(err.Error())
+			os.Exit(1)
+		}
+		logging.Debug("using the kubeconfig", kubeconfig)
+		os.Exit(runWithKubeconfig(exec.Command(args[1], args[2:]...), ref, kubeconfig))
+	},
+}
+
+var (
+	execProvider string
+	execRegion   string
+)
+
+// findKubeconfig the cluster of the name with the path of its kubeconfig, the provider is given as on
+// the command line e.g. ha-civo and narrows the clusters down along with the region
+func findKubeconfig(name, provider, region string) (util.ClusterRef, string, error) {
+	var clusterType string
+	if len(provider) != 0 {
+		providerName, payload := resolveProvider(strings.ToLower(provider), util.ClusterPayload{ClusterName: name})
+		provider, clusterType = providerName, util.PayloadRef(providerName, payload).Type
+	}
+	refs, err := util.FindClusters(name, provider, clusterType, region)
+	if err != nil {
+		return util.ClusterRef{}, "", err
+	}
+	switch len(refs) {
+	case 0:
+		return util.ClusterRef{}, "", fmt.Errorf("cluster %s not found", name)
+	case 1:
+	default:
+		found := make([]string, 0, len(refs))
+		for _, ref := range refs {
+			found = append(found, fmt.Sprintf("%s %s %s", ref.Provider, ref.Type, ref.Region))
+		}
+		return util.ClusterRef{}, "", fmt.Errorf("found %d clusters named %s (%s), use --provider and --region to choose one",
+			len(refs), name, strings.Join(found, ", "))
+	}
+	kubeconfig, err := util.KubeconfigPath(refs[0])
+	if err != nil {
+		return refs[0], "", fmt.Errorf("unable to find the kubeconfig of the cluster %s: %v", name, err)
+	}
+	return refs[0], kubeconfig, nil
+}
+
+// runWithKubeconfig runs the command attached to the terminal and returns its exit code
+// ksctl ignores the interrupts meanwhile so that ctrl+c only reaches the command
+func runWithKubeconfig(command *exec.Cmd, ref util.ClusterRef, kubeconfig string) int {
+	command.Env = append(os.Environ(), "KUBECONFIG="+kubeconfig, "KSCTL_CLUSTER="+ref.Name, "KSCTL_PROVIDER="+ref.Provider)
+	command.Stdin, command.Stdout, command.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	err := command.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		if code := exitErr.ExitCode(); code != -1 {
+			return code
+		}
+		// killed by a signal
+		return 1
+	}
+	fmt.Fprintln(os.Stderr, err)
+	// the shells use 127 when the command is not found
+	if errors.Is(err, exec.ErrNotFound) {
+		return 127
+	}
+	return 1
+}
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+	execCmd.Flags().StringVarP(&execProvider, "provider", "p", "", "Provider, every provider when not given")
+	execCmd.Flags().StringVarP(&execRegion, "region", "r", "", "Region, every region when not given")
+	execCmd.Flags().BoolP("verbose", "v", false, "for verbose output")
+}
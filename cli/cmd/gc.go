@@ -0,0 +1,152 @@
+package cmd
+
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kubesimplify/ksctl/api/logger"
+	util "github.com/kubesimplify/ksctl/api/utils"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Use to delete the resources left behind by the failed operations",
+	Long: `It is used to find the resources named by ksctl which no state management file points at,
+e.g. the instances of a creation which was killed before it saved them, and to delete them after
+a confirmation. The resources of the clusters being created or deleted are skipped. For example:
+
+ksctl gc -p <civo,azure> -r <region>
+ksctl gc -p civo --dry-run -o json
+ksctl gc -p azure -r eastus --yes
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		logging := newLogger(cmd, !gcDryRun)
+		switch gcOutput {
+		case OUTPUT_TABLE, OUTPUT_JSON, OUTPUT_YAML:
+		default:
+			logging.Err(fmt.Sprintf("invalid --output %q, use %s, %s or %s", gcOutput, OUTPUT_TABLE, OUTPUT_JSON, OUTPUT_YAML))
+			return
+		}
+		name, _ := resolveProvider(gcProvider, util.ClusterPayload{})
+		name = strings.ToLower(name)
+		collector, err := util.GetGarbageCollector(name)
+		if err != nil {
+			logging.Err(fmt.Sprintf("%v, use one of %s", err, strings.Join(util.GarbageCollectedProviders(), ", ")))
+			return
+		}
+		if len(profile) != 0 && !util.IsValidProfile(profile) {
+			logging.Err(fmt.Sprintf("invalid profile: %s", profile))
+			return
+		}
+		if len(profile) != 0 {
+			util.UseProfile(name, profile)
+		} else {
+			util.UseProfile(name, util.DefaultProfile(name))
+		}
+
+		ctx, cancel := newContext()
+		defer cancel()
+		orphans, err := collector.Orphaned(ctx, logging, gcRegion)
+		if err != nil {
+			logging.Err(err.Error())
+			return
+		}
+		if err := printOrphans(logging, orphans, gcOutput); err != nil {
+			logging.Err(err.Error())
+			return
+		}
+		if len(orphans) == 0 || gcDryRun {
+			return
+		}
+		if !gcYes {
+			// the json and yaml output are meant for the scripts which must pass --yes to delete
+			if gcOutput != OUTPUT_TABLE {
+				return
+			}
+			logging.Print("Enter your choice to continue..[y/N]")
+			choice := "n"
+			fmt.Scanf("%s", &choice)
+			if choice != "y" && choice != "yes" && choice != "Y" {
+				return
+			}
+		}
+
+		if err := collector.Delete(ctx, logging, orphans); err != nil {
+			logging.Err(err.Error())
+			return
+		}
+		logging.Info("DELETED ORPHANED RESOURCES", fmt.Sprintf("%d", len(orphans)))
+	},
+}
+
+var (
+	gcProvider string
+	gcRegion   string
+	gcOutput   string
+	gcYes      bool
+	gcDryRun   bool
+)
+
+// printOrphans prints the orphaned resources with the estimated monthly cost
+func printOrphans(logging logger.Logger, orphans []util.OrphanedResource, output string) error {
+	switch output {
+	case OUTPUT_JSON:
+		if orphans == nil {
+			orphans = []util.OrphanedResource{}
+		}
+		raw, err := json.MarshalIndent(orphans, "", "  ")
+		if err != nil {
+			return fmt.Errorf("JSON Convertion failed: %w", err)
+		}
+		fmt.Println(string(raw))
+		return nil
+
+	case OUTPUT_YAML:
+		if orphans == nil {
+			orphans = []util.OrphanedResource{}
+		}
+		raw, err := yaml.Marshal(orphans)
+		if err != nil {
+			return fmt.Errorf("YAML Convertion failed: %w", err)
+		}
+		fmt.Print(string(raw))
+		return nil
+	}
+
+	if len(orphans) == 0 {
+		logging.Info("No orphaned resources found", "")
+		return nil
+	}
+	rows := make([][]string, 0, len(orphans))
+	for _, orphan := range orphans {
+		cost := "-"
+		if orphan.MonthlyCost != 0 {
+			cost = fmt.Sprintf("$%.2f", orphan.MonthlyCost)
+		}
+		rows = append(rows, []string{orphan.Kind, orphan.Name, orphan.ID, orphan.Region, orphan.Cluster, cost})
+	}
+	logging.Table([]string{"KIND", "NAME", "ID", "REGION", "CLUSTER", "MONTHLY-COST"}, rows)
+	logging.Note(fmt.Sprintf("%d orphaned resources cost an estimated $%.2f per month", len(orphans), util.MonthlyCost(orphans)))
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(gcCmd)
+	gcCmd.Flags().StringVarP(&gcProvider, "provider", "p", "", "Provider")
+	gcCmd.Flags().StringVarP(&gcRegion, "region", "r", "", "Region, every region of the provider when not given")
+	gcCmd.Flags().StringVarP(&gcOutput, "output", "o", OUTPUT_TABLE, "Output format: table, json or yaml")
+	gcCmd.Flags().BoolVarP(&gcYes, "yes", "y", false, "Delete without the confirmation")
+	gcCmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "Only list the orphaned resources")
+	gcCmd.Flags().BoolP("verbose", "v", false, "for verbose output")
+	gcCmd.MarkFlagRequired("provider")
+}
@@ -7,110 +7,158 @@ Kubesimplify
 */
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/kubesimplify/ksctl/api/logger"
 	util "github.com/kubesimplify/ksctl/api/utils"
 	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
 )
 
-type printer struct {
-	ClusterName string `json:"cluster_name"`
-	Region      string `json:"region"`
-	Provider    string `json:"provider"`
-}
-
 const (
-	ALL    = int(0)
-	CIVOC  = int(1)
-	LOCALC = int(2)
-    AZUREC = int(3)
+	OUTPUT_TABLE = "table"
+	OUTPUT_WIDE  = "wide"
+	OUTPUT_JSON  = "json"
+	OUTPUT_YAML  = "yaml"
 )
 
-func printUtil(cargo []byte) {
-	//TODO: Added Table type display
-	log := logger.Logger{}
-	log.Print("\n" + string(cargo))
-}
-
-// Printer
-func Printer(i int) {
-	var toBePrinted []printer
+var (
+	getClusterOutput string
+	getClusterFilter clusterFilter
+)
 
-	folders, err := os.ReadDir(util.GetPath(util.CLUSTER_PATH, "civo", "managed"))
-	if err != nil {
-		log.Fatal(err)
-	}
+// clusterFilter selects the clusters to be printed, the empty fields match every cluster
+type clusterFilter struct {
+	provider    string
+	clusterType string
+	region      string
+}
 
-	for _, file := range folders {
-		if file.IsDir() {
-			info := strings.Split(file.Name(), " ")
-			toBePrinted = append(toBePrinted, printer{ClusterName: info[0], Region: info[1], Provider: "CIVO (MANAGED)"})
+func (filter clusterFilter) validate() error {
+	if len(filter.provider) != 0 {
+		if _, err := util.GetProvider(filter.provider, util.ClusterPayload{}); err != nil {
+			return err
 		}
 	}
-
-	folders, err = os.ReadDir(util.GetPath(util.CLUSTER_PATH, "civo", "ha"))
-	if err != nil {
-		log.Fatal(err)
+	switch filter.clusterType {
+	case "", util.CLUSTER_TYPE_HA, util.CLUSTER_TYPE_MANAGED, util.CLUSTER_TYPE_LOCAL:
+		return nil
 	}
+	return fmt.Errorf("invalid --type %q, use %s, %s or %s", filter.clusterType, util.CLUSTER_TYPE_HA, util.CLUSTER_TYPE_MANAGED, util.CLUSTER_TYPE_LOCAL)
+}
 
-	for _, file := range folders {
-		if file.IsDir() {
-			info := strings.Split(file.Name(), " ")
-			toBePrinted = append(toBePrinted, printer{ClusterName: info[0], Region: info[1], Provider: "CIVO (HA)"})
-		}
+// providers the names of the providers whose state has to be read
+func (filter clusterFilter) providers() []string {
+	if len(filter.provider) != 0 {
+		return []string{strings.ToLower(filter.provider)}
 	}
+	return util.RegisteredProviders()
+}
 
-	folders, err = os.ReadDir(util.GetPath(util.CLUSTER_PATH, "azure", "ha"))
-	if err != nil {
-		log.Fatal(err)
+func (filter clusterFilter) match(cluster util.ClusterInfo) bool {
+	if len(filter.clusterType) != 0 && cluster.Type != filter.clusterType {
+		return false
+	}
+	if len(filter.region) != 0 && !strings.EqualFold(cluster.Region, filter.region) {
+		return false
 	}
+	return true
+}
 
-	for _, file := range folders {
-		if file.IsDir() {
-			info := strings.Split(file.Name(), " ")
-			toBePrinted = append(toBePrinted, printer{ClusterName: info[0], Region: info[2], Provider: "AZURE (HA)"})
+// clusterRow is the row of the table, the empty values are shown as -
+func clusterRow(cluster util.ClusterInfo, wide bool) []string {
+	orDash := func(value string) string {
+		if len(value) == 0 {
+			return "-"
 		}
+		return value
 	}
-
-	folders, err = os.ReadDir(util.GetPath(util.CLUSTER_PATH, "azure", "managed"))
-	if err != nil {
-		log.Fatal(err)
+	row := []string{
+		cluster.ClusterName,
+		cluster.Provider,
+		orDash(cluster.Type),
+		orDash(cluster.Region),
+		strconv.Itoa(cluster.ControlPlaneNodes),
+		strconv.Itoa(cluster.WorkerNodes),
+		orDash(cluster.CreatedAt),
 	}
-
-	for _, file := range folders {
-		if file.IsDir() {
-			info := strings.Split(file.Name(), " ")
-			toBePrinted = append(toBePrinted, printer{ClusterName: info[0], Region: info[2], Provider: "AZURE (MANAGED)"})
-		}
+	if wide {
+		row = append(row, orDash(cluster.NodeSize), orDash(cluster.KubeconfigPath))
 	}
+	return row
+}
+
+// Printer lists the clusters of all the providers and prints them in the output format
+func Printer(ctx context.Context, logging logger.Logger, output string, filter clusterFilter) error {
+	var toBePrinted []util.ClusterInfo
 
-	folders, err = os.ReadDir(util.GetPath(util.CLUSTER_PATH, "local"))
-	if err != nil {
-		log.Fatal(err)
+	// the messages of the providers while reading the state must not mix with the output
+	listLogging := logging
+	listLogging.Out = os.Stderr
+	if !listLogging.Verbose {
+		listLogging.Level = logger.LevelWarn
 	}
 
-	for _, file := range folders {
-		if file.IsDir() {
-			toBePrinted = append(toBePrinted, printer{ClusterName: file.Name(), Region: "N/A", Provider: "local"})
+	for _, name := range filter.providers() {
+		provider, err := util.GetProvider(name, util.ClusterPayload{})
+		if err != nil {
+			return err
+		}
+		clusters, err := provider.List(ctx, listLogging)
+		if err != nil {
+			return fmt.Errorf("unable to list the %s clusters: %w", name, err)
+		}
+		for _, cluster := range clusters {
+			if filter.match(cluster) {
+				toBePrinted = append(toBePrinted, cluster)
+			}
 		}
 	}
 
-	arr, err := json.MarshalIndent(toBePrinted, "", "  ")
-	if err != nil {
-		panic(fmt.Errorf("JSON Convertion failed"))
-	}
-	if len(toBePrinted) == 0 {
-		log := logger.Logger{}
-		log.Info("No clusters found", "")
-	} else {
-		printUtil(arr)
+	switch output {
+	case OUTPUT_JSON:
+		if toBePrinted == nil {
+			toBePrinted = []util.ClusterInfo{}
+		}
+		raw, err := json.MarshalIndent(toBePrinted, "", "  ")
+		if err != nil {
+			return fmt.Errorf("JSON Convertion failed: %w", err)
+		}
+		fmt.Println(string(raw))
+
+	case OUTPUT_YAML:
+		if toBePrinted == nil {
+			toBePrinted = []util.ClusterInfo{}
+		}
+		raw, err := yaml.Marshal(toBePrinted)
+		if err != nil {
+			return fmt.Errorf("YAML Convertion failed: %w", err)
+		}
+		fmt.Print(string(raw))
 
+	default:
+		if len(toBePrinted) == 0 {
+			logging.Info("No clusters found", "")
+			return nil
+		}
+		wide := output == OUTPUT_WIDE
+		headers := []string{"NAME", "PROVIDER", "TYPE", "REGION", "CONTROLPLANES", "WORKERS", "CREATED"}
+		if wide {
+			headers = append(headers, "NODE-SIZE", "KUBECONFIG")
+		}
+		rows := make([][]string, 0, len(toBePrinted))
+		for _, cluster := range toBePrinted {
+			rows = append(rows, clusterRow(cluster, wide))
+		}
+		table := logger.Logger{}
+		table.Table(headers, rows)
 	}
+	return nil
 }
 
 // viewClusterCmd represents the viewCluster command
@@ -120,14 +168,36 @@ var getClusterCmd = &cobra.Command{
 	Short:   "Use to get clusters",
 	Long: `It is used to view clusters. For example:
 
-ksctl get-clusters `,
+ksctl get-clusters
+ksctl get-clusters -o wide
+ksctl get-clusters -o json
+ksctl get-clusters --provider civo --type ha --region LON1`,
 	Run: func(cmd *cobra.Command, args []string) {
-		Printer(ALL)
-		//Printer(CIVOC)
-		//Printer(LOCALC)
+		ctx, cancel := newContext()
+		defer cancel()
+		logging := newLogger(cmd, false)
+		switch getClusterOutput {
+		case OUTPUT_TABLE, OUTPUT_WIDE, OUTPUT_JSON, OUTPUT_YAML:
+		default:
+			logging.Err(fmt.Sprintf("invalid --output %q, use %s, %s, %s or %s", getClusterOutput, OUTPUT_TABLE, OUTPUT_WIDE, OUTPUT_JSON, OUTPUT_YAML))
+			return
+		}
+		if err := getClusterFilter.validate(); err != nil {
+			logging.Err(err.Error())
+			return
+		}
+		if err := Printer(ctx, logging, getClusterOutput, getClusterFilter); err != nil {
+			logging.Err(err.Error())
+			return
+		}
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(getClusterCmd)
+	getClusterCmd.Flags().StringVarP(&getClusterOutput, "output", "o", OUTPUT_TABLE, "Output format: table, wide, json or yaml")
+	getClusterCmd.Flags().StringVarP(&getClusterFilter.provider, "provider", "p", "", "Only the clusters of the provider: "+strings.Join(util.RegisteredProviders(), ", "))
+	getClusterCmd.Flags().StringVarP(&getClusterFilter.clusterType, "type", "t", "", "Only the clusters of the type: ha, managed or local")
+	getClusterCmd.Flags().StringVarP(&getClusterFilter.region, "region", "r", "", "Only the clusters in the region")
+	getClusterCmd.Flags().BoolP("verbose", "v", false, "for verbose output")
 }
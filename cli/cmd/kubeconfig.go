@@ -0,0 +1,172 @@
+package cmd
+
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	util "github.com/kubesimplify/ksctl/api/utils"
+	"github.com/spf13/cobra"
+)
+
+var kubeconfigCmd = &cobra.Command{
+	Use:   "kubeconfig",
+	Short: "Use to get, refresh or write the kubeconfig of a cluster",
+	Long: `It is used to get the kubeconfig saved for a cluster, to fetch it again from the provider e.g.
+after the certificates of the cluster were rotated, and to write it into a kubeconfig file.
+With --user and --namespace a service account is created in the namespace with the cluster role
+given by --role and the kubeconfig uses its token instead of the admin credentials. The kubeconfig
+of the aws managed cluster authenticates with the aws cli so it cannot be scoped.
+The kubeconfig of the azure managed cluster has the user credentials of AKS, with --admin get and
+write fetch its admin credentials and refresh saves them. For example:
+
+ksctl kubeconfig get <clustername> -p <civo,local,ha-civo,ha-azure,azure,ha-aws,aws> -r <region>
+ksctl kubeconfig refresh demo -p ha-civo -r LON1
+ksctl kubeconfig write demo -p azure -r eastus --output ~/.kube/config
+ksctl kubeconfig get demo -p azure -r eastus --admin
+ksctl kubeconfig get demo -p ha-civo -r LON1 --user dev --namespace apps --role view --output dev.yaml
+`,
+}
+
+var kubeconfigGetCmd = &cobra.Command{
+	Use:   "get <clustername>",
+	Short: "Print the kubeconfig of the cluster, or save it to --output",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runKubeconfig(cmd, args[0], false, false)
+	},
+}
+
+var kubeconfigRefreshCmd = &cobra.Command{
+	Use:   "refresh <clustername>",
+	Short: "Fetch the kubeconfig of the cluster again from the provider and save it",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runKubeconfig(cmd, args[0], true, false)
+	},
+}
+
+var kubeconfigWriteCmd = &cobra.Command{
+	Use:   "write <clustername>",
+	Short: "Merge the kubeconfig of the cluster into --output, ~/.kube/config by default",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runKubeconfig(cmd, args[0], false, true)
+	},
+}
+
+var (
+	kcProvider  string
+	kcRegion    string
+	kcOutput    string
+	kcUser      string
+	kcNamespace string
+	kcRole      string
+	kcDuration  time.Duration
+	kcAdmin     bool
+)
+
+// runKubeconfig the kubeconfig is refreshed first when asked, then scoped to the user and written
+// to --output which is merged into when write is set
+func runKubeconfig(cmd *cobra.Command, clusterName string, refresh, write bool) {
+	logging := newLogger(cmd, refresh).With("cluster", clusterName)
+	if !refresh && !write && len(kcOutput) == 0 {
+		// the kubeconfig is printed on stdout
+		logging.Out = os.Stderr
+	}
+	if kcProvider != "local" && len(kcRegion) == 0 {
+		logging.Err("Region is Required")
+		return
+	}
+	scoped := len(kcUser) != 0 || len(kcNamespace) != 0
+	if scoped && kcProvider == "aws" {
+		// the EKS kubeconfig runs the aws cli to get its token which ksctl doesn't do
+		logging.Err("--user and --namespace are not supported by the aws managed clusters")
+		return
+	}
+	if kcAdmin && kcProvider != "azure" {
+		logging.Err("--admin is only used by the azure managed clusters, the other kubeconfigs have admin credentials")
+		return
+	}
+	payload := util.ClusterPayload{ClusterName: clusterName, Region: kcRegion, AdminKubeconfig: kcAdmin}
+	provider, err := newProvider(kcProvider, payload)
+	if err != nil {
+		logging.Err(err.Error())
+		return
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+	kubeconfig, err := provider.Kubeconfig(ctx, logging, refresh)
+	if err != nil {
+		logging.Err(err.Error())
+		return
+	}
+	if refresh {
+		logging.Info("Refreshed the kubeconfig", clusterName)
+	}
+	if scoped {
+		kubeconfig, err = util.ScopedKubeconfig(ctx, kubeconfig, util.KubeconfigScope{
+			User:      kcUser,
+			Namespace: kcNamespace,
+			Role:      kcRole,
+			Duration:  kcDuration,
+		})
+		if err != nil {
+			logging.Err(err.Error())
+			return
+		}
+		logging.Note(fmt.Sprintf("the kubeconfig can %s in the namespace %s, its token expires in %v", kcRole, kcNamespace, kcDuration))
+	}
+
+	switch {
+	case write:
+		name, payload := resolveProvider(kcProvider, payload)
+		contextName := util.KubeconfigContextName(util.PayloadRef(name, payload))
+		if scoped {
+			contextName += "-" + kcUser
+		}
+		path := kcOutput
+		if len(path) == 0 {
+			path = util.DefaultKubeconfigPath()
+		}
+		if err := util.MergeKubeconfig(path, contextName, kubeconfig); err != nil {
+			logging.Err(err.Error())
+			return
+		}
+		logging.Info("Written the context "+contextName, path)
+
+	case len(kcOutput) != 0:
+		if err := os.WriteFile(kcOutput, kubeconfig, 0600); err != nil {
+			logging.Err(err.Error())
+			return
+		}
+		logging.Info("Saved the kubeconfig", kcOutput)
+
+	case !refresh:
+		fmt.Print(string(kubeconfig))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(kubeconfigCmd)
+	kubeconfigCmd.AddCommand(kubeconfigGetCmd, kubeconfigRefreshCmd, kubeconfigWriteCmd)
+	flags := kubeconfigCmd.PersistentFlags()
+	flags.StringVarP(&kcProvider, "provider", "p", "", "Provider")
+	flags.StringVarP(&kcRegion, "region", "r", "", "Region")
+	flags.StringVarP(&kcOutput, "output", "o", "", "Path of the kubeconfig to write")
+	flags.StringVar(&kcUser, "user", "", "Service account of the scoped kubeconfig, not supported by the aws managed clusters")
+	flags.StringVar(&kcNamespace, "namespace", "", "Namespace of the scoped kubeconfig")
+	flags.StringVar(&kcRole, "role", "edit", "Cluster role of the scoped kubeconfig in its namespace e.g. edit or view")
+	flags.DurationVar(&kcDuration, "duration", 24*time.Hour, "Validity of the token of the scoped kubeconfig")
+	flags.BoolVar(&kcAdmin, "admin", false, "Use the admin credentials of the azure managed cluster instead of its user credentials")
+	flags.BoolP("verbose", "v", false, "for verbose output")
+	kubeconfigCmd.MarkPersistentFlagRequired("provider")
+}
@@ -0,0 +1,94 @@
+package cmd
+
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/kubesimplify/ksctl/api/logger"
+	util "github.com/kubesimplify/ksctl/api/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logFormat string
+	noColor   bool
+
+	// logFiles opened by the operations, closed when the command returns
+	logFiles []*os.File
+)
+
+// validateLogFlags is run before every command
+func validateLogFlags(cmd *cobra.Command, args []string) error {
+	if !log.IsValidFormat(logFormat) {
+		return fmt.Errorf("invalid --log-format %q, use %s or %s", logFormat, log.FORMAT_TEXT, log.FORMAT_JSON)
+	}
+	return nil
+}
+
+// newLogger returns the logger configured by the global flags with the provider, cluster and region
+// of the command as fields, when logToFile is set the messages are also written to ~/.ksctl/logs
+func newLogger(cmd *cobra.Command, logToFile bool) log.Logger {
+	logger := log.Logger{Format: logFormat, NoColor: noColor}
+	if flag := cmd.Flags().Lookup("verbose"); flag != nil {
+		logger.Verbose = flag.Changed
+	}
+
+	var fields []string
+	// the provider is the --provider flag or the subcommand of create-cluster, delete-cluster, ...
+	if flag := cmd.Flags().Lookup("provider"); flag != nil {
+		if len(flag.Value.String()) != 0 {
+			fields = append(fields, "provider", flag.Value.String())
+		}
+	} else if cmd.HasParent() && cmd.Parent() != rootCmd {
+		fields = append(fields, "provider", cmd.Name())
+	}
+	for _, flagField := range [][2]string{{"name", "cluster"}, {"region", "region"}} {
+		if flag := cmd.Flags().Lookup(flagField[0]); flag != nil && len(flag.Value.String()) != 0 {
+			fields = append(fields, flagField[1], flag.Value.String())
+		}
+	}
+	logger = logger.With(fields...)
+
+	if logToFile {
+		file, err := openLogFile(cmd)
+		if err != nil {
+			logger.Warn("unable to create the log file", err.Error())
+		} else {
+			logger.Sinks = append(logger.Sinks, file)
+			logger.Debug("writing the logs to", file.Name())
+		}
+	}
+	return logger
+}
+
+// openLogFile creates the log file of the operation, e.g. ~/.ksctl/logs/create-cluster-ha-civo-20230712-150405.log
+func openLogFile(cmd *cobra.Command) (*os.File, error) {
+	if err := os.MkdirAll(util.GetPath(util.LOG_PATH, ""), 0750); err != nil {
+		return nil, err
+	}
+	operation := strings.ReplaceAll(strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()+" "), " ", "-")
+	name := fmt.Sprintf("%s-%s.log", operation, time.Now().Format("20060102-150405"))
+
+	file, err := os.OpenFile(util.GetPath(util.LOG_PATH, "", name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return nil, err
+	}
+	logFiles = append(logFiles, file)
+	return file, nil
+}
+
+func closeLogFiles() {
+	for _, file := range logFiles {
+		_ = file.Close()
+	}
+	logFiles = nil
+}
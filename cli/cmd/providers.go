@@ -0,0 +1,40 @@
+package cmd
+
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+import (
+	"strings"
+
+	util "github.com/kubesimplify/ksctl/api/utils"
+
+	// providers register themselves with the utils package
+	_ "github.com/kubesimplify/ksctl/api/aws"
+	_ "github.com/kubesimplify/ksctl/api/azure"
+	_ "github.com/kubesimplify/ksctl/api/civo"
+	_ "github.com/kubesimplify/ksctl/api/local"
+)
+
+// resolveProvider splits the provider name used on the command line ("civo", "ha-civo", ...)
+// into the registered provider name with the HA toggle of the payload set accordingly
+func resolveProvider(name string, payload util.ClusterPayload) (string, util.ClusterPayload) {
+	if strings.HasPrefix(name, "ha-") {
+		name = strings.TrimPrefix(name, "ha-")
+		payload.HACluster = true
+	}
+	return name, payload
+}
+
+// profile of the credentials used by the provider, see util.ClusterPayload
+var profile string
+
+// newProvider returns the registered provider for the name used on the command line
+func newProvider(name string, payload util.ClusterPayload) (util.Provider, error) {
+	name, payload = resolveProvider(name, payload)
+	payload.Profile = profile
+	return util.GetProvider(name, payload)
+}
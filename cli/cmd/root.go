@@ -8,8 +8,13 @@ Kubesimplify
 package cmd
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	util "github.com/kubesimplify/ksctl/api/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -22,23 +27,53 @@ from local clusters to cloud provider specific clusters.`,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRunE: persistentPreRun,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	err := rootCmd.Execute()
+	closeLogFiles()
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
+// timeout is the global limit on the time taken by the provider operations, 0 means no limit
+var timeout time.Duration
+
+// newContext returns the context passed to the provider operations
+// it is cancelled on SIGINT or SIGTERM and when the --timeout expires
+// so that the polling and the ssh retries stop with the state saved for later deletion
+// the requests to the state store are bound to it as well
+func newContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if timeout <= 0 {
+		util.SetStateContext(ctx)
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	util.SetStateContext(ctx)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
 func init() {
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
 	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.Kubesimpctl.yaml)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "format of the logs (text or json)")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "disable the colours of the text logs")
+	rootCmd.PersistentFlags().StringVar(&stateStore, "state-store", defaultStateStore(), "where the state of the clusters is kept (local or s3), s3 is configured by ksctl cred or the KSCTL_S3_* environment variables")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "maximum time for the operation (e.g. 30m), 0 means no limit")
+	rootCmd.PersistentFlags().DurationVar(&lockWait, "lock-wait", 0, "time to wait for the lock of the cluster held by another operation (e.g. 5m), 0 fails immediately")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "profile of the credentials, the one the cluster was created with or the default profile of the provider when not given")
+	rootCmd.PersistentFlags().StringVar(&keyFile, "key-file", "", "file used instead of the passphrase to encrypt the credentials, the KSCTL_PASSPHRASE and KSCTL_KEY_FILE environment variables are used otherwise")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -0,0 +1,101 @@
+package cmd
+
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	util "github.com/kubesimplify/ksctl/api/utils"
+	"github.com/spf13/cobra"
+)
+
+var shellCmd = &cobra.Command{
+	Use:   "shell <clustername>",
+	Short: "Use to open a shell with the kubeconfig of a cluster",
+	Long: `It is used to start $SHELL with KUBECONFIG pointing at the kubeconfig of the cluster, exit the
+shell to go back. The prompt of bash starts with (ksctl:<provider>/<clustername>), the other shells
+can show $KSCTL_CLUSTER in their prompt. The cluster is looked up like ksctl exec does. For example:
+
+ksctl shell demo
+ksctl shell demo -p ha-azure -r eastus
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		logging := newLogger(cmd, false).With("cluster", args[0])
+		ref, kubeconfig, err := findKubeconfig(args[0], shellProvider, shellRegion)
+		if err != nil {
+			logging.Err(err.Error())
+			os.Exit(1)
+		}
+		if current := os.Getenv("KSCTL_CLUSTER"); len(current) != 0 {
+			logging.Warn("Already in the shell of the cluster " + current + ", exit it to go back")
+		}
+
+		shell, rcFile, err := shellCommand(ref)
+		if err != nil {
+			logging.Err(err.Error())
+			os.Exit(1)
+		}
+		logging.Info("Starting a shell for the cluster", ref.Name)
+		code := runWithKubeconfig(shell, ref, kubeconfig)
+		if len(rcFile) != 0 {
+			_ = os.Remove(rcFile)
+		}
+		logging.Info("Exited the shell of the cluster", ref.Name)
+		if code != 0 {
+			os.Exit(code)
+		}
+	},
+}
+
+var (
+	shellProvider string
+	shellRegion   string
+)
+
+// shellCommand the shell of the user with the prompt marker of the cluster
+// bash reads the rc file returned which is removed once the shell exits
+func shellCommand(ref util.ClusterRef) (*exec.Cmd, string, error) {
+	marker := fmt.Sprintf("(ksctl:%s/%s) ", ref.Provider, ref.Name)
+	shell := os.Getenv("SHELL")
+	if len(shell) == 0 {
+		shell = "/bin/sh"
+		if runtime.GOOS == "windows" {
+			shell = "powershell.exe"
+		}
+	}
+	if filepath.Base(shell) != "bash" {
+		command := exec.Command(shell)
+		command.Env = append(os.Environ(), "PS1="+marker+os.Getenv("PS1"))
+		return command, "", nil
+	}
+
+	// bash sets PS1 in ~/.bashrc so the marker is added after reading it
+	rc, err := os.CreateTemp("", "ksctl-bashrc-*")
+	if err != nil {
+		return nil, "", err
+	}
+	defer rc.Close()
+	script := fmt.Sprintf("[ -f ~/.bashrc ] && . ~/.bashrc\nPS1=%q\"$PS1\"\n", marker)
+	if _, err := rc.WriteString(script); err != nil {
+		os.Remove(rc.Name())
+		return nil, "", err
+	}
+	return exec.Command(shell, "--rcfile", rc.Name(), "-i"), rc.Name(), nil
+}
+
+func init() {
+	rootCmd.AddCommand(shellCmd)
+	shellCmd.Flags().StringVarP(&shellProvider, "provider", "p", "", "Provider, every provider when not given")
+	shellCmd.Flags().StringVarP(&shellRegion, "region", "r", "", "Region, every region when not given")
+	shellCmd.Flags().BoolP("verbose", "v", false, "for verbose output")
+}
@@ -0,0 +1,58 @@
+package cmd
+
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+import (
+	"os"
+	"time"
+
+	log "github.com/kubesimplify/ksctl/api/logger"
+	util "github.com/kubesimplify/ksctl/api/utils"
+	"github.com/spf13/cobra"
+)
+
+// noStateStore is the annotation of the commands which don't read the state
+// e.g. the s3 state store is configured by the cred command so it cannot depend on it
+const noStateStore = "ksctl/no-state-store"
+
+// stateStore is where the state of the clusters is kept, the KSCTL_STATE_STORE environment variable sets the default
+var stateStore string
+
+func defaultStateStore() string {
+	if store, ok := os.LookupEnv("KSCTL_STATE_STORE"); ok {
+		return store
+	}
+	return util.STATE_STORE_LOCAL
+}
+
+// setupStateStore selects the state store of the providers before the command is run
+func setupStateStore(cmd *cobra.Command, args []string) error {
+	if _, skip := cmd.Annotations[noStateStore]; skip {
+		return nil
+	}
+	store, err := util.NewStateStore(stateStore)
+	if err != nil {
+		return err
+	}
+	util.SetStateStore(store)
+	// the state of the clusters created by the older versions is moved to the current layout
+	return util.MigrateLayout(log.Logger{Format: logFormat, NoColor: noColor})
+}
+
+// lockWait is how long the create, delete, add-nodes and delete-nodes operations wait for the lock of the cluster
+var lockWait time.Duration
+
+// persistentPreRun is run before every command
+func persistentPreRun(cmd *cobra.Command, args []string) error {
+	if err := validateLogFlags(cmd, args); err != nil {
+		return err
+	}
+	util.SetLockWait(lockWait)
+	util.SetCredentialKeyFile(keyFile)
+	return setupStateStore(cmd, args)
+}
@@ -1,10 +1,7 @@
 package cmd
 
 import (
-	"github.com/kubesimplify/ksctl/api/azure"
-	"github.com/kubesimplify/ksctl/api/civo"
-	"github.com/kubesimplify/ksctl/api/local"
-	"github.com/kubesimplify/ksctl/api/logger"
+	util "github.com/kubesimplify/ksctl/api/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -12,59 +9,30 @@ var switchCluster = &cobra.Command{
 	Use:     "switch-cluster",
 	Aliases: []string{"switch"},
 	Short:   "Use to switch between clusters",
-	Long: `It is used to switch cluster with the given clusterName from user. For example:
+	Long: `It is used to switch cluster with the given clusterName from user. The kubeconfig of the cluster
+is merged into ~/.kube/config under the context ksctl-<provider>-<cluster ID> which is made the
+current context, so kubectl uses the cluster without exporting KUBECONFIG. For example:
 
-ksctl switch-context -p <civo,local,ha-civo,ha-azure,azure>  -n <clustername> -r <region> <arguments to civo cloud provider>
+ksctl switch-context -p <civo,local,ha-civo,ha-azure,azure,ha-aws,aws>  -n <clustername> -r <region> <arguments to civo cloud provider>
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 
-		log := logger.Logger{}
-		switch sprovider {
-		case "local":
-			err := local.SwitchContext(log, sclusterName)
-			if err != nil {
-				log.Err(err.Error())
-			}
-		case "civo", "ha-civo":
-			if len(sregion) == 0 {
-				log.Err("Region is Required")
-			}
-			payload := civo.CivoProvider{
-				ClusterName: sclusterName,
-				Region:      sregion,
-			}
-			if "civo" == sprovider {
-				payload.HACluster = false
-			} else {
-				payload.HACluster = true
-			}
-
-			err := payload.SwitchContext(log)
-			if err != nil {
-				log.Err(err.Error())
-			}
-
-		case "azure", "ha-azure":
-			if len(sregion) == 0 {
-				log.Err("Region is Required")
-			}
-			payload := azure.AzureProvider{
-				ClusterName: sclusterName,
-				Region:      sregion,
-			}
-			if "azure" == sprovider {
-				payload.HACluster = false
-			} else {
-				payload.HACluster = true
-			}
-			err := payload.SwitchContext(log)
-			if err != nil {
-				log.Err(err.Error())
-			}
-		case "aws":
-			log.Warn("UNDER DEVELOPMENT")
-		default:
-			log.Err("invalid provider!")
+		log := newLogger(cmd, false)
+		if sprovider != "local" && len(sregion) == 0 {
+			log.Err("Region is Required")
+		}
+		provider, err := newProvider(sprovider, util.ClusterPayload{
+			ClusterName: sclusterName,
+			Region:      sregion,
+		})
+		if err != nil {
+			log.Err(err.Error())
+			return
+		}
+		ctx, cancel := newContext()
+		defer cancel()
+		if err := provider.SwitchContext(ctx, log); err != nil {
+			log.Err(err.Error())
 		}
 	},
 }
@@ -0,0 +1,72 @@
+package cmd
+
+/*
+Kubesimplify
+@maintainer: 	Dipankar Das <dipankardas0115@gmail.com>
+				Anurag Kumar <contact.anurag7@gmail.com>
+				Avinesh Tripathi <avineshtripathi1@gmail.com>
+*/
+
+import (
+	"fmt"
+	"strings"
+
+	util "github.com/kubesimplify/ksctl/api/utils"
+	"github.com/spf13/cobra"
+)
+
+var unlockCmd = &cobra.Command{
+	Use:   "unlock",
+	Short: "Use to remove the lock of a cluster",
+	Long: `The create, delete, add-nodes and delete-nodes operations lock the cluster so that
+they don't run concurrently. When an operation is killed before it releases the lock,
+it is removed by this command. Make sure that no operation is running on the cluster. For example:
+
+ksctl unlock -p <civo,local,ha-civo,ha-azure,azure,ha-aws,aws> -n <clustername> -r <region>
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		logging := newLogger(cmd, true)
+		name, payload := resolveProvider(uProvider, util.ClusterPayload{
+			ClusterName: uClusterName,
+			Region:      uRegion,
+		})
+		name = strings.ToLower(name)
+		found := false
+		for _, provider := range util.RegisteredProviders() {
+			found = found || provider == name
+		}
+		if !found {
+			logging.Err(fmt.Sprintf("invalid provider: %s", uProvider))
+			return
+		}
+		if name != "local" && len(uRegion) == 0 {
+			logging.Err("Region is Required")
+			return
+		}
+
+		info, err := util.ForceUnlock(name, payload)
+		if err != nil {
+			logging.Err(err.Error())
+			return
+		}
+		if len(info.Owner) != 0 {
+			logging.Note("Lock was held by", info.String())
+		}
+		logging.Info("UNLOCKED CLUSTER", uClusterName)
+	},
+}
+
+var (
+	uClusterName string
+	uRegion      string
+	uProvider    string
+)
+
+func init() {
+	rootCmd.AddCommand(unlockCmd)
+	unlockCmd.Flags().StringVarP(&uClusterName, "name", "n", "", "Cluster name")
+	unlockCmd.Flags().StringVarP(&uRegion, "region", "r", "", "Region")
+	unlockCmd.Flags().StringVarP(&uProvider, "provider", "p", "", "Provider")
+	unlockCmd.MarkFlagRequired("name")
+	unlockCmd.MarkFlagRequired("provider")
+}
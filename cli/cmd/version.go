@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 
+	util "github.com/kubesimplify/ksctl/api/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -11,8 +12,7 @@ const ksctl = `
 | | _____  ___| |_| |
 | |/ / __|/ __| __| |
 |   <\__ \ (__| |_| |
-|_|\_\___/\___|\__|_|
-`
+|_|\_\___/\___|\__|_|`
 
 // change this using ldflags
 var Version string = "dev"
@@ -32,4 +32,7 @@ var versionCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(versionCmd)
+	// the version is recorded in the ownership tags of the created resources
+	util.KsctlVersion = Version
+	versionCmd.Annotations = map[string]string{noStateStore: ""}
 }
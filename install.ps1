@@ -17,6 +17,9 @@ mkdir -Force $env:USERPROFILE\.ksctl\config\civo\managed
 mkdir -Force $env:USERPROFILE\.ksctl\config\azure
 mkdir -Force $env:USERPROFILE\.ksctl\config\azure\ha
 mkdir -Force $env:USERPROFILE\.ksctl\config\azure\managed
+mkdir -Force $env:USERPROFILE\.ksctl\config\aws
+mkdir -Force $env:USERPROFILE\.ksctl\config\aws\ha
+mkdir -Force $env:USERPROFILE\.ksctl\config\aws\managed
 mkdir -Force $env:USERPROFILE\.ksctl\config\local
 
 Write-Host "Available Releases"  -ForegroundColor Cyan